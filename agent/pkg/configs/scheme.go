@@ -27,6 +27,8 @@ import (
 	appsubv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1"
 	appsubv1alpha1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1alpha1"
 	appv1beta1 "sigs.k8s.io/application/api/v1beta1"
+
+	operatorv1alpha1 "github.com/stolostron/multicluster-global-hub/operator/api/operator/v1alpha1"
 )
 
 func GetRuntimeScheme() *runtime.Scheme {
@@ -52,5 +54,6 @@ func GetRuntimeScheme() *runtime.Scheme {
 	utilruntime.Must(clusterinfov1beta1.AddToScheme(scheme))
 	utilruntime.Must(klusterletv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(addonv1.SchemeBuilder.AddToScheme(scheme))
+	utilruntime.Must(operatorv1alpha1.AddToScheme(scheme))
 	return scheme
 }
@@ -0,0 +1,114 @@
+package allowlist
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/stolostron/multicluster-global-hub/operator/api/operator/v1alpha1"
+)
+
+const all = "*"
+
+// AllowLists are the rules of all the GlobalResourceAllowLists on the hub. The nil AllowLists allow all the objects,
+// which is the case if the hub admin doesn't create any allow list.
+type AllowLists struct {
+	names []string
+	rules []rule
+}
+
+type rule struct {
+	operatorv1alpha1.GlobalResourceAllowRule
+	selector labels.Selector
+}
+
+// Load reads the allow lists from the hub, it returns nil if there is no allow list or the CRD isn't installed.
+// The reader should be uncached, so the changes of the allow lists take effect with the next bundle.
+func Load(ctx context.Context, reader client.Reader) (*AllowLists, error) {
+	allowLists := &operatorv1alpha1.GlobalResourceAllowListList{}
+	if err := reader.List(ctx, allowLists); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list the global resource allow lists: %w", err)
+	}
+	if len(allowLists.Items) == 0 {
+		return nil, nil
+	}
+
+	loaded := &AllowLists{}
+	for _, allowList := range allowLists.Items {
+		loaded.names = append(loaded.names, allowList.Name)
+		for _, allowRule := range allowList.Spec.Rules {
+			selector := labels.Everything()
+			if allowRule.LabelSelector != nil {
+				var err error
+				if selector, err = metav1.LabelSelectorAsSelector(allowRule.LabelSelector); err != nil {
+					// the invalid rule allows nothing, the other rules still apply
+					selector = labels.Nothing()
+				}
+			}
+			loaded.rules = append(loaded.rules, rule{GlobalResourceAllowRule: allowRule, selector: selector})
+		}
+	}
+	sort.Strings(loaded.names)
+	return loaded, nil
+}
+
+// Check returns the error telling why the object isn't allowed, or nil if it's allowed by any rule
+func (a *AllowLists) Check(obj *unstructured.Unstructured) error {
+	return a.check(obj, true)
+}
+
+// CheckDeletion returns the error telling why the deletion of the object isn't allowed. The deleted object is only
+// the identity without the labels, so the label selectors of the rules aren't checked, the object is still only
+// deleted if it's delivered by the global hub.
+func (a *AllowLists) CheckDeletion(obj *unstructured.Unstructured) error {
+	return a.check(obj, false)
+}
+
+func (a *AllowLists) check(obj *unstructured.Unstructured, matchLabels bool) error {
+	if a == nil {
+		return nil
+	}
+	for _, allowRule := range a.rules {
+		if allowRule.allows(obj, matchLabels) {
+			return nil
+		}
+	}
+	name := obj.GetName()
+	if obj.GetNamespace() != "" {
+		name = obj.GetNamespace() + "/" + name
+	}
+	return fmt.Errorf("the %s %s isn't allowed by the allow lists %v of the hub",
+		obj.GroupVersionKind().GroupKind().String(), name, a.names)
+}
+
+func (r *rule) allows(obj *unstructured.Unstructured, matchLabels bool) bool {
+	gvk := obj.GroupVersionKind()
+	if !matches(r.APIGroups, gvk.Group, false) || !matches(r.Versions, gvk.Version, true) ||
+		!matches(r.Kinds, gvk.Kind, false) || !matches(r.Namespaces, obj.GetNamespace(), true) {
+		return false
+	}
+	return !matchLabels || r.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// matches returns true if the values contain the value or "*", the empty values match all if emptyAll is true
+func matches(values []string, value string, emptyAll bool) bool {
+	if len(values) == 0 {
+		return emptyAll
+	}
+	for _, v := range values {
+		if v == value || v == all {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package allowlist
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/stolostron/multicluster-global-hub/operator/api/operator/v1alpha1"
+)
+
+func newObject(apiVersion, kind, namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func TestAllowLists(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := operatorv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// all the objects are allowed without any allow list
+	allowLists, err := Load(ctx, fake.NewClientBuilder().WithScheme(scheme).Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := allowLists.Check(newObject("v1", "ConfigMap", "default", "cm1", nil)); err != nil {
+		t.Errorf("expected the object is allowed without the allow lists, but got %v", err)
+	}
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&operatorv1alpha1.GlobalResourceAllowList{
+			ObjectMeta: metav1.ObjectMeta{Name: "policies"},
+			Spec: operatorv1alpha1.GlobalResourceAllowListSpec{
+				Rules: []operatorv1alpha1.GlobalResourceAllowRule{{
+					APIGroups:  []string{"policy.open-cluster-management.io"},
+					Kinds:      []string{"Policy", "PlacementBinding"},
+					Namespaces: []string{"global-policies"},
+				}},
+			},
+		},
+		&operatorv1alpha1.GlobalResourceAllowList{
+			ObjectMeta: metav1.ObjectMeta{Name: "configmaps"},
+			Spec: operatorv1alpha1.GlobalResourceAllowListSpec{
+				Rules: []operatorv1alpha1.GlobalResourceAllowRule{{
+					APIGroups: []string{""},
+					Versions:  []string{"v1"},
+					Kinds:     []string{all},
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"global-hub.open-cluster-management.io/allowed": "true"},
+					},
+				}},
+			},
+		},
+	).Build()
+	allowLists, err = Load(ctx, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowedLabels := map[string]string{"global-hub.open-cluster-management.io/allowed": "true"}
+	cases := []struct {
+		name    string
+		obj     *unstructured.Unstructured
+		allowed bool
+	}{
+		{
+			name: "the policy in the allowed namespace",
+			obj: newObject("policy.open-cluster-management.io/v1", "Policy", "global-policies",
+				"policy1", nil),
+			allowed: true,
+		},
+		{
+			name:    "the policy in the other namespace",
+			obj:     newObject("policy.open-cluster-management.io/v1", "Policy", "default", "policy1", nil),
+			allowed: false,
+		},
+		{
+			name: "the kind isn't allowed",
+			obj: newObject("policy.open-cluster-management.io/v1", "PolicySet", "global-policies",
+				"set1", nil),
+			allowed: false,
+		},
+		{
+			name:    "the core object with the allowed labels",
+			obj:     newObject("v1", "ConfigMap", "default", "cm1", allowedLabels),
+			allowed: true,
+		},
+		{
+			name:    "the cluster scoped core object with the allowed labels",
+			obj:     newObject("v1", "Namespace", "", "ns1", allowedLabels),
+			allowed: true,
+		},
+		{
+			name:    "the core object without the allowed labels",
+			obj:     newObject("v1", "ConfigMap", "default", "cm1", nil),
+			allowed: false,
+		},
+		{
+			name:    "the object of the other group with the allowed labels",
+			obj:     newObject("apps/v1", "Deployment", "default", "deploy1", allowedLabels),
+			allowed: false,
+		},
+	}
+	for _, c := range cases {
+		err := allowLists.Check(c.obj)
+		if c.allowed && err != nil {
+			t.Errorf("%s: expected the object is allowed, but got %v", c.name, err)
+		}
+		if !c.allowed && err == nil {
+			t.Errorf("%s: expected the object is rejected", c.name)
+		}
+	}
+
+	// the deleted object is the identity without the labels, so the deletion of the admitted object is allowed
+	deletedCases := []struct {
+		name    string
+		obj     *unstructured.Unstructured
+		allowed bool
+	}{
+		{
+			name:    "the deleted core object without the labels",
+			obj:     newObject("v1", "ConfigMap", "default", "cm1", nil),
+			allowed: true,
+		},
+		{
+			name:    "the deleted object of the other group",
+			obj:     newObject("apps/v1", "Deployment", "default", "deploy1", nil),
+			allowed: false,
+		},
+		{
+			name:    "the deleted policy in the other namespace",
+			obj:     newObject("policy.open-cluster-management.io/v1", "Policy", "default", "policy1", nil),
+			allowed: false,
+		},
+	}
+	for _, c := range deletedCases {
+		err := allowLists.CheckDeletion(c.obj)
+		if c.allowed && err != nil {
+			t.Errorf("%s: expected the deletion is allowed, but got %v", c.name, err)
+		}
+		if !c.allowed && err == nil {
+			t.Errorf("%s: expected the deletion is rejected", c.name)
+		}
+	}
+
+	expected := "the Policy.policy.open-cluster-management.io default/policy1 isn't allowed by the allow lists " +
+		"[configmaps policies] of the hub"
+	err = allowLists.Check(newObject("policy.open-cluster-management.io/v1", "Policy", "default", "policy1", nil))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected the error %q, but got %v", expected, err)
+	}
+}
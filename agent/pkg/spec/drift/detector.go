@@ -0,0 +1,441 @@
+package drift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/configs"
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
+	"github.com/stolostron/multicluster-global-hub/pkg/utils"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+var addToMgr = false
+
+// request is the object of a kind watched by the detector, the kinds are only known once they're applied
+type request struct {
+	gvk schema.GroupVersionKind
+	key types.NamespacedName
+}
+
+// Detector watches the global resources applied from the spec bundles, and reverts or reports the changes of their
+// spec fields made on the hub by the drift mode annotation. The global resources are the objects with the global
+// resource label, and the changes are detected against the last applied annotation set by the generic syncer.
+type Detector struct {
+	log         *zap.SugaredLogger
+	client      client.Client
+	reader      client.Reader
+	cache       cache.Cache
+	controller  controller.TypedController[request]
+	producer    transport.Producer
+	topic       string
+	leafHubName string
+	version     *eventversion.Version
+
+	lock sync.Mutex
+	// the kinds watched by the detector
+	watched map[schema.GroupVersionKind]bool
+	// the objects deleted by the generic syncer aren't the drifts
+	expectedDeletions map[request]bool
+	// the last state of the deleted objects, they're applied again in the enforce mode
+	lastKnown map[request]*unstructured.Unstructured
+	// the reported drifts in the report mode, so the same drift isn't reported again
+	reported map[request]string
+}
+
+// AddDetectorToMgr adds the drift detector with its cache of the global resources to the manager
+func AddDetectorToMgr(mgr ctrl.Manager, producer transport.Producer, config *configs.AgentConfig) (*Detector, error) {
+	if addToMgr {
+		return nil, nil
+	}
+
+	requirement, err := labels.NewRequirement(constants.GlobalHubGlobalResourceLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	globalResourceCache, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:               mgr.GetScheme(),
+		Mapper:               mgr.GetRESTMapper(),
+		DefaultLabelSelector: labels.NewSelector().Add(*requirement),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the cache of the global resources: %w", err)
+	}
+	if err := mgr.Add(globalResourceCache); err != nil {
+		return nil, err
+	}
+
+	topic := ""
+	if config.TransportConfig != nil && config.TransportConfig.KafkaCredential != nil {
+		topic = config.TransportConfig.KafkaCredential.StatusTopic
+	}
+	d := &Detector{
+		log:               logger.ZapLogger("spec-drift"),
+		client:            mgr.GetClient(),
+		reader:            mgr.GetAPIReader(),
+		cache:             globalResourceCache,
+		producer:          producer,
+		topic:             topic,
+		leafHubName:       config.LeafHubName,
+		version:           eventversion.NewVersion(),
+		watched:           map[schema.GroupVersionKind]bool{},
+		expectedDeletions: map[request]bool{},
+		lastKnown:         map[request]*unstructured.Unstructured{},
+		reported:          map[request]string{},
+	}
+	d.controller, err = controller.NewTyped[request]("spec-drift", mgr, controller.TypedOptions[request]{
+		Reconciler: d,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addToMgr = true
+	return d, nil
+}
+
+// SetLastApplied sets the object to be applied as its last applied annotation
+func SetLastApplied(obj *unstructured.Unstructured) error {
+	lastApplied := obj.DeepCopy()
+	annotations := lastApplied.GetAnnotations()
+	delete(annotations, constants.LastAppliedAnnotation)
+	lastApplied.SetAnnotations(annotations)
+	unstructured.RemoveNestedField(lastApplied.Object, "status")
+	data, err := json.Marshal(lastApplied.Object)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the last applied object: %w", err)
+	}
+
+	annotations = obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[constants.LastAppliedAnnotation] = string(data)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// lastApplied returns the object of the last applied annotation, it's nil if the object isn't applied by the agent
+func lastApplied(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	data, found := obj.GetAnnotations()[constants.LastAppliedAnnotation]
+	if !found {
+		return nil, nil
+	}
+	lastApplied := &unstructured.Unstructured{}
+	if err := json.Unmarshal([]byte(data), &lastApplied.Object); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the last applied object: %w", err)
+	}
+	return lastApplied, nil
+}
+
+// Applied starts watching the kind of the applied object, and forgets its earlier drift
+func (d *Detector) Applied(obj *unstructured.Unstructured) {
+	if d == nil {
+		return
+	}
+	req := requestOf(obj)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.expectedDeletions, req)
+	delete(d.reported, req)
+	if d.watched[req.gvk] {
+		return
+	}
+
+	watchedObject := &unstructured.Unstructured{}
+	watchedObject.SetGroupVersionKind(req.gvk)
+	if err := d.controller.Watch(source.TypedKind(d.cache, watchedObject, d.eventHandler())); err != nil {
+		d.log.Errorw("failed to watch the global resources", "gvk", req.gvk, "error", err)
+		return
+	}
+	d.watched[req.gvk] = true
+	d.log.Infow("watching the drifts of the global resources", "gvk", req.gvk)
+}
+
+// ExpectDeletion marks the object is going to be deleted by the generic syncer
+func (d *Detector) ExpectDeletion(obj *unstructured.Unstructured) {
+	if d == nil {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.expectedDeletions[requestOf(obj)] = true
+}
+
+func requestOf(obj client.Object) request {
+	return request{
+		gvk: obj.GetObjectKind().GroupVersionKind(),
+		key: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()},
+	}
+}
+
+func (d *Detector) eventHandler() handler.TypedEventHandler[*unstructured.Unstructured, request] {
+	return handler.TypedFuncs[*unstructured.Unstructured, request]{
+		CreateFunc: func(ctx context.Context, e event.TypedCreateEvent[*unstructured.Unstructured],
+			q workqueue.TypedRateLimitingInterface[request],
+		) {
+			q.Add(requestOf(e.Object))
+		},
+		UpdateFunc: func(ctx context.Context, e event.TypedUpdateEvent[*unstructured.Unstructured],
+			q workqueue.TypedRateLimitingInterface[request],
+		) {
+			q.Add(requestOf(e.ObjectNew))
+		},
+		DeleteFunc: func(ctx context.Context, e event.TypedDeleteEvent[*unstructured.Unstructured],
+			q workqueue.TypedRateLimitingInterface[request],
+		) {
+			req := requestOf(e.Object)
+			d.lock.Lock()
+			defer d.lock.Unlock()
+			if d.expectedDeletions[req] {
+				delete(d.expectedDeletions, req)
+				delete(d.reported, req)
+				return
+			}
+			d.lastKnown[req] = e.Object
+			q.Add(req)
+		},
+	}
+}
+
+func (d *Detector) Reconcile(ctx context.Context, req request) (reconcile.Result, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(req.gvk)
+	if err := d.cache.Get(ctx, req.key, live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, d.reconcileDeleted(ctx, req)
+		}
+		return reconcile.Result{}, err
+	}
+	if live.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	desired, err := lastApplied(live)
+	if err != nil || desired == nil {
+		return reconcile.Result{}, err
+	}
+	fields, err := driftedFields(desired, live)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(fields) == 0 {
+		d.lock.Lock()
+		delete(d.reported, req)
+		d.lock.Unlock()
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{}, d.handleDrift(ctx, req, desired, wiremodels.SpecDriftModified, fields)
+}
+
+// reconcileDeleted handles the object removed from the cache, it's either deleted or its global resource label is
+// removed on the hub
+func (d *Detector) reconcileDeleted(ctx context.Context, req request) error {
+	d.lock.Lock()
+	last := d.lastKnown[req]
+	d.lock.Unlock()
+	if last == nil {
+		return nil
+	}
+	desired, err := lastApplied(last)
+	if err != nil || desired == nil {
+		return err
+	}
+
+	driftType, fields := wiremodels.SpecDriftDeleted, []string(nil)
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(req.gvk)
+	if err := d.reader.Get(ctx, req.key, existing); err == nil {
+		driftType, fields = wiremodels.SpecDriftModified, []string{"metadata.labels"}
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if err := d.handleDrift(ctx, req, desired, driftType, fields); err != nil {
+		return err
+	}
+	d.lock.Lock()
+	delete(d.lastKnown, req)
+	d.lock.Unlock()
+	return nil
+}
+
+// handleDrift applies the desired object again in the enforce mode, or reports the drift once in the report mode
+func (d *Detector) handleDrift(ctx context.Context, req request, desired *unstructured.Unstructured,
+	driftType string, fields []string,
+) error {
+	mode := desired.GetAnnotations()[constants.DriftModeAnnotation]
+	if mode != wiremodels.DriftModeEnforce {
+		mode = wiremodels.DriftModeReport
+	}
+	driftEvent := wiremodels.SpecDriftEvent{
+		UID:        desired.GetAnnotations()[constants.OriginOwnerReferenceAnnotation],
+		APIVersion: desired.GetAPIVersion(),
+		Kind:       desired.GetKind(),
+		Namespace:  desired.GetNamespace(),
+		Name:       desired.GetName(),
+		DriftType:  driftType,
+		Mode:       mode,
+		Fields:     fields,
+		CreatedAt:  time.Now(),
+	}
+
+	var revertErr error
+	if mode == wiremodels.DriftModeReport {
+		digest := driftType + "/" + strings.Join(fields, ",")
+		d.lock.Lock()
+		reported := d.reported[req] == digest
+		d.reported[req] = digest
+		d.lock.Unlock()
+		if reported {
+			return nil
+		}
+		driftEvent.Action = wiremodels.SpecDriftReported
+	} else {
+		revertErr = d.revert(ctx, desired)
+		driftEvent.Action = wiremodels.SpecDriftReverted
+		if revertErr != nil {
+			driftEvent.Action = wiremodels.SpecDriftRevertFailed
+			driftEvent.Message = revertErr.Error()
+		}
+	}
+	d.log.Infow("global resource drifted", "kind", driftEvent.Kind, "namespace", driftEvent.Namespace,
+		"name", driftEvent.Name, "type", driftType, "fields", fields, "action", driftEvent.Action)
+
+	if err := d.send(ctx, driftEvent); err != nil {
+		d.log.Errorw("failed to report the drift", "error", err)
+	}
+	return revertErr
+}
+
+// revert applies the last applied object with its last applied annotation
+func (d *Detector) revert(ctx context.Context, desired *unstructured.Unstructured) error {
+	obj := desired.DeepCopy()
+	if err := SetLastApplied(obj); err != nil {
+		return err
+	}
+	return utils.UpdateObject(ctx, d.client, obj)
+}
+
+func (d *Detector) send(ctx context.Context, driftEvent wiremodels.SpecDriftEvent) error {
+	if d.producer == nil {
+		return nil
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.version.Incr()
+	evt := cloudevents.NewEvent()
+	evt.SetSource(d.leafHubName)
+	evt.SetType(string(enum.SpecDriftType))
+	evt.SetExtension(eventversion.ExtVersion, d.version.String())
+	if err := evt.SetData(cloudevents.ApplicationJSON, wiremodels.SpecDriftEvents{driftEvent}); err != nil {
+		return fmt.Errorf("failed to set the data of the drift: %w", err)
+	}
+	if err := d.producer.SendEvent(cecontext.WithTopic(ctx, d.topic), evt); err != nil {
+		return fmt.Errorf("failed to send the drift: %w", err)
+	}
+	d.version.Next()
+	return nil
+}
+
+// driftedFields returns the top level fields of the desired object which aren't kept by the live object. The fields
+// added by the hub, e.g. the defaults, aren't the drifts.
+func driftedFields(desired, live *unstructured.Unstructured) ([]string, error) {
+	fields := []string{}
+	for field, desiredValue := range desired.Object {
+		switch field {
+		case "apiVersion", "kind", "metadata", "status":
+			continue
+		}
+		normalizedDesired, err := normalize(desiredValue)
+		if err != nil {
+			return nil, err
+		}
+		normalizedLive, err := normalize(live.Object[field])
+		if err != nil {
+			return nil, err
+		}
+		if !contains(normalizedLive, normalizedDesired) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// normalize converts the value to the types of the json decoding, so the numbers of the cache are comparable
+func normalize(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// contains reports whether the live value keeps the desired value, the maps may have more keys than the desired
+func contains(live, desired interface{}) bool {
+	switch desiredValue := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			return live == nil && len(desiredValue) == 0
+		}
+		for key, value := range desiredValue {
+			if !contains(liveValue[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok {
+			return live == nil && len(desiredValue) == 0
+		}
+		if len(liveValue) != len(desiredValue) {
+			return false
+		}
+		for i := range desiredValue {
+			if !contains(liveValue[i], desiredValue[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(live, desired)
+	}
+}
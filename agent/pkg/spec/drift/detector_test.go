@@ -0,0 +1,108 @@
+package drift
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+)
+
+func TestLastApplied(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":        "cm1",
+			"namespace":   "default",
+			"annotations": map[string]interface{}{constants.DriftModeAnnotation: "enforce"},
+		},
+		"data":   map[string]interface{}{"hello": "world"},
+		"status": map[string]interface{}{"phase": "ready"},
+	}}
+	if err := SetLastApplied(obj); err != nil {
+		t.Fatal(err)
+	}
+	// the annotation is set again when the object is applied again
+	if err := SetLastApplied(obj); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := lastApplied(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := applied.GetAnnotations()[constants.LastAppliedAnnotation]; found {
+		t.Errorf("expected the last applied object without the annotation, but got %v", applied.GetAnnotations())
+	}
+	if _, found := applied.Object["status"]; found {
+		t.Errorf("expected the last applied object without the status, but got %v", applied.Object)
+	}
+	if applied.GetAnnotations()[constants.DriftModeAnnotation] != "enforce" ||
+		!reflect.DeepEqual(applied.Object["data"], obj.Object["data"]) {
+		t.Errorf("unexpected last applied object %v", applied.Object)
+	}
+
+	if applied, err := lastApplied(&unstructured.Unstructured{Object: map[string]interface{}{}}); err != nil ||
+		applied != nil {
+		t.Errorf("expected no last applied object, but got %v, %v", applied, err)
+	}
+}
+
+func TestDriftedFields(t *testing.T) {
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cluster.open-cluster-management.io/v1beta1",
+		"kind":       "Placement",
+		"metadata":   map[string]interface{}{"name": "placement1"},
+		"spec": map[string]interface{}{
+			"numberOfClusters": float64(2),
+			"clusterSets":      []interface{}{"set1"},
+		},
+	}}
+	cases := []struct {
+		name     string
+		live     map[string]interface{}
+		expected []string
+	}{
+		{
+			name: "the defaults of the hub",
+			live: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "placement1", "labels": map[string]interface{}{"a": "b"}},
+				"spec": map[string]interface{}{
+					"numberOfClusters": int64(2),
+					"clusterSets":      []interface{}{"set1"},
+					"tolerations":      []interface{}{},
+				},
+				"status": map[string]interface{}{"numberOfSelectedClusters": int64(1)},
+			},
+			expected: []string{},
+		},
+		{
+			name: "the changed cluster sets",
+			live: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"numberOfClusters": int64(2),
+					"clusterSets":      []interface{}{"set1", "set2"},
+				},
+			},
+			expected: []string{"spec"},
+		},
+		{
+			name:     "the removed spec",
+			live:     map[string]interface{}{},
+			expected: []string{"spec"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fields, err := driftedFields(desired, &unstructured.Unstructured{Object: c.live})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fields, c.expected) {
+				t.Errorf("expected %v, but got %v", c.expected, fields)
+			}
+		})
+	}
+}
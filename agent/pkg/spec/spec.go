@@ -7,6 +7,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/stolostron/multicluster-global-hub/agent/pkg/configs"
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/drift"
 	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/syncers"
 	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/workers"
 	"github.com/stolostron/multicluster-global-hub/pkg/constants"
@@ -28,7 +29,8 @@ func AddToManager(context context.Context, mgr ctrl.Manager, transportClient tra
 	}
 
 	// add worker pool to manager
-	workers, err := workers.AddWorkerPoolToMgr(mgr, agentConfig.SpecWorkPoolSize, mgr.GetConfig())
+	workers, err := workers.AddWorkerPoolToMgr(mgr, agentConfig.SpecWorkPoolSize, mgr.GetConfig(),
+		agentConfig.PodNamespace)
 	if err != nil {
 		return fmt.Errorf("failed to add k8s workers pool to runtime manager: %w", err)
 	}
@@ -41,10 +43,25 @@ func AddToManager(context context.Context, mgr ctrl.Manager, transportClient tra
 
 	// register syncer to the dispatcher
 	if agentConfig.EnableGlobalResource {
-		dispatcher.RegisterSyncer(constants.GenericSpecMsgKey,
-			syncers.NewGenericSyncer(workers, agentConfig))
-		dispatcher.RegisterSyncer(constants.ManagedClustersLabelsMsgKey,
-			syncers.NewManagedClusterLabelSyncer(workers))
+		// add the drift detector of the global resources to manager
+		driftDetector, err := drift.AddDetectorToMgr(mgr, transportClient.GetProducer(), agentConfig)
+		if err != nil {
+			return fmt.Errorf("failed to add drift detector to runtime manager: %w", err)
+		}
+		genericSyncer, err := syncers.NewGenericSyncer(mgr, workers, agentConfig, transportClient.GetProducer(),
+			driftDetector)
+		if err != nil {
+			return fmt.Errorf("failed to add generic syncer to runtime manager: %w", err)
+		}
+		dispatcher.RegisterSyncer(constants.GenericSpecMsgKey, genericSyncer)
+		// add the controller of the managed cluster labels to manager
+		labelSyncer, err := syncers.NewManagedClusterLabelSyncer(mgr, transportClient.GetProducer(), agentConfig)
+		if err != nil {
+			return fmt.Errorf("failed to add managed cluster labels syncer to runtime manager: %w", err)
+		}
+		dispatcher.RegisterSyncer(constants.ManagedClustersLabelsMsgKey, labelSyncer)
+		dispatcher.RegisterSyncer(constants.SpecDryRunMsgKey,
+			syncers.NewDryRunSyncer(workers, agentConfig, transportClient.GetProducer(), mgr.GetAPIReader()))
 	}
 
 	dispatcher.RegisterSyncer(constants.CloudEventTypeMigrationFrom,
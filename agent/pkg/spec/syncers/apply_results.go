@@ -0,0 +1,142 @@
+package syncers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+// applyResultsFlushInterval is the interval to send the results recorded after the bundle is synced, e.g. by the
+// retried objects
+const applyResultsFlushInterval = 10 * time.Second
+
+// applyResults keeps the latest apply result of each object delivered from the global hub. All the results are sent
+// after each bundle is synced, and flushed periodically once they change, so the event of the results is a complete
+// state and the earlier ones can be conflated.
+type applyResults struct {
+	lock    sync.Mutex
+	results map[string]wiremodels.SpecApplyResult
+	// changed is whether the results are recorded since they're sent last time
+	changed     bool
+	leafHubName string
+	producer    transport.Producer
+	topic       string
+	version     *eventversion.Version
+}
+
+func newApplyResults(leafHubName string, producer transport.Producer, topic string) *applyResults {
+	return &applyResults{
+		results:     map[string]wiremodels.SpecApplyResult{},
+		leafHubName: leafHubName,
+		producer:    producer,
+		topic:       topic,
+		version:     eventversion.NewVersion(),
+	}
+}
+
+// record updates the result of the object, the object deleted from the global hub is forgotten once it's deleted
+// from the hub
+func (r *applyResults) record(obj *unstructured.Unstructured, result string, deleted bool, err error) {
+	key := fmt.Sprintf("%s/%s/%s", obj.GroupVersionKind().GroupKind().String(), obj.GetNamespace(), obj.GetName())
+	uid := obj.GetAnnotations()[constants.OriginOwnerReferenceAnnotation]
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changed = true
+	if deleted && uid == "" && err == nil {
+		delete(r.results, key)
+		return
+	}
+	applyResult := wiremodels.SpecApplyResult{
+		UID:        uid,
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Result:     result,
+		Deleted:    deleted,
+		UpdatedAt:  time.Now(),
+	}
+	if err != nil {
+		applyResult.Error = err.Error()
+	}
+	if result == wiremodels.SpecApplied {
+		applyResult.ResourceVersion = obj.GetResourceVersion()
+	}
+	r.results[key] = applyResult
+}
+
+// send sends all the results to the global hub
+func (r *applyResults) send(ctx context.Context) error {
+	if r.producer == nil {
+		return nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	keys := make([]string, 0, len(r.results))
+	for key := range r.results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	payload := wiremodels.SpecApplyResults{Results: make([]wiremodels.SpecApplyResult, 0, len(keys))}
+	for _, key := range keys {
+		payload.Results = append(payload.Results, r.results[key])
+	}
+
+	r.version.Incr()
+	evt := cloudevents.NewEvent()
+	evt.SetSource(r.leafHubName)
+	evt.SetType(string(enum.SpecApplyResultsType))
+	evt.SetExtension(eventversion.ExtVersion, r.version.String())
+	if err := evt.SetData(cloudevents.ApplicationJSON, payload); err != nil {
+		return fmt.Errorf("failed to set the data of the apply results: %w", err)
+	}
+	if err := r.producer.SendEvent(cecontext.WithTopic(ctx, r.topic), evt); err != nil {
+		return fmt.Errorf("failed to send the apply results: %w", err)
+	}
+	r.version.Next()
+	r.changed = false
+	return nil
+}
+
+// Start flushes the results recorded after the last sending, the jobs are applied and retried by the worker pool
+// asynchronously, so their results are reported without waiting for the next bundle
+func (r *applyResults) Start(ctx context.Context) error {
+	ticker := time.NewTicker(applyResultsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.flush(ctx); err != nil {
+				logger.DefaultZapLogger().Errorw("failed to flush the apply results", "error", err)
+			}
+		}
+	}
+}
+
+// flush sends the results only if they're changed since the last sending
+func (r *applyResults) flush(ctx context.Context) error {
+	r.lock.Lock()
+	changed := r.changed
+	r.lock.Unlock()
+	if !changed {
+		return nil
+	}
+	return r.send(ctx)
+}
@@ -0,0 +1,121 @@
+package syncers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+func newTestObject(name, uid string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("policy.open-cluster-management.io/v1")
+	obj.SetKind("Policy")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetResourceVersion("10")
+	if uid != "" {
+		obj.SetAnnotations(map[string]string{constants.OriginOwnerReferenceAnnotation: uid})
+	}
+	return obj
+}
+
+func TestApplyResults(t *testing.T) {
+	events := []cloudevents.Event{}
+	producer := &transport.ProducerMock{
+		SendEventFunc: func(ctx context.Context, evt cloudevents.Event) error {
+			events = append(events, evt)
+			return nil
+		},
+		ReconnectFunc: func(config *transport.TransportInternalConfig) error { return nil },
+	}
+	results := newApplyResults("hub1", producer, "status")
+
+	results.record(newTestObject("policy1", "uid1"), wiremodels.SpecApplied, false, nil)
+	results.record(newTestObject("policy2", "uid2"), wiremodels.SpecApplyFailed, false, errors.New("forbidden"))
+	results.record(newTestObject("policy3", "uid3"), wiremodels.SpecApplySkipped, true, nil)
+	// the object deleted from the global hub is forgotten once it's deleted from the hub
+	results.record(newTestObject("policy4", ""), wiremodels.SpecApplied, false, nil)
+	results.record(newTestObject("policy4", ""), wiremodels.SpecApplySkipped, true, nil)
+
+	if err := results.send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type() != string(enum.SpecApplyResultsType) || events[0].Source() != "hub1" {
+		t.Fatalf("expected the event of the apply results, but got %v", events)
+	}
+	payload := &wiremodels.SpecApplyResults{}
+	if err := events[0].DataAs(payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Results) != 3 {
+		t.Fatalf("expected 3 results, but got %v", payload.Results)
+	}
+	for i, expected := range []wiremodels.SpecApplyResult{
+		{UID: "uid1", Name: "policy1", Result: wiremodels.SpecApplied, ResourceVersion: "10"},
+		{UID: "uid2", Name: "policy2", Result: wiremodels.SpecApplyFailed, Error: "forbidden"},
+		{UID: "uid3", Name: "policy3", Result: wiremodels.SpecApplySkipped, Deleted: true},
+	} {
+		actual := payload.Results[i]
+		if actual.UID != expected.UID || actual.Name != expected.Name || actual.Result != expected.Result ||
+			actual.Error != expected.Error || actual.ResourceVersion != expected.ResourceVersion ||
+			actual.Deleted != expected.Deleted || actual.Kind != "Policy" || actual.Namespace != "default" {
+			t.Errorf("expected the result %v, but got %v", expected, actual)
+		}
+	}
+}
+
+func TestFlushApplyResults(t *testing.T) {
+	events := []cloudevents.Event{}
+	producer := &transport.ProducerMock{
+		SendEventFunc: func(ctx context.Context, evt cloudevents.Event) error {
+			events = append(events, evt)
+			return nil
+		},
+		ReconnectFunc: func(config *transport.TransportInternalConfig) error { return nil },
+	}
+	results := newApplyResults("hub1", producer, "status")
+
+	// nothing is sent before any result is recorded
+	if err := results.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no event, but got %v", events)
+	}
+
+	// the result recorded by the retried job is flushed without the next bundle
+	results.record(newTestObject("policy1", "uid1"), wiremodels.SpecApplyFailed, false, errors.New("no matches"))
+	if err := results.send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	results.record(newTestObject("policy1", "uid1"), wiremodels.SpecApplied, false, nil)
+	if err := results.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, but got %v", events)
+	}
+	payload := &wiremodels.SpecApplyResults{}
+	if err := events[1].DataAs(payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Results) != 1 || payload.Results[0].Result != wiremodels.SpecApplied {
+		t.Fatalf("expected the applied result, but got %v", payload.Results)
+	}
+
+	// the unchanged results aren't sent again
+	if err := results.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, but got %v", events)
+	}
+}
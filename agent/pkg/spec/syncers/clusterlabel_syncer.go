@@ -3,47 +3,106 @@ package syncers
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/workers"
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/configs"
 	specbundle "github.com/stolostron/multicluster-global-hub/pkg/bundle/spec"
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
 	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
 	"github.com/stolostron/multicluster-global-hub/pkg/utils"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
 )
 
 const (
 	// periodicApplyInterval = 5 * time.Second
 	hohFieldManager = "mgh-agent"
+
+	// labelConflictReason is the reason of the events of the label conflicts on the managed clusters
+	labelConflictReason = "LabelConflict"
 )
 
-// managedClusterLabelsBundleSyncer syncs managed clusters metadata from received bundles.
+// the syncer is only added to the manager once, since its controller can't be registered again
+var managedClusterLabelSyncer *managedClusterLabelsBundleSyncer
+
+// managedClusterLabelsBundleSyncer syncs managed clusters labels from received bundles by the owners of the label
+// keys. The labels are reconciled once the bundle is received or the labels are changed on the hub, and the labels
+// whose values on the hub differ from the ones of the global hub are reported as the conflicts.
 type managedClusterLabelsBundleSyncer struct {
-	log                          *zap.SugaredLogger
-	latestBundle                 *specbundle.ManagedClusterLabelsSpecBundle
-	managedClusterToTimestampMap map[string]*time.Time
-	workerPool                   *workers.WorkerPool
-	bundleProcessingWaitingGroup sync.WaitGroup
-	latestBundleLock             sync.Mutex
+	log         *zap.SugaredLogger
+	client      client.Client
+	recorder    record.EventRecorder
+	requests    chan event.GenericEvent
+	producer    transport.Producer
+	topic       string
+	leafHubName string
+	version     *eventversion.Version
+
+	lock sync.Mutex
+	// the labels of the clusters from the latest bundle
+	labelsSpecs map[string]*specbundle.ManagedClusterLabelsSpec
+	owners      map[string]string
+	// the reported conflicts of the labels, so the same conflict isn't reported again
+	reported map[string]string
 }
 
-func NewManagedClusterLabelSyncer(workers *workers.WorkerPool) *managedClusterLabelsBundleSyncer {
-	return &managedClusterLabelsBundleSyncer{
-		log:                          logger.ZapLogger("managed-clusters-labels-syncer"),
-		latestBundle:                 nil,
-		managedClusterToTimestampMap: make(map[string]*time.Time),
-		workerPool:                   workers,
-		bundleProcessingWaitingGroup: sync.WaitGroup{},
-		latestBundleLock:             sync.Mutex{},
+func NewManagedClusterLabelSyncer(mgr ctrl.Manager, producer transport.Producer, config *configs.AgentConfig,
+) (*managedClusterLabelsBundleSyncer, error) {
+	if managedClusterLabelSyncer != nil {
+		return managedClusterLabelSyncer, nil
+	}
+
+	topic := ""
+	if config.TransportConfig != nil && config.TransportConfig.KafkaCredential != nil {
+		topic = config.TransportConfig.KafkaCredential.StatusTopic
 	}
+	syncer := &managedClusterLabelsBundleSyncer{
+		log:         logger.ZapLogger("managed-clusters-labels-syncer"),
+		client:      mgr.GetClient(),
+		recorder:    mgr.GetEventRecorderFor("multicluster-global-hub-agent"),
+		requests:    make(chan event.GenericEvent, 100),
+		producer:    producer,
+		topic:       topic,
+		leafHubName: config.LeafHubName,
+		version:     eventversion.NewVersion(),
+		labelsSpecs: map[string]*specbundle.ManagedClusterLabelsSpec{},
+		owners:      map[string]string{},
+		reported:    map[string]string{},
+	}
+	err := ctrl.NewControllerManagedBy(mgr).Named("managed-cluster-labels").
+		For(&clusterv1.ManagedCluster{}, builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		WatchesRawSource(source.Channel(syncer.requests, &handler.EnqueueRequestForObject{})).
+		Complete(syncer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add the managed cluster labels controller: %w", err)
+	}
+
+	managedClusterLabelSyncer = syncer
+	return syncer, nil
 }
 
 func (syncer *managedClusterLabelsBundleSyncer) Sync(ctx context.Context, payload []byte) error {
@@ -51,117 +110,270 @@ func (syncer *managedClusterLabelsBundleSyncer) Sync(ctx context.Context, payloa
 	if err := json.Unmarshal(payload, bundle); err != nil {
 		return err
 	}
-	syncer.setLatestBundle(bundle) // uses latestBundle
-	syncer.handleBundle()
 
+	// the bundle contains the labels of all the clusters of the hub
+	labelsSpecs := make(map[string]*specbundle.ManagedClusterLabelsSpec, len(bundle.Objects))
+	for _, labelsSpec := range bundle.Objects {
+		labelsSpecs[labelsSpec.ClusterName] = labelsSpec
+	}
+	syncer.lock.Lock()
+	syncer.labelsSpecs = labelsSpecs
+	syncer.owners = bundle.Owners
+	syncer.lock.Unlock()
+
+	for clusterName := range labelsSpecs {
+		select {
+		case syncer.requests <- event.GenericEvent{
+			Object: &clusterv1.ManagedCluster{ObjectMeta: v1.ObjectMeta{Name: clusterName}},
+		}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 	return nil
 }
 
-func (syncer *managedClusterLabelsBundleSyncer) setLatestBundle(newBundle *specbundle.ManagedClusterLabelsSpecBundle) {
-	syncer.latestBundleLock.Lock()
-	defer syncer.latestBundleLock.Unlock()
-
-	syncer.latestBundle = newBundle
-}
+func (syncer *managedClusterLabelsBundleSyncer) Reconcile(ctx context.Context, req reconcile.Request,
+) (reconcile.Result, error) {
+	syncer.lock.Lock()
+	labelsSpec := syncer.labelsSpecs[req.Name]
+	owners := syncer.owners
+	syncer.lock.Unlock()
+	if labelsSpec == nil {
+		return reconcile.Result{}, nil
+	}
 
-func (syncer *managedClusterLabelsBundleSyncer) handleBundle() {
-	syncer.latestBundleLock.Lock()
-	defer syncer.latestBundleLock.Unlock()
+	managedCluster := &clusterv1.ManagedCluster{}
+	if err := syncer.client.Get(ctx, req.NamespacedName, managedCluster); k8serrors.IsNotFound(err) {
+		syncer.log.Debugw("managed cluster ignored - not found", "name", req.Name)
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		return reconcile.Result{}, err
+	}
+	if managedCluster.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
 
-	for _, managedClusterLabelsSpec := range syncer.latestBundle.Objects {
-		lastProcessedTimestampPtr := syncer.getManagedClusterLastProcessedTimestamp(managedClusterLabelsSpec.ClusterName)
-		if managedClusterLabelsSpec.UpdateTimestamp.After(*lastProcessedTimestampPtr) { // handle (success) once
-			syncer.bundleProcessingWaitingGroup.Add(1)
-			syncer.updateManagedClusterAsync(managedClusterLabelsSpec, lastProcessedTimestampPtr)
+	resolved, err := resolveLabels(managedCluster, labelsSpec, owners)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !reflect.DeepEqual(resolved.labels, managedCluster.Labels) ||
+		managedCluster.Annotations[constants.LastAppliedLabelsAnnotation] != resolved.lastApplied {
+		managedCluster.Labels = resolved.labels
+		if managedCluster.Annotations == nil {
+			managedCluster.Annotations = map[string]string{}
 		}
+		managedCluster.Annotations[constants.LastAppliedLabelsAnnotation] = resolved.lastApplied
+		if err := syncer.updateManagedFieldEntry(managedCluster, resolved.appliedKeys); err != nil {
+			return reconcile.Result{}, err
+		}
+		// update CR with replace API: fails if CR was modified since client.get
+		if err := syncer.client.Update(ctx, managedCluster,
+			&client.UpdateOptions{FieldManager: hohFieldManager}); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to update managed cluster %s: %w", req.Name, err)
+		}
+		syncer.log.Debugw("managed cluster updated", "name", req.Name)
 	}
 
-	// ensure all updates and deletes have finished before reading next bundle
-	syncer.bundleProcessingWaitingGroup.Wait()
+	syncer.reportConflicts(ctx, managedCluster, resolved.conflicts)
+	return reconcile.Result{}, nil
 }
 
-func (s *managedClusterLabelsBundleSyncer) updateManagedClusterAsync(
-	labelsSpec *specbundle.ManagedClusterLabelsSpec, lastProcessedTimestampPtr *time.Time,
-) {
-	s.workerPool.Submit(workers.NewJob(labelsSpec, func(ctx context.Context,
-		k8sClient client.Client, obj interface{},
-	) {
-		defer s.bundleProcessingWaitingGroup.Done()
+// resolvedLabels are the labels of the cluster resolved by the owners of the label keys
+type resolvedLabels struct {
+	labels map[string]string
+	// lastApplied is the annotation of the labels applied from the global hub
+	lastApplied string
+	// appliedKeys are the keys kept with the values of the global hub
+	appliedKeys []string
+	conflicts   []wiremodels.ManagedClusterLabelConflict
+}
 
-		s.log.Debug("update the label bundle to ManagedCluster CR...")
-		labelsSpec, ok := obj.(*specbundle.ManagedClusterLabelsSpec)
-		if !ok {
-			s.log.Error(errors.New("job obj is not a ManagedClusterLabelsSpec type"), "invalid obj type")
+// resolveLabels resolves the labels of the global hub against the ones of the cluster. The label is changed by the
+// hub if its value differs from the last applied one, and changed by the global hub if the value of the global hub
+// differs from the last applied one, or it's deleted by the global hub. The label of the global owner is kept with the
+// value of the global hub, the one of the hub owner is kept with the value of the hub, and the shared one is kept
+// with the value of the side changing it last.
+func resolveLabels(managedCluster *clusterv1.ManagedCluster, labelsSpec *specbundle.ManagedClusterLabelsSpec,
+	owners map[string]string,
+) (*resolvedLabels, error) {
+	lastApplied := map[string]string{}
+	if value, found := managedCluster.Annotations[constants.LastAppliedLabelsAnnotation]; found {
+		if err := json.Unmarshal([]byte(value), &lastApplied); err != nil {
+			// the annotation changed on the hub is ignored, the labels are resolved as they're never applied
+			lastApplied = map[string]string{}
 		}
+	}
 
-		managedCluster := &clusterv1.ManagedCluster{}
-		if err := k8sClient.Get(ctx, client.ObjectKey{
-			Name: labelsSpec.ClusterName,
-		}, managedCluster); k8serrors.IsNotFound(err) {
-			s.log.Info("managed cluster ignored - not found", "name", labelsSpec.ClusterName)
-			s.managedClusterMarkUpdated(labelsSpec, lastProcessedTimestampPtr) // if not found then irrelevant
-
-			return
-		} else if err != nil {
-			s.log.Error(err, "failed to get managed cluster", "name", labelsSpec.ClusterName)
-			return
+	desired := map[string]*string{}
+	for key, value := range labelsSpec.Labels {
+		desired[key] = &value
+	}
+	deleted := map[string]bool{}
+	for _, key := range labelsSpec.DeletedLabelKeys {
+		if _, found := desired[key]; !found {
+			desired[key] = nil
+			deleted[key] = true
 		}
+	}
 
-		if managedCluster.Labels == nil {
-			managedCluster.Labels = make(map[string]string)
+	resolved := &resolvedLabels{labels: map[string]string{}}
+	for key, value := range managedCluster.Labels {
+		resolved.labels[key] = value
+	}
+	applied := map[string]string{}
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		globalValue := desired[key]
+		hubValue := valueOf(managedCluster.Labels, key)
+		lastValue := valueOf(lastApplied, key)
+		owner := owners[key]
+		if owner != wiremodels.LabelOwnerGlobal && owner != wiremodels.LabelOwnerHub {
+			owner = wiremodels.LabelOwnerShared
 		}
 
-		// enforce received labels state (overwrite if exists)
-		for key, value := range labelsSpec.Labels {
-			managedCluster.Labels[key] = value
+		winner := wiremodels.LabelSourceGlobal
+		if owner == wiremodels.LabelOwnerHub {
+			winner = wiremodels.LabelSourceHub
+		} else if owner == wiremodels.LabelOwnerShared && !equalValues(hubValue, lastValue) {
+			// the deleted keys are always changed by the global hub, since they're deleted after they're applied
+			globalChanged := deleted[key] || !equalValues(globalValue, lastValue)
+			if !globalChanged || labelWrittenAt(managedCluster, key).After(labelsSpec.UpdateTimestamp) {
+				winner = wiremodels.LabelSourceHub
+			}
 		}
 
-		// delete labels by key
-		for _, labelKey := range labelsSpec.DeletedLabelKeys {
-			delete(managedCluster.Labels, labelKey)
+		if !equalValues(globalValue, hubValue) && (winner == wiremodels.LabelSourceHub ||
+			!equalValues(hubValue, lastValue)) {
+			resolved.conflicts = append(resolved.conflicts, wiremodels.ManagedClusterLabelConflict{
+				ClusterName: managedCluster.Name,
+				Key:         key,
+				Owner:       owner,
+				GlobalValue: globalValue,
+				HubValue:    hubValue,
+				Winner:      winner,
+				CreatedAt:   time.Now(),
+			})
 		}
-
-		if err := s.updateManagedFieldEntry(managedCluster, labelsSpec); err != nil {
-			s.log.Error(err, "failed to update managed cluster", "name", labelsSpec.ClusterName)
-			return
+		if winner == wiremodels.LabelSourceHub {
+			continue
 		}
-
-		// update CR with replace API: fails if CR was modified since client.get
-		if err := k8sClient.Update(ctx, managedCluster, &client.UpdateOptions{FieldManager: hohFieldManager}); err != nil {
-			s.log.Error(err, "failed to update managed cluster", "name", labelsSpec.ClusterName)
-			return
+		if globalValue == nil {
+			delete(resolved.labels, key)
+			continue
 		}
+		resolved.labels[key] = *globalValue
+		applied[key] = *globalValue
+		resolved.appliedKeys = append(resolved.appliedKeys, key)
+	}
 
-		s.log.Debug("managed cluster updated", "name", labelsSpec.ClusterName)
-		s.managedClusterMarkUpdated(labelsSpec, lastProcessedTimestampPtr)
-	}))
+	lastAppliedBytes, err := json.Marshal(applied)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the applied labels: %w", err)
+	}
+	resolved.lastApplied = string(lastAppliedBytes)
+	return resolved, nil
 }
 
-func (syncer *managedClusterLabelsBundleSyncer) managedClusterMarkUpdated(
-	labelsSpec *specbundle.ManagedClusterLabelsSpec, lastProcessedTimestampPtr *time.Time,
-) {
-	*lastProcessedTimestampPtr = labelsSpec.UpdateTimestamp
+// labelWrittenAt returns the last time the label is written by the managers other than the agent, it's zero if the
+// label isn't written by them
+func labelWrittenAt(managedCluster *clusterv1.ManagedCluster, key string) time.Time {
+	writtenAt := time.Time{}
+	for _, entry := range managedCluster.ManagedFields {
+		if entry.Manager == hohFieldManager || entry.FieldsV1 == nil || entry.Time == nil {
+			continue
+		}
+		metadataField := utils.MetadataField{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &metadataField); err != nil {
+			continue
+		}
+		if _, found := metadataField.Labels["f:"+key]; found && entry.Time.After(writtenAt) {
+			writtenAt = entry.Time.Time
+		}
+	}
+	return writtenAt
 }
 
-func (syncer *managedClusterLabelsBundleSyncer) getManagedClusterLastProcessedTimestamp(name string) *time.Time {
-	timestamp, found := syncer.managedClusterToTimestampMap[name]
-	if found {
-		return timestamp
+// reportConflicts records the events of the new conflicts on the cluster, and sends them to the global hub
+func (syncer *managedClusterLabelsBundleSyncer) reportConflicts(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster, conflicts []wiremodels.ManagedClusterLabelConflict,
+) {
+	syncer.lock.Lock()
+	conflicted := map[string]bool{}
+	newConflicts := []wiremodels.ManagedClusterLabelConflict{}
+	for _, conflict := range conflicts {
+		key := managedCluster.Name + "/" + conflict.Key
+		digest := fmt.Sprintf("%s/%s/%s", conflict.Winner, formatLabelValue(conflict.GlobalValue),
+			formatLabelValue(conflict.HubValue))
+		conflicted[key] = true
+		if syncer.reported[key] == digest {
+			continue
+		}
+		syncer.reported[key] = digest
+		newConflicts = append(newConflicts, conflict)
+	}
+	for key := range syncer.reported {
+		if strings.HasPrefix(key, managedCluster.Name+"/") && !conflicted[key] {
+			delete(syncer.reported, key)
+		}
+	}
+	syncer.lock.Unlock()
+	if len(newConflicts) == 0 {
+		return
 	}
 
-	timestamp = &time.Time{}
-	syncer.managedClusterToTimestampMap[name] = timestamp
+	for _, conflict := range newConflicts {
+		winnerValue, loser, loserValue := conflict.GlobalValue, wiremodels.LabelSourceHub, conflict.HubValue
+		if conflict.Winner == wiremodels.LabelSourceHub {
+			winnerValue, loser, loserValue = conflict.HubValue, wiremodels.LabelSourceGlobal, conflict.GlobalValue
+		}
+		syncer.log.Infow("managed cluster label conflicted", "cluster", managedCluster.Name, "key", conflict.Key,
+			"owner", conflict.Owner, "winner", conflict.Winner)
+		syncer.recorder.Eventf(managedCluster, corev1.EventTypeWarning, labelConflictReason,
+			"the %s label %s is kept with the %s value %s, the %s value %s is overridden", conflict.Owner,
+			conflict.Key, conflict.Winner, formatLabelValue(winnerValue), loser, formatLabelValue(loserValue))
+	}
+	if err := syncer.send(ctx, newConflicts); err != nil {
+		syncer.log.Errorw("failed to report the label conflicts", "error", err)
+	}
+}
 
-	return timestamp
+func (syncer *managedClusterLabelsBundleSyncer) send(ctx context.Context,
+	conflicts wiremodels.ManagedClusterLabelConflicts,
+) error {
+	if syncer.producer == nil {
+		return nil
+	}
+
+	syncer.lock.Lock()
+	defer syncer.lock.Unlock()
+	syncer.version.Incr()
+	evt := cloudevents.NewEvent()
+	evt.SetSource(syncer.leafHubName)
+	evt.SetType(string(enum.ManagedClusterLabelConflictType))
+	evt.SetExtension(eventversion.ExtVersion, syncer.version.String())
+	if err := evt.SetData(cloudevents.ApplicationJSON, conflicts); err != nil {
+		return fmt.Errorf("failed to set the data of the label conflicts: %w", err)
+	}
+	if err := syncer.producer.SendEvent(cecontext.WithTopic(ctx, syncer.topic), evt); err != nil {
+		return fmt.Errorf("failed to send the label conflicts: %w", err)
+	}
+	syncer.version.Next()
+	return nil
 }
 
 // updateManagedFieldEntry inserts/updates the hohFieldManager managed-field entry in a given managedCluster.
 func (syncer *managedClusterLabelsBundleSyncer) updateManagedFieldEntry(managedCluster *clusterv1.ManagedCluster,
-	managedClusterLabelsSpec *specbundle.ManagedClusterLabelsSpec,
+	appliedKeys []string,
 ) error {
 	// create label fields
 	labelFields := utils.LabelsField{Labels: map[string]struct{}{}}
-	for key := range managedClusterLabelsSpec.Labels {
+	for _, key := range appliedKeys {
 		labelFields.Labels[fmt.Sprintf("f:%s", key)] = struct{}{}
 	}
 	// create metadata field
@@ -197,3 +409,24 @@ func (syncer *managedClusterLabelsBundleSyncer) updateManagedFieldEntry(managedC
 
 	return nil
 }
+
+func valueOf(labels map[string]string, key string) *string {
+	if value, found := labels[key]; found {
+		return &value
+	}
+	return nil
+}
+
+func equalValues(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func formatLabelValue(value *string) string {
+	if value == nil {
+		return "<deleted>"
+	}
+	return fmt.Sprintf("%q", *value)
+}
@@ -0,0 +1,175 @@
+package syncers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	specbundle "github.com/stolostron/multicluster-global-hub/pkg/bundle/spec"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+func newTestManagedCluster(labels, lastApplied map[string]string, writtenAt time.Time) *clusterv1.ManagedCluster {
+	lastAppliedBytes, _ := json.Marshal(lastApplied)
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "cluster1",
+			Labels:      labels,
+			Annotations: map[string]string{constants.LastAppliedLabelsAnnotation: string(lastAppliedBytes)},
+		},
+	}
+	fields := `{"f:metadata":{"f:labels":{`
+	first := true
+	for key := range labels {
+		if !first {
+			fields += ","
+		}
+		fields += `"f:` + key + `":{}`
+		first = false
+	}
+	fields += `}}}`
+	managedCluster.ManagedFields = []v1.ManagedFieldsEntry{{
+		Manager:  "kubectl",
+		Time:     &v1.Time{Time: writtenAt},
+		FieldsV1: &v1.FieldsV1{Raw: []byte(fields)},
+	}}
+	return managedCluster
+}
+
+func TestResolveLabels(t *testing.T) {
+	specUpdatedAt := time.Now()
+	cases := []struct {
+		name            string
+		owner           string
+		hubValue        string
+		lastApplied     string
+		globalValue     string
+		deleted         bool
+		writtenAt       time.Time
+		expectedValue   string
+		expectedDeleted bool
+		expectedWinner  string
+	}{
+		{
+			name:          "the global owned label is applied",
+			owner:         wiremodels.LabelOwnerGlobal,
+			hubValue:      "v1",
+			lastApplied:   "v1",
+			globalValue:   "v2",
+			expectedValue: "v2",
+		},
+		{
+			name:           "the global owned label changed on the hub is reverted",
+			owner:          wiremodels.LabelOwnerGlobal,
+			hubValue:       "local",
+			lastApplied:    "v1",
+			globalValue:    "v1",
+			expectedValue:  "v1",
+			expectedWinner: wiremodels.LabelSourceGlobal,
+		},
+		{
+			name:           "the hub owned label is kept",
+			owner:          wiremodels.LabelOwnerHub,
+			hubValue:       "local",
+			globalValue:    "v1",
+			expectedValue:  "local",
+			expectedWinner: wiremodels.LabelSourceHub,
+		},
+		{
+			name:          "the shared label changed by the global hub is applied",
+			owner:         wiremodels.LabelOwnerShared,
+			hubValue:      "v1",
+			lastApplied:   "v1",
+			globalValue:   "v2",
+			expectedValue: "v2",
+		},
+		{
+			name:           "the shared label changed on the hub is kept",
+			owner:          wiremodels.LabelOwnerShared,
+			hubValue:       "local",
+			lastApplied:    "v1",
+			globalValue:    "v1",
+			expectedValue:  "local",
+			expectedWinner: wiremodels.LabelSourceHub,
+		},
+		{
+			name:           "the shared label changed on the hub later is kept",
+			owner:          wiremodels.LabelOwnerShared,
+			hubValue:       "local",
+			lastApplied:    "v1",
+			globalValue:    "v2",
+			writtenAt:      specUpdatedAt.Add(time.Minute),
+			expectedValue:  "local",
+			expectedWinner: wiremodels.LabelSourceHub,
+		},
+		{
+			name:           "the shared label changed by the global hub later is applied",
+			owner:          wiremodels.LabelOwnerShared,
+			hubValue:       "local",
+			lastApplied:    "v1",
+			globalValue:    "v2",
+			writtenAt:      specUpdatedAt.Add(-time.Minute),
+			expectedValue:  "v2",
+			expectedWinner: wiremodels.LabelSourceGlobal,
+		},
+		{
+			name:            "the label deleted by the global hub is removed",
+			owner:           wiremodels.LabelOwnerShared,
+			hubValue:        "v1",
+			lastApplied:     "v1",
+			deleted:         true,
+			expectedDeleted: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lastApplied := map[string]string{}
+			if c.lastApplied != "" {
+				lastApplied["env"] = c.lastApplied
+			}
+			managedCluster := newTestManagedCluster(map[string]string{"env": c.hubValue}, lastApplied, c.writtenAt)
+			labelsSpec := &specbundle.ManagedClusterLabelsSpec{
+				ClusterName:     "cluster1",
+				Labels:          map[string]string{},
+				UpdateTimestamp: specUpdatedAt,
+			}
+			if c.deleted {
+				labelsSpec.DeletedLabelKeys = []string{"env"}
+			} else {
+				labelsSpec.Labels["env"] = c.globalValue
+			}
+
+			resolved, err := resolveLabels(managedCluster, labelsSpec, map[string]string{"env": c.owner})
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, found := resolved.labels["env"]
+			if c.expectedDeleted && found {
+				t.Errorf("expected the label is deleted, but got %s", value)
+			}
+			if !c.expectedDeleted && value != c.expectedValue {
+				t.Errorf("expected the label value %s, but got %s", c.expectedValue, value)
+			}
+			if c.expectedWinner == "" && len(resolved.conflicts) > 0 {
+				t.Errorf("expected no conflict, but got %+v", resolved.conflicts)
+			}
+			if c.expectedWinner != "" && (len(resolved.conflicts) != 1 ||
+				resolved.conflicts[0].Winner != c.expectedWinner) {
+				t.Errorf("expected the conflict won by %s, but got %+v", c.expectedWinner, resolved.conflicts)
+			}
+
+			applied := map[string]string{}
+			if err := json.Unmarshal([]byte(resolved.lastApplied), &applied); err != nil {
+				t.Fatal(err)
+			}
+			_, lastAppliedFound := applied["env"]
+			if expectedFound := c.expectedWinner != wiremodels.LabelSourceHub && !c.expectedDeleted; lastAppliedFound != expectedFound {
+				t.Errorf("expected the label is last applied %v, but got %v", expectedFound, applied)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package syncers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/configs"
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/allowlist"
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/workers"
+	"github.com/stolostron/multicluster-global-hub/pkg/bundle/spec"
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
+	"github.com/stolostron/multicluster-global-hub/pkg/utils"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+// dryRunSyncer applies the objects of the dry run bundle by the server-side dry run with the same identity as the
+// generic syncer, and reports whether each of them would be created, updated or failed on the hub.
+type dryRunSyncer struct {
+	log            *zap.SugaredLogger
+	workerPool     *workers.WorkerPool
+	enforceHohRbac bool
+	leafHubName    string
+	producer       transport.Producer
+	topic          string
+	version        *eventversion.Version
+	// allowListReader reads the allow lists of the hub, the rejected objects fail the dry run
+	allowListReader client.Reader
+}
+
+func NewDryRunSyncer(workerPool *workers.WorkerPool, config *configs.AgentConfig,
+	producer transport.Producer, allowListReader client.Reader,
+) *dryRunSyncer {
+	topic := ""
+	if config.TransportConfig != nil && config.TransportConfig.KafkaCredential != nil {
+		topic = config.TransportConfig.KafkaCredential.StatusTopic
+	}
+	return &dryRunSyncer{
+		log:             logger.ZapLogger("spec-dry-run"),
+		workerPool:      workerPool,
+		enforceHohRbac:  config.SpecEnforceHohRbac,
+		leafHubName:     config.LeafHubName,
+		producer:        producer,
+		topic:           topic,
+		version:         eventversion.NewVersion(),
+		allowListReader: allowListReader,
+	}
+}
+
+func (s *dryRunSyncer) Sync(ctx context.Context, payload []byte) error {
+	dryRunBundle := &spec.DryRunSpecBundle{}
+	if err := json.Unmarshal(payload, dryRunBundle); err != nil {
+		return err
+	}
+
+	allowLists, err := allowlist.Load(ctx, s.allowListReader)
+	if err != nil {
+		return err
+	}
+
+	results := make([]wiremodels.SpecDryRunResult, len(dryRunBundle.Objects))
+	wg := sync.WaitGroup{}
+	for i, bundleObject := range dryRunBundle.Objects {
+		if err := allowLists.Check(bundleObject); err != nil {
+			results[i] = wiremodels.SpecDryRunResult{
+				APIVersion: bundleObject.GetAPIVersion(),
+				Kind:       bundleObject.GetKind(),
+				Namespace:  bundleObject.GetNamespace(),
+				Name:       bundleObject.GetName(),
+				Result:     wiremodels.SpecDryRunFail,
+				Message:    err.Error(),
+				ReportedAt: time.Now(),
+			}
+			continue
+		}
+		if !s.enforceHohRbac { // if rbac not enforced, use controller's identity.
+			bundleObject = anonymize(bundleObject)
+		}
+		wg.Add(1)
+		s.workerPool.Submit(workers.NewJob(bundleObject, func(ctx context.Context,
+			k8sClient client.Client, obj interface{},
+		) {
+			defer wg.Done()
+			unstructuredObject, _ := obj.(*unstructured.Unstructured)
+			results[i] = s.dryRun(ctx, k8sClient, unstructuredObject)
+		}))
+	}
+	wg.Wait()
+
+	s.log.Infow("dry run finished", "id", dryRunBundle.ID, "objects", len(results))
+	return s.send(ctx, &wiremodels.SpecDryRunResults{ID: dryRunBundle.ID, Results: results})
+}
+
+// dryRun returns the result of applying the object as the generic syncer does, but with the server-side dry run
+func (s *dryRunSyncer) dryRun(ctx context.Context, k8sClient client.Client,
+	obj *unstructured.Unstructured,
+) wiremodels.SpecDryRunResult {
+	result := wiremodels.SpecDryRunResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		ReportedAt: time.Now(),
+	}
+	fail := func(err error) wiremodels.SpecDryRunResult {
+		result.Result = wiremodels.SpecDryRunFail
+		result.Message = err.Error()
+		return result
+	}
+
+	if err := prepareObject(obj); err != nil {
+		return fail(err)
+	}
+	if !s.enforceHohRbac && obj.GetNamespace() != "" {
+		// the namespace is created by the generic syncer, the object can't be applied by the dry run without it
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, &corev1.Namespace{})
+		if apierrors.IsNotFound(err) {
+			result.Result = wiremodels.SpecDryRunCreate
+			result.Message = fmt.Sprintf("the namespace %s would be created, the object isn't checked by the server",
+				obj.GetNamespace())
+			return result
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	found := true
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fail(err)
+		}
+		found = false
+	}
+
+	if err := utils.DryRunObject(ctx, k8sClient, obj); err != nil {
+		s.log.Debugw("the object would fail to be applied", "kind", obj.GetKind(), "namespace", obj.GetNamespace(),
+			"name", obj.GetName(), "error", err)
+		return fail(err)
+	}
+	if !found {
+		result.Result = wiremodels.SpecDryRunCreate
+		return result
+	}
+	result.Diff = diffObjects(live.Object, obj.Object)
+	result.Result = wiremodels.SpecDryRunUpdate
+	if len(result.Diff) == 0 {
+		result.Result = wiremodels.SpecDryRunUnchanged
+	}
+	return result
+}
+
+func (s *dryRunSyncer) send(ctx context.Context, results *wiremodels.SpecDryRunResults) error {
+	if s.producer == nil {
+		return nil
+	}
+	s.version.Incr()
+	evt := cloudevents.NewEvent()
+	evt.SetSource(s.leafHubName)
+	evt.SetType(string(enum.SpecDryRunResultsType))
+	evt.SetExtension(eventversion.ExtVersion, s.version.String())
+	if err := evt.SetData(cloudevents.ApplicationJSON, results); err != nil {
+		return fmt.Errorf("failed to set the data of the dry run results: %w", err)
+	}
+	if err := s.producer.SendEvent(cecontext.WithTopic(ctx, s.topic), evt); err != nil {
+		return fmt.Errorf("failed to send the dry run results: %w", err)
+	}
+	s.version.Next()
+	return nil
+}
+
+// diffObjects returns the changed fields from the live object to the dry run one, the fields set by the server and
+// the annotations of the agent aren't compared
+func diffObjects(live, dryRun map[string]interface{}) []wiremodels.SpecDryRunChange {
+	changes := []wiremodels.SpecDryRunChange{}
+	diffFields("", comparableObject(live), comparableObject(dryRun), &changes)
+	return changes
+}
+
+func diffFields(path string, before, after interface{}, changes *[]wiremodels.SpecDryRunChange) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := map[string]bool{}
+		for key := range beforeMap {
+			keys[key] = true
+		}
+		for key := range afterMap {
+			keys[key] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+		for _, key := range sortedKeys {
+			diffFields(strings.TrimPrefix(path+"."+key, "."), beforeMap[key], afterMap[key], changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, wiremodels.SpecDryRunChange{Path: path, Before: before, After: after})
+	}
+}
+
+func comparableObject(obj map[string]interface{}) map[string]interface{} {
+	copied := (&unstructured.Unstructured{Object: obj}).DeepCopy()
+	for _, field := range []string{"managedFields", "resourceVersion", "generation", "creationTimestamp", "uid"} {
+		unstructured.RemoveNestedField(copied.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(copied.Object, "status")
+	annotations := copied.GetAnnotations()
+	delete(annotations, constants.LastAppliedAnnotation)
+	delete(annotations, constants.OriginOwnerReferenceAnnotation)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(copied.Object, "metadata", "annotations")
+	} else {
+		copied.SetAnnotations(annotations)
+	}
+	return copied.Object
+}
@@ -0,0 +1,63 @@
+package syncers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+func TestDiffObjects(t *testing.T) {
+	live := map[string]interface{}{
+		"apiVersion": "policy.open-cluster-management.io/v1",
+		"kind":       "Policy",
+		"metadata": map[string]interface{}{
+			"name":            "policy1",
+			"namespace":       "default",
+			"resourceVersion": "100",
+			"annotations": map[string]interface{}{
+				constants.LastAppliedAnnotation:          `{"spec":{"remediationAction":"inform"}}`,
+				constants.OriginOwnerReferenceAnnotation: "uid1",
+			},
+		},
+		"spec": map[string]interface{}{
+			"remediationAction": "inform",
+			"disabled":          false,
+			"policy-templates":  []interface{}{map[string]interface{}{"objectDefinition": "a"}},
+		},
+		"status": map[string]interface{}{"compliant": "NonCompliant"},
+	}
+	dryRun := map[string]interface{}{
+		"apiVersion": "policy.open-cluster-management.io/v1",
+		"kind":       "Policy",
+		"metadata": map[string]interface{}{
+			"name":            "policy1",
+			"namespace":       "default",
+			"resourceVersion": "101",
+			"annotations": map[string]interface{}{
+				constants.LastAppliedAnnotation: `{"spec":{"remediationAction":"enforce"}}`,
+			},
+			"labels": map[string]interface{}{"env": "dev"},
+		},
+		"spec": map[string]interface{}{
+			"remediationAction": "enforce",
+			"disabled":          false,
+			"policy-templates":  []interface{}{map[string]interface{}{"objectDefinition": "a"}},
+		},
+	}
+
+	expected := []wiremodels.SpecDryRunChange{
+		{Path: "metadata.labels", After: map[string]interface{}{"env": "dev"}},
+		{Path: "spec.remediationAction", Before: "inform", After: "enforce"},
+	}
+	if changes := diffObjects(live, dryRun); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, but got %v", expected, changes)
+	}
+	if changes := diffObjects(live, live); len(changes) != 0 {
+		t.Errorf("expected no changes, but got %v", changes)
+	}
+	if _, found := live["status"]; !found {
+		t.Errorf("expected the live object isn't changed, but got %v", live)
+	}
+}
@@ -3,35 +3,60 @@ package syncers
 import (
 	"context"
 	"encoding/json"
-	"sync"
+	"fmt"
 
 	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/stolostron/multicluster-global-hub/agent/pkg/configs"
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/allowlist"
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/drift"
 	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/rbac"
 	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/workers"
 	"github.com/stolostron/multicluster-global-hub/pkg/bundle/spec"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
 	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
 	"github.com/stolostron/multicluster-global-hub/pkg/utils"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
 )
 
 // genericBundleSyncer syncs objects spec from received bundles.
 type genericBundleSyncer struct {
-	log                          *zap.SugaredLogger
-	workerPool                   *workers.WorkerPool
-	bundleProcessingWaitingGroup sync.WaitGroup
-	enforceHohRbac               bool
+	log            *zap.SugaredLogger
+	workerPool     *workers.WorkerPool
+	enforceHohRbac bool
+	applyResults   *applyResults
+	driftDetector  *drift.Detector
+	// allowListReader reads the allow lists of the hub without the cache, the changes take effect with the next bundle
+	allowListReader client.Reader
 }
 
-func NewGenericSyncer(workerPool *workers.WorkerPool, config *configs.AgentConfig) *genericBundleSyncer {
-	return &genericBundleSyncer{
-		log:                          logger.DefaultZapLogger(),
-		workerPool:                   workerPool,
-		bundleProcessingWaitingGroup: sync.WaitGroup{},
-		enforceHohRbac:               config.SpecEnforceHohRbac,
+func NewGenericSyncer(mgr ctrl.Manager, workerPool *workers.WorkerPool, config *configs.AgentConfig,
+	producer transport.Producer, driftDetector *drift.Detector,
+) (*genericBundleSyncer, error) {
+	topic := ""
+	if config.TransportConfig != nil && config.TransportConfig.KafkaCredential != nil {
+		topic = config.TransportConfig.KafkaCredential.StatusTopic
 	}
+	syncer := &genericBundleSyncer{
+		log:             logger.DefaultZapLogger(),
+		workerPool:      workerPool,
+		enforceHohRbac:  config.SpecEnforceHohRbac,
+		applyResults:    newApplyResults(config.LeafHubName, producer, topic),
+		driftDetector:   driftDetector,
+		allowListReader: mgr.GetAPIReader(),
+	}
+	// the objects failed before the agent restarted are retried once the pool is started
+	workerPool.SetRestoreFunc(syncer.restoreRetries)
+	// the results of the retried objects are flushed periodically
+	if err := mgr.Add(syncer.applyResults); err != nil {
+		return nil, fmt.Errorf("failed to add the apply results to the manager: %w", err)
+	}
+	return syncer, nil
 }
 
 func (syncer *genericBundleSyncer) Sync(ctx context.Context, payload []byte) error {
@@ -40,92 +65,211 @@ func (syncer *genericBundleSyncer) Sync(ctx context.Context, payload []byte) err
 		return err
 	}
 
-	syncer.bundleProcessingWaitingGroup.Add(len(genericBundle.Objects) + len(genericBundle.DeletedObjects))
-	syncer.syncObjects(genericBundle.Objects)
-	syncer.syncDeletedObjects(genericBundle.DeletedObjects)
-	syncer.bundleProcessingWaitingGroup.Wait()
+	// nothing is applied if the allow lists can't be read, the bundle is synced again with the next one
+	allowLists, err := allowlist.Load(ctx, syncer.allowListReader)
+	if err != nil {
+		return err
+	}
+
+	// the objects are applied in the order of their kinds, and the failed ones are retried by the worker pool
+	syncer.syncObjects(syncer.allowed(allowLists, genericBundle.Objects, false))
+	syncer.syncDeletedObjects(syncer.allowed(allowLists, genericBundle.DeletedObjects, true))
+
+	// the failure to report the results doesn't fail the sync, they are reported again with the next bundle
+	if err := syncer.applyResults.send(ctx); err != nil {
+		syncer.log.Errorw("failed to report the apply results", "error", err)
+	}
 	return nil
 }
 
+// allowed returns the objects allowed by the allow lists, the others are reported as rejected and not retried
+func (s *genericBundleSyncer) allowed(allowLists *allowlist.AllowLists, objs []*unstructured.Unstructured,
+	deleted bool,
+) []*unstructured.Unstructured {
+	allowed := make([]*unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		check := allowLists.Check
+		if deleted {
+			check = allowLists.CheckDeletion
+		}
+		if err := check(obj); err != nil {
+			s.log.Infow("the object is rejected", "kind", obj.GetKind(), "namespace", obj.GetNamespace(),
+				"name", obj.GetName(), "deleted", deleted, "reason", err.Error())
+			s.workerPool.Cancel(obj)
+			s.applyResults.record(obj, wiremodels.SpecApplyRejected, deleted, err)
+			continue
+		}
+		allowed = append(allowed, obj)
+	}
+	return allowed
+}
+
+// restoreRetries returns the jobs of the objects failed before the agent restarted, they're checked by the allow
+// lists again
+func (s *genericBundleSyncer) restoreRetries(ctx context.Context, retries []workers.PersistedRetry) ([]*workers.Job,
+	[]*workers.Job, error,
+) {
+	allowLists, err := allowlist.Load(ctx, s.allowListReader)
+	if err != nil {
+		return nil, nil, err
+	}
+	objs, deletedObjs := []*unstructured.Unstructured{}, []*unstructured.Unstructured{}
+	for _, retry := range retries {
+		if retry.Deleted {
+			deletedObjs = append(deletedObjs, retry.Object)
+		} else {
+			objs = append(objs, retry.Object)
+		}
+	}
+	jobs, deletedJobs := []*workers.Job{}, []*workers.Job{}
+	for _, obj := range s.allowed(allowLists, objs, false) {
+		jobs = append(jobs, s.applyJob(obj))
+	}
+	for _, obj := range s.allowed(allowLists, deletedObjs, true) {
+		deletedJobs = append(deletedJobs, s.deleteJob(obj))
+	}
+	return jobs, deletedJobs, nil
+}
+
 func (s *genericBundleSyncer) syncObjects(bundleObjects []*unstructured.Unstructured) {
+	jobs := make([]*workers.Job, 0, len(bundleObjects))
 	for _, bundleObject := range bundleObjects {
-		if !s.enforceHohRbac { // if rbac not enforced, use controller's identity.
-			bundleObject = s.anonymize(bundleObject) // anonymize removes the user identity from the obj if exists
-		}
+		jobs = append(jobs, s.applyJob(bundleObject))
+	}
+	s.workerPool.SubmitInOrder(jobs)
+}
 
-		s.workerPool.Submit(workers.NewJob(bundleObject, func(ctx context.Context,
-			k8sClient client.Client, obj interface{},
-		) {
-			defer s.bundleProcessingWaitingGroup.Done()
+// applyJob returns the job applying the object, which is retried until it succeeds
+func (s *genericBundleSyncer) applyJob(bundleObject *unstructured.Unstructured) *workers.Job {
+	if !s.enforceHohRbac { // if rbac not enforced, use controller's identity.
+		bundleObject = anonymize(bundleObject) // anonymize removes the user identity from the obj if exists
+	}
 
-			unstructuredObject, _ := obj.(*unstructured.Unstructured)
+	return workers.NewRetryableJob(bundleObject, func(ctx context.Context,
+		k8sClient client.Client, obj interface{},
+	) error {
+		unstructuredObject, _ := obj.(*unstructured.Unstructured)
 
-			if !s.enforceHohRbac { // if rbac not enforced, create missing namespaces.
-				if err := utils.CreateNamespaceIfNotExist(ctx, k8sClient,
-					unstructuredObject.GetNamespace()); err != nil {
-					s.log.Error(err, "failed to create namespace", unstructuredObject.GetNamespace())
-					return
-				}
+		if !s.enforceHohRbac { // if rbac not enforced, create missing namespaces.
+			if err := utils.CreateNamespaceIfNotExist(ctx, k8sClient,
+				unstructuredObject.GetNamespace()); err != nil {
+				s.log.Error(err, "failed to create namespace", unstructuredObject.GetNamespace())
+				s.applyResults.record(unstructuredObject, wiremodels.SpecApplyFailed, false, err)
+				return err
 			}
+		}
 
-			// Deprecated: skip the "bindingOverrides" from the placementbinding
-			// Reference: https://github.com/open-cluster-management-io/governance-policy-propagator/pull/110
-			delete(unstructuredObject.Object, "bindingOverrides")
-
-			// Deprecated: skip the "spec.decisionStrategy" and "spec.spreadPolicy" from the placement
-			// Reference:
-			//   "spec.spreadPolicy": https://github.com/open-cluster-management-io/api/pull/225
-			//   "spec.decisionStrategy": https://github.com/open-cluster-management-io/api/pull/242
-			spec, ok := unstructuredObject.Object["spec"]
-			if ok {
-				specMap := spec.(map[string]interface{})
-				delete(specMap, "decisionStrategy")
-				delete(specMap, "spreadPolicy")
-				unstructuredObject.Object["spec"] = specMap
-			}
+		// the changes made on the hub are detected against the last applied annotation
+		if err := prepareObject(unstructuredObject); err != nil {
+			s.log.Errorw("failed to set the last applied annotation", "error", err)
+			s.applyResults.record(unstructuredObject, wiremodels.SpecApplyFailed, false, err)
+			return err
+		}
+		err := utils.UpdateObject(ctx, k8sClient, unstructuredObject)
+		if err != nil {
+			s.log.Error(err, "failed to update object", "name", unstructuredObject.GetName(),
+				"namespace", unstructuredObject.GetNamespace(), "kind", unstructuredObject.GetKind())
+			s.applyResults.record(unstructuredObject, wiremodels.SpecApplyFailed, false, err)
+			return err
+		}
+		s.applyResults.record(unstructuredObject, wiremodels.SpecApplied, false, nil)
+		s.driftDetector.Applied(unstructuredObject)
+		s.log.Debug("object updated", "name", unstructuredObject.GetName(), "namespace",
+			unstructuredObject.GetNamespace(), "kind", unstructuredObject.GetKind())
+		return nil
+	})
+}
 
-			delete(unstructuredObject.Object, "status")
-			err := utils.UpdateObject(ctx, k8sClient, unstructuredObject)
+func (s *genericBundleSyncer) syncDeletedObjects(deletedObjects []*unstructured.Unstructured) {
+	jobs := make([]*workers.Job, 0, len(deletedObjects))
+	for _, deletedBundleObj := range deletedObjects {
+		jobs = append(jobs, s.deleteJob(deletedBundleObj))
+	}
+	s.workerPool.SubmitDeletionsInOrder(jobs)
+}
+
+// deleteJob returns the job deleting the object, which is retried until it succeeds
+func (s *genericBundleSyncer) deleteJob(deletedBundleObj *unstructured.Unstructured) *workers.Job {
+	if !s.enforceHohRbac { // if rbac not enforced, use controller's identity.
+		deletedBundleObj = anonymize(deletedBundleObj) // anonymize removes the user identity from the obj if exists
+	}
+
+	return workers.NewRetryableJob(deletedBundleObj, func(ctx context.Context,
+		k8sClient client.Client, obj interface{},
+	) error {
+		unstructuredObject, _ := obj.(*unstructured.Unstructured)
+
+		// the object isn't targeted at the hub any more, only delete it if it's delivered by the global hub
+		if origin, found := unstructuredObject.GetAnnotations()[constants.OriginOwnerReferenceAnnotation]; found {
+			delivered, err := s.isDelivered(ctx, k8sClient, unstructuredObject, origin)
 			if err != nil {
-				s.log.Error(err, "failed to update object", "name", unstructuredObject.GetName(),
+				s.log.Errorw("failed to get object", "error", err, "name", unstructuredObject.GetName(),
 					"namespace", unstructuredObject.GetNamespace(), "kind", unstructuredObject.GetKind())
-				return
+				s.applyResults.record(unstructuredObject, wiremodels.SpecApplyFailed, true, err)
+				return err
 			}
-			s.log.Debug("object updated", "name", unstructuredObject.GetName(), "namespace",
-				unstructuredObject.GetNamespace(), "kind", unstructuredObject.GetKind())
-		}))
-	}
+			if !delivered {
+				s.applyResults.record(unstructuredObject, wiremodels.SpecApplySkipped, true, nil)
+				return nil
+			}
+		}
+
+		// the object not targeted at the hub is skipped after it's deleted
+		s.driftDetector.ExpectDeletion(unstructuredObject)
+		deleted, err := utils.DeleteObject(ctx, k8sClient, unstructuredObject)
+		if err != nil {
+			s.log.Error("failed to delete object",
+				"error", err,
+				"name", unstructuredObject.GetName(),
+				"namespace", unstructuredObject.GetNamespace(),
+				"kind", unstructuredObject.GetKind())
+			s.applyResults.record(unstructuredObject, wiremodels.SpecApplyFailed, true, err)
+			return err
+		}
+		s.applyResults.record(unstructuredObject, wiremodels.SpecApplySkipped, true, nil)
+		if deleted {
+			s.log.Infow("object deleted", "name", unstructuredObject.GetName(),
+				"namespace", unstructuredObject.GetNamespace(), "kind", unstructuredObject.GetKind())
+		}
+		return nil
+	})
 }
 
-func (s *genericBundleSyncer) syncDeletedObjects(deletedObjects []*unstructured.Unstructured) {
-	for _, deletedBundleObj := range deletedObjects {
-		if !s.enforceHohRbac { // if rbac not enforced, use controller's identity.
-			deletedBundleObj = s.anonymize(deletedBundleObj) // anonymize removes the user identity from the obj if exists
+// isDelivered returns true if the object exists and is delivered from the global hub object of the origin
+func (s *genericBundleSyncer) isDelivered(ctx context.Context, k8sClient client.Client,
+	obj *unstructured.Unstructured, origin string,
+) (bool, error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
 		}
+		return false, err
+	}
+	return existing.GetAnnotations()[constants.OriginOwnerReferenceAnnotation] == origin, nil
+}
 
-		s.workerPool.Submit(workers.NewJob(deletedBundleObj, func(ctx context.Context,
-			k8sClient client.Client, obj interface{},
-		) {
-			defer s.bundleProcessingWaitingGroup.Done()
-
-			unstructuredObject, _ := obj.(*unstructured.Unstructured)
-
-			// syncer.deleteObject(ctx, k8sClient, obj.(*unstructured.Unstructured))
-			if deleted, err := utils.DeleteObject(ctx, k8sClient, unstructuredObject); err != nil {
-				s.log.Error("failed to delete object",
-					"error", err,
-					"name", unstructuredObject.GetName(),
-					"namespace", unstructuredObject.GetNamespace(),
-					"kind", unstructuredObject.GetKind())
-			} else if deleted {
-				s.log.Infow("object deleted", "name", unstructuredObject.GetName(),
-					"namespace", unstructuredObject.GetNamespace(), "kind", unstructuredObject.GetKind())
-			}
-		}))
+// prepareObject removes the fields the hub doesn't accept, and sets the last applied annotation
+func prepareObject(obj *unstructured.Unstructured) error {
+	// Deprecated: skip the "bindingOverrides" from the placementbinding
+	// Reference: https://github.com/open-cluster-management-io/governance-policy-propagator/pull/110
+	delete(obj.Object, "bindingOverrides")
+
+	// Deprecated: skip the "spec.decisionStrategy" and "spec.spreadPolicy" from the placement
+	// Reference:
+	//   "spec.spreadPolicy": https://github.com/open-cluster-management-io/api/pull/225
+	//   "spec.decisionStrategy": https://github.com/open-cluster-management-io/api/pull/242
+	if specMap, ok := obj.Object["spec"].(map[string]interface{}); ok {
+		delete(specMap, "decisionStrategy")
+		delete(specMap, "spreadPolicy")
 	}
+
+	delete(obj.Object, "status")
+	return drift.SetLastApplied(obj)
 }
 
-func (syncer *genericBundleSyncer) anonymize(obj *unstructured.Unstructured) *unstructured.Unstructured {
+func anonymize(obj *unstructured.Unstructured) *unstructured.Unstructured {
 	annotations := obj.GetAnnotations()
 	delete(annotations, rbac.UserIdentityAnnotation)
 	delete(annotations, rbac.UserGroupsAnnotation)
@@ -2,7 +2,9 @@ package workers
 
 import (
 	"context"
+	"fmt"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -12,6 +14,16 @@ import (
 type Job struct {
 	obj         interface{}
 	handlerFunc func(context.Context, client.Client, interface{})
+	// retryHandlerFunc is set for the job retried by the retry queue of the pool until it succeeds
+	retryHandlerFunc func(context.Context, client.Client, interface{}) error
+	key              string
+	kind             string
+	retries          *retryQueue
+	attempts         int
+	// deleted means the job deletes the object
+	deleted bool
+	// done is called once the job is run for the first time after it's submitted
+	done func()
 }
 
 // NewJob creates a new instance of K8sJob.
@@ -21,3 +33,41 @@ func NewJob(obj interface{}, handlerFunc func(context.Context, client.Client, in
 		handlerFunc: handlerFunc,
 	}
 }
+
+// NewRetryableJob creates the job of the object, which is retried with the exponential backoff if the handler fails,
+// until it succeeds, or it's replaced by the next job of the same object.
+func NewRetryableJob(obj *unstructured.Unstructured,
+	handlerFunc func(context.Context, client.Client, interface{}) error,
+) *Job {
+	return &Job{
+		obj:              obj,
+		retryHandlerFunc: handlerFunc,
+		key:              jobKey(obj),
+		kind:             obj.GetKind(),
+	}
+}
+
+// jobKey identifies the object of the retryable job
+func jobKey(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s/%s/%s", obj.GroupVersionKind().GroupKind().String(), obj.GetNamespace(), obj.GetName())
+}
+
+func (job *Job) run(ctx context.Context, k8sClient client.Client) {
+	if job.retryHandlerFunc == nil {
+		job.handlerFunc(ctx, k8sClient, job.obj)
+		job.finish(nil)
+		return
+	}
+	job.finish(job.retryHandlerFunc(ctx, k8sClient, job.obj))
+}
+
+// finish records the result of the retryable job, and notifies the submitter once the job is run for the first time
+func (job *Job) finish(err error) {
+	if job.retries != nil {
+		job.retries.finished(job, err)
+	}
+	if done := job.done; done != nil {
+		job.done = nil
+		done()
+	}
+}
@@ -0,0 +1,63 @@
+package workers
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// kindOrder is the order of applying the kinds, the object is applied after the ones it depends on, e.g. the
+// placement binding refers to the placement and the policy. The kinds not listed are applied with the policies.
+var kindOrder = map[string]int{
+	"Namespace":                0,
+	"ManagedClusterSetBinding": 1,
+	"Placement":                2,
+	"PlacementRule":            2,
+	"Policy":                   3,
+	"PolicySet":                3,
+	"PlacementBinding":         4,
+}
+
+const defaultKindOrder = 3
+
+func orderOf(job *Job) int {
+	obj, ok := job.obj.(*unstructured.Unstructured)
+	if !ok {
+		return defaultKindOrder
+	}
+	if order, found := kindOrder[obj.GetKind()]; found {
+		return order
+	}
+	return defaultKindOrder
+}
+
+// groupJobs groups the jobs by the order of their kinds, the objects are deleted in the reverse order
+func groupJobs(jobs []*Job, deleting bool) [][]*Job {
+	groups := map[int][]*Job{}
+	orders := []int{}
+	for _, job := range jobs {
+		order := orderOf(job)
+		if _, found := groups[order]; !found {
+			orders = append(orders, order)
+		}
+		groups[order] = append(groups[order], job)
+	}
+	sort.Ints(orders)
+	if deleting {
+		sort.Sort(sort.Reverse(sort.IntSlice(orders)))
+	}
+	grouped := make([][]*Job, 0, len(orders))
+	for _, order := range orders {
+		grouped = append(grouped, groups[order])
+	}
+	return grouped
+}
+
+// sortJobs returns the jobs in the order of their kinds
+func sortJobs(jobs []*Job, deleting bool) []*Job {
+	sorted := make([]*Job, 0, len(jobs))
+	for _, group := range groupJobs(jobs, deleting) {
+		sorted = append(sorted, group...)
+	}
+	return sorted
+}
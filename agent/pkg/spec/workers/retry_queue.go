@@ -0,0 +1,247 @@
+package workers
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// retryBaseDelay is the delay of the first retry, it's doubled for each failed retry until the retryMaxDelay
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 5 * time.Minute
+
+	retrySucceeded = "succeeded"
+	retryFailed    = "failed"
+)
+
+var (
+	retryQueueGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "multicluster_global_hub_agent_spec_retry_queue_objects",
+			Help: "The number of the objects of the global resources waiting to be retried.",
+		},
+		[]string{"kind"},
+	)
+	retryCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "multicluster_global_hub_agent_spec_retries_total",
+			Help: "The number of the retries of the objects of the global resources, by the result.",
+		},
+		[]string{"kind", "result"},
+	)
+	retryAttemptsGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "multicluster_global_hub_agent_spec_retry_max_attempts",
+			Help: "The most failed attempts of the objects waiting to be retried.",
+		},
+		[]string{"kind"},
+	)
+)
+
+// RegisterMetrics registers the metrics of the retry queue with the global prometheus registry
+func RegisterMetrics() {
+	metrics.Registry.MustRegister(retryQueueGaugeVec, retryCounterVec, retryAttemptsGaugeVec)
+}
+
+// retryQueue keeps the latest failed job of each object, and resubmits it with the exponential backoff until it
+// succeeds, or the next job of the object is submitted. The failed jobs are kept across the bundles, so the object
+// failed by the missing dependency, e.g. the placement binding applied before its placement, is applied once the
+// dependency is ready without waiting for the next bundle. The failed objects are persisted by the pool, so they're
+// still retried after the agent restarts.
+type retryQueue struct {
+	lock sync.Mutex
+	// latest is the latest job of each object, the earlier ones aren't retried any more
+	latest  map[string]*Job
+	pending map[string]time.Time
+	// failed is the failed object of each latest job, which is persisted by the pool until the job succeeds
+	failed map[string]PersistedRetry
+	wake   chan struct{}
+	now    func() time.Time
+}
+
+func newRetryQueue() *retryQueue {
+	return &retryQueue{
+		latest:  map[string]*Job{},
+		pending: map[string]time.Time{},
+		failed:  map[string]PersistedRetry{},
+		wake:    make(chan struct{}, 1),
+		now:     time.Now,
+	}
+}
+
+// submitted replaces the pending retry of the object with the job
+func (q *retryQueue) submitted(job *Job) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	job.retries = q
+	q.latest[job.key] = job
+	delete(q.pending, job.key)
+	q.updateMetrics()
+}
+
+// cancel forgets the pending retry of the object
+func (q *retryQueue) cancel(key string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.latest, key)
+	delete(q.pending, key)
+	delete(q.failed, key)
+	q.updateMetrics()
+}
+
+// restore retries the job of the object failed before the agent restarted, unless the object has a newer job
+func (q *retryQueue) restore(job *Job, retry PersistedRetry) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if _, found := q.latest[job.key]; found {
+		return false
+	}
+	job.retries = q
+	job.deleted = retry.Deleted
+	job.attempts = retry.Attempts
+	q.latest[job.key] = job
+	q.pending[job.key] = q.now()
+	q.failed[job.key] = retry
+	q.updateMetrics()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// failedRetries returns the failed objects waiting to be retried, ordered by their keys
+func (q *retryQueue) failedRetries() []PersistedRetry {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	keys := make([]string, 0, len(q.failed))
+	for key := range q.failed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	retries := make([]PersistedRetry, 0, len(keys))
+	for _, key := range keys {
+		retries = append(retries, q.failed[key])
+	}
+	return retries
+}
+
+// finished schedules the retry of the failed job, or forgets the succeeded one
+func (q *retryQueue) finished(job *Job, err error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.latest[job.key] != job {
+		// the job is replaced by the next job of the object
+		return
+	}
+	if job.attempts > 0 {
+		result := retrySucceeded
+		if err != nil {
+			result = retryFailed
+		}
+		retryCounterVec.WithLabelValues(job.kind, result).Inc()
+	}
+	if err == nil {
+		delete(q.latest, job.key)
+		delete(q.pending, job.key)
+		delete(q.failed, job.key)
+		q.updateMetrics()
+		return
+	}
+	q.pending[job.key] = q.now().Add(backoff(job.attempts))
+	job.attempts++
+	// the object is copied after the handler returns, since it's changed by the handler
+	if obj, ok := job.obj.(*unstructured.Unstructured); ok {
+		q.failed[job.key] = PersistedRetry{Object: persistedObject(obj), Deleted: job.deleted, Attempts: job.attempts}
+	}
+	q.updateMetrics()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// due returns the jobs to retry at the time, and the time of the next retry, which is zero if there is none
+func (q *retryQueue) due(now time.Time) ([]*Job, time.Time) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	jobs := []*Job{}
+	next := time.Time{}
+	for key, retryAt := range q.pending {
+		if !retryAt.After(now) {
+			jobs = append(jobs, q.latest[key])
+			delete(q.pending, key)
+			continue
+		}
+		if next.IsZero() || retryAt.Before(next) {
+			next = retryAt
+		}
+	}
+	if len(jobs) > 0 {
+		q.updateMetrics()
+	}
+	return jobs, next
+}
+
+// start resubmits the due jobs in the order of their kinds until the context is done
+func (q *retryQueue) start(ctx context.Context, submit func(*Job)) {
+	for {
+		jobs, next := q.due(q.now())
+		for _, job := range sortJobs(jobs, false) {
+			if ctx.Err() != nil {
+				return
+			}
+			submit(job)
+		}
+		wait := retryMaxDelay
+		if !next.IsZero() {
+			wait = next.Sub(q.now())
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// updateMetrics updates the gauges of the objects waiting to be retried, it's called with the lock held
+func (q *retryQueue) updateMetrics() {
+	objects := map[string]float64{}
+	attempts := map[string]float64{}
+	for key := range q.pending {
+		job := q.latest[key]
+		objects[job.kind]++
+		if float64(job.attempts) > attempts[job.kind] {
+			attempts[job.kind] = float64(job.attempts)
+		}
+	}
+	retryQueueGaugeVec.Reset()
+	retryAttemptsGaugeVec.Reset()
+	for kind, count := range objects {
+		retryQueueGaugeVec.WithLabelValues(kind).Set(count)
+		retryAttemptsGaugeVec.WithLabelValues(kind).Set(attempts[kind])
+	}
+}
+
+// backoff returns the delay of the retry after the failed attempts
+func backoff(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 0; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		return retryMaxDelay
+	}
+	return delay
+}
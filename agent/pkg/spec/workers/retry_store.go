@@ -0,0 +1,126 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+)
+
+const (
+	// RetryStateConfigMapName is the ConfigMap of the agent namespace persisting the objects waiting to be retried
+	RetryStateConfigMapName = "multicluster-global-hub-agent-spec-retries"
+	retryStateKey           = "retries"
+	retryStateSyncInterval  = 10 * time.Second
+	// maxRetryStateBytes keeps the ConfigMap under its size limit, the objects beyond it aren't persisted, and they're
+	// applied again with the next bundle after the agent restarts
+	maxRetryStateBytes = 900 * 1024
+)
+
+// PersistedRetry is the failed object waiting to be retried, which is persisted across the restarts of the agent
+type PersistedRetry struct {
+	Object   *unstructured.Unstructured `json:"object"`
+	Deleted  bool                       `json:"deleted,omitempty"`
+	Attempts int                        `json:"attempts"`
+}
+
+// RestoreFunc returns the jobs of the persisted objects to apply and to delete, the objects not allowed by the hub
+// any more are dropped. The objects are persisted again if it fails.
+type RestoreFunc func(ctx context.Context, retries []PersistedRetry) (jobs []*Job, deletedJobs []*Job, err error)
+
+// retryStore reads and writes the objects waiting to be retried in the ConfigMap
+type retryStore struct {
+	client    client.Client
+	namespace string
+	// saved is the state written last time, the ConfigMap is only updated once it changes
+	saved []byte
+}
+
+func (s *retryStore) load(ctx context.Context) ([]PersistedRetry, error) {
+	cm := &corev1.ConfigMap{}
+	err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: RetryStateConfigMapName}, cm)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the retry state - %w", err)
+	}
+	retries := []PersistedRetry{}
+	if data := cm.Data[retryStateKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &retries); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the retry state - %w", err)
+		}
+		s.saved = []byte(data)
+	}
+	return retries, nil
+}
+
+// save writes the retries to the ConfigMap, it returns the number of the retries not persisted due to the size limit
+func (s *retryStore) save(ctx context.Context, retries []PersistedRetry) (int, error) {
+	size, kept := 2, 0
+	for _, retry := range retries {
+		retryBytes, err := json.Marshal(retry)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal the retry state - %w", err)
+		}
+		if size += len(retryBytes) + 1; size > maxRetryStateBytes {
+			break
+		}
+		kept++
+	}
+	dropped := len(retries) - kept
+	data, err := json.Marshal(retries[:kept])
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal the retry state - %w", err)
+	}
+	if s.saved != nil && bytes.Equal(data, s.saved) {
+		return dropped, nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: RetryStateConfigMapName}, cm)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: RetryStateConfigMapName, Namespace: s.namespace},
+			Data:       map[string]string{retryStateKey: string(data)},
+		}
+		if err := s.client.Create(ctx, cm); err != nil {
+			return 0, fmt.Errorf("failed to create the retry state - %w", err)
+		}
+		s.saved = data
+		return dropped, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the retry state - %w", err)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[retryStateKey] = string(data)
+	if err := s.client.Update(ctx, cm); err != nil {
+		return 0, fmt.Errorf("failed to update the retry state - %w", err)
+	}
+	s.saved = data
+	return dropped, nil
+}
+
+// persistedObject returns the copy of the object to persist, the last applied annotation is set again when it's
+// retried, so it isn't persisted to halve the size
+func persistedObject(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	persisted := obj.DeepCopy()
+	annotations := persisted.GetAnnotations()
+	if _, found := annotations[constants.LastAppliedAnnotation]; found {
+		delete(annotations, constants.LastAppliedAnnotation)
+		persisted.SetAnnotations(annotations)
+	}
+	return persisted
+}
@@ -49,7 +49,7 @@ func (worker *Worker) start(ctx context.Context) {
 			case <-ctx.Done(): // received a signal to stop
 				return
 			case job := <-worker.jobQueue: // Worker received a job request.
-				job.handlerFunc(ctx, worker.client, job.obj)
+				job.run(ctx, worker.client)
 			}
 		}
 	}()
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/configs"
 	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/rbac"
 	"github.com/stolostron/multicluster-global-hub/pkg/logger"
 )
@@ -26,13 +30,22 @@ type WorkerPool struct {
 	impersonationManager       *rbac.ImpersonationManager
 	impersonationWorkersQueues map[string]chan *Job
 	impersonationWorkersLock   sync.Mutex
+	retries                    *retryQueue
+	// retryStore persists the failed objects, and restore returns their jobs after the agent restarts
+	retryStore *retryStore
+	restore    RestoreFunc
 }
 
-// AddK8sWorkerPool adds k8s workers pool to the manager and returns it.
-func AddWorkerPoolToMgr(mgr ctrl.Manager, size int, config *rest.Config) (*WorkerPool, error) {
+// AddK8sWorkerPool adds k8s workers pool to the manager and returns it. The failed objects waiting to be retried are
+// persisted in the namespace.
+func AddWorkerPoolToMgr(mgr ctrl.Manager, size int, config *rest.Config, namespace string) (*WorkerPool, error) {
 	if addToMgr {
 		return nil, nil
 	}
+	storeClient, err := client.New(config, client.Options{Scheme: configs.GetRuntimeScheme()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the client of the retry state - %w", err)
+	}
 
 	// for impersonation workers we have additional workers, one per impersonated user.
 	workerPool := &WorkerPool{
@@ -44,8 +57,11 @@ func AddWorkerPoolToMgr(mgr ctrl.Manager, size int, config *rest.Config) (*Worke
 		impersonationManager:       rbac.NewImpersonationManager(config),
 		impersonationWorkersQueues: make(map[string]chan *Job),
 		impersonationWorkersLock:   sync.Mutex{},
+		retries:                    newRetryQueue(),
+		retryStore:                 &retryStore{client: storeClient, namespace: namespace},
 	}
 	workerPool.initializationWaitingGroup.Add(1)
+	RegisterMetrics()
 
 	if err := mgr.Add(workerPool); err != nil {
 		return nil, err
@@ -68,6 +84,10 @@ func (pool *WorkerPool) Start(ctx context.Context) error {
 
 		worker.start(ctx)
 	}
+	go pool.retries.start(ctx, pool.dispatch)
+	if pool.retryStore != nil {
+		go pool.persistRetries(ctx)
+	}
 
 	<-ctx.Done() // blocking wait for stop event
 
@@ -81,12 +101,117 @@ func (pool *WorkerPool) Start(ctx context.Context) error {
 	return nil
 }
 
+// SubmitInOrder submits the jobs of the objects in the order of their kinds, the jobs of each kind are submitted
+// after the ones of the kinds they depend on are run, and it returns once all the jobs are run. The failed retryable
+// jobs are retried by the pool afterwards.
+func (pool *WorkerPool) SubmitInOrder(jobs []*Job) {
+	pool.submitGroups(groupJobs(jobs, false))
+}
+
+// SubmitDeletionsInOrder submits the jobs deleting the objects in the reverse order of SubmitInOrder, and returns once
+// all the jobs are run.
+func (pool *WorkerPool) SubmitDeletionsInOrder(jobs []*Job) {
+	for _, job := range jobs {
+		job.deleted = true
+	}
+	pool.submitGroups(groupJobs(jobs, true))
+}
+
+// SetRestoreFunc sets the function returning the jobs of the objects failed before the agent restarted, it's called
+// before the pool is started. The failed objects aren't restored without it.
+func (pool *WorkerPool) SetRestoreFunc(restore RestoreFunc) {
+	pool.restore = restore
+}
+
+// persistRetries restores the failed objects persisted before the agent restarted, and then persists the failed
+// objects periodically. The state isn't overwritten until it's restored.
+func (pool *WorkerPool) persistRetries(ctx context.Context) {
+	restored := pool.restore == nil
+	ticker := time.NewTicker(retryStateSyncInterval)
+	defer ticker.Stop()
+	for {
+		if !restored {
+			if err := pool.restoreRetries(ctx); err != nil {
+				pool.log.Errorw("failed to restore the retries", "error", err)
+			} else {
+				restored = true
+			}
+		}
+		if restored {
+			dropped, err := pool.retryStore.save(ctx, pool.retries.failedRetries())
+			if err != nil {
+				pool.log.Errorw("failed to persist the retries", "error", err)
+			}
+			if dropped > 0 {
+				pool.log.Warnw("the retries exceeding the size limit aren't persisted", "dropped", dropped)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (pool *WorkerPool) restoreRetries(ctx context.Context) error {
+	retries, err := pool.retryStore.load(ctx)
+	if err != nil || len(retries) == 0 {
+		return err
+	}
+	persisted := map[string]PersistedRetry{}
+	for _, retry := range retries {
+		if retry.Object != nil {
+			persisted[jobKey(retry.Object)] = retry
+		}
+	}
+	jobs, deletedJobs, err := pool.restore(ctx, retries)
+	if err != nil {
+		return err
+	}
+	restored := 0
+	for _, job := range append(jobs, deletedJobs...) {
+		if retry, found := persisted[job.key]; found && pool.retries.restore(job, retry) {
+			restored++
+		}
+	}
+	pool.log.Infow("restored the retries", "persisted", len(retries), "restored", restored)
+	return nil
+}
+
+func (pool *WorkerPool) submitGroups(groups [][]*Job) {
+	for _, group := range groups {
+		wg := sync.WaitGroup{}
+		wg.Add(len(group))
+		for _, job := range group {
+			job.done = wg.Done
+			pool.Submit(job)
+		}
+		wg.Wait()
+	}
+}
+
 func (pool *WorkerPool) Submit(job *Job) {
 	pool.initializationWaitingGroup.Wait() // start running jobs only after some initialization steps have finished.
 
+	if job.retryHandlerFunc != nil {
+		// the job replaces the pending retry of the object
+		pool.retries.submitted(job)
+	}
+	pool.dispatch(job)
+}
+
+// Cancel stops retrying the failed job of the object, e.g. the object isn't allowed to be applied on the hub any more
+func (pool *WorkerPool) Cancel(obj *unstructured.Unstructured) {
+	pool.retries.cancel(jobKey(obj))
+}
+
+// dispatch pushes the job to the queue of the worker using the identity of the object
+func (pool *WorkerPool) dispatch(job *Job) {
 	userIdentity, err := pool.impersonationManager.GetUserIdentity(job.obj)
 	if err != nil {
 		pool.log.Error(err, "failed to get user identity from obj")
+		job.finish(err)
 		return
 	}
 	// if it doesn't contain impersonation info, let the controller worker pool handle it.
@@ -98,6 +223,7 @@ func (pool *WorkerPool) Submit(job *Job) {
 	base64UserGroups, userGroups, err := pool.impersonationManager.GetUserGroups(job.obj)
 	if err != nil {
 		pool.log.Error(err, "failed to get user groups from obj")
+		job.finish(err)
 		return
 	}
 
@@ -107,7 +233,9 @@ func (pool *WorkerPool) Submit(job *Job) {
 
 	if _, found := pool.impersonationWorkersQueues[workerIdentifier]; !found {
 		if err := pool.createUserWorker(userIdentity, userGroups, workerIdentifier); err != nil {
+			pool.impersonationWorkersLock.Unlock()
 			pool.log.Error(err, "failed to create user worker", "user", userIdentity)
+			job.finish(err)
 			return
 		}
 	}
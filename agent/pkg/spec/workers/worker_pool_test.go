@@ -0,0 +1,233 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/multicluster-global-hub/agent/pkg/spec/rbac"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+)
+
+func newObject(kind, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	return obj
+}
+
+// newTestPool returns the started pool with the workers of the nil client, and the retries by the clock
+func newTestPool(ctx context.Context, size int, now func() time.Time) *WorkerPool {
+	pool := &WorkerPool{
+		log:                        logger.DefaultZapLogger(),
+		jobsQueue:                  make(chan *Job, size),
+		poolSize:                   size,
+		impersonationManager:       rbac.NewImpersonationManager(&rest.Config{}),
+		impersonationWorkersQueues: map[string]chan *Job{},
+		retries:                    newRetryQueue(),
+	}
+	pool.ctx = ctx
+	pool.retries.now = now
+	for i := 1; i <= size; i++ {
+		newWorkerWithClient(i, "", nil, pool.jobsQueue).start(ctx)
+	}
+	go pool.retries.start(ctx, pool.dispatch)
+	return pool
+}
+
+func TestSubmitInOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := newTestPool(ctx, 4, time.Now)
+
+	lock := sync.Mutex{}
+	applied := []string{}
+	record := func(ctx context.Context, c client.Client, obj interface{}) error {
+		lock.Lock()
+		defer lock.Unlock()
+		applied = append(applied, obj.(*unstructured.Unstructured).GetKind())
+		return nil
+	}
+	jobs := []*Job{}
+	for _, kind := range []string{"PlacementBinding", "Policy", "ConfigMap", "Placement", "ManagedClusterSetBinding",
+		"Namespace"} {
+		jobs = append(jobs, NewRetryableJob(newObject(kind, "obj1"), record))
+	}
+
+	pool.SubmitInOrder(jobs)
+	if len(applied) != len(jobs) {
+		t.Fatalf("expected all the jobs are run, but got %v", applied)
+	}
+	order := func(kind string) int {
+		for i, appliedKind := range applied {
+			if appliedKind == kind {
+				return i
+			}
+		}
+		return -1
+	}
+	if !(order("Namespace") < order("ManagedClusterSetBinding") &&
+		order("ManagedClusterSetBinding") < order("Placement") && order("Placement") < order("Policy") &&
+		order("Placement") < order("ConfigMap") && order("Policy") < order("PlacementBinding")) {
+		t.Errorf("unexpected order of the applied kinds %v", applied)
+	}
+
+	applied = []string{}
+	pool.SubmitDeletionsInOrder(jobs)
+	if applied[0] != "PlacementBinding" || applied[len(applied)-1] != "Namespace" {
+		t.Errorf("unexpected order of the deleted kinds %v", applied)
+	}
+}
+
+func TestRetryQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := sync.Mutex{}
+	now := time.Now()
+	pool := newTestPool(ctx, 2, func() time.Time {
+		clock.Lock()
+		defer clock.Unlock()
+		return now
+	})
+
+	attempts := make(chan int, 10)
+	count := 0
+	failing := NewRetryableJob(newObject("PlacementBinding", "binding1"),
+		func(ctx context.Context, c client.Client, obj interface{}) error {
+			count++
+			attempts <- count
+			if count < 3 {
+				return errors.New("the placement isn't found")
+			}
+			return nil
+		})
+	pool.SubmitInOrder([]*Job{failing})
+	<-attempts
+
+	waitFor(t, func() bool {
+		pool.retries.lock.Lock()
+		defer pool.retries.lock.Unlock()
+		return pool.retries.pending[failing.key].Equal(now.Add(retryBaseDelay))
+	})
+
+	// the retry is run once it's due, and the delay is doubled after it fails again
+	clock.Lock()
+	now = now.Add(retryBaseDelay)
+	clock.Unlock()
+	pool.retries.wake <- struct{}{}
+	if attempt := <-attempts; attempt != 2 {
+		t.Fatalf("expected the second attempt, but got %d", attempt)
+	}
+	waitFor(t, func() bool {
+		pool.retries.lock.Lock()
+		defer pool.retries.lock.Unlock()
+		return pool.retries.pending[failing.key].Equal(now.Add(2 * retryBaseDelay))
+	})
+
+	// the next job of the object replaces the pending retry
+	replaced := NewRetryableJob(newObject("PlacementBinding", "binding1"),
+		func(ctx context.Context, c client.Client, obj interface{}) error { return nil })
+	pool.SubmitInOrder([]*Job{replaced})
+	pool.retries.lock.Lock()
+	if len(pool.retries.pending) != 0 || len(pool.retries.latest) != 0 {
+		t.Errorf("expected the retry is replaced, but got %v", pool.retries.pending)
+	}
+	pool.retries.lock.Unlock()
+}
+
+func TestCancelRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := newTestPool(ctx, 1, time.Now)
+
+	obj := newObject("Policy", "policy1")
+	pool.SubmitInOrder([]*Job{NewRetryableJob(obj, func(ctx context.Context, c client.Client, obj interface{}) error {
+		return errors.New("the namespace isn't found")
+	})})
+	pool.retries.lock.Lock()
+	if len(pool.retries.pending) != 1 {
+		t.Errorf("expected the failed job is retried, but got %v", pool.retries.pending)
+	}
+	pool.retries.lock.Unlock()
+
+	pool.Cancel(obj)
+	pool.retries.lock.Lock()
+	defer pool.retries.lock.Unlock()
+	if len(pool.retries.pending) != 0 || len(pool.retries.latest) != 0 {
+		t.Errorf("expected the retry is canceled, but got %v", pool.retries.pending)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if backoff(0) != retryBaseDelay || backoff(1) != 2*retryBaseDelay || backoff(2) != 4*retryBaseDelay {
+		t.Errorf("expected the exponential backoff, but got %s, %s, %s", backoff(0), backoff(1), backoff(2))
+	}
+	if backoff(100) != retryMaxDelay {
+		t.Errorf("expected the backoff is limited to %s, but got %s", retryMaxDelay, backoff(100))
+	}
+}
+
+func TestPersistRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &retryStore{client: fake.NewClientBuilder().Build(), namespace: "default"}
+	pool := newTestPool(ctx, 1, time.Now)
+
+	failing := func(ctx context.Context, c client.Client, obj interface{}) error {
+		return errors.New("the placement isn't found")
+	}
+	pool.SubmitInOrder([]*Job{NewRetryableJob(newObject("PlacementBinding", "binding1"), failing)})
+	pool.SubmitDeletionsInOrder([]*Job{NewRetryableJob(newObject("Policy", "policy1"), failing)})
+	if _, err := store.save(ctx, pool.retries.failedRetries()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failed objects are retried by the pool after the agent restarts
+	restarted := newTestPool(ctx, 1, time.Now)
+	restarted.retryStore = &retryStore{client: store.client, namespace: store.namespace}
+	restarted.SetRestoreFunc(func(ctx context.Context, retries []PersistedRetry) ([]*Job, []*Job, error) {
+		jobs, deletedJobs := []*Job{}, []*Job{}
+		for _, retry := range retries {
+			job := NewRetryableJob(retry.Object, failing)
+			if retry.Deleted {
+				deletedJobs = append(deletedJobs, job)
+			} else {
+				jobs = append(jobs, job)
+			}
+		}
+		return jobs, deletedJobs, nil
+	})
+	if err := restarted.restoreRetries(ctx); err != nil {
+		t.Fatal(err)
+	}
+	restarted.retries.lock.Lock()
+	defer restarted.retries.lock.Unlock()
+	if len(restarted.retries.latest) != 2 {
+		t.Fatalf("expected the failed objects are retried, but got %v", restarted.retries.latest)
+	}
+	for key, job := range restarted.retries.latest {
+		if job.attempts < 1 || job.deleted != (job.kind == "Policy") {
+			t.Errorf("expected the retry %s is restored, but got attempts %d and deleted %v", key, job.attempts,
+				job.deleted)
+		}
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the condition")
+}
@@ -29,11 +29,15 @@ import (
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/cronjob"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/hubmanagement"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
 	specsyncer "github.com/stolostron/multicluster-global-hub/manager/pkg/spec"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/sharding"
 	mgrwebhook "github.com/stolostron/multicluster-global-hub/manager/pkg/webhook"
 	"github.com/stolostron/multicluster-global-hub/pkg/constants"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/archive"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/migration"
 	"github.com/stolostron/multicluster-global-hub/pkg/logger"
 	commonobjects "github.com/stolostron/multicluster-global-hub/pkg/objects"
 	"github.com/stolostron/multicluster-global-hub/pkg/statistics"
@@ -50,6 +54,15 @@ const (
 	kafkaTransportType         = "kafka"
 	leaderElectionLockID       = "multicluster-global-hub-manager-lock"
 	launchJobNamesEnv          = "LAUNCH_JOB_NAMES"
+	podNameEnv                 = "POD_NAME"
+	archiveEndpointEnv         = "DATA_ARCHIVE_ENDPOINT"
+	archiveBucketEnv           = "DATA_ARCHIVE_BUCKET"
+	archiveRegionEnv           = "DATA_ARCHIVE_REGION"
+	archivePrefixEnv           = "DATA_ARCHIVE_PREFIX"
+	archiveInsecureEnv         = "DATA_ARCHIVE_INSECURE"
+	archiveAccessKeyEnv        = "DATA_ARCHIVE_ACCESS_KEY_ID"
+	archiveSecretKeyEnv        = "DATA_ARCHIVE_SECRET_ACCESS_KEY"
+	defaultArchiveBucket       = "multicluster-global-hub-archive"
 	namespacePath              = "metadata.namespace"
 )
 
@@ -62,8 +75,10 @@ var (
 
 func parseFlags() *configs.ManagerConfig {
 	managerConfig := &configs.ManagerConfig{
-		SyncerConfig:   &configs.SyncerConfig{},
-		DatabaseConfig: &configs.DatabaseConfig{},
+		SyncerConfig: &configs.SyncerConfig{},
+		DatabaseConfig: &configs.DatabaseConfig{
+			ArchiveConfig: &archive.Config{},
+		},
 		TransportConfig: &transport.TransportInternalConfig{
 			IsManager:            true,
 			ConsumerGroupId:      "global-hub-manager",
@@ -72,6 +87,7 @@ func parseFlags() *configs.ManagerConfig {
 		StatisticsConfig:    &statistics.StatisticsConfig{},
 		RestAPIServerConfig: &restapis.RestApiServerConfig{},
 		ElectionConfig:      &commonobjects.LeaderElectionConfig{},
+		ShardingConfig:      &configs.ShardingConfig{},
 		LaunchJobNames:      "",
 	}
 
@@ -83,7 +99,8 @@ func parseFlags() *configs.ManagerConfig {
 		"The job scheduler interval for moving policy compliance history, "+
 			"can be 'month', 'week', 'day', 'hour', 'minute' or 'second', default value is 'day'.")
 	pflag.DurationVar(&managerConfig.SyncerConfig.SpecSyncInterval, "spec-sync-interval", 5*time.Second,
-		"The synchronization interval of resources in spec.")
+		"The synchronization interval of resources in spec, the changes of the spec tables are also synced once "+
+			"they're notified.")
 	pflag.DurationVar(&managerConfig.SyncerConfig.StatusSyncInterval, "status-sync-interval", 5*time.Second,
 		"The synchronization interval of resources in status.")
 	pflag.DurationVar(&managerConfig.SyncerConfig.DeletedLabelsTrimmingInterval, "deleted-labels-trimming-interval",
@@ -106,9 +123,25 @@ func parseFlags() *configs.ManagerConfig {
 		"/var/run/secrets/kubernetes.io/serviceaccount/ca.crt", "The CA bundle path for cluster API.")
 	pflag.StringVar(&managerConfig.RestAPIServerConfig.ServerBasePath, "server-base-path",
 		"/global-hub-api/v1", "The base path for nonK8s API server.")
+	pflag.StringVar(&managerConfig.RestAPIServerConfig.AuthorizationMode, "authorization-mode",
+		authorization.ModeSubjectAccessReview, "The authorization of the nonK8s API server: SubjectAccessReview, "+
+			"OPA or None.")
+	pflag.StringVar(&managerConfig.RestAPIServerConfig.OPAURL, "opa-url", "",
+		"The policy decision URL of the OPA authorization, e.g. http://localhost:8181/v1/data/globalhub/allow.")
 	pflag.IntVar(&managerConfig.ElectionConfig.LeaseDuration, "lease-duration", 137, "controller leader lease duration")
 	pflag.IntVar(&managerConfig.ElectionConfig.RenewDeadline, "renew-deadline", 107, "controller leader renew deadline")
 	pflag.IntVar(&managerConfig.ElectionConfig.RetryPeriod, "retry-period", 26, "controller leader retry period")
+	pflag.BoolVar(&managerConfig.ShardingConfig.Enabled, "enable-status-sharding", false,
+		"split the status of the leaf hubs between the active manager replicas instead of the leader only")
+	pflag.DurationVar(&managerConfig.ShardingConfig.LeaseDuration, "shard-lease-duration", 30*time.Second,
+		"the duration that the other replicas wait before taking over the hubs of an unresponsive replica")
+	pflag.DurationVar(&managerConfig.ShardingConfig.RenewPeriod, "shard-renew-period", 10*time.Second,
+		"the interval to renew the shard lease and rebalance the hubs between the replicas")
+	pflag.IntVar(&managerConfig.ShardingConfig.MaxMembers, "shard-max-members", 4,
+		"the number of the shard members, which bounds the consumer groups of the replicas. It should cover the "+
+			"replicas surged in the rollout")
+	pflag.IntVar(&managerConfig.ShardingConfig.VirtualNodes, "shard-virtual-nodes", sharding.DefaultVirtualNodes,
+		"the points of each shard member on the hash ring, the more points the more evenly the hubs are spread")
 	pflag.IntVar(&managerConfig.DatabaseConfig.DataRetention, "data-retention", 18,
 		"data retention indicates how many months the expired data will kept in the database")
 	pflag.BoolVar(&managerConfig.EnableGlobalResource, "enable-global-resource", false,
@@ -118,6 +151,12 @@ func parseFlags() *configs.ManagerConfig {
 	pflag.BoolVar(&managerConfig.ImportClusterInHosted, "import-cluster-in-hosted", false,
 		"import cluster in hosted mode")
 	pflag.BoolVar(&managerConfig.EnablePprof, "enable-pprof", false, "enable the pprof tool")
+	pflag.BoolVar(&managerConfig.PrintPendingMigrations, "print-pending-migrations", false,
+		"print the schema migrations haven't been applied to the database, then exit")
+	pflag.StringVar(&managerConfig.RestoreArchive, "restore-archive", "",
+		"restore the archived partition of the manifest object key into the restore schema, then exit")
+	pflag.StringVar(&managerConfig.RestoreSchema, "restore-schema", "archive_restore",
+		"the schema to restore the archived partition")
 	pflag.IntVar(&managerConfig.TransportConfig.FailureThreshold, "transport-failure-threshold", 10,
 		"Restart the pod if the transport error count exceeds the transport-failure-threshold within 5 minutes.")
 	pflag.Parse()
@@ -141,6 +180,27 @@ func completeConfig(managerConfig *configs.ManagerConfig) error {
 	if ok && val != "" {
 		managerConfig.LaunchJobNames = val
 	}
+	if managerConfig.ShardingConfig.Enabled {
+		managerConfig.ShardingConfig.PodName = os.Getenv(podNameEnv)
+		if managerConfig.ShardingConfig.PodName == "" {
+			return fmt.Errorf("env %s for status sharding: %w", podNameEnv, errFlagParameterEmpty)
+		}
+	}
+	// the object storage to archive the expired partitions is read from the optional secret
+	archiveConfig := managerConfig.DatabaseConfig.ArchiveConfig
+	archiveConfig.Endpoint = os.Getenv(archiveEndpointEnv)
+	archiveConfig.Bucket = os.Getenv(archiveBucketEnv)
+	if archiveConfig.Bucket == "" {
+		archiveConfig.Bucket = defaultArchiveBucket
+	}
+	archiveConfig.Region = os.Getenv(archiveRegionEnv)
+	archiveConfig.Prefix = os.Getenv(archivePrefixEnv)
+	archiveConfig.Insecure = os.Getenv(archiveInsecureEnv) == "true"
+	archiveConfig.AccessKeyID = os.Getenv(archiveAccessKeyEnv)
+	archiveConfig.SecretAccessKey = os.Getenv(archiveSecretKeyEnv)
+	if managerConfig.RestoreArchive != "" && !archiveConfig.Enabled() {
+		return fmt.Errorf("env %s for restoring the archive: %w", archiveEndpointEnv, errFlagParameterEmpty)
+	}
 	return nil
 }
 
@@ -206,11 +266,24 @@ func createManager(ctx context.Context,
 		return nil, fmt.Errorf("failed to add configmap controller to manager: %w", err)
 	}
 
-	err = controller.NewTransportCtrl(managerConfig.ManagerNamespace, constants.GHTransportConfigSecret,
+	transportCtrl := controller.NewTransportCtrl(managerConfig.ManagerNamespace, constants.GHTransportConfigSecret,
 		transportCallback(mgr, managerConfig),
 		managerConfig.TransportConfig,
-	).SetupWithManager(mgr)
-	if err != nil {
+	)
+	// the status path needs the consumer on all the replicas
+	if managerConfig.ShardingConfig.Enabled {
+		if err := sharding.AcquireMember(ctx, mgr.GetClient(), mgr.GetAPIReader(), managerConfig.ManagerNamespace,
+			managerConfig.ShardingConfig); err != nil {
+			return nil, fmt.Errorf("failed to acquire the shard member: %w", err)
+		}
+		// every replica consumes all the status events, and only processes the events from the hubs it owns. The
+		// consumer group and offsets are named by the member, so they're reused by the replica in the next rollout
+		managerConfig.TransportConfig.ConsumerGroupId = fmt.Sprintf("%s-%s",
+			managerConfig.TransportConfig.ConsumerGroupId, managerConfig.ShardingConfig.Identity)
+		managerConfig.TransportConfig.OffsetMember = managerConfig.ShardingConfig.Identity
+		transportCtrl = transportCtrl.WithoutLeaderElection()
+	}
+	if err = transportCtrl.SetupWithManager(mgr); err != nil {
 		return nil, fmt.Errorf("failed to add the transport controller")
 	}
 
@@ -248,6 +321,11 @@ func transportCallback(mgr ctrl.Manager, managerConfig *configs.ManagerConfig) c
 			}
 		}
 
+		// shard the status of the leaf hubs between the replicas
+		if err := sharding.AddShardManager(mgr, managerConfig, producer); err != nil {
+			return fmt.Errorf("failed to add the shard manager: %w", err)
+		}
+
 		if err := status.AddStatusSyncers(mgr, consumer, managerConfig); err != nil {
 			return fmt.Errorf("failed to add transport-to-db syncers: %w", err)
 		}
@@ -292,6 +370,23 @@ func doMain(ctx context.Context, restConfig *rest.Config) error {
 	}
 	defer database.CloseGorm(database.GetSqlDb())
 
+	migrator, err := migration.NewMigrator(database.GetSqlDb())
+	if err != nil {
+		return fmt.Errorf("failed to load the schema migrations %w", err)
+	}
+	if managerConfig.PrintPendingMigrations {
+		return printPendingMigrations(ctx, migrator)
+	}
+	if managerConfig.RestoreArchive != "" {
+		return restoreArchive(ctx, managerConfig)
+	}
+	// refuse to write the database which is migrated by a newer version
+	if err := migrator.CheckCompatible(ctx); errors.Is(err, migration.ErrNewerSchema) {
+		return fmt.Errorf("the manager doesn't support the database schema: %w", err)
+	} else if err != nil {
+		log.Warnw("failed to check the database schema version", "error", err)
+	}
+
 	// Init the backup gorm instance, it's used to add lock when backup database
 	_, sqlBackupConn, err := database.NewGormConn(databaseConfig)
 	if err != nil {
@@ -323,6 +418,35 @@ func doMain(ctx context.Context, restConfig *rest.Config) error {
 	return nil
 }
 
+func printPendingMigrations(ctx context.Context, migrator *migration.Migrator) error {
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the pending migrations: %w", err)
+	}
+	if len(pending) == 0 {
+		fmt.Println("the database schema is up to date")
+		return nil
+	}
+	for _, m := range pending {
+		fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, m.Checksum)
+	}
+	return nil
+}
+
+func restoreArchive(ctx context.Context, managerConfig *configs.ManagerConfig) error {
+	archiver, err := archive.NewArchiver(managerConfig.DatabaseConfig.ArchiveConfig,
+		managerConfig.DatabaseConfig.ProcessDatabaseURL, managerConfig.DatabaseConfig.CACertPath)
+	if err != nil {
+		return fmt.Errorf("failed to create the archiver: %w", err)
+	}
+	table, err := archiver.Restore(ctx, managerConfig.RestoreArchive, managerConfig.RestoreSchema)
+	if err != nil {
+		return fmt.Errorf("failed to restore the archive %s: %w", managerConfig.RestoreArchive, err)
+	}
+	fmt.Printf("the archive %s is restored into the table %s\n", managerConfig.RestoreArchive, table)
+	return nil
+}
+
 func main() {
 	defer func() { _ = logger.CoreZapLogger().Sync() }()
 	if err := doMain(ctrl.SetupSignalHandler(), ctrl.GetConfigOrDie()); err != nil {
@@ -0,0 +1,202 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+)
+
+const (
+	// the sources of the audit events
+	SourceRESTAPI = "restapi"
+	SourceSpec    = "spec"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	// UnknownUser is the user of the global resource without the user identity annotation
+	UnknownUser = "unknown"
+
+	// the base64 encoded user identity and the comma separated groups of the global resource, they're the same
+	// annotations impersonated by the agent
+	UserIdentityAnnotation = "open-cluster-management.io/user-identity"
+	UserGroupsAnnotation   = "open-cluster-management.io/user-group"
+)
+
+// the audit events are streamed to the log besides the table, so they can be collected with the other logs
+var auditLog = logger.ZapLogger("audit")
+
+// Object is the object affected by the audited action. The Before and After are the changed part of the object, e.g.
+// the labels of the managed cluster or the global resource, the Before is empty for the created object and the After is
+// empty for the deleted one.
+type Object struct {
+	ID          string      `json:"id,omitempty"`
+	Name        string      `json:"name"`
+	Namespace   string      `json:"namespace,omitempty"`
+	LeafHubName string      `json:"leafHubName,omitempty"`
+	Before      interface{} `json:"before,omitempty"`
+	After       interface{} `json:"after,omitempty"`
+}
+
+// Request is the REST API request of the audit event
+type Request struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  string          `json:"query,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Event is the audit event of the REST API mutation or the change of the global resource
+type Event struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Source    string    `json:"source"`
+	User      string    `json:"user"`
+	Groups    []string  `json:"groups,omitempty"`
+	Action    string    `json:"action"`
+	Resource  string    `json:"resource"`
+	Request   *Request  `json:"request,omitempty"`
+	Objects   []Object  `json:"objects"`
+	Result    string    `json:"result"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// Record writes the event to the audit log and the audit.events table. The event is logged even if it fails to be
+// inserted, so the audit trail isn't lost when the database is unavailable.
+func Record(ctx context.Context, event *Event) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if event.User == "" {
+		event.User = UnknownUser
+	}
+	if event.Objects == nil {
+		event.Objects = []Object{}
+	}
+	auditLog.Infow("audit event", "id", event.ID, "source", event.Source, "user", event.User, "groups",
+		event.Groups, "action", event.Action, "resource", event.Resource, "request", event.Request, "objects",
+		event.Objects, "result", event.Result, "message", event.Message)
+
+	row, err := toModel(event)
+	if err != nil {
+		return err
+	}
+	db := database.GetGorm()
+	if db == nil {
+		return fmt.Errorf("failed to insert the audit event %s: the database isn't initialized", event.ID)
+	}
+	if err := db.WithContext(ctx).Create(row).Error; err != nil {
+		return fmt.Errorf("failed to insert the audit event %s: %w", event.ID, err)
+	}
+	return nil
+}
+
+func toModel(event *Event) (*models.AuditEvent, error) {
+	row := &models.AuditEvent{
+		ID:        event.ID,
+		CreatedAt: event.CreatedAt,
+		Source:    event.Source,
+		Username:  event.User,
+		Action:    event.Action,
+		Resource:  event.Resource,
+		Result:    event.Result,
+		Message:   event.Message,
+	}
+	var err error
+	if len(event.Groups) > 0 {
+		if row.Groups, err = json.Marshal(event.Groups); err != nil {
+			return nil, err
+		}
+	}
+	if event.Request != nil {
+		if row.Request, err = json.Marshal(event.Request); err != nil {
+			return nil, err
+		}
+	}
+	if row.Objects, err = json.Marshal(event.Objects); err != nil {
+		return nil, fmt.Errorf("failed to marshal the objects of the audit event: %w", err)
+	}
+	return row, nil
+}
+
+// FromModel converts the row of the audit.events to the event
+func FromModel(row *models.AuditEvent) (*Event, error) {
+	event := &Event{
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		Source:    row.Source,
+		User:      row.Username,
+		Action:    row.Action,
+		Resource:  row.Resource,
+		Result:    row.Result,
+		Message:   row.Message,
+		Objects:   []Object{},
+	}
+	if len(row.Groups) > 0 {
+		if err := json.Unmarshal(row.Groups, &event.Groups); err != nil {
+			return nil, err
+		}
+	}
+	if len(row.Request) > 0 {
+		event.Request = &Request{}
+		if err := json.Unmarshal(row.Request, event.Request); err != nil {
+			return nil, err
+		}
+	}
+	if len(row.Objects) > 0 {
+		if err := json.Unmarshal(row.Objects, &event.Objects); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
+
+// Result returns the result of the action and its message
+func Result(err error) (string, string) {
+	if err != nil {
+		return ResultFailure, err.Error()
+	}
+	return ResultSuccess, ""
+}
+
+// UserFromAnnotations returns the user and groups decoded from the identity annotations of the global resource, the
+// user is unknown if the annotation is missing or can't be decoded.
+func UserFromAnnotations(annotations map[string]string) (string, []string) {
+	user := decodeAnnotation(annotations, UserIdentityAnnotation)
+	if user == "" {
+		user = UnknownUser
+	}
+	var groups []string
+	if decoded := decodeAnnotation(annotations, UserGroupsAnnotation); decoded != "" {
+		groups = strings.Split(decoded, ",")
+	}
+	return user, groups
+}
+
+func decodeAnnotation(annotations map[string]string, annotation string) string {
+	value, found := annotations[annotation]
+	if !found {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		auditLog.Warnw("failed to decode the annotation", "annotation", annotation, "error", err)
+		return ""
+	}
+	return string(decoded)
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package audit
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestModelRoundTrip(t *testing.T) {
+	event := &Event{
+		ID:        "3f1c6a4e-7d1b-4c55-9b83-8a3f0d1e2b4c",
+		CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Source:    SourceRESTAPI,
+		User:      "alice",
+		Groups:    []string{"dev", "ops"},
+		Action:    "patch",
+		Resource:  "managedclusters",
+		Request:   &Request{Method: "PATCH", Path: "/global-hub-api/v1/managedcluster/1", Body: []byte(`[{"op":"add"}]`)},
+		Objects: []Object{{
+			ID: "1", Name: "cluster1", LeafHubName: "hub1",
+			Before: map[string]interface{}{"env": "dev"}, After: map[string]interface{}{"env": "prod"},
+		}},
+		Result: ResultSuccess,
+	}
+	row, err := toModel(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Username != "alice" || row.TableName() != "audit.events" {
+		t.Errorf("unexpected row %+v", row)
+	}
+	actual, err := FromModel(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, event) {
+		t.Errorf("expected %+v, but got %+v", event, actual)
+	}
+}
+
+func TestUserFromAnnotations(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	user, groups := UserFromAnnotations(map[string]string{
+		UserIdentityAnnotation: encode("alice"),
+		UserGroupsAnnotation:   encode("dev,ops"),
+	})
+	if user != "alice" || !reflect.DeepEqual(groups, []string{"dev", "ops"}) {
+		t.Errorf("unexpected user %s and groups %v", user, groups)
+	}
+	if user, groups := UserFromAnnotations(nil); user != UnknownUser || groups != nil {
+		t.Errorf("expected the unknown user, but got %s and %v", user, groups)
+	}
+	if user, _ := UserFromAnnotations(map[string]string{UserIdentityAnnotation: "not base64!"}); user != UnknownUser {
+		t.Errorf("expected the unknown user for the invalid annotation, but got %s", user)
+	}
+}
+
+func TestResult(t *testing.T) {
+	if result, message := Result(nil); result != ResultSuccess || message != "" {
+		t.Errorf("unexpected result %s: %s", result, message)
+	}
+	if result, message := Result(errors.New("conflict")); result != ResultFailure || message != "conflict" {
+		t.Errorf("unexpected result %s: %s", result, message)
+	}
+}
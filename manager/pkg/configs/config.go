@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/archive"
 	commonobjects "github.com/stolostron/multicluster-global-hub/pkg/objects"
 	"github.com/stolostron/multicluster-global-hub/pkg/statistics"
 	"github.com/stolostron/multicluster-global-hub/pkg/transport"
@@ -22,11 +23,18 @@ type ManagerConfig struct {
 	StatisticsConfig      *statistics.StatisticsConfig
 	RestAPIServerConfig   *restapis.RestApiServerConfig
 	ElectionConfig        *commonobjects.LeaderElectionConfig
+	ShardingConfig        *ShardingConfig
 	EnableGlobalResource  bool
 	ImportClusterInHosted bool
 	WithACM               bool
 	LaunchJobNames        string
 	EnablePprof           bool
+	// PrintPendingMigrations prints the pending schema migrations without starting the manager
+	PrintPendingMigrations bool
+	// RestoreArchive is the manifest of the archived partition to restore into the RestoreSchema, the manager exits
+	// after restoring it
+	RestoreArchive string
+	RestoreSchema  string
 }
 
 type SyncerConfig struct {
@@ -41,4 +49,21 @@ type DatabaseConfig struct {
 	CACertPath                 string
 	MaxOpenConns               int
 	DataRetention              int
+	// ArchiveConfig is the object storage to archive the expired partitions, the archive is disabled if it's empty
+	ArchiveConfig *archive.Config
+}
+
+// ShardingConfig splits the status of the leaf hubs between the active manager replicas
+type ShardingConfig struct {
+	Enabled bool
+	// Identity is the member acquired by the replica, e.g. shard-0. It's stable across the rollouts, so it's used to
+	// name the consumer group and the offsets of the replica
+	Identity string
+	// PodName is the holder of the member lease
+	PodName string
+	// MaxMembers is the number of the members can be acquired, it should cover the surge replicas of the rollout
+	MaxMembers    int
+	LeaseDuration time.Duration
+	RenewPeriod   time.Duration
+	VirtualNodes  int
 }
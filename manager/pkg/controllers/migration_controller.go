@@ -14,6 +14,7 @@ import (
 	addonv1 "github.com/stolostron/klusterlet-addon-controller/pkg/apis/agent/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/clientcmd"
@@ -30,6 +31,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/schedules"
 	migrationv1alpha1 "github.com/stolostron/multicluster-global-hub/operator/api/migration/v1alpha1"
 	bundleevent "github.com/stolostron/multicluster-global-hub/pkg/bundle/event"
 	"github.com/stolostron/multicluster-global-hub/pkg/constants"
@@ -60,6 +62,11 @@ func NewMigrationController(client client.Client, producer transport.Producer,
 const (
 	klusterletConfigNamePrefix = "migration-"
 	bootstrapSecretNamePrefix  = "bootstrap-"
+
+	// migrationDeferredCondition is true while the migration is deferred by the schedules of the hubs
+	migrationDeferredCondition = "Deferred"
+	// migrationDeferredRequeueInterval is the interval of checking the deferred migration without the release time
+	migrationDeferredRequeueInterval = 10 * time.Minute
 )
 
 var migrationLog = logger.ZapLogger("migration-ctrl")
@@ -161,6 +168,9 @@ func (m *MigrationController) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 
 		// send the klusterletaddonconfig to the target cluster
+		if requeueAfter, err := m.deferMigration(ctx, migration, migration.Spec.To); err != nil || requeueAfter > 0 {
+			return ctrl.Result{RequeueAfter: requeueAfter}, err
+		}
 		return ctrl.Result{}, m.syncMigrationTo(ctx, migration)
 	} else {
 		// check if the managedserviceaccout is created by managedclustermigration
@@ -205,9 +215,13 @@ func (m *MigrationController) Reconcile(ctx context.Context, req ctrl.Request) (
 			migrationLog.Error(err, "failed to get managedclustermigration")
 			return ctrl.Result{}, err
 		}
-		if err := m.syncMigration(ctx, migration, klusterletConfig); err != nil {
+		requeueAfter, err := m.syncMigration(ctx, migration, klusterletConfig)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
+		if requeueAfter > 0 {
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
 	}
 	return ctrl.Result{}, nil
 }
@@ -337,44 +351,107 @@ func (m *MigrationController) deleteManagedServiceAccount(ctx context.Context,
 	return m.Delete(ctx, msa)
 }
 
+// syncMigration sends the migration to the managed hubs, it returns the time to requeue the migration if any of the
+// hubs is deferred by its schedules
 func (m *MigrationController) syncMigration(ctx context.Context,
 	migration *migrationv1alpha1.ManagedClusterMigration,
 	klusterletConfig *klusterletv1alpha1.KlusterletConfig,
-) error {
+) (time.Duration, error) {
+	managedClusterMap := make(map[string][]string)
 	if migration.Spec.From != "" {
-		// send the migration event to migration.from managed hub
-		if err := m.syncMigrationFrom(ctx, migration.Spec.From,
-			migration.Spec.IncludedManagedClusters, klusterletConfig); err != nil {
-			return err
-		}
+		managedClusterMap[migration.Spec.From] = migration.Spec.IncludedManagedClusters
 	} else {
 		db := database.GetGorm()
-		managedClusterMap := make(map[string][]string)
 		rows, err := db.Raw(`SELECT leaf_hub_name, cluster_name FROM status.managed_clusters
 			WHERE cluster_name IN (?)`,
 			migration.Spec.IncludedManagedClusters).Rows()
 		if err != nil {
-			return fmt.Errorf("failed to get leaf hub name and managed clusters - %w", err)
+			return 0, fmt.Errorf("failed to get leaf hub name and managed clusters - %w", err)
 		}
 		defer rows.Close()
 		for rows.Next() {
 			var leafHubName, managedClusterName string
 			if err := rows.Scan(&leafHubName, &managedClusterName); err != nil {
-				return fmt.Errorf("failed to scan leaf hub name and managed cluster name - %w", err)
+				return 0, fmt.Errorf("failed to scan leaf hub name and managed cluster name - %w", err)
 			}
 			managedClusterMap[leafHubName] = append(managedClusterMap[leafHubName], managedClusterName)
 		}
+	}
 
-		// send the migration event to migration.from managed hub(s)
-		for leafHubName, managedClusters := range managedClusterMap {
-			if err := m.syncMigrationFrom(ctx, leafHubName, managedClusters, klusterletConfig); err != nil {
-				return err
-			}
+	// the migration is sent once none of the hubs is deferred, so no hub gets a part of it
+	hubs := []string{migration.Spec.To}
+	for leafHubName := range managedClusterMap {
+		hubs = append(hubs, leafHubName)
+	}
+	if requeueAfter, err := m.deferMigration(ctx, migration, hubs...); err != nil || requeueAfter > 0 {
+		return requeueAfter, err
+	}
+
+	// send the migration event to migration.from managed hub(s)
+	for leafHubName, managedClusters := range managedClusterMap {
+		if err := m.syncMigrationFrom(ctx, leafHubName, managedClusters, klusterletConfig); err != nil {
+			return 0, err
 		}
 	}
 
 	// send the migration event to migration.to managed hub
-	return m.syncMigrationTo(ctx, migration)
+	return 0, m.syncMigrationTo(ctx, migration)
+}
+
+// deferMigration returns the time to requeue the migration if any of the hubs is deferred by its maintenance windows
+// or change freezes, and records the deferral in the condition of the migration.
+func (m *MigrationController) deferMigration(ctx context.Context,
+	migration *migrationv1alpha1.ManagedClusterMigration, hubs ...string,
+) (time.Duration, error) {
+	hubSchedules, err := schedules.Load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	requeueAfter := time.Duration(0)
+	messages := []string{}
+	for _, hub := range hubs {
+		deferral := hubSchedules.Deferral(hub, now)
+		if !deferral.Deferred {
+			continue
+		}
+		// the migration is checked again once the earliest hub is released
+		after := migrationDeferredRequeueInterval
+		if deferral.Until != nil {
+			after = deferral.Until.Sub(now)
+			messages = append(messages, fmt.Sprintf("the hub %s is deferred until %s: %s", hub,
+				deferral.Until.Format(time.RFC3339), deferral.Reason))
+		} else {
+			messages = append(messages, fmt.Sprintf("the hub %s is deferred: %s", hub, deferral.Reason))
+		}
+		if requeueAfter == 0 || after < requeueAfter {
+			requeueAfter = after
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:    migrationDeferredCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Released",
+		Message: "none of the hubs is deferred",
+	}
+	if requeueAfter > 0 {
+		migrationLog.Infow("the migration is deferred", "name", migration.Name, "requeueAfter", requeueAfter,
+			"hubs", messages)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "HubScheduled"
+		condition.Message = strings.Join(messages, "; ")
+	} else if meta.FindStatusCondition(migration.Status.Conditions, migrationDeferredCondition) == nil {
+		// the condition is only recorded once the migration is deferred
+		return 0, nil
+	}
+	if meta.SetStatusCondition(&migration.Status.Conditions, condition) {
+		if err := m.Status().Update(ctx, migration); err != nil {
+			return 0, fmt.Errorf("failed to update the deferred condition of the migration %s - %w",
+				migration.Name, err)
+		}
+	}
+	return requeueAfter, nil
 }
 
 func (m *MigrationController) syncMigrationFrom(ctx context.Context,
@@ -10,6 +10,7 @@ import (
 
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/configs"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/cronjob/task"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/archive"
 	"github.com/stolostron/multicluster-global-hub/pkg/logger"
 )
 
@@ -43,21 +44,7 @@ func AddSchedulerToManager(ctx context.Context, mgr ctrl.Manager,
 	// The cluster may be in a different timezones, Here we choose to be consistent with the local GH timezone.
 	scheduler := gocron.NewScheduler(time.Local)
 
-	switch managerConfig.SchedulerInterval {
-	case EveryMonth:
-		scheduler = scheduler.Every(1).Month(1)
-	case EveryWeek:
-		scheduler = scheduler.Every(1).Week()
-	case EveryHour:
-		scheduler = scheduler.Every(1).Hour()
-	case EveryMinute:
-		scheduler = scheduler.Every(1).Minute()
-	case EverySecond:
-		scheduler = scheduler.Every(1).Second()
-	default:
-		scheduler = scheduler.Every(1).Day().At("00:00")
-	}
-	complianceHistoryJob, err := scheduler.
+	complianceHistoryJob, err := every(scheduler, managerConfig.SchedulerInterval).
 		Tag(task.LocalComplianceTaskName).
 		DoWithJobDetails(task.LocalComplianceHistory, ctx)
 	if err != nil {
@@ -65,14 +52,36 @@ func AddSchedulerToManager(ctx context.Context, mgr ctrl.Manager,
 	}
 	log.Infow("set SyncLocalCompliance job", "scheduleAt", complianceHistoryJob.ScheduledAtTime())
 
+	// the status.compliance of the global policies is only available when the global resource is enabled
+	if managerConfig.EnableGlobalResource {
+		globalComplianceHistoryJob, err := every(scheduler, managerConfig.SchedulerInterval).
+			Tag(task.GlobalComplianceTaskName).
+			DoWithJobDetails(task.GlobalComplianceHistory, ctx)
+		if err != nil {
+			return err
+		}
+		log.Infow("set SyncGlobalCompliance job", "scheduleAt", globalComplianceHistoryJob.ScheduledAtTime())
+	}
+
+	// archive the expired partitions to the object storage before dropping them
+	var archiver *archive.Archiver
+	databaseConfig := managerConfig.DatabaseConfig
+	if databaseConfig.ArchiveConfig.Enabled() {
+		archiver, err = archive.NewArchiver(databaseConfig.ArchiveConfig, databaseConfig.ProcessDatabaseURL,
+			databaseConfig.CACertPath)
+		if err != nil {
+			return err
+		}
+	}
 	dataRetentionJob, err := scheduler.
 		Every(1).Month(1, 15, 28).At("00:00").
 		Tag(task.RetentionTaskName).
-		DoWithJobDetails(task.DataRetention, ctx, managerConfig.DatabaseConfig.DataRetention)
+		DoWithJobDetails(task.DataRetention, ctx, databaseConfig.DataRetention, archiver)
 	if err != nil {
 		return err
 	}
-	log.Info("set DataRetention job", "scheduleAt", dataRetentionJob.ScheduledAtTime())
+	log.Info("set DataRetention job", "scheduleAt", dataRetentionJob.ScheduledAtTime(),
+		"archive", databaseConfig.ArchiveConfig.Enabled())
 
 	// register the metrics before starting the jobs
 	task.RegisterMetrics()
@@ -82,11 +91,30 @@ func AddSchedulerToManager(ctx context.Context, mgr ctrl.Manager,
 		strings.Split(managerConfig.LaunchJobNames, ",")))
 }
 
+// every sets the interval of the next job by the scheduler interval, it runs at midnight every day by default.
+func every(scheduler *gocron.Scheduler, interval string) *gocron.Scheduler {
+	switch interval {
+	case EveryMonth:
+		return scheduler.Every(1).Month(1)
+	case EveryWeek:
+		return scheduler.Every(1).Week()
+	case EveryHour:
+		return scheduler.Every(1).Hour()
+	case EveryMinute:
+		return scheduler.Every(1).Minute()
+	case EverySecond:
+		return scheduler.Every(1).Second()
+	default:
+		return scheduler.Every(1).Day().At("00:00")
+	}
+}
+
 func (s *GlobalHubJobScheduler) Start(ctx context.Context) error {
 	log.Infow("start job scheduler")
 	// Set the status of the job to 0 (success) when the job is started.
 	task.GlobalHubCronJobGaugeVec.WithLabelValues(task.RetentionTaskName).Set(0)
 	task.GlobalHubCronJobGaugeVec.WithLabelValues(task.LocalComplianceTaskName).Set(0)
+	task.GlobalHubCronJobGaugeVec.WithLabelValues(task.GlobalComplianceTaskName).Set(0)
 	s.scheduler.StartAsync()
 	if err := s.ExecJobs(); err != nil {
 		return err
@@ -2,6 +2,7 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/hubmanagement"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/archive"
 	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
 	"github.com/stolostron/multicluster-global-hub/pkg/logger"
 )
@@ -17,7 +19,8 @@ import (
 var (
 	// The main tasks of this job are:
 	// 1. create partition tables for days in the future, the partition table for the next month is created
-	// 2. delete partition tables that are no longer needed, the partition table for the previous 18 month is deleted
+	// 2. delete partition tables that are no longer needed, the partition tables for the previous 18 month and before
+	//    are deleted, they're archived to the object storage before deleting if the archiver is configured
 	// 3. completely delete the soft deleted records from database after retainedMonths
 	RetentionTaskName = "data-retention"
 
@@ -39,24 +42,22 @@ var (
 		"event.local_root_policies",
 		"history.local_compliance",
 		"event.managed_clusters",
+		"history.managed_clusters",
+		// it's only created when the global resource is enabled
+		"history.compliance",
+		"audit.events",
+		"event.spec_drifts",
+		"event.managed_cluster_label_conflicts",
 	}
 	retentionLog = logger.ZapLogger(RetentionTaskName)
 )
 
-func DataRetention(ctx context.Context, retentionMonth int, job gocron.Job) {
+// DataRetention drops the expired partitions and records, the archiver is nil if the archive isn't configured
+func DataRetention(ctx context.Context, retentionMonth int, archiver *archive.Archiver, job gocron.Job) {
 	now := time.Now()
 	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 
 	var err error
-	db := database.GetGorm()
-	conn := database.GetConn()
-	err = database.Lock(conn)
-	if err != nil {
-		retentionLog.Error(err, "failed to run data retention")
-		return
-	}
-	defer database.Unlock(conn)
-
 	defer func() {
 		if err != nil {
 			GlobalHubCronJobGaugeVec.WithLabelValues(RetentionTaskName).Set(1)
@@ -67,62 +68,180 @@ func DataRetention(ctx context.Context, retentionMonth int, job gocron.Job) {
 
 	createMonth := currentMonth.AddDate(0, 1, 0)
 	deleteMonth := currentMonth.AddDate(0, -(retentionMonth + 1), 0)
+	// a table failing to be updated doesn't stop the others, so the partitions of the next month are always created
 	for _, tableName := range PartitionTables {
-		err = updatePartitionTables(tableName, createMonth, deleteMonth)
-		if e := traceDataRetentionLog(tableName, currentMonth, err, true); e != nil {
+		exists, e := tableExists(tableName)
+		if e != nil {
+			err = e
+			retentionLog.Error(err, "failed to check the partition table", "table", tableName)
+			continue
+		}
+		if !exists {
+			retentionLog.Info("skip the partition table which isn't created", "table", tableName)
+			continue
+		}
+		manifests, e := updatePartitionTables(ctx, archiver, tableName, createMonth, deleteMonth)
+		for _, manifest := range manifests {
+			if e := traceDataRetentionLog(tableName, currentMonth, nil, true, manifest); e != nil {
+				retentionLog.Error(e, "failed to trace data retention log")
+			}
+		}
+		if e := traceDataRetentionLog(tableName, currentMonth, e, true, nil); e != nil {
 			retentionLog.Error(e, "failed to trace data retention log")
 		}
-		if err != nil {
-			retentionLog.Error(err, "failed to update partition tables")
-			return
+		if e != nil {
+			err = e
+			retentionLog.Error(err, "failed to update partition tables", "table", tableName)
 		}
 	}
 
 	// delete the soft deleted records from database
 	minTime := currentMonth.AddDate(0, -retentionMonth, 0)
+	if e := withLock(func() error { return deleteExpiredData(currentMonth, minTime) }); e != nil {
+		err = e
+		retentionLog.Error(err, "failed to delete the expired records")
+		return
+	}
+	retentionLog.Info("finish running", "nextRun", job.NextRun().Format(TimeFormat))
+}
+
+// withLock runs the function with the database lock, which pauses the backup of the database
+func withLock(f func() error) error {
+	conn := database.GetConn()
+	if err := database.Lock(conn); err != nil {
+		return err
+	}
+	defer database.Unlock(conn)
+	return f()
+}
+
+func deleteExpiredData(currentMonth, minTime time.Time) error {
 	for _, tableName := range RetentionTables {
-		err = deleteExpiredRecords(tableName, minTime)
-		if e := traceDataRetentionLog(tableName, currentMonth, err, false); e != nil {
+		err := deleteExpiredRecords(tableName, minTime)
+		if e := traceDataRetentionLog(tableName, currentMonth, err, false, nil); e != nil {
 			retentionLog.Error(e, "failed to trace data retention log")
 		}
 		if err != nil {
-			retentionLog.Error(err, "failed to delete soft deleted records")
-			return
+			return err
 		}
 	}
-	err = db.Where("last_timestamp < ? AND status = ?", minTime, hubmanagement.HubInactive).
+	err := database.GetGorm().Where("last_timestamp < ? AND status = ?", minTime, hubmanagement.HubInactive).
 		Delete(&models.LeafHubHeartbeat{}).Error
 	if err != nil {
-		retentionLog.Error(err, "failed to delete the expired leaf hub heartbeat")
-		return
+		return fmt.Errorf("failed to delete the expired leaf hub heartbeat: %w", err)
 	}
-	retentionLog.Info("finish running", "nextRun", job.NextRun().Format(TimeFormat))
+	return deleteExpiredDryRuns(minTime)
 }
 
-func updatePartitionTables(tableName string, createTime, deleteTime time.Time) error {
-	db := database.GetGorm()
+// deleteExpiredDryRuns deletes the expired dry runs with their results, it's skipped if the tables aren't created,
+// e.g. the database isn't migrated yet
+func deleteExpiredDryRuns(minTime time.Time) error {
+	for _, tableName := range []string{models.SpecDryRun{}.TableName(), models.SpecDryRunResult{}.TableName()} {
+		exists, err := tableExists(tableName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			retentionLog.Info("skip the dry runs since the table isn't created", "table", tableName)
+			return nil
+		}
+	}
+	// the results are deleted with their dry runs
+	expiredDryRuns := database.GetGorm().Model(&models.SpecDryRun{}).Select("id").Where("created_at < ?", minTime)
+	if err := database.GetGorm().Where("dry_run_id IN (?)", expiredDryRuns).
+		Delete(&models.SpecDryRunResult{}).Error; err != nil {
+		return fmt.Errorf("failed to delete the expired dry run results: %w", err)
+	}
+	if err := database.GetGorm().Where("created_at < ?", minTime).Delete(&models.SpecDryRun{}).Error; err != nil {
+		return fmt.Errorf("failed to delete the expired dry runs: %w", err)
+	}
+	return nil
+}
+
+// updatePartitionTables creates the partition table of the next month, and then deletes the expired partition tables,
+// which are the partitions of the deleteTime and the months before it. The expired partition is archived before it's
+// deleted if the archiver is configured. The archive runs without the database lock, since it might take a long time
+// to upload a large partition. If a partition fails to be archived, it's kept and the others are still deleted, the
+// kept partition is expired in the next run too, so it's archived and deleted then.
+func updatePartitionTables(ctx context.Context, archiver *archive.Archiver, tableName string,
+	createTime, deleteTime time.Time,
+) ([]*archive.Manifest, error) {
+	var errs []error
+	if err := withLock(func() error { return createPartitionTable(tableName, createTime) }); err != nil {
+		errs = append(errs, err)
+	}
 
-	// create the partition tables for the next month
+	expiredPartitions, err := listExpiredPartitions(tableName, deleteTime)
+	if err != nil {
+		return nil, errors.Join(append(errs, err)...)
+	}
+	manifests := []*archive.Manifest{}
+	for _, partition := range expiredPartitions {
+		if archiver != nil {
+			manifest, err := archiver.Archive(ctx, tableName, partition)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to archive partition table %s: %w", partition, err))
+				continue
+			}
+			manifests = append(manifests, manifest)
+		}
+		err := withLock(func() error {
+			return database.GetGorm().Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", partition)).Error
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete partition table %s: %w", partition, err))
+			continue
+		}
+		retentionLog.Info("delete partition table", "table", partition)
+	}
+	return manifests, errors.Join(errs...)
+}
+
+func createPartitionTable(tableName string, createTime time.Time) error {
 	startTime := time.Date(createTime.Year(), createTime.Month(), 1, 0, 0, 0, 0, createTime.Location())
 	endTime := startTime.AddDate(0, 1, 0)
 	createPartitionTableName := fmt.Sprintf("%s_%s", tableName, startTime.Format(PartitionDateFormat))
 
 	creationSql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
 		createPartitionTableName, tableName, startTime.Format(DateFormat), endTime.Format(DateFormat))
-	if result := db.Exec(creationSql); result.Error != nil {
+	if result := database.GetGorm().Exec(creationSql); result.Error != nil {
 		return fmt.Errorf("failed to create partition table %s: %w", tableName, result.Error)
 	}
 	retentionLog.Info("create partition table", "table", createPartitionTableName, "start", startTime.Format(DateFormat),
 		"end", endTime.Format(DateFormat))
+	return nil
+}
 
-	// delete the partition tables that are expired
-	deletePartitionTableName := fmt.Sprintf("%s_%s", tableName, deleteTime.Format(PartitionDateFormat))
-	deletionSql := fmt.Sprintf("DROP TABLE IF EXISTS %s", deletePartitionTableName)
-	if result := db.Exec(deletionSql); result.Error != nil {
-		return fmt.Errorf("failed to delete partition table %s: %w", tableName, result.Error)
+// listExpiredPartitions returns the partitions of the table whose month is the deleteTime or before it, e.g.
+// event.local_policies_2023_01, in the order of the month
+func listExpiredPartitions(tableName string, deleteTime time.Time) ([]string, error) {
+	partitions, err := listPartitions(tableName)
+	if err != nil {
+		return nil, err
 	}
-	retentionLog.Info("delete partition table", "table", deletePartitionTableName)
-	return nil
+	deleteMonth := time.Date(deleteTime.Year(), deleteTime.Month(), 1, 0, 0, 0, 0, time.UTC)
+	prefix := strings.SplitN(tableName, ".", 2)[1] + "_"
+	expired := []string{}
+	for _, partition := range partitions {
+		month, err := time.Parse(PartitionDateFormat, strings.TrimPrefix(partition.Table, prefix))
+		if err != nil {
+			// the partition isn't created by the data retention, e.g. the default partition
+			continue
+		}
+		if !month.After(deleteMonth) {
+			expired = append(expired, fmt.Sprintf("%s.%s", partition.Schema, partition.Table))
+		}
+	}
+	return expired, nil
+}
+
+func tableExists(tableName string) (bool, error) {
+	var exists bool
+	err := database.GetGorm().Raw("SELECT to_regclass(?) IS NOT NULL", tableName).Row().Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether the table %s exists: %w", tableName, err)
+	}
+	return exists, nil
 }
 
 func deleteExpiredRecords(tableName string, minDate time.Time) error {
@@ -136,7 +255,9 @@ func deleteExpiredRecords(tableName string, minDate time.Time) error {
 	return nil
 }
 
-func traceDataRetentionLog(tableName string, startTime time.Time, err error, partition bool) error {
+func traceDataRetentionLog(tableName string, startTime time.Time, err error, partition bool,
+	manifest *archive.Manifest,
+) error {
 	db := database.GetGorm()
 	dataRetentionLog := &models.DataRetentionJobLog{
 		Name:    tableName,
@@ -147,6 +268,10 @@ func traceDataRetentionLog(tableName string, startTime time.Time, err error, par
 	if err != nil {
 		dataRetentionLog.Error = err.Error()
 	}
+	if manifest != nil {
+		dataRetentionLog.ArchiveManifest = &manifest.Key
+		dataRetentionLog.ArchivedRows = &manifest.Rows
+	}
 
 	if partition {
 		minPartition, maxPartition, err := getMinMaxPartitions(tableName)
@@ -164,13 +289,26 @@ func traceDataRetentionLog(tableName string, startTime time.Time, err error, par
 }
 
 func getMinMaxPartitions(tableName string) (string, string, error) {
+	tables, err := listPartitions(tableName)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get min/max partition table: %w", err)
+	}
+	if len(tables) < 1 {
+		retentionLog.Info("no partition table found", "table", tableName)
+		return "", "", nil
+	}
+	return tables[0].Table, tables[len(tables)-1].Table, nil
+}
+
+// listPartitions returns the partitions of the table in the order of the name
+func listPartitions(tableName string) ([]models.Table, error) {
 	db := database.GetGorm()
 
 	schemaTable := strings.Split(tableName, ".")
 	if len(schemaTable) != 2 {
-		return "", "", fmt.Errorf("invalid table name: %s", tableName)
+		return nil, fmt.Errorf("invalid table name: %s", tableName)
 	}
-	sql := fmt.Sprintf(`
+	sql := `
 		SELECT
 			nmsp_child.nspname AS schema_name,
 			child.relname AS table_name
@@ -181,22 +319,16 @@ func getMinMaxPartitions(tableName string) (string, string, error) {
 			JOIN pg_namespace nmsp_parent ON nmsp_parent.oid = parent.relnamespace
 			JOIN pg_namespace nmsp_child ON nmsp_child.oid = child.relnamespace
 		WHERE
-			nmsp_parent.nspname='%s'
-			AND parent.relname='%s'
+			nmsp_parent.nspname = ?
+			AND parent.relname = ?
 		ORDER BY
-			child.relname ASC;`,
-		schemaTable[0], schemaTable[1])
+			child.relname ASC;`
 
 	var tables []models.Table
-	result := db.Raw(sql).Find(&tables)
-	if result.Error != nil {
-		return "", "", fmt.Errorf("failed to get min/max partition table: %w", result.Error)
+	if result := db.Raw(sql, schemaTable[0], schemaTable[1]).Find(&tables); result.Error != nil {
+		return nil, result.Error
 	}
-	if len(tables) < 1 {
-		retentionLog.Info("no partition table found", "table", tableName)
-		return "", "", nil
-	}
-	return tables[0].Table, tables[len(tables)-1].Table, nil
+	return tables, nil
 }
 
 func getMinDeletionTime(tableName string) (time.Time, error) {
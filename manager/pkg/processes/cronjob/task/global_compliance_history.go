@@ -0,0 +1,120 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-co-op/gocron"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+)
+
+// GlobalComplianceTaskName is the job to snapshot the compliance of the global policies to the history.compliance
+// daily. The changes within the day are recorded by the trigger of the status.compliance, so the job only carries
+// the unchanged compliance over to the new day.
+var GlobalComplianceTaskName = "global-compliance-history"
+
+func GlobalComplianceHistory(ctx context.Context, job gocron.Job) {
+	start := time.Now()
+	taskLog := logger.ZapLogger(GlobalComplianceTaskName).With("date", start.Format(DateFormat))
+	taskLog.Infow("start running", "currentRun", job.LastRun().Format(TimeFormat))
+
+	var err error
+	defer func() {
+		if err != nil {
+			GlobalHubCronJobGaugeVec.WithLabelValues(GlobalComplianceTaskName).Set(1)
+		} else {
+			GlobalHubCronJobGaugeVec.WithLabelValues(GlobalComplianceTaskName).Set(0)
+		}
+	}()
+
+	err = snapshotGlobalComplianceToHistory(ctx, taskLog, start)
+	if err != nil {
+		taskLog.Error(err, "sync from status.compliance to history.compliance failed")
+		return
+	}
+
+	taskLog.Infow("finish running", "nextRun", job.NextRun().Format(TimeFormat))
+}
+
+func snapshotGlobalComplianceToHistory(ctx context.Context, taskLog *zap.SugaredLogger, start time.Time) error {
+	db := database.GetGorm()
+	var totalCount int64
+	err := db.Model(&models.StatusCompliance{}).Count(&totalCount).Error
+	if err != nil {
+		return err
+	}
+	taskLog.Infow("The number of compliance need to be synchronized", "count", totalCount)
+
+	insertedCount := int64(0)
+	for offset := int64(0); offset < totalCount; offset += batchSize {
+		batchInsertedCount, err := globalBatchSync(ctx, taskLog, start, totalCount, offset)
+		if err != nil {
+			return err
+		}
+		insertedCount += batchInsertedCount
+	}
+	taskLog.Infow("The number of compliance has been synchronized", "insertedCount", insertedCount)
+	return nil
+}
+
+func globalBatchSync(ctx context.Context, taskLog *zap.SugaredLogger, start time.Time, totalCount, offset int64,
+) (int64, error) {
+	batchInsert := int64(0)
+	var err error
+	defer func() {
+		e := traceComplianceHistoryLog(GlobalComplianceTaskName, totalCount, offset, offset+batchInsert, start, err)
+		if e != nil {
+			taskLog.Info("trace global compliance job failed, retrying", "error", e)
+		}
+	}()
+	err = wait.PollUntilContextTimeout(ctx, 5*time.Second, 10*time.Minute, true,
+		func(ctx context.Context) (done bool, err error) {
+			batchSyncSQLTemplate := `
+				INSERT INTO history.compliance (
+					policy_id,
+					cluster_name,
+					leaf_hub_name,
+					cluster_id,
+					compliance,
+					compliance_date
+				)
+				(
+					SELECT
+						policy_id,
+						cluster_name,
+						leaf_hub_name,
+						cluster_id,
+						compliance,
+						CURRENT_DATE
+					FROM
+						status.compliance
+					ORDER BY leaf_hub_name, policy_id, cluster_name
+					LIMIT %d
+					OFFSET %d
+				)
+				ON CONFLICT (
+					leaf_hub_name,
+					policy_id,
+					cluster_name,
+					compliance_date
+				) DO NOTHING;
+			`
+
+			db := database.GetGorm()
+			ret := db.Exec(fmt.Sprintf(batchSyncSQLTemplate, batchSize, offset))
+			if ret.Error != nil {
+				taskLog.Info("exec failed, retrying", "error", ret.Error)
+				return false, nil
+			}
+			batchInsert = ret.RowsAffected
+			taskLog.Infow("sync compliance to history", "batch", batchSize, "batchInsert", batchInsert, "offset", offset)
+			return true, nil
+		})
+	return batchInsert, err
+}
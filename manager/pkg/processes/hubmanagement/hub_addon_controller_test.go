@@ -28,6 +28,10 @@ func (m *mockHubManagement) reactive(ctx context.Context, hubs []models.LeafHubH
 	return nil
 }
 
+func (m *mockHubManagement) resync(ctx context.Context, hubName string) error {
+	return nil
+}
+
 func TestManagerClusterAddonReconcile(t *testing.T) {
 	scheme := runtime.NewScheme()
 	err := addonv1alpha1.AddToScheme(scheme)
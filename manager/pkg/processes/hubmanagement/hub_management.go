@@ -6,6 +6,7 @@ package hubmanagement
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -32,11 +33,17 @@ const (
 	ProbeDuration = 2 * time.Minute // the duration to detect run the updating
 )
 
-var hubStatusManager HubStatusManager
+var (
+	hubStatusManager HubStatusManager
+
+	// ErrHubManagementNotStarted means the transport isn't ready, so the resync event can't be sent to the hubs
+	ErrHubManagementNotStarted = errors.New("the hub management isn't started")
+)
 
 type HubStatusManager interface {
 	inactive(ctx context.Context, hubs []models.LeafHubHeartbeat) error
 	reactive(ctx context.Context, hubs []models.LeafHubHeartbeat) error
+	resync(ctx context.Context, hubName string) error
 }
 
 // manage the leaf hub lifecycle based on the heartbeat
@@ -201,6 +208,19 @@ func (h *HubManagement) reactive(ctx context.Context, hubs []models.LeafHubHeart
 }
 
 func (h *HubManagement) resync(ctx context.Context, hubName string) error {
+	return ResyncHub(ctx, h.producer, hubName)
+}
+
+// Resync sends the resync event of the hub management to the hub, e.g. it's requested by the REST API
+func Resync(ctx context.Context, hubName string) error {
+	if hubStatusManager == nil {
+		return ErrHubManagementNotStarted
+	}
+	return hubStatusManager.resync(ctx, hubName)
+}
+
+// ResyncHub requests the hub(or broadcast to all the hubs) to resend the status of the necessary resources
+func ResyncHub(ctx context.Context, producer transport.Producer, hubName string) error {
 	resyncResources := []string{
 		string(enum.HubClusterInfoType),
 		string(enum.ManagedClusterType),
@@ -214,5 +234,5 @@ func (h *HubManagement) resync(ctx context.Context, hubName string) error {
 
 	e := utils.ToCloudEvent(constants.ResyncMsgKey, constants.CloudEventSourceGlobalHub, hubName, payloadBytes)
 
-	return h.producer.SendEvent(ctx, e)
+	return producer.SendEvent(ctx, e)
 }
@@ -15,9 +15,18 @@ import (
 	"go.uber.org/zap"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/auditevents"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authentication"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/dryruns"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/events"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/graphql"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/managedclusters"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/managedhubs"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/policies"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/rollouts"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/security"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/specapply"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/subscriptions"
 	"github.com/stolostron/multicluster-global-hub/pkg/logger"
 )
@@ -30,6 +39,11 @@ type RestApiServerConfig struct {
 	ClusterAPIURL          string
 	ClusterAPICABundlePath string
 	ServerBasePath         string
+	// AuthorizationMode is SubjectAccessReview, OPA or None, the OPAURL is the policy decision of the OPA mode
+	AuthorizationMode string
+	OPAURL            string
+	// Authorizer is created from the AuthorizationMode if it isn't set
+	Authorizer authorization.Authorizer
 }
 
 // NeedLeaderElection implements the LeaderElectionRunnable interface, which indicates
@@ -61,6 +75,13 @@ func readCertificateAuthority(nonK8sAPIServerConfig *RestApiServerConfig) ([]byt
 
 // AddRestApiServer adds the non-k8s-api-server to the Manager.
 func AddRestApiServer(mgr ctrl.Manager, restApiConfig *RestApiServerConfig) error {
+	if restApiConfig.Authorizer == nil {
+		authorizer, err := newAuthorizer(mgr, restApiConfig)
+		if err != nil {
+			return err
+		}
+		restApiConfig.Authorizer = authorizer
+	}
 	router, err := SetupRouter(restApiConfig)
 	if err != nil {
 		return err
@@ -81,6 +102,24 @@ func AddRestApiServer(mgr ctrl.Manager, restApiConfig *RestApiServerConfig) erro
 	return nil
 }
 
+func newAuthorizer(mgr ctrl.Manager, restApiConfig *RestApiServerConfig) (authorization.Authorizer, error) {
+	switch restApiConfig.AuthorizationMode {
+	case authorization.ModeSubjectAccessReview:
+		return authorization.NewSubjectAccessReviewAuthorizer(mgr.GetClient()), nil
+	case authorization.ModeOPA:
+		if restApiConfig.OPAURL == "" {
+			return nil, fmt.Errorf("the OPA URL must be specified for the %s authorization", authorization.ModeOPA)
+		}
+		return authorization.NewOPAAuthorizer(restApiConfig.OPAURL), nil
+	case authorization.ModeNone, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid authorization mode %q, it should be %s, %s or %s",
+			restApiConfig.AuthorizationMode, authorization.ModeSubjectAccessReview, authorization.ModeOPA,
+			authorization.ModeNone)
+	}
+}
+
 // @title         Multicluster Global Hub API
 // @version       1.0.0
 // @description   This documentation is for the APIs of multicluster global hub resources for {product-title}.
@@ -108,16 +147,51 @@ func SetupRouter(nonK8sAPIServerConfig *RestApiServerConfig) (*gin.Engine, error
 			return nil, fmt.Errorf("failed to read certificates authority: %w", err)
 		}
 		router.Use(authentication.Authentication(nonK8sAPIServerConfig.ClusterAPIURL, clusterAPICABundle))
+		// the authorization decides by the authenticated user
+		if nonK8sAPIServerConfig.Authorizer != nil {
+			router.Use(authorization.Authorization(nonK8sAPIServerConfig.Authorizer))
+		}
 	}
 
 	routerGroup := router.Group(nonK8sAPIServerConfig.ServerBasePath)
 	routerGroup.GET("/managedclusters", managedclusters.ListManagedClusters())
 	routerGroup.PATCH("/managedcluster/:clusterID",
 		managedclusters.PatchManagedCluster())
+	routerGroup.GET("/managedcluster/:clusterID/history", managedclusters.GetManagedClusterHistory())
+	routerGroup.POST("/managedclusters/labels", managedclusters.PatchManagedClusterLabels())
+	routerGroup.GET("/managedclusters/labeljob/:jobID", managedclusters.GetManagedClusterLabelJob())
+	routerGroup.GET("/managedclusters/labelowners", managedclusters.ListLabelOwners())
+	routerGroup.PUT("/managedclusters/labelowners", managedclusters.PutLabelOwner())
+	routerGroup.DELETE("/managedclusters/labelowners", managedclusters.DeleteLabelOwner())
+	routerGroup.GET("/managedcluster/:clusterID/labelsources", managedclusters.GetManagedClusterLabelSources())
+	routerGroup.GET("/managedcluster/:clusterID/compliancetimeline", policies.GetClusterComplianceTimeline())
+	routerGroup.GET("/managedhubs", managedhubs.ListManagedHubs())
+	routerGroup.GET("/managedhub/:name", managedhubs.GetManagedHub())
+	routerGroup.POST("/managedhub/:name/resync", managedhubs.ResyncManagedHub())
+	routerGroup.GET("/managedhub/:name/schedules", managedhubs.ListHubSchedules())
+	routerGroup.PUT("/managedhub/:name/schedule/:schedule", managedhubs.PutHubSchedule())
+	routerGroup.DELETE("/managedhub/:name/schedule/:schedule", managedhubs.DeleteHubSchedule())
+	routerGroup.GET("/managedhub/:name/compliancetimeline", policies.GetHubComplianceTimeline())
 	routerGroup.GET("/policies", policies.ListPolicies())
 	routerGroup.GET("/policy/:policyID/status", policies.GetPolicyStatus())
+	routerGroup.GET("/policy/:policyID/compliancehistory", policies.GetPolicyComplianceHistory())
+	routerGroup.GET("/policies/compliancechanges", policies.ListPolicyComplianceChanges())
+	routerGroup.GET("/localpolicy/:policyID/compliancetimeline", policies.GetPolicyComplianceTimeline())
 	routerGroup.GET("/subscriptions", subscriptions.ListSubscriptions())
 	routerGroup.GET("/subscriptionreport/:subscriptionID", subscriptions.GetSubscriptionReport())
+	routerGroup.GET("/events/managedclusters", events.ListManagedClusterEvents())
+	routerGroup.GET("/events/policies", events.ListPolicyEvents())
+	routerGroup.GET("/security/alertcounts", security.ListAlertCounts())
+	routerGroup.GET("/audit/events", auditevents.ListAuditEvents())
+	routerGroup.GET("/specapplyresults", specapply.ListSpecApplyResults())
+	routerGroup.GET("/rollouts", rollouts.ListRollouts())
+	routerGroup.POST("/rollout/:uid/pause", rollouts.PauseRollout())
+	routerGroup.POST("/rollout/:uid/resume", rollouts.ResumeRollout())
+	routerGroup.POST("/rollout/:uid/abort", rollouts.AbortRollout())
+	routerGroup.POST("/dryruns", dryruns.CreateDryRun())
+	routerGroup.GET("/dryrun/:id", dryruns.GetDryRun())
+	routerGroup.GET("/graphql", graphql.Query())
+	routerGroup.POST("/graphql", graphql.Query())
 
 	return router, nil
 }
@@ -0,0 +1,125 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package auditevents
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+const (
+	serverInternalErrorMsg = "internal error"
+	// the audit events in a response are limited to protect the database
+	defaultLimit = 500
+	maxLimit     = 5000
+)
+
+// AuditEventList is the audit events ordered by the created time descending
+type AuditEventList struct {
+	Items []audit.Event `json:"items"`
+}
+
+// ListAuditEvents godoc
+// @summary list the audit events
+// @description list the audit events of the REST API mutations and the changes of the global resources, newest first
+// @accept json
+// @produce json
+// @param        user      query     string  false  "only list the events of the user"
+// @param        source    query     string  false  "only list the events of the source, restapi or spec"
+// @param        resource  query     string  false  "only list the events of the resource, e.g. managedclusters"
+// @param        action    query     string  false  "only list the events of the action, e.g. patch"
+// @param        result    query     string  false  "only list the events of the result, success or failure"
+// @param        since     query     string  false  "only list the events created at or after the time, RFC3339"
+// @param        until     query     string  false  "only list the events created before the time, RFC3339"
+// @param        limit     query     int     false  "maximum number of the events, 500 by default and 5000 at most"
+// @success      200  {object}    AuditEventList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /audit/events [get]
+func ListAuditEvents() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		if !authorization.AuthorizeOrAbort(ginCtx, "list", authorization.AuditEvents, "", "") {
+			return
+		}
+
+		db, limit, err := auditEventsQuery(ginCtx, database.GetGorm())
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rows := []models.AuditEvent{}
+		if err := db.Order("created_at DESC").Limit(limit).Find(&rows).Error; err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the audit events: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		list := AuditEventList{Items: []audit.Event{}}
+		for i := range rows {
+			event, err := audit.FromModel(&rows[i])
+			if err != nil {
+				fmt.Fprintf(gin.DefaultWriter, "error in unmarshaling the audit event %s: %v\n", rows[i].ID, err)
+				ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+				return
+			}
+			list.Items = append(list.Items, *event)
+		}
+		ginCtx.JSON(http.StatusOK, list)
+	}
+}
+
+// auditEventsQuery adds the filters of the query parameters to the db and returns the limit of the events
+func auditEventsQuery(ginCtx *gin.Context, db *gorm.DB) (*gorm.DB, int, error) {
+	for param, column := range map[string]string{
+		"user":     "username",
+		"source":   "source",
+		"resource": "resource",
+		"action":   "action",
+		"result":   "result",
+	} {
+		if value := ginCtx.Query(param); value != "" {
+			db = db.Where(column+" = ?", value)
+		}
+	}
+	for _, param := range []struct {
+		name      string
+		condition string
+	}{{"since", "created_at >= ?"}, {"until", "created_at < ?"}} {
+		value := ginCtx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid %s %q, the format should be RFC3339", param.name, value)
+		}
+		// the created_at is a timestamp without time zone in the local time of the manager
+		db = db.Where(param.condition, t.Local())
+	}
+
+	limit := defaultLimit
+	if value := ginCtx.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxLimit {
+			return nil, 0, fmt.Errorf("invalid limit %q, it should be between 1 and %d", value, maxLimit)
+		}
+		limit = n
+	}
+	return db, limit, nil
+}
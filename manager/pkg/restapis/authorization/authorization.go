@@ -0,0 +1,254 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package authorization
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authentication"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+)
+
+const (
+	// AuthorizerKey - the key for the authorizer in context.
+	AuthorizerKey = "authorizer"
+
+	// APIGroup is the group of the global hub RBAC model. The managed hubs are the virtual resources of the group, and
+	// the resources synced from the hubs are their subresources, e.g. the role below allows to list the managed
+	// clusters of hub1 and hub2:
+	//
+	//	- apiGroups: ["global-hub.open-cluster-management.io"]
+	//	  resources: ["managedhubs/managedclusters"]
+	//	  resourceNames: ["hub1", "hub2"]
+	//	  verbs: ["list"]
+	//
+	// The policies and subscriptions are namespaced, the role bound in the namespace allows to access them in the
+	// namespace of all the hubs.
+	APIGroup = "global-hub.open-cluster-management.io"
+	// ManagedHubs is the resource of the managed hubs
+	ManagedHubs = "managedhubs"
+	// the subresources of the managed hubs
+	ManagedClusters  = "managedclusters"
+	Policies         = "policies"
+	Subscriptions    = "subscriptions"
+	Events           = "events"
+	SecurityAlerts   = "securityalerts"
+	SpecApplyResults = "specapplyresults"
+	Resync           = "resync"
+	Schedules        = "schedules"
+	// AuditEvents is the audit trail of all the hubs, so it's only authorized without the resource name
+	AuditEvents = "auditevents"
+	// Rollouts is the rollouts of the global resources to all the hubs, so it's only authorized without the resource
+	// name, and in the namespace of the global resource
+	Rollouts = "rollouts"
+	// DryRuns is the dry runs of the global resources on all the hubs, so it's only authorized without the resource
+	// name, and in the namespaces of the objects
+	DryRuns = "dryruns"
+	// LabelOwners is the owners of the label keys of the managed clusters of all the hubs, so it's only authorized
+	// without the resource name
+	LabelOwners = "labelowners"
+
+	// the modes of the authorization
+	ModeSubjectAccessReview = "SubjectAccessReview"
+	ModeOPA                 = "OPA"
+	ModeNone                = "None"
+
+	// leafHubsQuery lists the hubs reported by the heartbeat or the hub cluster info, the same as the managed hubs API
+	leafHubsQuery = `SELECT leaf_hub_name FROM status.leaf_hub_heartbeats
+		UNION
+		SELECT leaf_hub_name FROM status.leaf_hubs WHERE deleted_at IS NULL`
+
+	forbiddenMsg = "forbidden"
+)
+
+// Attributes are the request to authorize. The empty LeafHub or Namespace means all the hubs or namespaces, and the
+// Resource is either the managedhubs or its subresource.
+type Attributes struct {
+	User      string   `json:"user"`
+	Groups    []string `json:"groups"`
+	Verb      string   `json:"verb"`
+	Resource  string   `json:"resource"`
+	LeafHub   string   `json:"leafHub,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+}
+
+// Authorizer decides whether the user is allowed to access the resource of the hub in the namespace
+type Authorizer interface {
+	Authorize(ctx context.Context, attrs *Attributes) (bool, error)
+}
+
+// Authorization middleware. It must run after the authentication, which sets the user and groups of the request.
+func Authorization(authorizer Authorizer) gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		ginCtx.Set(AuthorizerKey, authorizer)
+		ginCtx.Next()
+	}
+}
+
+// Authorize reports whether the user of the request is allowed to access the resource of the hub in the namespace,
+// it's always allowed if the authorization isn't enabled.
+func Authorize(ginCtx *gin.Context, verb, resource, leafHub, namespace string) (bool, error) {
+	authorizer := getAuthorizer(ginCtx)
+	if authorizer == nil {
+		return true, nil
+	}
+	attrs := newAttributes(ginCtx, verb, resource)
+	attrs.LeafHub = leafHub
+	attrs.Namespace = namespace
+	return authorizer.Authorize(ginCtx.Request.Context(), attrs)
+}
+
+// AuthorizeOrAbort writes the response if the user isn't allowed or the authorization fails, the caller returns then.
+func AuthorizeOrAbort(ginCtx *gin.Context, verb, resource, leafHub, namespace string) bool {
+	allowed, err := Authorize(ginCtx, verb, resource, leafHub, namespace)
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in authorizing the request: %v\n", err)
+		ginCtx.String(http.StatusInternalServerError, "internal error")
+		return false
+	}
+	if !allowed {
+		ginCtx.String(http.StatusForbidden, forbiddenMsg)
+		return false
+	}
+	return true
+}
+
+// Filter is the hubs and namespaces the user is allowed to access. It's applied to the query, instead of the query
+// result, so that the limit and continue token of the list are decided by the visible rows only.
+type Filter struct {
+	// All means the user is allowed to access all the hubs and namespaces
+	All        bool
+	LeafHubs   []string
+	Namespaces []string
+}
+
+// NewFilter returns the hubs and namespaces the user of the request is allowed to access the resource with the verb.
+// The namespacesQuery lists the candidate namespaces of the namespaced resource, it's empty for the cluster scoped
+// resource.
+func NewFilter(ginCtx *gin.Context, verb, resource, namespacesQuery string) (*Filter, error) {
+	authorizer := getAuthorizer(ginCtx)
+	if authorizer == nil {
+		return &Filter{All: true}, nil
+	}
+	ctx := ginCtx.Request.Context()
+	attrs := newAttributes(ginCtx, verb, resource)
+	allowed, err := authorizer.Authorize(ctx, attrs)
+	if err != nil || allowed {
+		return &Filter{All: allowed}, err
+	}
+
+	filter := &Filter{LeafHubs: []string{}, Namespaces: []string{}}
+	filter.LeafHubs, err = filterValues(ctx, authorizer, leafHubsQuery, func(value string) *Attributes {
+		hubAttrs := *attrs
+		hubAttrs.LeafHub = value
+		return &hubAttrs
+	})
+	if err != nil {
+		return nil, err
+	}
+	if namespacesQuery == "" {
+		return filter, nil
+	}
+	filter.Namespaces, err = filterValues(ctx, authorizer, namespacesQuery, func(value string) *Attributes {
+		namespaceAttrs := *attrs
+		namespaceAttrs.Namespace = value
+		return &namespaceAttrs
+	})
+	return filter, err
+}
+
+// Condition returns the condition appended to the WHERE clause of the query and its arguments. The row is visible if
+// its hub or namespace is allowed, the empty column isn't filtered by.
+func (f *Filter) Condition(leafHubColumn, namespaceColumn string) (string, []interface{}) {
+	condition, args := f.Where(leafHubColumn, namespaceColumn)
+	if condition == "" {
+		return "", nil
+	}
+	return " AND " + condition, args
+}
+
+// Where returns the condition as a whole WHERE clause, it's empty if the user is allowed to access all the rows.
+func (f *Filter) Where(leafHubColumn, namespaceColumn string) (string, []interface{}) {
+	if f.All {
+		return "", nil
+	}
+	conditions := []string{}
+	args := []interface{}{}
+	if leafHubColumn != "" && len(f.LeafHubs) > 0 {
+		conditions = append(conditions, leafHubColumn+" IN ?")
+		args = append(args, f.LeafHubs)
+	}
+	if namespaceColumn != "" && len(f.Namespaces) > 0 {
+		conditions = append(conditions, namespaceColumn+" IN ?")
+		args = append(args, f.Namespaces)
+	}
+	if len(conditions) == 0 {
+		return "FALSE", nil
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// AllowsLeafHub reports whether the user is allowed to access the resources of the hub
+func (f *Filter) AllowsLeafHub(leafHub string) bool {
+	return f.All || slices.Contains(f.LeafHubs, leafHub)
+}
+
+// Scope applies the condition to the query built by the gorm chain
+func (f *Filter) Scope(leafHubColumn, namespaceColumn string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		condition, args := f.Where(leafHubColumn, namespaceColumn)
+		if condition == "" {
+			return db
+		}
+		return db.Where(condition, args...)
+	}
+}
+
+func filterValues(ctx context.Context, authorizer Authorizer, query string,
+	toAttributes func(value string) *Attributes,
+) ([]string, error) {
+	var values []string
+	if err := database.GetGorm().Raw(query).Scan(&values).Error; err != nil {
+		return nil, fmt.Errorf("failed to list the candidates: %w", err)
+	}
+	allowedValues := []string{}
+	for _, value := range values {
+		allowed, err := authorizer.Authorize(ctx, toAttributes(value))
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			allowedValues = append(allowedValues, value)
+		}
+	}
+	return allowedValues, nil
+}
+
+func getAuthorizer(ginCtx *gin.Context) Authorizer {
+	value, found := ginCtx.Get(AuthorizerKey)
+	if !found {
+		return nil
+	}
+	authorizer, ok := value.(Authorizer)
+	if !ok {
+		return nil
+	}
+	return authorizer
+}
+
+func newAttributes(ginCtx *gin.Context, verb, resource string) *Attributes {
+	return &Attributes{
+		User:     ginCtx.GetString(authentication.UserKey),
+		Groups:   ginCtx.GetStringSlice(authentication.GroupsKey),
+		Verb:     verb,
+		Resource: resource,
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestFilterCondition(t *testing.T) {
+	cases := []struct {
+		name         string
+		filter       *Filter
+		hubColumn    string
+		nsColumn     string
+		expectedSql  string
+		expectedArgs []interface{}
+	}{
+		{"all", &Filter{All: true}, "leaf_hub_name", "namespace", "", nil},
+		{"nothing", &Filter{LeafHubs: []string{}, Namespaces: []string{}}, "leaf_hub_name", "", " AND FALSE", nil},
+		{
+			"hubs", &Filter{LeafHubs: []string{"hub1", "hub2"}}, "leaf_hub_name", "",
+			" AND (leaf_hub_name IN ?)", []interface{}{[]string{"hub1", "hub2"}},
+		},
+		{
+			"namespaces without the column", &Filter{Namespaces: []string{"default"}}, "leaf_hub_name", "",
+			" AND FALSE", nil,
+		},
+		{
+			"hubs or namespaces", &Filter{LeafHubs: []string{"hub1"}, Namespaces: []string{"default"}},
+			"h.leaf_hub_name", "namespace", " AND (h.leaf_hub_name IN ? OR namespace IN ?)",
+			[]interface{}{[]string{"hub1"}, []string{"default"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sql, args := c.filter.Condition(c.hubColumn, c.nsColumn)
+			if sql != c.expectedSql {
+				t.Errorf("expected %q, but got %q", c.expectedSql, sql)
+			}
+			if !reflect.DeepEqual(args, c.expectedArgs) {
+				t.Errorf("expected %v, but got %v", c.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestAuthorizeWithoutAuthorizer(t *testing.T) {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest(http.MethodGet, "/managedclusters", nil)
+
+	allowed, err := Authorize(ginCtx, "list", ManagedClusters, "hub1", "")
+	if err != nil || !allowed {
+		t.Errorf("expected the request is allowed without the authorizer, but got %v, %v", allowed, err)
+	}
+	filter, err := NewFilter(ginCtx, "list", ManagedClusters, "")
+	if err != nil || !filter.All {
+		t.Errorf("expected all the rows are visible without the authorizer, but got %v, %v", filter, err)
+	}
+}
+
+func TestSubjectAccessReviewAuthorizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := authorizationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	reviews := []*authorizationv1.ResourceAttributes{}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			review := obj.(*authorizationv1.SubjectAccessReview)
+			reviews = append(reviews, review.Spec.ResourceAttributes)
+			review.Status.Allowed = review.Spec.User == "alice" && review.Spec.ResourceAttributes.Name == "hub1"
+			return nil
+		},
+	}).Build()
+	authorizer := NewSubjectAccessReviewAuthorizer(c)
+
+	attrs := &Attributes{User: "alice", Verb: "list", Resource: ManagedClusters, LeafHub: "hub1"}
+	for i := 0; i < 2; i++ {
+		allowed, err := authorizer.Authorize(context.Background(), attrs)
+		if err != nil || !allowed {
+			t.Fatalf("expected alice is allowed to list the clusters of hub1, but got %v, %v", allowed, err)
+		}
+	}
+	// the second decision is cached
+	if len(reviews) != 1 {
+		t.Fatalf("expected 1 review, but got %d", len(reviews))
+	}
+	expected := &authorizationv1.ResourceAttributes{
+		Group: APIGroup, Resource: ManagedHubs, Subresource: ManagedClusters, Verb: "list", Name: "hub1",
+	}
+	if !reflect.DeepEqual(reviews[0], expected) {
+		t.Errorf("expected the review %v, but got %v", expected, reviews[0])
+	}
+
+	allowed, err := authorizer.Authorize(context.Background(),
+		&Attributes{User: "alice", Verb: "get", Resource: ManagedHubs, LeafHub: "hub2"})
+	if err != nil || allowed {
+		t.Errorf("expected alice isn't allowed to get hub2, but got %v, %v", allowed, err)
+	}
+	if reviews[1].Subresource != "" {
+		t.Errorf("expected no subresource for the managed hub, but got %s", reviews[1].Subresource)
+	}
+}
+
+func TestOPAAuthorizer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := &opaRequest{}
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch request.Input.LeafHub {
+		case "hub1":
+			_, _ = w.Write([]byte(`{"result": true}`))
+		case "hub2":
+			_, _ = w.Write([]byte(`{"result": false}`))
+		case "hub3":
+			// the undefined decision
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	authorizer := NewOPAAuthorizer(server.URL)
+	for hub, expected := range map[string]bool{"hub1": true, "hub2": false, "hub3": false} {
+		allowed, err := authorizer.Authorize(context.Background(),
+			&Attributes{User: "alice", Verb: "list", Resource: ManagedClusters, LeafHub: hub})
+		if err != nil {
+			t.Fatalf("failed to authorize %s: %v", hub, err)
+		}
+		if allowed != expected {
+			t.Errorf("expected %v for %s, but got %v", expected, hub, allowed)
+		}
+	}
+	if _, err := authorizer.Authorize(context.Background(), &Attributes{LeafHub: "hub4"}); err == nil {
+		t.Errorf("expected the error when the decision fails")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package authorization
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const opaRequestTimeout = 5 * time.Second
+
+// OPAAuthorizer authorizes the request with the policy of the Open Policy Agent. The attributes are sent as the input
+// to the data API of the policy decision, e.g. http://localhost:8181/v1/data/globalhub/allow, which returns:
+//
+//	{"result": true}
+//
+// The undefined decision, which has no result, is denied.
+type OPAAuthorizer struct {
+	url    string
+	client *http.Client
+}
+
+func NewOPAAuthorizer(url string) *OPAAuthorizer {
+	return &OPAAuthorizer{
+		url:    url,
+		client: &http.Client{Timeout: opaRequestTimeout},
+	}
+}
+
+type opaRequest struct {
+	Input *Attributes `json:"input"`
+}
+
+type opaResponse struct {
+	Result *bool `json:"result"`
+}
+
+func (a *OPAAuthorizer) Authorize(ctx context.Context, attrs *Attributes) (bool, error) {
+	body, err := json.Marshal(&opaRequest{Input: attrs})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to query the policy decision: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to query the policy decision: %s", resp.Status)
+	}
+
+	decision := &opaResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(decision); err != nil {
+		return false, fmt.Errorf("failed to decode the policy decision: %w", err)
+	}
+	return decision.Result != nil && *decision.Result, nil
+}
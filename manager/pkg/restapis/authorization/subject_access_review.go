@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package authorization
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the decisions are cached, so the filter of a list doesn't review all the hubs again for each page
+const reviewCacheTTL = 30 * time.Second
+
+type reviewDecision struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+// SubjectAccessReviewAuthorizer authorizes the request with the SubjectAccessReview of the kubernetes, so the access
+// to the hubs and namespaces is granted by the roles of the APIGroup.
+type SubjectAccessReviewAuthorizer struct {
+	client client.Client
+	ttl    time.Duration
+
+	mutex     sync.Mutex
+	decisions map[string]reviewDecision
+}
+
+func NewSubjectAccessReviewAuthorizer(c client.Client) *SubjectAccessReviewAuthorizer {
+	return &SubjectAccessReviewAuthorizer{
+		client:    c,
+		ttl:       reviewCacheTTL,
+		decisions: map[string]reviewDecision{},
+	}
+}
+
+func (a *SubjectAccessReviewAuthorizer) Authorize(ctx context.Context, attrs *Attributes) (bool, error) {
+	key := cacheKey(attrs)
+	now := time.Now()
+	a.mutex.Lock()
+	decision, found := a.decisions[key]
+	a.mutex.Unlock()
+	if found && now.Before(decision.expiresAt) {
+		return decision.allowed, nil
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               attrs.User,
+			Groups:             attrs.Groups,
+			ResourceAttributes: toResourceAttributes(attrs),
+		},
+	}
+	if err := a.client.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("failed to create the subject access review: %w", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	// drop the expired decisions, so the cache doesn't grow with the users
+	for k, d := range a.decisions {
+		if now.After(d.expiresAt) {
+			delete(a.decisions, k)
+		}
+	}
+	a.decisions[key] = reviewDecision{allowed: review.Status.Allowed, expiresAt: now.Add(a.ttl)}
+	return review.Status.Allowed, nil
+}
+
+// toResourceAttributes maps the request to the managed hub, or its subresource, named by the hub
+func toResourceAttributes(attrs *Attributes) *authorizationv1.ResourceAttributes {
+	resourceAttributes := &authorizationv1.ResourceAttributes{
+		Group:     APIGroup,
+		Resource:  ManagedHubs,
+		Verb:      attrs.Verb,
+		Name:      attrs.LeafHub,
+		Namespace: attrs.Namespace,
+	}
+	if attrs.Resource != ManagedHubs {
+		resourceAttributes.Subresource = attrs.Resource
+	}
+	return resourceAttributes
+}
+
+func cacheKey(attrs *Attributes) string {
+	return strings.Join([]string{
+		attrs.User, strings.Join(attrs.Groups, ","), attrs.Verb, attrs.Resource, attrs.LeafHub, attrs.Namespace,
+	}, "/")
+}
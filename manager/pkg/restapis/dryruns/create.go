@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package dryruns
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authentication"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+const serverInternalErrorMsg = "internal error"
+
+// DryRunRequest is the global resources to apply by the server-side dry run on the hubs they target, the Hubs limit
+// the targets if they're set.
+type DryRunRequest struct {
+	Objects []map[string]interface{} `json:"objects" binding:"required" swaggertype:"array,object"`
+	Hubs    []string                 `json:"hubs,omitempty"`
+}
+
+// CreatedDryRun is the dry run created for the request, its results are got by the ID
+type CreatedDryRun struct {
+	ID string `json:"id"`
+}
+
+// CreateDryRun godoc
+// @summary dry run the global resources
+// @description apply the global resources by the server-side dry run on the managed hubs they target, nothing is
+// @description changed on the hubs. Each hub reports whether each object would be created, updated with the diff, or
+// @description failed, e.g. rejected by the admission webhooks.
+// @accept json
+// @produce json
+// @param        dryRun    body     DryRunRequest  true   "the global resources and the hubs to dry run them"
+// @success      201  {object}  CreatedDryRun
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /dryruns [post]
+func CreateDryRun() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		request := &DryRunRequest{}
+		if err := ginCtx.ShouldBindJSON(request); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid dry run: %v", err))
+			return
+		}
+		if len(request.Objects) == 0 {
+			ginCtx.String(http.StatusBadRequest, "no object to dry run")
+			return
+		}
+		namespaces := map[string]bool{}
+		for i, object := range request.Objects {
+			obj := &unstructured.Unstructured{Object: object}
+			if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf(
+					"the object %d should have the apiVersion, kind and metadata.name", i))
+				return
+			}
+			namespaces[obj.GetNamespace()] = true
+		}
+		for _, namespace := range sortedKeys(namespaces) {
+			if !authorization.AuthorizeOrAbort(ginCtx, "create", authorization.DryRuns, "", namespace) {
+				return
+			}
+		}
+
+		dryRun := &models.SpecDryRun{
+			ID:        uuid.New().String(),
+			CreatedBy: ginCtx.GetString(authentication.UserKey),
+		}
+		var err error
+		if dryRun.Objects, err = json.Marshal(request.Objects); err == nil && len(request.Hubs) > 0 {
+			dryRun.Hubs, err = json.Marshal(request.Hubs)
+		}
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid dry run: %v", err))
+			return
+		}
+
+		err = database.GetGorm().WithContext(ginCtx.Request.Context()).Create(dryRun).Error
+		util.RecordAudit(ginCtx, "create", authorization.DryRuns, request, []audit.Object{
+			{ID: dryRun.ID, Name: dryRun.ID},
+		}, err)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to create the dry run: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		ginCtx.JSON(http.StatusCreated, CreatedDryRun{ID: dryRun.ID})
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
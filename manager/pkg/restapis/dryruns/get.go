@@ -0,0 +1,163 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package dryruns
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+// DryRunResult is the result of applying the object by the server-side dry run on the hub, the Diff is the changed
+// fields of the object that would be updated.
+type DryRunResult struct {
+	HubName    string                        `json:"hubName"`
+	APIVersion string                        `json:"apiVersion"`
+	Kind       string                        `json:"kind"`
+	Namespace  string                        `json:"namespace,omitempty"`
+	Name       string                        `json:"name"`
+	Result     string                        `json:"result"`
+	Diff       []wiremodels.SpecDryRunChange `json:"diff,omitempty"`
+	Message    string                        `json:"message,omitempty"`
+	ReportedAt time.Time                     `json:"reportedAt"`
+}
+
+// DryRun is the dry run with the results reported by the hubs, the TargetHubs are empty until the dry run is sent to
+// the hubs, and a target hub without any result hasn't reported yet.
+type DryRun struct {
+	ID         string         `json:"id"`
+	CreatedBy  string         `json:"createdBy"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	SentAt     *time.Time     `json:"sentAt,omitempty"`
+	TargetHubs []string       `json:"targetHubs"`
+	Results    []DryRunResult `json:"results"`
+}
+
+// GetDryRun godoc
+// @summary get the dry run
+// @description get the dry run of the global resources with the results reported by the managed hubs
+// @accept json
+// @produce json
+// @param        id        path     string  true   "ID of the dry run"
+// @param        hub       query    string  false  "only list the results of the hub"
+// @param        result    query    string  false  "only list the results of would-create, would-update, unchanged or would-fail"
+// @success      200  {object}    DryRun
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /dryrun/{id} [get]
+func GetDryRun() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		id := ginCtx.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid dry run ID %q", id))
+			return
+		}
+		db := database.GetGorm().WithContext(ginCtx.Request.Context())
+		dryRun := &models.SpecDryRun{}
+		err := db.Where("id = ?", id).First(dryRun).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ginCtx.String(http.StatusNotFound, fmt.Sprintf("dry run %s not found", id))
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the dry run %s: %v\n", id, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		// the dry run is got by the user allowed to create it
+		objects := []*unstructured.Unstructured{}
+		if err := json.Unmarshal(dryRun.Objects, &objects); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in unmarshalling the objects of the dry run %s: %v\n", id, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		namespaces := map[string]bool{}
+		for _, obj := range objects {
+			namespaces[obj.GetNamespace()] = true
+		}
+		for _, namespace := range sortedKeys(namespaces) {
+			if !authorization.AuthorizeOrAbort(ginCtx, "get", authorization.DryRuns, "", namespace) {
+				return
+			}
+		}
+
+		query := db.Where("dry_run_id = ?", id)
+		if hub := ginCtx.Query("hub"); hub != "" {
+			query = query.Where("leaf_hub_name = ?", hub)
+		}
+		if result := ginCtx.Query("result"); result != "" {
+			switch result {
+			case wiremodels.SpecDryRunCreate, wiremodels.SpecDryRunUpdate, wiremodels.SpecDryRunUnchanged,
+				wiremodels.SpecDryRunFail:
+				query = query.Where("result = ?", result)
+			default:
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf(
+					"invalid result %q, it should be %s, %s, %s or %s", result, wiremodels.SpecDryRunCreate,
+					wiremodels.SpecDryRunUpdate, wiremodels.SpecDryRunUnchanged, wiremodels.SpecDryRunFail))
+				return
+			}
+		}
+		rows := []models.SpecDryRunResult{}
+		if err := query.Order("leaf_hub_name, kind, namespace, name").Find(&rows).Error; err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the results of the dry run %s: %v\n", id, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		resp := DryRun{
+			ID:         dryRun.ID,
+			CreatedBy:  dryRun.CreatedBy,
+			CreatedAt:  dryRun.CreatedAt,
+			SentAt:     dryRun.SentAt,
+			TargetHubs: []string{},
+			Results:    make([]DryRunResult, 0, len(rows)),
+		}
+		if len(dryRun.TargetHubs) > 0 {
+			if err := json.Unmarshal(dryRun.TargetHubs, &resp.TargetHubs); err != nil {
+				fmt.Fprintf(gin.DefaultWriter, "error in unmarshalling the target hubs of the dry run %s: %v\n", id, err)
+				ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+				return
+			}
+		}
+		for _, row := range rows {
+			result := DryRunResult{
+				HubName:    row.LeafHubName,
+				APIVersion: row.APIVersion,
+				Kind:       row.Kind,
+				Namespace:  row.Namespace,
+				Name:       row.Name,
+				Result:     row.Result,
+				Message:    row.Message,
+				ReportedAt: row.ReportedAt,
+			}
+			if len(row.Diff) > 0 {
+				if err := json.Unmarshal(row.Diff, &result.Diff); err != nil {
+					fmt.Fprintf(gin.DefaultWriter, "error in unmarshalling the diff of the dry run %s: %v\n", id, err)
+					ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+					return
+				}
+			}
+			resp.Results = append(resp.Results, result)
+		}
+		ginCtx.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+)
+
+const (
+	serverInternalErrorMsg = "internal error"
+	syncIntervalInSeconds  = 4
+	// the events in a page and in a watch interval are limited to protect the database
+	defaultLimit = 500
+	// cursorTimeFormat keeps the microseconds of the created_at, which is a timestamp without time zone
+	cursorTimeFormat = "2006-01-02 15:04:05.999999"
+	// watchLookback is the window of the inserted time the watch scans again on every interval, an event inserted by
+	// a transaction which commits later than the newer events is still streamed if it's committed within the window
+	watchLookback = time.Minute
+)
+
+// eventCursor is the position of an event in the order of (created_at, event_key), the event_key is unique for the
+// events created at the same time
+type eventCursor struct {
+	createdAt time.Time
+	key       string
+}
+
+// event is a row of the base query with its position in the list and in the watch
+type event struct {
+	object    interface{}
+	createdAt time.Time
+	key       string
+	// ingestedAt is the time the event is inserted into the database, it's the created_at of the events inserted
+	// before the inserted_at column is added
+	ingestedAt time.Time
+}
+
+// eventList is the page of the events, the continue token is set if there might be more events
+type eventList struct {
+	Metadata metav1.ListMeta `json:"metadata"`
+	Items    []interface{}   `json:"items"`
+}
+
+// eventQuery selects the events from the base query, which must return the created_at, event_key and ingested_at
+// columns
+type eventQuery struct {
+	base       string
+	conditions []string
+	args       []interface{}
+	// scan runs the query and returns the events
+	scan func(query string, args []interface{}) ([]event, error)
+}
+
+func (q *eventQuery) where(condition string, args ...interface{}) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+// build returns the query of the events after the cursor, ordered by the created time
+func (q *eventQuery) build(after *eventCursor, limit int) (string, []interface{}) {
+	conditions := append([]string{}, q.conditions...)
+	args := append([]interface{}{}, q.args...)
+	if after != nil {
+		conditions = append(conditions, "(created_at, event_key) > (?::timestamp, ?)")
+		args = append(args, after.createdAt.Format(cursorTimeFormat), after.key)
+	}
+	query := fmt.Sprintf("SELECT * FROM (%s) e", q.base)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at, event_key LIMIT ?"
+	return query, append(args, limit)
+}
+
+// buildForWatch returns the query of the events inserted after the time, ordered by the inserted time
+func (q *eventQuery) buildForWatch(after time.Time) (string, []interface{}) {
+	conditions := append(append([]string{}, q.conditions...), "ingested_at > ?::timestamp")
+	args := append(append([]interface{}{}, q.args...), after.Format(cursorTimeFormat))
+	return fmt.Sprintf("SELECT * FROM (%s) e WHERE %s ORDER BY ingested_at, event_key", q.base,
+		strings.Join(conditions, " AND ")), args
+}
+
+// parseEventQuery adds the common filters of the events to the query: since, until, hub, cluster, reason and message
+func parseEventQuery(ginCtx *gin.Context, query *eventQuery) error {
+	for _, param := range []struct {
+		name      string
+		condition string
+	}{{"since", "created_at >= ?"}, {"until", "created_at < ?"}} {
+		value := ginCtx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q, the format should be RFC3339", param.name, value)
+		}
+		query.where(param.condition, t.UTC().Format(cursorTimeFormat))
+	}
+	if hub := ginCtx.Query("hub"); hub != "" {
+		query.where("leaf_hub_name = ?", hub)
+	}
+	if cluster := ginCtx.Query("cluster"); cluster != "" {
+		query.where("cluster_name = ?", cluster)
+	}
+	if reason := ginCtx.Query("reason"); reason != "" {
+		query.where("reason = ?", reason)
+	}
+	// the substring is matched case-insensitively, strpos doesn't treat the % and _ as the wildcards like the LIKE
+	if message := ginCtx.Query("message"); message != "" {
+		query.where("strpos(lower(message), lower(?)) > 0", message)
+	}
+	return nil
+}
+
+func parseLimit(ginCtx *gin.Context) (int, error) {
+	limit := ginCtx.Query("limit")
+	if limit == "" {
+		return defaultLimit, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid limit %q", limit)
+	}
+	return n, nil
+}
+
+func decodeCursor(continueToken string) (*eventCursor, error) {
+	if continueToken == "" {
+		return nil, nil
+	}
+	createdAt, key, err := util.DecodeContinue(continueToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid continue token: %w", err)
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid continue token: %w", err)
+	}
+	return &eventCursor{createdAt: t, key: key}, nil
+}
+
+func encodeCursor(cursor *eventCursor) (string, error) {
+	return util.EncodeContinue(cursor.createdAt.Format(time.RFC3339Nano), cursor.key)
+}
+
+// handleEvents lists a page of the events, or streams the events if the watch is requested
+func handleEvents(ginCtx *gin.Context, query *eventQuery, kind string) {
+	if err := parseEventQuery(ginCtx, query); err != nil {
+		ginCtx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parseLimit(ginCtx)
+	if err != nil {
+		ginCtx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor, err := decodeCursor(ginCtx.Query("continue"))
+	if err != nil {
+		ginCtx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, watch := ginCtx.GetQuery("watch")
+	verb := "list"
+	if watch {
+		verb = "watch"
+	}
+	// only the events of the hubs the user is allowed to access are listed
+	filter, err := authorization.NewFilter(ginCtx, verb, authorization.Events, "")
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in authorizing the %s: %v\n", kind, err)
+		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+		return
+	}
+	if condition, args := filter.Where("leaf_hub_name", ""); condition != "" {
+		query.where(condition, args...)
+	}
+
+	if watch {
+		handleEventsForWatch(ginCtx, query, cursor)
+		return
+	}
+
+	sql, args := query.build(cursor, limit)
+	fmt.Fprintf(gin.DefaultWriter, "%s query: %v, args: %v\n", kind, sql, args)
+	events, err := query.scan(sql, args)
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in querying %s: %v\n", kind, err)
+		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+		return
+	}
+
+	list := eventList{Items: make([]interface{}, 0, len(events))}
+	for _, e := range events {
+		list.Items = append(list.Items, e.object)
+	}
+	// there might be more events if the page is full
+	if len(events) > 0 && len(events) == limit {
+		last := events[len(events)-1]
+		continueToken, err := encodeCursor(&eventCursor{createdAt: last.createdAt, key: last.key})
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in encoding the continue token: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		list.Metadata.Continue = continueToken
+	}
+	ginCtx.JSON(http.StatusOK, list)
+}
+
+// eventWatcher tracks the events sent by the watch. The watch is keyed on the inserted time instead of the created
+// time, since the events of a slower hub are inserted after the newer events of the other hubs. The events inserted in
+// the lookback window are scanned again on every interval and the sent ones are skipped.
+type eventWatcher struct {
+	query *eventQuery
+	// floor is the inserted time the watch begins with, the events inserted before it aren't sent
+	floor time.Time
+	// latest is the newest inserted time of the sent events
+	latest time.Time
+	// sent are the events sent in the lookback window, keyed by the event key and created time
+	sent map[string]time.Time
+}
+
+func newEventWatcher(query *eventQuery, floor time.Time) *eventWatcher {
+	return &eventWatcher{query: query, floor: floor, latest: floor, sent: map[string]time.Time{}}
+}
+
+// after returns the inserted time of the next scan
+func (w *eventWatcher) after() time.Time {
+	after := w.latest.Add(-watchLookback)
+	if after.Before(w.floor) {
+		return w.floor
+	}
+	return after
+}
+
+// next returns the events that aren't sent yet, and forgets the sent events which are out of the lookback window
+func (w *eventWatcher) next(events []event) []event {
+	unsent := []event{}
+	for _, e := range events {
+		key := e.key + "/" + e.createdAt.Format(cursorTimeFormat)
+		if _, ok := w.sent[key]; ok {
+			continue
+		}
+		w.sent[key] = e.ingestedAt
+		if e.ingestedAt.After(w.latest) {
+			w.latest = e.ingestedAt
+		}
+		unsent = append(unsent, e)
+	}
+	after := w.after()
+	for key, ingestedAt := range w.sent {
+		if !ingestedAt.After(after) {
+			delete(w.sent, key)
+		}
+	}
+	return unsent
+}
+
+// prime marks the events of the lookback window as sent without sending them, so the watch begins with the events
+// inserted after it's started
+func (w *eventWatcher) prime() error {
+	w.floor = w.latest.Add(-watchLookback)
+	sql, args := w.query.buildForWatch(w.floor)
+	events, err := w.query.scan(sql, args)
+	if err != nil {
+		return err
+	}
+	w.next(events)
+	return nil
+}
+
+// handleEventsForWatch streams the events inserted after the since or the created time of the continue token, it
+// begins with the events inserted after the request if neither of them is specified.
+func handleEventsForWatch(ginCtx *gin.Context, query *eventQuery, cursor *eventCursor) {
+	var watcher *eventWatcher
+	if since := ginCtx.Query("since"); since != "" {
+		// the since is validated by the parseEventQuery, the floor is exclusive
+		t, _ := time.Parse(time.RFC3339, since)
+		watcher = newEventWatcher(query, t.UTC().Add(-time.Microsecond))
+	} else if cursor != nil {
+		watcher = newEventWatcher(query, cursor.createdAt)
+	} else {
+		var now time.Time
+		if err := database.GetGorm().Raw("SELECT LOCALTIMESTAMP").Row().Scan(&now); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the database time: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		watcher = newEventWatcher(query, now)
+		if err := watcher.prime(); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying events: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+	}
+
+	writer := ginCtx.Writer
+	header := writer.Header()
+	header.Set("Transfer-Encoding", "chunked")
+	header.Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(syncIntervalInSeconds * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-writer.CloseNotify():
+			return
+		case <-ticker.C:
+			if ginCtx.Err() != nil || ginCtx.IsAborted() {
+				return
+			}
+			sql, args := query.buildForWatch(watcher.after())
+			events, err := query.scan(sql, args)
+			if err != nil {
+				fmt.Fprintf(gin.DefaultWriter, "error in querying events: %v\n", err)
+				continue
+			}
+			for _, e := range watcher.next(events) {
+				raw, err := json.Marshal(e.object)
+				if err != nil {
+					fmt.Fprintf(gin.DefaultWriter, "error in marshaling the event: %v\n", err)
+					continue
+				}
+				if err := util.SendWatchEvent(&metav1.WatchEvent{
+					Type:   "ADDED",
+					Object: runtime.RawExtension{Raw: raw},
+				}, writer); err != nil {
+					fmt.Fprintf(gin.DefaultWriter, "error in sending watch event: %v\n", err)
+				}
+			}
+			writer.Flush()
+		}
+	}
+}
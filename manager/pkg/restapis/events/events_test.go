@@ -0,0 +1,142 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package events
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEventQueryBuild(t *testing.T) {
+	query := &eventQuery{base: "SELECT * FROM event.managed_clusters"}
+	query.where("leaf_hub_name = ?", "hub1")
+
+	sql, args := query.build(nil, 10)
+	expected := "SELECT * FROM (SELECT * FROM event.managed_clusters) e WHERE leaf_hub_name = ? " +
+		"ORDER BY created_at, event_key LIMIT ?"
+	if sql != expected {
+		t.Errorf("expected %s, but got %s", expected, sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"hub1", 10}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	cursor := &eventCursor{createdAt: time.Date(2024, 5, 1, 8, 30, 0, 123456000, time.UTC), key: "hub1/event1"}
+	sql, args = query.build(cursor, 10)
+	expected = "SELECT * FROM (SELECT * FROM event.managed_clusters) e WHERE leaf_hub_name = ? AND " +
+		"(created_at, event_key) > (?::timestamp, ?) ORDER BY created_at, event_key LIMIT ?"
+	if sql != expected {
+		t.Errorf("expected %s, but got %s", expected, sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"hub1", "2024-05-01 08:30:00.123456", "hub1/event1", 10}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+	// the cursor mustn't change the conditions of the query, which are reused by the watch
+	if len(query.conditions) != 1 || len(query.args) != 1 {
+		t.Errorf("the query is changed by the build: %v, %v", query.conditions, query.args)
+	}
+}
+
+func TestEventCursor(t *testing.T) {
+	cursor := &eventCursor{createdAt: time.Date(2024, 5, 1, 8, 30, 0, 123456000, time.UTC), key: "hub1/event1"}
+	token, err := encodeCursor(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeCursor(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.createdAt.Equal(cursor.createdAt) || decoded.key != cursor.key {
+		t.Errorf("expected %v, but got %v", cursor, decoded)
+	}
+
+	if _, err := decodeCursor("invalid"); err == nil {
+		t.Error("the invalid continue token should be rejected")
+	}
+}
+
+func TestParseEventQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		url        string
+		conditions int
+		invalid    bool
+	}{
+		{"/events?since=2024-05-01T08:00:00Z&until=2024-05-01T09:00:00Z", 2, false},
+		{"/events?hub=hub1&cluster=cluster1&reason=Failed&message=timeout", 4, false},
+		{"/events?since=yesterday", 0, true},
+	}
+	for _, c := range cases {
+		ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ginCtx.Request = httptest.NewRequest("GET", c.url, nil)
+		query := &eventQuery{}
+		err := parseEventQuery(ginCtx, query)
+		if c.invalid != (err != nil) {
+			t.Errorf("%s: unexpected error %v", c.url, err)
+		}
+		if !c.invalid && len(query.conditions) != c.conditions {
+			t.Errorf("%s: expected %d conditions, but got %v", c.url, c.conditions, query.conditions)
+		}
+	}
+}
+
+func TestEventQueryBuildForWatch(t *testing.T) {
+	query := &eventQuery{base: "SELECT * FROM event.managed_clusters"}
+	query.where("leaf_hub_name = ?", "hub1")
+
+	sql, args := query.buildForWatch(time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC))
+	expected := "SELECT * FROM (SELECT * FROM event.managed_clusters) e WHERE leaf_hub_name = ? AND " +
+		"ingested_at > ?::timestamp ORDER BY ingested_at, event_key"
+	if sql != expected {
+		t.Errorf("expected %s, but got %s", expected, sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"hub1", "2024-05-01 08:30:00"}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestEventWatcher(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	watcher := newEventWatcher(&eventQuery{}, start)
+	if !watcher.after().Equal(start) {
+		t.Errorf("the watch should begin with the floor %v, but got %v", start, watcher.after())
+	}
+
+	newer := event{key: "hub1/event1", createdAt: start.Add(time.Second), ingestedAt: start.Add(2 * time.Second)}
+	if sent := watcher.next([]event{newer}); len(sent) != 1 {
+		t.Fatalf("expected the new event is sent, but got %v", sent)
+	}
+
+	// the event of a slower hub is created before the sent event, but inserted after it
+	late := event{key: "hub2/event1", createdAt: start.Add(-time.Hour), ingestedAt: start.Add(3 * time.Second)}
+	sent := watcher.next([]event{newer, late})
+	if len(sent) != 1 || sent[0].key != late.key {
+		t.Fatalf("expected only the late event is sent, but got %v", sent)
+	}
+
+	// the event committed later than the newer events is rescanned in the lookback window
+	committed := event{key: "hub3/event1", createdAt: start, ingestedAt: start.Add(time.Second)}
+	if !watcher.after().Before(committed.ingestedAt) {
+		t.Errorf("the event inserted at %v should be in the scan after %v", committed.ingestedAt, watcher.after())
+	}
+	sent = watcher.next([]event{committed, newer, late})
+	if len(sent) != 1 || sent[0].key != committed.key {
+		t.Fatalf("expected only the committed event is sent, but got %v", sent)
+	}
+
+	// the sent events are forgotten once they are out of the lookback window
+	next := event{key: "hub1/event2", createdAt: start, ingestedAt: start.Add(watchLookback + time.Hour)}
+	watcher.next([]event{next})
+	if len(watcher.sent) != 1 {
+		t.Errorf("expected only the latest event is tracked, but got %v", watcher.sent)
+	}
+	if !watcher.after().Equal(next.ingestedAt.Add(-watchLookback)) {
+		t.Errorf("expected the scan after %v, but got %v", next.ingestedAt.Add(-watchLookback), watcher.after())
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package events
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+// the events of a hub are unique by the event name and the created time
+const managedClusterEventsQuery = `SELECT event_namespace, event_name, cluster_name, cluster_id, leaf_hub_name,
+		message, reason, reporting_controller, reporting_instance, event_type, created_at,
+		leaf_hub_name || '/' || event_name AS event_key, coalesce(inserted_at, created_at) AS ingested_at
+	FROM event.managed_clusters`
+
+type ManagedClusterEvent struct {
+	models.ManagedClusterEvent
+	EventKey   string    `gorm:"column:event_key" json:"-"`
+	IngestedAt time.Time `gorm:"column:ingested_at" json:"-"`
+}
+
+type ManagedClusterEventList struct {
+	Metadata metav1.ListMeta       `json:"metadata"`
+	Items    []ManagedClusterEvent `json:"items"`
+}
+
+// ListManagedClusterEvents godoc
+// @summary list managed cluster events
+// @description list the events of the managed clusters in the order of the created time
+// @accept json
+// @produce json
+// @param        since       query     string  false  "only list the events created after the time, in RFC3339 format"
+// @param        until       query     string  false  "only list the events created before the time, in RFC3339 format"
+// @param        hub         query     string  false  "only list the events of the managed hub"
+// @param        cluster     query     string  false  "only list the events of the managed cluster"
+// @param        reason      query     string  false  "only list the events of the reason"
+// @param        message     query     string  false  "only list the events whose message contains the substring"
+// @param        limit       query     int     false  "maximum event number to receive, it's 500 by default"
+// @param        continue    query     string  false  "continue token to request next request"
+// @param        watch       query     bool    false  "stream the events inserted after the since or the request"
+// @success      200  {object}    ManagedClusterEventList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /events/managedclusters [get]
+func ListManagedClusterEvents() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		handleEvents(ginCtx, &eventQuery{
+			base: managedClusterEventsQuery,
+			scan: scanManagedClusterEvents,
+		}, "managed cluster events")
+	}
+}
+
+func scanManagedClusterEvents(query string, args []interface{}) ([]event, error) {
+	rows := []ManagedClusterEvent{}
+	if err := database.GetGorm().Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	events := make([]event, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, event{
+			object:     row,
+			createdAt:  row.CreatedAt,
+			key:        row.EventKey,
+			ingestedAt: row.IngestedAt,
+		})
+	}
+	return events, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package events
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+)
+
+// the replicated policy events on the managed clusters and the root policy events on the hubs, the events of a table
+// are unique by the event name, count and the created time
+const policyEventsQuery = `SELECT event_name, event_namespace, policy_id, cluster_id, cluster_name, leaf_hub_name,
+		message, reason, count, compliance, source, created_at,
+		'cluster/' || event_name || '/' || count AS event_key, coalesce(inserted_at, created_at) AS ingested_at
+	FROM event.local_policies
+	UNION ALL
+	SELECT event_name, event_namespace, policy_id, NULL::uuid, NULL::text, leaf_hub_name,
+		message, reason, count, compliance, source, created_at,
+		'root/' || event_name || '/' || count AS event_key, coalesce(inserted_at, created_at) AS ingested_at
+	FROM event.local_root_policies`
+
+// PolicyEvent is the event of the policy, the cluster is empty for the event of the root policy on the hub
+type PolicyEvent struct {
+	EventName      string         `gorm:"column:event_name" json:"eventName"`
+	EventNamespace string         `gorm:"column:event_namespace" json:"eventNamespace"`
+	PolicyID       string         `gorm:"column:policy_id" json:"policyId"`
+	ClusterID      *string        `gorm:"column:cluster_id" json:"clusterId,omitempty"`
+	ClusterName    *string        `gorm:"column:cluster_name" json:"clusterName,omitempty"`
+	LeafHubName    string         `gorm:"column:leaf_hub_name" json:"leafHubName"`
+	Message        string         `gorm:"column:message" json:"message"`
+	Reason         string         `gorm:"column:reason" json:"reason"`
+	Count          int            `gorm:"column:count" json:"count"`
+	Compliance     string         `gorm:"column:compliance" json:"compliance"`
+	Source         datatypes.JSON `gorm:"column:source" json:"source,omitempty"`
+	CreatedAt      time.Time      `gorm:"column:created_at" json:"createdAt"`
+	EventKey       string         `gorm:"column:event_key" json:"-"`
+	IngestedAt     time.Time      `gorm:"column:ingested_at" json:"-"`
+}
+
+type PolicyEventList struct {
+	Metadata metav1.ListMeta `json:"metadata"`
+	Items    []PolicyEvent   `json:"items"`
+}
+
+// ListPolicyEvents godoc
+// @summary list policy events
+// @description list the events of the policies on the managed clusters and hubs in the order of the created time
+// @accept json
+// @produce json
+// @param        since       query     string  false  "only list the events created after the time, in RFC3339 format"
+// @param        until       query     string  false  "only list the events created before the time, in RFC3339 format"
+// @param        hub         query     string  false  "only list the events of the managed hub"
+// @param        cluster     query     string  false  "only list the events of the managed cluster"
+// @param        policy      query     string  false  "only list the events of the policy ID"
+// @param        reason      query     string  false  "only list the events of the reason"
+// @param        message     query     string  false  "only list the events whose message contains the substring"
+// @param        limit       query     int     false  "maximum event number to receive, it's 500 by default"
+// @param        continue    query     string  false  "continue token to request next request"
+// @param        watch       query     bool    false  "stream the events inserted after the since or the request"
+// @success      200  {object}    PolicyEventList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /events/policies [get]
+func ListPolicyEvents() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		query := &eventQuery{
+			base: policyEventsQuery,
+			scan: scanPolicyEvents,
+		}
+		if policyID := ginCtx.Query("policy"); policyID != "" {
+			if _, err := uuid.Parse(policyID); err != nil {
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid policy ID %q", policyID))
+				return
+			}
+			query.where("policy_id = ?", policyID)
+		}
+		handleEvents(ginCtx, query, "policy events")
+	}
+}
+
+func scanPolicyEvents(query string, args []interface{}) ([]event, error) {
+	rows := []PolicyEvent{}
+	if err := database.GetGorm().Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	events := make([]event, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, event{
+			object:     row,
+			createdAt:  row.CreatedAt,
+			key:        row.EventKey,
+			ingestedAt: row.IngestedAt,
+		})
+	}
+	return events, nil
+}
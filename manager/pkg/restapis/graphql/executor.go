@@ -0,0 +1,498 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	// the nested selections are limited to protect the database, each level is resolved by a query of each field
+	maxDepth = 10
+	// the object fields of the query are limited too, since the aliases select the same field many times at each
+	// level, and each of them is resolved by a query
+	maxObjectFields = 100
+
+	// the types of the arguments
+	argString  = "String"
+	argInt     = "Int"
+	argBoolean = "Boolean"
+)
+
+// objectType is the object type of the schema, its fields are either the scalars read from the source object or the
+// objects resolved by the queries.
+type objectType struct {
+	name   string
+	fields map[string]*fieldDefinition
+}
+
+// resolveFunc resolves the field of all the sources of the same level at once, so the field is resolved by a single
+// query regardless of the number of the sources. It returns the children of each source in the order of the sources.
+type resolveFunc func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([][]interface{}, error)
+
+type fieldDefinition struct {
+	// args are the types of the arguments by the names
+	args map[string]string
+	// value returns the scalar of the source, it's set for the scalar field
+	value func(source interface{}) interface{}
+	// typ is the type of the object field resolved by the resolve
+	typ     *objectType
+	list    bool
+	resolve resolveFunc
+}
+
+// Request is the GraphQL request over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of the request, the data is null if any error occurs
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+type Error struct {
+	Message string `json:"message"`
+}
+
+// requestError is the error of the invalid request, which is returned to the client as it is. The other errors are
+// the internal errors of the resolvers.
+type requestError struct {
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func requestErrorf(format string, args ...interface{}) error {
+	return &requestError{message: fmt.Sprintf(format, args...)}
+}
+
+// executor executes the query operation of the request against the schema of the query type
+type executor struct {
+	doc       *document
+	variables map[string]interface{}
+	// objectFields is the number of the object fields executed so far
+	objectFields int
+}
+
+// execute parses and executes the request, it returns the data ordered as the selections
+func execute(ctx context.Context, query *objectType, request *Request) (interface{}, error) {
+	doc, err := parse(request.Query)
+	if err != nil {
+		return nil, &requestError{message: err.Error()}
+	}
+	op, err := selectOperation(doc, request.OperationName)
+	if err != nil {
+		return nil, err
+	}
+	if op.kind != "query" {
+		return nil, requestErrorf("the %s isn't supported, the endpoint is read-only", op.kind)
+	}
+	e := &executor{doc: doc}
+	if e.variables, err = coerceVariables(op.variables, request.Variables); err != nil {
+		return nil, err
+	}
+	results, err := e.executeSelections(ctx, query, []interface{}{nil}, op.selections, 1, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+func selectOperation(doc *document, operationName string) (*operation, error) {
+	if operationName == "" {
+		if len(doc.operations) > 1 {
+			return nil, requestErrorf("the operationName is required for the document of multiple operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == operationName {
+			return op, nil
+		}
+	}
+	return nil, requestErrorf("unknown operation %q", operationName)
+}
+
+// coerceVariables returns the values of the variables by the definitions, the missing variable is the default value
+func coerceVariables(definitions []*variableDefinition, values map[string]interface{}) (map[string]interface{},
+	error,
+) {
+	variables := map[string]interface{}{}
+	for _, definition := range definitions {
+		v, found := values[definition.name]
+		if !found && definition.defaultValue != nil {
+			var err error
+			if v, err = valueOf(definition.defaultValue, nil); err != nil {
+				return nil, err
+			}
+			found = true
+		}
+		if !found || v == nil {
+			if definition.nonNull {
+				return nil, requestErrorf("the variable $%s of the type %s! is required", definition.name,
+					definition.typeName)
+			}
+			variables[definition.name] = nil
+			continue
+		}
+		coerced, err := coerce(definition.typeName, v)
+		if err != nil {
+			return nil, requestErrorf("invalid variable $%s: %v", definition.name, err)
+		}
+		variables[definition.name] = coerced
+	}
+	return variables, nil
+}
+
+// coerce checks the value is of the scalar type, the number decoded from the JSON variables is converted to the Int
+func coerce(typeName string, v interface{}) (interface{}, error) {
+	switch typeName {
+	case argString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case argInt:
+		switch n := v.(type) {
+		case int64:
+			return n, nil
+		case float64:
+			if n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32 {
+				return int64(n), nil
+			}
+		case json.Number:
+			if i, err := n.Int64(); err == nil {
+				return i, nil
+			}
+		}
+	case argBoolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %s", typeName)
+	}
+	return nil, fmt.Errorf("expected the %s, but got %v", typeName, v)
+}
+
+// valueOf returns the value of the literal or the variable
+func valueOf(v *value, variables map[string]interface{}) (interface{}, error) {
+	switch v.kind {
+	case valueVariable:
+		value, found := variables[v.raw]
+		if !found {
+			return nil, requestErrorf("the variable $%s isn't defined", v.raw)
+		}
+		return value, nil
+	case valueInt:
+		n, err := strconv.ParseInt(v.raw, 10, 32)
+		if err != nil {
+			return nil, requestErrorf("invalid Int %s", v.raw)
+		}
+		return n, nil
+	case valueFloat:
+		f, err := strconv.ParseFloat(v.raw, 64)
+		if err != nil {
+			return nil, requestErrorf("invalid Float %s", v.raw)
+		}
+		return f, nil
+	case valueString, valueEnum:
+		return v.raw, nil
+	case valueBoolean:
+		return v.raw == "true", nil
+	case valueNull:
+		return nil, nil
+	case valueList:
+		list := []interface{}{}
+		for _, item := range v.list {
+			itemValue, err := valueOf(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, itemValue)
+		}
+		return list, nil
+	default:
+		object := map[string]interface{}{}
+		for name, fieldValue := range v.fields {
+			itemValue, err := valueOf(fieldValue, variables)
+			if err != nil {
+				return nil, err
+			}
+			object[name] = itemValue
+		}
+		return object, nil
+	}
+}
+
+// collectedField is the fields of the same response key, their selections are merged
+type collectedField struct {
+	key    string
+	fields []*field
+}
+
+// collectFields flattens the fragments and skips the fields excluded by the directives
+func (e *executor) collectFields(typ *objectType, selections []selection, collected []*collectedField,
+	visited map[string]bool,
+) ([]*collectedField, error) {
+	for _, s := range selections {
+		switch sel := s.(type) {
+		case *field:
+			included, err := e.included(sel.directives)
+			if err != nil {
+				return nil, err
+			}
+			if !included {
+				continue
+			}
+			key := sel.responseKey()
+			found := false
+			for _, c := range collected {
+				if c.key == key {
+					if c.fields[0].name != sel.name {
+						return nil, requestErrorf("the fields %s and %s conflict as the response key %s",
+							c.fields[0].name, sel.name, key)
+					}
+					c.fields = append(c.fields, sel)
+					found = true
+					break
+				}
+			}
+			if !found {
+				collected = append(collected, &collectedField{key: key, fields: []*field{sel}})
+			}
+		case *fragmentSpread:
+			included, err := e.included(sel.directives)
+			if err != nil {
+				return nil, err
+			}
+			if !included {
+				continue
+			}
+			if visited[sel.name] {
+				return nil, requestErrorf("the fragment %s spreads itself", sel.name)
+			}
+			f, found := e.doc.fragments[sel.name]
+			if !found {
+				return nil, requestErrorf("unknown fragment %q", sel.name)
+			}
+			if f.typeCondition != typ.name {
+				continue
+			}
+			visited[sel.name] = true
+			collected, err = e.collectFields(typ, f.selections, collected, visited)
+			delete(visited, sel.name)
+			if err != nil {
+				return nil, err
+			}
+		case *inlineFragment:
+			included, err := e.included(sel.directives)
+			if err != nil {
+				return nil, err
+			}
+			if !included || (sel.typeCondition != "" && sel.typeCondition != typ.name) {
+				continue
+			}
+			if collected, err = e.collectFields(typ, sel.selections, collected, visited); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return collected, nil
+}
+
+// included evaluates the @skip and @include directives
+func (e *executor) included(directives []*directive) (bool, error) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			return false, requestErrorf("unknown directive @%s", d.name)
+		}
+		condition, found := d.arguments["if"]
+		if !found {
+			return false, requestErrorf("the argument \"if\" of the directive @%s is required", d.name)
+		}
+		v, err := valueOf(condition, e.variables)
+		if err != nil {
+			return false, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return false, requestErrorf("the argument \"if\" of the directive @%s must be a Boolean", d.name)
+		}
+		if (d.name == "skip") == b {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// arguments returns the values of the arguments checked against the definition of the field
+func (e *executor) arguments(typ *objectType, definition *fieldDefinition, f *field) (map[string]interface{},
+	error,
+) {
+	args := map[string]interface{}{}
+	for name, v := range f.arguments {
+		argType, found := definition.args[name]
+		if !found {
+			return nil, requestErrorf("unknown argument %q on the field %s.%s", name, typ.name, f.name)
+		}
+		value, err := valueOf(v, e.variables)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+		if args[name], err = coerce(argType, value); err != nil {
+			return nil, requestErrorf("invalid argument %q on the field %s.%s: %v", name, typ.name, f.name, err)
+		}
+	}
+	return args, nil
+}
+
+// executeSelections executes the selections on all the sources of the type, and returns the result of each source.
+// The object field is resolved once for all the sources, then its selections are executed on all the children.
+func (e *executor) executeSelections(ctx context.Context, typ *objectType, sources []interface{},
+	selections []selection, depth int, visited map[string]bool,
+) ([]*orderedMap, error) {
+	if depth > maxDepth {
+		return nil, requestErrorf("the query exceeds the maximum depth %d", maxDepth)
+	}
+	collected, err := e.collectFields(typ, selections, nil, visited)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*orderedMap, len(sources))
+	for i := range results {
+		results[i] = &orderedMap{values: map[string]interface{}{}}
+	}
+
+	for _, c := range collected {
+		f := c.fields[0]
+		if f.name == "__typename" {
+			for _, result := range results {
+				result.set(c.key, typ.name)
+			}
+			continue
+		}
+		definition, found := typ.fields[f.name]
+		if !found {
+			return nil, requestErrorf("cannot query the field %q on the type %s", f.name, typ.name)
+		}
+		subSelections := []selection{}
+		for _, sameKey := range c.fields {
+			subSelections = append(subSelections, sameKey.selections...)
+		}
+
+		if definition.typ == nil {
+			if len(subSelections) > 0 {
+				return nil, requestErrorf("the scalar field %s.%s can't have the selections", typ.name, f.name)
+			}
+			if len(f.arguments) > 0 {
+				return nil, requestErrorf("the field %s.%s doesn't have the arguments", typ.name, f.name)
+			}
+			for i, source := range sources {
+				results[i].set(c.key, definition.value(source))
+			}
+			continue
+		}
+
+		if len(subSelections) == 0 {
+			return nil, requestErrorf("the field %s.%s of the type %s must have the selections", typ.name, f.name,
+				definition.typ.name)
+		}
+		args, err := e.arguments(typ, definition, f)
+		if err != nil {
+			return nil, err
+		}
+		// the field is counted regardless of the sources, so the limit doesn't depend on the data
+		if e.objectFields++; e.objectFields > maxObjectFields {
+			return nil, requestErrorf("the query exceeds the maximum %d object fields", maxObjectFields)
+		}
+		// the selections of the field are still validated when there isn't any source to resolve
+		children := [][]interface{}{}
+		if len(sources) > 0 {
+			if children, err = definition.resolve(ctx, sources, args); err != nil {
+				return nil, err
+			}
+		}
+		if len(children) != len(sources) {
+			return nil, fmt.Errorf("the field %s.%s is resolved for %d sources, but expected %d", typ.name, f.name,
+				len(children), len(sources))
+		}
+		flattened := []interface{}{}
+		for _, items := range children {
+			flattened = append(flattened, items...)
+		}
+		childResults, err := e.executeSelections(ctx, definition.typ, flattened, subSelections, depth+1, visited)
+		if err != nil {
+			return nil, err
+		}
+		offset := 0
+		for i, items := range children {
+			childResult := childResults[offset : offset+len(items)]
+			offset += len(items)
+			if !definition.list {
+				if len(childResult) == 0 {
+					results[i].set(c.key, nil)
+				} else {
+					results[i].set(c.key, childResult[0])
+				}
+				continue
+			}
+			list := make([]interface{}, 0, len(childResult))
+			for _, item := range childResult {
+				list = append(list, item)
+			}
+			results[i].set(c.key, list)
+		}
+	}
+	return results, nil
+}
+
+// orderedMap is the object of the response, its keys are ordered as the selections
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, found := m.values[key]; !found {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	buffer.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(k)
+		buffer.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(v)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type testHub struct {
+	name     string
+	clusters []string
+}
+
+// newTestSchema returns the schema of the hubs and their clusters, the calls count the resolving of the clusters
+func newTestSchema(calls *int) *objectType {
+	clusterType := &objectType{name: "Cluster", fields: map[string]*fieldDefinition{
+		"name": {value: func(source interface{}) interface{} { return source.(string) }},
+	}}
+	hubType := &objectType{name: "Hub"}
+	hubType.fields = map[string]*fieldDefinition{
+		"name": {value: func(source interface{}) interface{} { return source.(*testHub).name }},
+		"clusters": {
+			args: map[string]string{"limit": argInt},
+			typ:  clusterType, list: true,
+			resolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([][]interface{},
+				error,
+			) {
+				*calls++
+				limit, err := limitOf(args)
+				if err != nil {
+					return nil, err
+				}
+				children := [][]interface{}{}
+				for _, source := range sources {
+					items := []interface{}{}
+					for i, cluster := range source.(*testHub).clusters {
+						if i < limit {
+							items = append(items, cluster)
+						}
+					}
+					children = append(children, items)
+				}
+				return children, nil
+			},
+		},
+	}
+	return &objectType{name: "Query", fields: map[string]*fieldDefinition{
+		"hubs": {
+			args: map[string]string{"name": argString},
+			typ:  hubType, list: true,
+			resolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([][]interface{},
+				error,
+			) {
+				hubs := []interface{}{}
+				for _, hub := range []*testHub{
+					{name: "hub1", clusters: []string{"cluster1", "cluster2"}},
+					{name: "hub2", clusters: []string{"cluster3"}},
+				} {
+					if name := stringArg(args, "name"); name == "" || name == hub.name {
+						hubs = append(hubs, hub)
+					}
+				}
+				return [][]interface{}{hubs}, nil
+			},
+		},
+		"hub": {
+			args: map[string]string{"name": argString},
+			typ:  hubType,
+			resolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([][]interface{},
+				error,
+			) {
+				return [][]interface{}{{}}, nil
+			},
+		},
+	}}
+}
+
+func executeTestQuery(t *testing.T, request *Request) (string, int, error) {
+	calls := 0
+	data, err := execute(context.Background(), newTestSchema(&calls), request)
+	if err != nil {
+		return "", calls, err
+	}
+	result, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(result), calls, nil
+}
+
+func TestExecute(t *testing.T) {
+	result, calls, err := executeTestQuery(t, &Request{Query: `
+		query Hubs($limit: Int = 5) {
+			hubs { ...hubFields first: clusters(limit: 1) { name } }
+			missing: hub(name: "hub3") { name }
+		}
+		fragment hubFields on Hub { __typename name clusters(limit: $limit) { name } }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"hubs":[` +
+		`{"__typename":"Hub","name":"hub1","clusters":[{"name":"cluster1"},{"name":"cluster2"}],` +
+		`"first":[{"name":"cluster1"}]},` +
+		`{"__typename":"Hub","name":"hub2","clusters":[{"name":"cluster3"}],"first":[{"name":"cluster3"}]}],` +
+		`"missing":null}`
+	if result != expected {
+		t.Errorf("expected %s, but got %s", expected, result)
+	}
+	// the clusters of all the hubs are resolved at once for each response key
+	if calls != 2 {
+		t.Errorf("expected the clusters are resolved twice, but got %d", calls)
+	}
+}
+
+func TestExecuteVariablesAndDirectives(t *testing.T) {
+	result, _, err := executeTestQuery(t, &Request{
+		Query: `query($hub: String!, $withClusters: Boolean!) {
+			hubs(name: $hub) { name clusters @include(if: $withClusters) { name } ... on Hub @skip(if: true) { x } }
+		}`,
+		Variables: map[string]interface{}{"hub": "hub2", "withClusters": false},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"hubs":[{"name":"hub2"}]}`; result != expected {
+		t.Errorf("expected %s, but got %s", expected, result)
+	}
+}
+
+func TestExecuteInvalidRequests(t *testing.T) {
+	for name, request := range map[string]*Request{
+		"mutation":           {Query: `mutation { hubs { name } }`},
+		"syntax":             {Query: `{ hubs { name }`},
+		"unknown field":      {Query: `{ hubs { namespace } }`},
+		"unknown argument":   {Query: `{ hubs(hub: "hub1") { name } }`},
+		"invalid argument":   {Query: `{ hubs(name: 1) { name } }`},
+		"scalar selections":  {Query: `{ hubs { name { x } } }`},
+		"object selections":  {Query: `{ hubs }`},
+		"missing variable":   {Query: `query($hub: String!) { hubs(name: $hub) { name } }`},
+		"undefined variable": {Query: `{ hubs(name: $hub) { name } }`},
+		"invalid limit":      {Query: `{ hubs { clusters(limit: 0) { name } } }`},
+		"fragment cycle":     {Query: `{ hubs { ...a } } fragment a on Hub { ...b } fragment b on Hub { ...a }`},
+		"operation name":     {Query: `query a { hubs { name } } query b { hubs { name } }`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := executeTestQuery(t, request)
+			var reqErr *requestError
+			if !errors.As(err, &reqErr) {
+				t.Errorf("expected the request error, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestExecuteMaxDepth(t *testing.T) {
+	selfType := &objectType{name: "Self"}
+	selfType.fields = map[string]*fieldDefinition{
+		"self": {typ: selfType, resolve: func(ctx context.Context, sources []interface{},
+			args map[string]interface{},
+		) ([][]interface{}, error) {
+			children := [][]interface{}{}
+			for range sources {
+				children = append(children, []interface{}{"self"})
+			}
+			return children, nil
+		}},
+		"name": {value: func(source interface{}) interface{} { return source }},
+	}
+	query := "{ name }"
+	for i := 0; i < maxDepth; i++ {
+		query = "{ self " + query + " }"
+	}
+	_, err := execute(context.Background(), selfType, &Request{Query: query})
+	var reqErr *requestError
+	if !errors.As(err, &reqErr) {
+		t.Errorf("expected the error of the maximum depth, but got %v", err)
+	}
+}
+
+func TestExecuteMaxObjectFields(t *testing.T) {
+	calls := 0
+	selfType := &objectType{name: "Self"}
+	selfType.fields = map[string]*fieldDefinition{
+		"self": {typ: selfType, resolve: func(ctx context.Context, sources []interface{},
+			args map[string]interface{},
+		) ([][]interface{}, error) {
+			calls++
+			children := [][]interface{}{}
+			for range sources {
+				children = append(children, []interface{}{"self"})
+			}
+			return children, nil
+		}},
+		"name": {value: func(source interface{}) interface{} { return source }},
+	}
+	// the aliases double the resolved fields at each level within the maximum depth
+	query := "{ name }"
+	for i := 0; i < 7; i++ {
+		query = "{ a: self " + query + " b: self " + query + " }"
+	}
+	_, err := execute(context.Background(), selfType, &Request{Query: query})
+	var reqErr *requestError
+	if !errors.As(err, &reqErr) {
+		t.Errorf("expected the error of the maximum object fields, but got %v", err)
+	}
+	if calls > maxObjectFields {
+		t.Errorf("expected at most %d fields are resolved, but got %d", maxObjectFields, calls)
+	}
+}
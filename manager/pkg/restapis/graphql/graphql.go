@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const serverInternalErrorMsg = "internal error"
+
+// Query godoc
+// @summary query the global hub data by GraphQL
+// @description read-only GraphQL queries over the managed hubs, managed clusters, policies, compliance, events,
+// @description subscriptions and security alert counts, each level of the nested fields is resolved by a single query
+// @accept json
+// @produce json
+// @param        request  body      Request  false  "the GraphQL request, or the query parameters of GET"
+// @success      200  {object}    Response
+// @failure      400  {object}    Response
+// @failure      401
+// @failure      500  {object}    Response
+// @failure      503
+// @security     ApiKeyAuth
+// @router /graphql [get]
+// @router /graphql [post]
+func Query() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		request := &Request{}
+		if ginCtx.Request.Method == http.MethodGet {
+			request.Query = ginCtx.Query("query")
+			request.OperationName = ginCtx.Query("operationName")
+			if variables := ginCtx.Query("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+					respondError(ginCtx, http.StatusBadRequest, fmt.Sprintf("invalid variables: %v", err))
+					return
+				}
+			}
+		} else if err := ginCtx.ShouldBindJSON(request); err != nil {
+			respondError(ginCtx, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		if request.Query == "" {
+			respondError(ginCtx, http.StatusBadRequest, "the query is required")
+			return
+		}
+
+		data, err := execute(withScope(ginCtx), schema, request)
+		if err != nil {
+			var reqErr *requestError
+			if errors.As(err, &reqErr) {
+				respondError(ginCtx, http.StatusBadRequest, reqErr.message)
+				return
+			}
+			fmt.Fprintf(gin.DefaultWriter, "error in executing the graphql query: %v\n", err)
+			respondError(ginCtx, http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		ginCtx.JSON(http.StatusOK, Response{Data: data})
+	}
+}
+
+func respondError(ginCtx *gin.Context, status int, message string) {
+	ginCtx.JSON(status, Response{Errors: []Error{{Message: message}}})
+}
@@ -0,0 +1,667 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// the parser only supports the executable documents: the operations, fragments, variables and directives. The type
+// system definitions aren't needed since the schema is defined in the code.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string
+	name       string
+	variables  []*variableDefinition
+	selections []selection
+}
+
+type variableDefinition struct {
+	name         string
+	typeName     string
+	nonNull      bool
+	defaultValue *value
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	selections    []selection
+}
+
+// selection is one of the *field, *fragmentSpread and *inlineFragment
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  map[string]*value
+	directives []*directive
+	selections []selection
+}
+
+// responseKey is the key of the field in the response, the alias if it's set
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+}
+
+type directive struct {
+	name      string
+	arguments map[string]*value
+}
+
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+type value struct {
+	kind   valueKind
+	raw    string
+	list   []*value
+	fields map[string]*value
+}
+
+type parser struct {
+	source string
+	pos    int
+	token  token
+}
+
+// parse parses the executable document of the GraphQL query
+func parse(source string) (*document, error) {
+	p := &parser{source: source}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunctuator, "{"):
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+		case p.peek(tokenName, "fragment"):
+			f, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, found := doc.fragments[f.name]; found {
+				return nil, fmt.Errorf("there can be only one fragment named %q", f.name)
+			}
+			doc.fragments[f.name] = f
+		case p.token.kind == tokenName:
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("the document doesn't have any operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: p.token.value}
+	if op.kind != "query" && op.kind != "mutation" && op.kind != "subscription" {
+		return nil, p.unexpected()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenName {
+		op.name = p.token.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokenPunctuator, "(") {
+		variables, err := p.parseVariableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		op.variables = variables
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinitions() ([]*variableDefinition, error) {
+	if err := p.expect(tokenPunctuator, "("); err != nil {
+		return nil, err
+	}
+	definitions := []*variableDefinition{}
+	for !p.peek(tokenPunctuator, ")") {
+		if err := p.expect(tokenPunctuator, "$"); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunctuator, ":"); err != nil {
+			return nil, err
+		}
+		definition := &variableDefinition{name: name}
+		if definition.typeName, definition.nonNull, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if p.peek(tokenPunctuator, "=") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if definition.defaultValue, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, p.next()
+}
+
+// parseType returns the type of the variable, the list type is returned as its raw form, e.g. [String!]
+func (p *parser) parseType() (string, bool, error) {
+	typeName := ""
+	if p.peek(tokenPunctuator, "[") {
+		if err := p.next(); err != nil {
+			return "", false, err
+		}
+		itemType, nonNull, err := p.parseType()
+		if err != nil {
+			return "", false, err
+		}
+		if nonNull {
+			itemType += "!"
+		}
+		if err := p.expect(tokenPunctuator, "]"); err != nil {
+			return "", false, err
+		}
+		typeName = "[" + itemType + "]"
+	} else {
+		name, err := p.parseName()
+		if err != nil {
+			return "", false, err
+		}
+		typeName = name
+	}
+	if p.peek(tokenPunctuator, "!") {
+		return typeName, true, p.next()
+	}
+	return typeName, false, nil
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("the fragment can't be named \"on\"")
+	}
+	if err := p.expect(tokenName, "on"); err != nil {
+		return nil, err
+	}
+	typeCondition, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: name, typeCondition: typeCondition, selections: selections}, nil
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if err := p.expect(tokenPunctuator, "{"); err != nil {
+		return nil, err
+	}
+	selections := []selection{}
+	for !p.peek(tokenPunctuator, "}") {
+		s, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("the selection set at %d is empty", p.token.pos)
+	}
+	return selections, p.next()
+}
+
+func (p *parser) parseSelection() (selection, error) {
+	if p.peek(tokenPunctuator, "...") {
+		return p.parseFragmentSelection()
+	}
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	f := &field{name: name}
+	if p.peek(tokenPunctuator, ":") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		f.alias = name
+		if f.name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokenPunctuator, "(") {
+		if f.arguments, err = p.parseArguments(); err != nil {
+			return nil, err
+		}
+	}
+	if f.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunctuator, "{") {
+		if f.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) parseFragmentSelection() (selection, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenName && p.token.value != "on" {
+		name := p.token.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		directives, err := p.parseDirectives()
+		if err != nil {
+			return nil, err
+		}
+		return &fragmentSpread{name: name, directives: directives}, nil
+	}
+	inline := &inlineFragment{}
+	if p.peek(tokenName, "on") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		typeCondition, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		inline.typeCondition = typeCondition
+	}
+	var err error
+	if inline.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if inline.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+func (p *parser) parseArguments() (map[string]*value, error) {
+	if err := p.expect(tokenPunctuator, "("); err != nil {
+		return nil, err
+	}
+	arguments := map[string]*value{}
+	for !p.peek(tokenPunctuator, ")") {
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		if _, found := arguments[name]; found {
+			return nil, fmt.Errorf("there can be only one argument named %q", name)
+		}
+		if err := p.expect(tokenPunctuator, ":"); err != nil {
+			return nil, err
+		}
+		if arguments[name], err = p.parseValue(false); err != nil {
+			return nil, err
+		}
+	}
+	return arguments, p.next()
+}
+
+func (p *parser) parseDirectives() ([]*directive, error) {
+	directives := []*directive{}
+	for p.peek(tokenPunctuator, "@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		d := &directive{name: name}
+		if p.peek(tokenPunctuator, "(") {
+			if d.arguments, err = p.parseArguments(); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// parseValue parses the value, the variables aren't allowed in the constant value, e.g. the default of the variable
+func (p *parser) parseValue(constant bool) (*value, error) {
+	t := p.token
+	switch {
+	case t.kind == tokenPunctuator && t.value == "$" && !constant:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		return &value{kind: valueVariable, raw: name}, nil
+	case t.kind == tokenPunctuator && t.value == "[":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		v := &value{kind: valueList, list: []*value{}}
+		for !p.peek(tokenPunctuator, "]") {
+			item, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+		}
+		return v, p.next()
+	case t.kind == tokenPunctuator && t.value == "{":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		v := &value{kind: valueObject, fields: map[string]*value{}}
+		for !p.peek(tokenPunctuator, "}") {
+			name, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokenPunctuator, ":"); err != nil {
+				return nil, err
+			}
+			if v.fields[name], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		return v, p.next()
+	case t.kind == tokenInt:
+		return &value{kind: valueInt, raw: t.value}, p.next()
+	case t.kind == tokenFloat:
+		return &value{kind: valueFloat, raw: t.value}, p.next()
+	case t.kind == tokenString:
+		return &value{kind: valueString, raw: t.value}, p.next()
+	case t.kind == tokenName:
+		v := &value{kind: valueEnum, raw: t.value}
+		switch t.value {
+		case "true", "false":
+			v.kind = valueBoolean
+		case "null":
+			v.kind = valueNull
+		}
+		return v, p.next()
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseName() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.token.value
+	return name, p.next()
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.token.kind == kind && p.token.value == value
+}
+
+func (p *parser) expect(kind tokenKind, value string) error {
+	if !p.peek(kind, value) {
+		return fmt.Errorf("expected %q at %d, but got %s", value, p.token.pos, p.describe())
+	}
+	return p.next()
+}
+
+func (p *parser) unexpected() error {
+	return fmt.Errorf("unexpected %s at %d", p.describe(), p.token.pos)
+}
+
+func (p *parser) describe() string {
+	if p.token.kind == tokenEOF {
+		return "end of the document"
+	}
+	return strconv.Quote(p.token.value)
+}
+
+// next reads the next token, the whitespaces, commas and comments are ignored
+func (p *parser) next() error {
+	for p.pos < len(p.source) {
+		c := p.source[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.source) && p.source[p.pos] != '\n' && p.source[p.pos] != '\r' {
+				p.pos++
+			}
+		case strings.HasPrefix(p.source[p.pos:], "\ufeff"):
+			p.pos += len("\ufeff")
+		default:
+			return p.readToken()
+		}
+	}
+	p.token = token{kind: tokenEOF, pos: p.pos}
+	return nil
+}
+
+func (p *parser) readToken() error {
+	start := p.pos
+	c := p.source[p.pos]
+	switch {
+	case strings.HasPrefix(p.source[p.pos:], "..."):
+		p.pos += 3
+		p.token = token{kind: tokenPunctuator, value: "...", pos: start}
+	case strings.ContainsRune("!$()&:=@[]{}|", rune(c)):
+		p.pos++
+		p.token = token{kind: tokenPunctuator, value: string(c), pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.source) && (p.source[p.pos] == '_' || isLetter(p.source[p.pos]) ||
+			isDigit(p.source[p.pos])) {
+			p.pos++
+		}
+		p.token = token{kind: tokenName, value: p.source[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		return p.readNumber()
+	case c == '"':
+		return p.readString()
+	default:
+		r, _ := utf8.DecodeRuneInString(p.source[p.pos:])
+		return fmt.Errorf("unexpected character %q at %d", r, start)
+	}
+	return nil
+}
+
+func (p *parser) readNumber() error {
+	start := p.pos
+	kind := tokenInt
+	if p.source[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() int {
+		n := 0
+		for p.pos < len(p.source) && isDigit(p.source[p.pos]) {
+			p.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return fmt.Errorf("invalid number at %d", start)
+	}
+	if p.pos < len(p.source) && p.source[p.pos] == '.' {
+		p.pos++
+		kind = tokenFloat
+		if digits() == 0 {
+			return fmt.Errorf("invalid number at %d", start)
+		}
+	}
+	if p.pos < len(p.source) && (p.source[p.pos] == 'e' || p.source[p.pos] == 'E') {
+		p.pos++
+		kind = tokenFloat
+		if p.pos < len(p.source) && (p.source[p.pos] == '+' || p.source[p.pos] == '-') {
+			p.pos++
+		}
+		if digits() == 0 {
+			return fmt.Errorf("invalid number at %d", start)
+		}
+	}
+	p.token = token{kind: kind, value: p.source[start:p.pos], pos: start}
+	return nil
+}
+
+func (p *parser) readString() error {
+	start := p.pos
+	if strings.HasPrefix(p.source[p.pos:], `"""`) {
+		end := strings.Index(p.source[p.pos+3:], `"""`)
+		if end < 0 {
+			return fmt.Errorf("unterminated string at %d", start)
+		}
+		raw := p.source[p.pos+3 : p.pos+3+end]
+		p.pos += end + 6
+		p.token = token{kind: tokenString, value: strings.ReplaceAll(raw, `\"""`, `"""`), pos: start}
+		return nil
+	}
+	p.pos++
+	builder := &strings.Builder{}
+	for p.pos < len(p.source) {
+		c := p.source[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			p.token = token{kind: tokenString, value: builder.String(), pos: start}
+			return nil
+		case c == '\n' || c == '\r':
+			return fmt.Errorf("unterminated string at %d", start)
+		case c == '\\':
+			if p.pos+1 >= len(p.source) {
+				return fmt.Errorf("unterminated string at %d", start)
+			}
+			escaped := p.source[p.pos+1]
+			p.pos += 2
+			switch escaped {
+			case '"', '\\', '/':
+				builder.WriteByte(escaped)
+			case 'b':
+				builder.WriteByte('\b')
+			case 'f':
+				builder.WriteByte('\f')
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			case 'u':
+				if p.pos+4 > len(p.source) {
+					return fmt.Errorf("invalid unicode escape at %d", p.pos)
+				}
+				code, err := strconv.ParseUint(p.source[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					return fmt.Errorf("invalid unicode escape at %d", p.pos)
+				}
+				builder.WriteRune(rune(code))
+				p.pos += 4
+			default:
+				return fmt.Errorf("invalid escape %q at %d", escaped, p.pos-1)
+			}
+		default:
+			builder.WriteByte(c)
+			p.pos++
+		}
+	}
+	return fmt.Errorf("unterminated string at %d", start)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
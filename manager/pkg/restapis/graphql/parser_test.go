@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package graphql
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# the clusters of the hub
+		query Clusters($hub: String = "hub1", $ids: [String!]!) @cached {
+			managedHubs(name: $hub) {
+				hubName: name
+				managedClusters(labelSelector: "env=\"dev\"!", limit: 10) { name }
+				... on ManagedHub { status }
+				...alerts
+			}
+		}
+		fragment alerts on ManagedHub { securityAlertCounts { total } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.operations) != 1 || len(doc.fragments) != 1 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	op := doc.operations[0]
+	if op.kind != "query" || op.name != "Clusters" || len(op.variables) != 2 {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+	if v := op.variables[1]; v.name != "ids" || v.typeName != "[String!]" || !v.nonNull {
+		t.Errorf("unexpected variable %+v", v)
+	}
+	if v := op.variables[0]; v.defaultValue == nil || v.defaultValue.raw != "hub1" {
+		t.Errorf("unexpected default value of the variable %+v", v)
+	}
+	hubs := op.selections[0].(*field)
+	if hubs.arguments["name"].kind != valueVariable || len(hubs.selections) != 4 {
+		t.Fatalf("unexpected field %+v", hubs)
+	}
+	if name := hubs.selections[0].(*field); name.responseKey() != "hubName" || name.name != "name" {
+		t.Errorf("unexpected alias %+v", name)
+	}
+	clusters := hubs.selections[1].(*field)
+	if selector := clusters.arguments["labelSelector"]; selector.raw != `env="dev"!` {
+		t.Errorf("unexpected string %q", selector.raw)
+	}
+	if limit := clusters.arguments["limit"]; limit.kind != valueInt || limit.raw != "10" {
+		t.Errorf("unexpected int %+v", limit)
+	}
+	if inline := hubs.selections[2].(*inlineFragment); inline.typeCondition != "ManagedHub" {
+		t.Errorf("unexpected inline fragment %+v", inline)
+	}
+	if spread := hubs.selections[3].(*fragmentSpread); spread.name != "alerts" {
+		t.Errorf("unexpected fragment spread %+v", spread)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`{}`,
+		`{ hubs`,
+		`{ hubs(name: ) { name } }`,
+		`{ hubs(name: "hub1) { name } }`,
+		`{ hubs(limit: 1.) { name } }`,
+		`query ($hub String) { hubs { name } }`,
+		`fragment on on Hub { name }`,
+		`{ a } fragment f on Hub { name } fragment f on Hub { name }`,
+		`{ hubs { name } } %`,
+	} {
+		if _, err := parse(query); err == nil {
+			t.Errorf("expected the error of the query %q", query)
+		}
+	}
+}
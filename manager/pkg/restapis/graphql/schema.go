@@ -0,0 +1,970 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/managedhubs"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+const (
+	clustersQuery = `SELECT cluster_id::text AS id, leaf_hub_name::text AS leaf_hub_name, FALSE AS local, payload
+		FROM status.managed_clusters WHERE deleted_at IS NULL`
+	// policiesQuery selects the global policies and the local policies of the hubs, the global policy doesn't have
+	// the hub
+	policiesQuery = `SELECT id::text AS id, NULL::text AS leaf_hub_name, FALSE AS local, payload
+			FROM spec.policies WHERE deleted = FALSE
+		UNION ALL
+		SELECT policy_id::text, leaf_hub_name::text, TRUE, payload
+			FROM local_spec.policies WHERE deleted_at IS NULL`
+	policyNamespacesQuery = `SELECT payload -> 'metadata' ->> 'namespace' FROM spec.policies WHERE deleted = FALSE
+		UNION
+		SELECT payload -> 'metadata' ->> 'namespace' FROM local_spec.policies WHERE deleted_at IS NULL`
+	// complianceQuery selects the compliance of the global and local policies with the namespace of the policy, so
+	// the compliance is authorized by either its hub or the namespace of its policy
+	complianceQuery = `SELECT c.policy_id::text AS policy_id, c.cluster_name::text AS cluster_name,
+			c.cluster_id::text AS cluster_id, c.leaf_hub_name::text AS leaf_hub_name, c.compliance::text AS compliance,
+			FALSE AS local, p.payload -> 'metadata' ->> 'namespace' AS namespace
+			FROM status.compliance c JOIN spec.policies p ON p.id = c.policy_id AND p.deleted = FALSE
+		UNION ALL
+		SELECT c.policy_id::text, c.cluster_name::text, c.cluster_id::text, c.leaf_hub_name::text, c.compliance::text,
+			TRUE, p.payload -> 'metadata' ->> 'namespace'
+			FROM local_status.compliance c JOIN local_spec.policies p ON p.policy_id = c.policy_id
+				AND p.deleted_at IS NULL`
+	subscriptionsQuery = `SELECT id::text AS id, NULL::text AS leaf_hub_name, FALSE AS local, payload
+		FROM spec.subscriptions WHERE deleted = FALSE`
+	subscriptionNamespacesQuery = `SELECT DISTINCT payload -> 'metadata' ->> 'namespace' FROM spec.subscriptions
+		WHERE deleted = FALSE`
+	subscriptionReportsQuery = `SELECT id::text AS id, leaf_hub_name::text AS leaf_hub_name, FALSE AS local, payload,
+			payload -> 'metadata' ->> 'namespace' || '/' || (payload -> 'metadata' ->> 'name') AS parent_key
+		FROM status.subscription_reports`
+	clusterEventsQuery = `SELECT event_name, event_namespace, cluster_name, cluster_id::text AS cluster_id,
+			leaf_hub_name::text AS leaf_hub_name, message, reason, event_type, reporting_controller, created_at
+		FROM event.managed_clusters`
+	policyEventsQuery = `SELECT event_name, event_namespace, policy_id::text AS policy_id, cluster_name,
+			cluster_id::text AS cluster_id, leaf_hub_name::text AS leaf_hub_name, message, reason, count,
+			compliance::text AS compliance, created_at
+		FROM event.local_policies`
+	clusterHistoryQuery = `SELECT cluster_id::text AS cluster_id, cluster_name, leaf_hub_name::text AS leaf_hub_name,
+			available, joined, accepted, openshift_version, labels, array_to_json(changes) AS changes, created_at
+		FROM history.managed_clusters`
+	alertCountsQuery = `SELECT hub_name, source, low, medium, high, critical, detail_url, created_at, updated_at
+		FROM security.alert_counts`
+
+	// timestampFormat keeps the microseconds of the timestamp without time zone
+	timestampFormat = "2006-01-02 15:04:05.999999"
+
+	namespaceColumn = "payload -> 'metadata' ->> 'namespace'"
+	nameColumn      = "payload -> 'metadata' ->> 'name'"
+)
+
+// resource is the kubernetes resource of the payload, the leaf hub is empty for the global resource
+type resource struct {
+	ID          string         `gorm:"column:id"`
+	LeafHubName string         `gorm:"column:leaf_hub_name"`
+	Local       bool           `gorm:"column:local"`
+	Payload     datatypes.JSON `gorm:"column:payload"`
+	// ParentKey is the key of the parent of the nested resource
+	ParentKey string `gorm:"column:parent_key"`
+	object    map[string]interface{}
+}
+
+type compliance struct {
+	PolicyID    string `gorm:"column:policy_id"`
+	ClusterName string `gorm:"column:cluster_name"`
+	ClusterID   string `gorm:"column:cluster_id"`
+	LeafHubName string `gorm:"column:leaf_hub_name"`
+	Compliance  string `gorm:"column:compliance"`
+	Local       bool   `gorm:"column:local"`
+	Namespace   string `gorm:"column:namespace"`
+}
+
+type clusterEvent struct {
+	EventName           string    `gorm:"column:event_name"`
+	EventNamespace      string    `gorm:"column:event_namespace"`
+	ClusterName         string    `gorm:"column:cluster_name"`
+	ClusterID           string    `gorm:"column:cluster_id"`
+	LeafHubName         string    `gorm:"column:leaf_hub_name"`
+	Message             string    `gorm:"column:message"`
+	Reason              string    `gorm:"column:reason"`
+	EventType           string    `gorm:"column:event_type"`
+	ReportingController string    `gorm:"column:reporting_controller"`
+	CreatedAt           time.Time `gorm:"column:created_at"`
+}
+
+type policyEvent struct {
+	EventName      string    `gorm:"column:event_name"`
+	EventNamespace string    `gorm:"column:event_namespace"`
+	PolicyID       string    `gorm:"column:policy_id"`
+	ClusterName    string    `gorm:"column:cluster_name"`
+	ClusterID      string    `gorm:"column:cluster_id"`
+	LeafHubName    string    `gorm:"column:leaf_hub_name"`
+	Message        string    `gorm:"column:message"`
+	Reason         string    `gorm:"column:reason"`
+	Count          int       `gorm:"column:count"`
+	Compliance     string    `gorm:"column:compliance"`
+	CreatedAt      time.Time `gorm:"column:created_at"`
+}
+
+type clusterHistory struct {
+	ClusterID        string         `gorm:"column:cluster_id"`
+	ClusterName      string         `gorm:"column:cluster_name"`
+	LeafHubName      string         `gorm:"column:leaf_hub_name"`
+	Available        string         `gorm:"column:available"`
+	Joined           string         `gorm:"column:joined"`
+	Accepted         string         `gorm:"column:accepted"`
+	OpenshiftVersion string         `gorm:"column:openshift_version"`
+	Labels           datatypes.JSON `gorm:"column:labels"`
+	Changes          datatypes.JSON `gorm:"column:changes"`
+	CreatedAt        time.Time      `gorm:"column:created_at"`
+}
+
+// schema is the query type of the schema, the types are linked in the init since they refer to each other
+var schema = &objectType{name: "Query"}
+
+var (
+	managedHubType     = &objectType{name: "ManagedHub"}
+	managedClusterType = &objectType{name: "ManagedCluster"}
+	policyType         = &objectType{name: "Policy"}
+	complianceType     = &objectType{name: "Compliance"}
+	subscriptionType   = &objectType{name: "Subscription"}
+	reportType         = &objectType{name: "SubscriptionReport"}
+	clusterEventType   = &objectType{name: "ManagedClusterEvent"}
+	policyEventType    = &objectType{name: "PolicyEvent"}
+	clusterHistoryType = &objectType{name: "ManagedClusterHistory"}
+	alertCountsType    = &objectType{name: "SecurityAlertCounts"}
+)
+
+func init() {
+	schema.fields = map[string]*fieldDefinition{
+		"managedHubs": {
+			args: map[string]string{"name": argString, "status": argString},
+			typ:  managedHubType, list: true, resolve: resolveManagedHubs,
+		},
+		"managedClusters": {
+			args: map[string]string{
+				"hub": argString, "name": argString, "labelSelector": argString, "limit": argInt,
+			},
+			typ: managedClusterType, list: true, resolve: resolveManagedClusters,
+		},
+		"policies": {
+			args: map[string]string{
+				"hub": argString, "namespace": argString, "name": argString, "local": argBoolean, "limit": argInt,
+			},
+			typ: policyType, list: true, resolve: resolvePolicies,
+		},
+		"subscriptions": {
+			args: map[string]string{"namespace": argString, "name": argString, "limit": argInt},
+			typ:  subscriptionType, list: true, resolve: resolveSubscriptions,
+		},
+		"securityAlertCounts": {
+			args: map[string]string{"hub": argString, "source": argString},
+			typ:  alertCountsType, list: true, resolve: resolveAlertCounts,
+		},
+	}
+
+	managedHubType.fields = map[string]*fieldDefinition{
+		"name":       hubValue(func(h *managedhubs.ManagedHub) interface{} { return h.Name }),
+		"clusterId":  hubValue(func(h *managedhubs.ManagedHub) interface{} { return h.ClusterID }),
+		"consoleURL": hubValue(func(h *managedhubs.ManagedHub) interface{} { return h.ConsoleURL }),
+		"grafanaURL": hubValue(func(h *managedhubs.ManagedHub) interface{} { return h.GrafanaURL }),
+		"status":     hubValue(func(h *managedhubs.ManagedHub) interface{} { return h.Status }),
+		"lastHeartbeat": hubValue(func(h *managedhubs.ManagedHub) interface{} {
+			if h.LastHeartbeat == nil {
+				return nil
+			}
+			return h.LastHeartbeat
+		}),
+		"managedClusterCount": hubValue(func(h *managedhubs.ManagedHub) interface{} { return h.ManagedClusters }),
+		"availableManagedClusterCount": hubValue(func(h *managedhubs.ManagedHub) interface{} {
+			return h.AvailableManagedClusters
+		}),
+		"managedClusters": {
+			args: map[string]string{"name": argString, "labelSelector": argString, "limit": argInt},
+			typ:  managedClusterType, list: true, resolve: resolveManagedClusters,
+		},
+		"policies": {
+			args: map[string]string{"namespace": argString, "name": argString, "limit": argInt},
+			typ:  policyType, list: true, resolve: resolvePolicies,
+		},
+		"securityAlertCounts": {
+			args: map[string]string{"source": argString},
+			typ:  alertCountsType, list: true, resolve: resolveAlertCounts,
+		},
+	}
+
+	managedClusterType.fields = map[string]*fieldDefinition{
+		"id":               resourceValue(func(r *resource) interface{} { return r.ID }),
+		"name":             resourceValue(func(r *resource) interface{} { return nested(r.object, "metadata", "name") }),
+		"hub":              resourceValue(func(r *resource) interface{} { return r.LeafHubName }),
+		"labels":           resourceValue(func(r *resource) interface{} { return nested(r.object, "metadata", "labels") }),
+		"available":        resourceValue(func(r *resource) interface{} { return conditionStatus(r.object, availableType) }),
+		"openshiftVersion": resourceValue(openshiftVersion),
+		"object":           resourceValue(func(r *resource) interface{} { return r.object }),
+		"managedHub": {
+			typ: managedHubType, resolve: resolveManagedHubOf,
+		},
+		"policies": {
+			args: map[string]string{"compliance": argString, "local": argBoolean, "limit": argInt},
+			typ:  complianceType, list: true, resolve: resolveClusterCompliance,
+		},
+		"events": {
+			args: map[string]string{"reason": argString, "since": argString, "limit": argInt},
+			typ:  clusterEventType, list: true, resolve: resolveClusterEvents,
+		},
+		"history": {
+			args: map[string]string{"since": argString, "limit": argInt},
+			typ:  clusterHistoryType, list: true, resolve: resolveClusterHistory,
+		},
+	}
+
+	policyType.fields = map[string]*fieldDefinition{
+		"id":        resourceValue(func(r *resource) interface{} { return r.ID }),
+		"name":      resourceValue(func(r *resource) interface{} { return nested(r.object, "metadata", "name") }),
+		"namespace": resourceValue(func(r *resource) interface{} { return nested(r.object, "metadata", "namespace") }),
+		"hub":       resourceValue(func(r *resource) interface{} { return nullable(r.LeafHubName) }),
+		"local":     resourceValue(func(r *resource) interface{} { return r.Local }),
+		"standards": resourceValue(policyAnnotation("policy.open-cluster-management.io/standards")),
+		"categories": resourceValue(policyAnnotation(
+			"policy.open-cluster-management.io/categories")),
+		"controls": resourceValue(policyAnnotation("policy.open-cluster-management.io/controls")),
+		"disabled": resourceValue(func(r *resource) interface{} { return nested(r.object, "spec", "disabled") }),
+		"remediationAction": resourceValue(func(r *resource) interface{} {
+			return nested(r.object, "spec", "remediationAction")
+		}),
+		"object": resourceValue(func(r *resource) interface{} { return r.object }),
+		"compliance": {
+			args: map[string]string{"compliance": argString, "hub": argString, "limit": argInt},
+			typ:  complianceType, list: true, resolve: resolvePolicyCompliance,
+		},
+	}
+
+	complianceType.fields = map[string]*fieldDefinition{
+		"policyId":    complianceValue(func(c *compliance) interface{} { return c.PolicyID }),
+		"clusterName": complianceValue(func(c *compliance) interface{} { return c.ClusterName }),
+		"clusterId":   complianceValue(func(c *compliance) interface{} { return nullable(c.ClusterID) }),
+		"hub":         complianceValue(func(c *compliance) interface{} { return c.LeafHubName }),
+		"compliance":  complianceValue(func(c *compliance) interface{} { return c.Compliance }),
+		"local":       complianceValue(func(c *compliance) interface{} { return c.Local }),
+		"policy": {
+			typ: policyType, resolve: resolvePolicyOf,
+		},
+		"managedCluster": {
+			typ: managedClusterType, resolve: resolveManagedClusterOf,
+		},
+		"events": {
+			args: map[string]string{"since": argString, "limit": argInt},
+			typ:  policyEventType, list: true, resolve: resolveComplianceEvents,
+		},
+	}
+
+	subscriptionType.fields = map[string]*fieldDefinition{
+		"id":        resourceValue(func(r *resource) interface{} { return r.ID }),
+		"name":      resourceValue(func(r *resource) interface{} { return nested(r.object, "metadata", "name") }),
+		"namespace": resourceValue(func(r *resource) interface{} { return nested(r.object, "metadata", "namespace") }),
+		"object":    resourceValue(func(r *resource) interface{} { return r.object }),
+		"reports": {
+			args: map[string]string{"hub": argString},
+			typ:  reportType, list: true, resolve: resolveSubscriptionReports,
+		},
+	}
+
+	reportType.fields = map[string]*fieldDefinition{
+		"hub":     resourceValue(func(r *resource) interface{} { return r.LeafHubName }),
+		"summary": resourceValue(func(r *resource) interface{} { return nested(r.object, "summary") }),
+		"object":  resourceValue(func(r *resource) interface{} { return r.object }),
+	}
+
+	clusterEventType.fields = map[string]*fieldDefinition{
+		"name":        clusterEventValue(func(e *clusterEvent) interface{} { return e.EventName }),
+		"namespace":   clusterEventValue(func(e *clusterEvent) interface{} { return e.EventNamespace }),
+		"clusterName": clusterEventValue(func(e *clusterEvent) interface{} { return e.ClusterName }),
+		"clusterId":   clusterEventValue(func(e *clusterEvent) interface{} { return e.ClusterID }),
+		"hub":         clusterEventValue(func(e *clusterEvent) interface{} { return e.LeafHubName }),
+		"message":     clusterEventValue(func(e *clusterEvent) interface{} { return e.Message }),
+		"reason":      clusterEventValue(func(e *clusterEvent) interface{} { return e.Reason }),
+		"type":        clusterEventValue(func(e *clusterEvent) interface{} { return e.EventType }),
+		"reportingController": clusterEventValue(func(e *clusterEvent) interface{} {
+			return e.ReportingController
+		}),
+		"createdAt": clusterEventValue(func(e *clusterEvent) interface{} { return e.CreatedAt }),
+	}
+
+	policyEventType.fields = map[string]*fieldDefinition{
+		"name":        policyEventValue(func(e *policyEvent) interface{} { return e.EventName }),
+		"namespace":   policyEventValue(func(e *policyEvent) interface{} { return e.EventNamespace }),
+		"policyId":    policyEventValue(func(e *policyEvent) interface{} { return e.PolicyID }),
+		"clusterName": policyEventValue(func(e *policyEvent) interface{} { return e.ClusterName }),
+		"clusterId":   policyEventValue(func(e *policyEvent) interface{} { return e.ClusterID }),
+		"hub":         policyEventValue(func(e *policyEvent) interface{} { return e.LeafHubName }),
+		"message":     policyEventValue(func(e *policyEvent) interface{} { return e.Message }),
+		"reason":      policyEventValue(func(e *policyEvent) interface{} { return e.Reason }),
+		"count":       policyEventValue(func(e *policyEvent) interface{} { return e.Count }),
+		"compliance":  policyEventValue(func(e *policyEvent) interface{} { return e.Compliance }),
+		"createdAt":   policyEventValue(func(e *policyEvent) interface{} { return e.CreatedAt }),
+	}
+
+	clusterHistoryType.fields = map[string]*fieldDefinition{
+		"available":        historyValue(func(h *clusterHistory) interface{} { return nullable(h.Available) }),
+		"joined":           historyValue(func(h *clusterHistory) interface{} { return nullable(h.Joined) }),
+		"accepted":         historyValue(func(h *clusterHistory) interface{} { return nullable(h.Accepted) }),
+		"openshiftVersion": historyValue(func(h *clusterHistory) interface{} { return nullable(h.OpenshiftVersion) }),
+		"labels":           historyValue(func(h *clusterHistory) interface{} { return rawJSON(h.Labels) }),
+		"changes":          historyValue(func(h *clusterHistory) interface{} { return rawJSON(h.Changes) }),
+		"createdAt":        historyValue(func(h *clusterHistory) interface{} { return h.CreatedAt }),
+	}
+
+	alertCountsType.fields = map[string]*fieldDefinition{
+		"hub":       alertCountsValue(func(c *models.SecurityAlertCounts) interface{} { return c.HubName }),
+		"source":    alertCountsValue(func(c *models.SecurityAlertCounts) interface{} { return c.Source }),
+		"detailURL": alertCountsValue(func(c *models.SecurityAlertCounts) interface{} { return c.DetailURL }),
+		"low":       alertCountsValue(func(c *models.SecurityAlertCounts) interface{} { return c.Low }),
+		"medium":    alertCountsValue(func(c *models.SecurityAlertCounts) interface{} { return c.Medium }),
+		"high":      alertCountsValue(func(c *models.SecurityAlertCounts) interface{} { return c.High }),
+		"critical":  alertCountsValue(func(c *models.SecurityAlertCounts) interface{} { return c.Critical }),
+		"total": alertCountsValue(func(c *models.SecurityAlertCounts) interface{} {
+			return c.Low + c.Medium + c.High + c.Critical
+		}),
+		"updatedAt": alertCountsValue(func(c *models.SecurityAlertCounts) interface{} { return c.UpdatedAt }),
+	}
+}
+
+const availableType = "ManagedClusterConditionAvailable"
+
+func hubValue(value func(*managedhubs.ManagedHub) interface{}) *fieldDefinition {
+	return &fieldDefinition{value: func(source interface{}) interface{} {
+		return value(source.(*managedhubs.ManagedHub))
+	}}
+}
+
+func resourceValue(value func(*resource) interface{}) *fieldDefinition {
+	return &fieldDefinition{value: func(source interface{}) interface{} { return value(source.(*resource)) }}
+}
+
+func complianceValue(value func(*compliance) interface{}) *fieldDefinition {
+	return &fieldDefinition{value: func(source interface{}) interface{} { return value(source.(*compliance)) }}
+}
+
+func clusterEventValue(value func(*clusterEvent) interface{}) *fieldDefinition {
+	return &fieldDefinition{value: func(source interface{}) interface{} { return value(source.(*clusterEvent)) }}
+}
+
+func policyEventValue(value func(*policyEvent) interface{}) *fieldDefinition {
+	return &fieldDefinition{value: func(source interface{}) interface{} { return value(source.(*policyEvent)) }}
+}
+
+func historyValue(value func(*clusterHistory) interface{}) *fieldDefinition {
+	return &fieldDefinition{value: func(source interface{}) interface{} { return value(source.(*clusterHistory)) }}
+}
+
+func alertCountsValue(value func(*models.SecurityAlertCounts) interface{}) *fieldDefinition {
+	return &fieldDefinition{value: func(source interface{}) interface{} {
+		return value(source.(*models.SecurityAlertCounts))
+	}}
+}
+
+// nested returns the value of the fields in the object, it's nil if any of the fields is missing
+func nested(object map[string]interface{}, fields ...string) interface{} {
+	var v interface{} = object
+	for _, f := range fields {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[f]
+	}
+	return v
+}
+
+// conditionStatus returns the status of the condition of the type in the status of the object
+func conditionStatus(object map[string]interface{}, conditionType string) interface{} {
+	conditions, _ := nested(object, "status", "conditions").([]interface{})
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == conditionType {
+			return condition["status"]
+		}
+	}
+	return nil
+}
+
+// openshiftVersion returns the version of the cluster claim of the OpenShift cluster
+func openshiftVersion(r *resource) interface{} {
+	claims, _ := nested(r.object, "status", "clusterClaims").([]interface{})
+	for _, c := range claims {
+		claim, ok := c.(map[string]interface{})
+		if ok && claim["name"] == "version.openshift.io" {
+			return claim["value"]
+		}
+	}
+	return nil
+}
+
+func policyAnnotation(annotation string) func(*resource) interface{} {
+	return func(r *resource) interface{} {
+		return nested(r.object, "metadata", "annotations", annotation)
+	}
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func rawJSON(data datatypes.JSON) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+// sinceArg returns the time of the since argument in UTC, the same as the events API compares the created time
+func sinceArg(args map[string]interface{}) (string, error) {
+	since := stringArg(args, "since")
+	if since == "" {
+		return "", nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return "", requestErrorf("invalid since %q, the format should be RFC3339", since)
+	}
+	return t.UTC().Format(timestampFormat), nil
+}
+
+// scanResources scans the resources of the query and unmarshals their payloads
+func scanResources(ctx context.Context, query string, args []interface{}) ([]interface{}, error) {
+	rows := []*resource{}
+	if err := scan(ctx, &rows, query, args); err != nil {
+		return nil, err
+	}
+	resources := make([]interface{}, 0, len(rows))
+	for _, r := range rows {
+		if err := json.Unmarshal(r.Payload, &r.object); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the payload of %s: %w", r.ID, err)
+		}
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+// the keys of the sources and children to group the children by their parents
+
+func hubName(source interface{}) string {
+	return source.(*managedhubs.ManagedHub).Name
+}
+
+func resourceHub(source interface{}) string {
+	return source.(*resource).LeafHubName
+}
+
+func resourceID(source interface{}) string {
+	return source.(*resource).ID
+}
+
+func resourceParentKey(source interface{}) string {
+	return source.(*resource).ParentKey
+}
+
+// namespacedName is the key of the namespaced resource, namespace/name
+func namespacedName(source interface{}) string {
+	r := source.(*resource)
+	namespace, _ := nested(r.object, "metadata", "namespace").(string)
+	name, _ := nested(r.object, "metadata", "name").(string)
+	return namespace + "/" + name
+}
+
+func compliancePolicyID(source interface{}) string {
+	return source.(*compliance).PolicyID
+}
+
+func complianceClusterID(source interface{}) string {
+	return source.(*compliance).ClusterID
+}
+
+// complianceCluster is the key of the cluster of the compliance, hub/cluster
+func complianceCluster(source interface{}) string {
+	c := source.(*compliance)
+	return c.LeafHubName + "/" + c.ClusterName
+}
+
+func complianceKey(source interface{}) string {
+	c := source.(*compliance)
+	if c.ClusterID == "" {
+		return ""
+	}
+	return c.PolicyID + "/" + c.ClusterID
+}
+
+func clusterKey(source interface{}) string {
+	r := source.(*resource)
+	name, _ := nested(r.object, "metadata", "name").(string)
+	return r.LeafHubName + "/" + name
+}
+
+// isRoot reports whether the field is resolved on the query type
+func isRoot(sources []interface{}) bool {
+	return len(sources) == 1 && sources[0] == nil
+}
+
+func resolveManagedHubs(ctx context.Context, sources []interface{}, args map[string]interface{}) ([][]interface{},
+	error,
+) {
+	filter, err := filterOf(ctx, authorization.ManagedHubs, "")
+	if err != nil {
+		return nil, err
+	}
+	condition, queryArgs := filter.Condition("hubs.leaf_hub_name", "")
+	condition = " WHERE TRUE" + condition
+	if name := stringArg(args, "name"); name != "" {
+		condition += " AND hubs.leaf_hub_name = ?"
+		queryArgs = append(queryArgs, name)
+	}
+	if status := stringArg(args, "status"); status != "" {
+		condition += " AND hb.status = ?"
+		queryArgs = append(queryArgs, status)
+	}
+	hubs, err := managedhubs.Query(condition+" ORDER BY hubs.leaf_hub_name", queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the managed hubs: %w", err)
+	}
+	items := make([]interface{}, 0, len(hubs))
+	for i := range hubs {
+		items = append(items, &hubs[i])
+	}
+	return [][]interface{}{items}, nil
+}
+
+// resolveManagedHubOf resolves the managed hub of the managed clusters
+func resolveManagedHubOf(ctx context.Context, sources []interface{}, args map[string]interface{}) ([][]interface{},
+	error,
+) {
+	filter, err := filterOf(ctx, authorization.ManagedHubs, "")
+	if err != nil {
+		return nil, err
+	}
+	condition, queryArgs := filter.Condition("hubs.leaf_hub_name", "")
+	hubs, err := managedhubs.Query(" WHERE hubs.leaf_hub_name IN ?"+condition,
+		append([]interface{}{keysOf(sources, resourceHub)}, queryArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the managed hubs: %w", err)
+	}
+	items := make([]interface{}, 0, len(hubs))
+	for i := range hubs {
+		items = append(items, &hubs[i])
+	}
+	return groupBy(sources, resourceHub, items, hubName), nil
+}
+
+// resolveManagedClusters resolves the managed clusters of the query or the managed hubs
+func resolveManagedClusters(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	limit, err := limitOf(args)
+	if err != nil {
+		return nil, err
+	}
+	q := newSQLQuery(clustersQuery)
+	if err := q.authorize(ctx, authorization.ManagedClusters, "", "leaf_hub_name", ""); err != nil {
+		return nil, err
+	}
+	if hub := stringArg(args, "hub"); hub != "" {
+		q.where("leaf_hub_name = ?", hub)
+	}
+	if name := stringArg(args, "name"); name != "" {
+		q.where(nameColumn+" = ?", name)
+	}
+	if labelSelector := stringArg(args, "labelSelector"); labelSelector != "" {
+		condition, labelArgs, err := util.ParseLabelSelector(labelSelector)
+		if err != nil {
+			return nil, requestErrorf("%s", strings.TrimSpace(err.Error()))
+		}
+		q.where("TRUE"+condition, labelArgs...)
+	}
+	if isRoot(sources) {
+		query, queryArgs := q.build("leaf_hub_name, "+nameColumn, limit)
+		clusters, err := scanResources(ctx, query, queryArgs)
+		return [][]interface{}{clusters}, err
+	}
+	q.where("leaf_hub_name IN ?", keysOf(sources, hubName))
+	query, queryArgs := q.buildPerKey("leaf_hub_name", nameColumn, limit)
+	clusters, err := scanResources(ctx, query, queryArgs)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(sources, hubName, clusters, resourceHub), nil
+}
+
+// resolveManagedClusterOf resolves the managed cluster of the compliance
+func resolveManagedClusterOf(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	q := newSQLQuery(clustersQuery)
+	if err := q.authorize(ctx, authorization.ManagedClusters, "", "leaf_hub_name", ""); err != nil {
+		return nil, err
+	}
+	hubClusters := [][]interface{}{}
+	for _, key := range keysOf(sources, complianceCluster) {
+		hub, cluster, _ := strings.Cut(key, "/")
+		hubClusters = append(hubClusters, []interface{}{hub, cluster})
+	}
+	q.where("(leaf_hub_name, "+nameColumn+") IN ?", hubClusters)
+	query, queryArgs := q.build("leaf_hub_name", len(hubClusters))
+	clusters, err := scanResources(ctx, query, queryArgs)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(sources, complianceCluster, clusters, clusterKey), nil
+}
+
+// resolvePolicies resolves the global and local policies of the query, or the local policies of the managed hubs.
+// The global policy is authorized by its namespace, and the local policy by either its hub or namespace.
+func resolvePolicies(ctx context.Context, sources []interface{}, args map[string]interface{}) ([][]interface{},
+	error,
+) {
+	limit, err := limitOf(args)
+	if err != nil {
+		return nil, err
+	}
+	q, err := authorizedPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if namespace := stringArg(args, "namespace"); namespace != "" {
+		q.where(namespaceColumn+" = ?", namespace)
+	}
+	if name := stringArg(args, "name"); name != "" {
+		q.where(nameColumn+" = ?", name)
+	}
+	if hub := stringArg(args, "hub"); hub != "" {
+		q.where("leaf_hub_name = ?", hub)
+	}
+	if local, found := args["local"]; found {
+		q.where("local = ?", local)
+	}
+	order := namespaceColumn + ", " + nameColumn + ", id"
+	if isRoot(sources) {
+		query, queryArgs := q.build("leaf_hub_name NULLS FIRST, "+order, limit)
+		policies, err := scanResources(ctx, query, queryArgs)
+		return [][]interface{}{policies}, err
+	}
+	q.where("leaf_hub_name IN ?", keysOf(sources, hubName))
+	query, queryArgs := q.buildPerKey("leaf_hub_name", order, limit)
+	policies, err := scanResources(ctx, query, queryArgs)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(sources, hubName, policies, resourceHub), nil
+}
+
+func authorizedPolicies(ctx context.Context) (*sqlQuery, error) {
+	filter, err := filterOf(ctx, authorization.Policies, policyNamespacesQuery)
+	if err != nil {
+		return nil, err
+	}
+	q := newSQLQuery(policiesQuery)
+	globalCondition, globalArgs := filter.Where("", namespaceColumn)
+	localCondition, localArgs := filter.Where("leaf_hub_name", namespaceColumn)
+	if globalCondition != "" {
+		q.where(fmt.Sprintf("((NOT local AND %s) OR (local AND %s))", globalCondition, localCondition),
+			append(globalArgs, localArgs...)...)
+	}
+	return q, nil
+}
+
+// resolvePolicyOf resolves the policy of the compliance
+func resolvePolicyOf(ctx context.Context, sources []interface{}, args map[string]interface{}) ([][]interface{},
+	error,
+) {
+	q, err := authorizedPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := keysOf(sources, compliancePolicyID)
+	q.where("id IN ?", ids)
+	query, queryArgs := q.build("id", len(ids))
+	policies, err := scanResources(ctx, query, queryArgs)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(sources, compliancePolicyID, policies, resourceID), nil
+}
+
+func authorizedCompliance(ctx context.Context, args map[string]interface{}) (*sqlQuery, error) {
+	q := newSQLQuery(complianceQuery)
+	if err := q.authorize(ctx, authorization.Policies, policyNamespacesQuery, "leaf_hub_name",
+		"namespace"); err != nil {
+		return nil, err
+	}
+	if c := stringArg(args, "compliance"); c != "" {
+		q.where("compliance = ?", c)
+	}
+	return q, nil
+}
+
+func scanCompliance(ctx context.Context, query string, args []interface{}) ([]interface{}, error) {
+	rows := []*compliance{}
+	if err := scan(ctx, &rows, query, args); err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row)
+	}
+	return items, nil
+}
+
+// resolvePolicyCompliance resolves the compliance of the clusters of the policies
+func resolvePolicyCompliance(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	limit, err := limitOf(args)
+	if err != nil {
+		return nil, err
+	}
+	q, err := authorizedCompliance(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if hub := stringArg(args, "hub"); hub != "" {
+		q.where("leaf_hub_name = ?", hub)
+	}
+	q.where("policy_id IN ?", keysOf(sources, resourceID))
+	query, queryArgs := q.buildPerKey("policy_id", "leaf_hub_name, cluster_name", limit)
+	items, err := scanCompliance(ctx, query, queryArgs)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(sources, resourceID, items, compliancePolicyID), nil
+}
+
+// resolveClusterCompliance resolves the compliance of the policies of the managed clusters
+func resolveClusterCompliance(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	limit, err := limitOf(args)
+	if err != nil {
+		return nil, err
+	}
+	q, err := authorizedCompliance(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if local, found := args["local"]; found {
+		q.where("local = ?", local)
+	}
+	hubClusters := [][]interface{}{}
+	for _, key := range keysOf(sources, clusterKey) {
+		hub, cluster, _ := strings.Cut(key, "/")
+		hubClusters = append(hubClusters, []interface{}{hub, cluster})
+	}
+	q.where("(leaf_hub_name, cluster_name) IN ?", hubClusters)
+	query, queryArgs := q.buildPerKey("leaf_hub_name, cluster_name", "local, policy_id", limit)
+	items, err := scanCompliance(ctx, query, queryArgs)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(sources, clusterKey, items, complianceCluster), nil
+}
+
+// resolveClusterEvents resolves the latest events of the managed clusters
+func resolveClusterEvents(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	limit, err := limitOf(args)
+	if err != nil {
+		return nil, err
+	}
+	since, err := sinceArg(args)
+	if err != nil {
+		return nil, err
+	}
+	q := newSQLQuery(clusterEventsQuery)
+	if err := q.authorize(ctx, authorization.Events, "", "leaf_hub_name", ""); err != nil {
+		return nil, err
+	}
+	if since != "" {
+		q.where("created_at >= ?::timestamp", since)
+	}
+	if reason := stringArg(args, "reason"); reason != "" {
+		q.where("reason = ?", reason)
+	}
+	q.where("cluster_id IN ?", keysOf(sources, resourceID))
+	query, queryArgs := q.buildPerKey("cluster_id", "created_at DESC, event_name", limit)
+	rows := []*clusterEvent{}
+	if err := scan(ctx, &rows, query, queryArgs); err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row)
+	}
+	return groupBy(sources, resourceID, items, func(child interface{}) string {
+		return child.(*clusterEvent).ClusterID
+	}), nil
+}
+
+// resolveComplianceEvents resolves the latest events of the policies on the clusters of the compliance
+func resolveComplianceEvents(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	limit, err := limitOf(args)
+	if err != nil {
+		return nil, err
+	}
+	since, err := sinceArg(args)
+	if err != nil {
+		return nil, err
+	}
+	policyClusters := [][]interface{}{}
+	for _, key := range keysOf(sources, complianceKey) {
+		policyID, clusterID, _ := strings.Cut(key, "/")
+		policyClusters = append(policyClusters, []interface{}{policyID, clusterID})
+	}
+	if len(policyClusters) == 0 {
+		return groupBy(sources, complianceKey, nil, nil), nil
+	}
+	q := newSQLQuery(policyEventsQuery)
+	if err := q.authorize(ctx, authorization.Events, "", "leaf_hub_name", ""); err != nil {
+		return nil, err
+	}
+	if since != "" {
+		q.where("created_at >= ?::timestamp", since)
+	}
+	q.where("(policy_id, cluster_id) IN ?", policyClusters)
+	query, queryArgs := q.buildPerKey("policy_id, cluster_id", "created_at DESC, event_name", limit)
+	rows := []*policyEvent{}
+	if err := scan(ctx, &rows, query, queryArgs); err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row)
+	}
+	return groupBy(sources, complianceKey, items, func(child interface{}) string {
+		e := child.(*policyEvent)
+		return e.PolicyID + "/" + e.ClusterID
+	}), nil
+}
+
+// resolveClusterHistory resolves the latest changes of the managed clusters
+func resolveClusterHistory(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	limit, err := limitOf(args)
+	if err != nil {
+		return nil, err
+	}
+	since, err := sinceArg(args)
+	if err != nil {
+		return nil, err
+	}
+	q := newSQLQuery(clusterHistoryQuery)
+	if since != "" {
+		q.where("created_at >= ?::timestamp", since)
+	}
+	q.where("cluster_id IN ?", keysOf(sources, resourceID))
+	query, queryArgs := q.buildPerKey("cluster_id", "created_at DESC", limit)
+	rows := []*clusterHistory{}
+	if err := scan(ctx, &rows, query, queryArgs); err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row)
+	}
+	return groupBy(sources, resourceID, items, func(child interface{}) string {
+		return child.(*clusterHistory).ClusterID
+	}), nil
+}
+
+func resolveSubscriptions(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	limit, err := limitOf(args)
+	if err != nil {
+		return nil, err
+	}
+	q := newSQLQuery(subscriptionsQuery)
+	if err := q.authorize(ctx, authorization.Subscriptions, subscriptionNamespacesQuery, "",
+		namespaceColumn); err != nil {
+		return nil, err
+	}
+	if namespace := stringArg(args, "namespace"); namespace != "" {
+		q.where(namespaceColumn+" = ?", namespace)
+	}
+	if name := stringArg(args, "name"); name != "" {
+		q.where(nameColumn+" = ?", name)
+	}
+	query, queryArgs := q.build(namespaceColumn+", "+nameColumn, limit)
+	subscriptions, err := scanResources(ctx, query, queryArgs)
+	return [][]interface{}{subscriptions}, err
+}
+
+// resolveSubscriptionReports resolves the reports of the subscriptions on the managed hubs
+func resolveSubscriptionReports(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	q := newSQLQuery(subscriptionReportsQuery)
+	if err := q.authorize(ctx, authorization.Subscriptions, subscriptionNamespacesQuery, "leaf_hub_name",
+		namespaceColumn); err != nil {
+		return nil, err
+	}
+	if hub := stringArg(args, "hub"); hub != "" {
+		q.where("leaf_hub_name = ?", hub)
+	}
+	q.where("parent_key IN ?", keysOf(sources, namespacedName))
+	query, queryArgs := q.build("parent_key, leaf_hub_name", maxLimit*len(sources))
+	reports, err := scanResources(ctx, query, queryArgs)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(sources, namespacedName, reports, resourceParentKey), nil
+}
+
+// resolveAlertCounts resolves the security alert counts of the query or the managed hubs
+func resolveAlertCounts(ctx context.Context, sources []interface{}, args map[string]interface{}) (
+	[][]interface{}, error,
+) {
+	q := newSQLQuery(alertCountsQuery)
+	if err := q.authorize(ctx, authorization.SecurityAlerts, "", "hub_name", ""); err != nil {
+		return nil, err
+	}
+	if hub := stringArg(args, "hub"); hub != "" {
+		q.where("hub_name = ?", hub)
+	}
+	if source := stringArg(args, "source"); source != "" {
+		q.where("source = ?", source)
+	}
+	if !isRoot(sources) {
+		q.where("hub_name IN ?", keysOf(sources, hubName))
+	}
+	// the alert counts are one row of each Central instance, so they aren't limited
+	query := fmt.Sprintf("SELECT * FROM (%s) q%s ORDER BY hub_name, source", q.base, q.whereClause())
+	rows := []*models.SecurityAlertCounts{}
+	if err := scan(ctx, &rows, query, q.args); err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row)
+	}
+	if isRoot(sources) {
+		return [][]interface{}{items}, nil
+	}
+	return groupBy(sources, hubName, items, func(child interface{}) string {
+		return child.(*models.SecurityAlertCounts).HubName
+	}), nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+)
+
+const (
+	// the items of each list are limited to protect the database, the limit of the nested list is applied to each
+	// of its parents
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type scopeKey struct{}
+
+// scope is the state of the request shared by the resolvers, the authorization filters are evaluated once for each
+// resource of the request
+type scope struct {
+	ginCtx  *gin.Context
+	filters map[string]*authorization.Filter
+}
+
+func withScope(ginCtx *gin.Context) context.Context {
+	return context.WithValue(ginCtx.Request.Context(), scopeKey{}, &scope{
+		ginCtx:  ginCtx,
+		filters: map[string]*authorization.Filter{},
+	})
+}
+
+// filterOf returns the hubs and namespaces of the resource the user is allowed to list
+func filterOf(ctx context.Context, resource, namespacesQuery string) (*authorization.Filter, error) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return &authorization.Filter{All: true}, nil
+	}
+	if filter, found := s.filters[resource]; found {
+		return filter, nil
+	}
+	filter, err := authorization.NewFilter(s.ginCtx, "list", resource, namespacesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authorize the %s: %w", resource, err)
+	}
+	s.filters[resource] = filter
+	return filter, nil
+}
+
+// sqlQuery selects the rows of the base query, which is wrapped as the subquery q so the conditions refer to its
+// columns
+type sqlQuery struct {
+	base       string
+	conditions []string
+	args       []interface{}
+}
+
+func newSQLQuery(base string) *sqlQuery {
+	return &sqlQuery{base: base}
+}
+
+func (q *sqlQuery) where(condition string, args ...interface{}) *sqlQuery {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// authorize adds the condition of the authorization filter of the resource
+func (q *sqlQuery) authorize(ctx context.Context, resource, namespacesQuery, leafHubColumn,
+	namespaceColumn string,
+) error {
+	filter, err := filterOf(ctx, resource, namespacesQuery)
+	if err != nil {
+		return err
+	}
+	if condition, args := filter.Where(leafHubColumn, namespaceColumn); condition != "" {
+		q.where(condition, args...)
+	}
+	return nil
+}
+
+func (q *sqlQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// build returns the query of the rows ordered by the order
+func (q *sqlQuery) build(order string, limit int) (string, []interface{}) {
+	query := fmt.Sprintf("SELECT * FROM (%s) q%s ORDER BY %s LIMIT ?", q.base, q.whereClause(), order)
+	return query, append(append([]interface{}{}, q.args...), limit)
+}
+
+// buildPerKey returns the query of at most limit rows of each key, so the nested list of all the parents is selected
+// by a single query
+func (q *sqlQuery) buildPerKey(key, order string, limit int) (string, []interface{}) {
+	query := fmt.Sprintf(`SELECT * FROM (
+			SELECT q.*, row_number() OVER (PARTITION BY %s ORDER BY %s) AS row_rank FROM (%s) q%s
+		) r WHERE row_rank <= ? ORDER BY %s, row_rank`, key, order, q.base, q.whereClause(), key)
+	return query, append(append([]interface{}{}, q.args...), limit)
+}
+
+// scan runs the query and scans the rows into the dest, which is the pointer of the slice of the rows
+func scan(ctx context.Context, dest interface{}, query string, args []interface{}) error {
+	if err := database.GetGorm().WithContext(ctx).Raw(query, args...).Scan(dest).Error; err != nil {
+		return fmt.Errorf("failed to query the rows: %w", err)
+	}
+	return nil
+}
+
+// limitOf returns the limit argument of the list
+func limitOf(args map[string]interface{}) (int, error) {
+	limit, found := args["limit"]
+	if !found {
+		return defaultLimit, nil
+	}
+	n := limit.(int64)
+	if n <= 0 || n > maxLimit {
+		return 0, requestErrorf("invalid limit %d, it should be between 1 and %d", n, maxLimit)
+	}
+	return int(n), nil
+}
+
+// keysOf returns the distinct non-empty keys of the sources
+func keysOf(sources []interface{}, key func(source interface{}) string) []string {
+	keys := []string{}
+	found := map[string]bool{}
+	for _, source := range sources {
+		k := key(source)
+		if k == "" || found[k] {
+			continue
+		}
+		found[k] = true
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// groupBy returns the children of each source by the keys of the sources and children
+func groupBy(sources []interface{}, sourceKey func(source interface{}) string, children []interface{},
+	childKey func(child interface{}) string,
+) [][]interface{} {
+	byKey := map[string][]interface{}{}
+	for _, child := range children {
+		k := childKey(child)
+		byKey[k] = append(byKey[k], child)
+	}
+	grouped := make([][]interface{}, len(sources))
+	for i, source := range sources {
+		grouped[i] = byKey[sourceKey(source)]
+		if grouped[i] == nil {
+			grouped[i] = []interface{}{}
+		}
+	}
+	return grouped
+}
+
+// stringArg returns the string argument, it's empty if the argument isn't set
+func stringArg(args map[string]interface{}, name string) string {
+	if v, found := args[name]; found {
+		return v.(string)
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedclusters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+// ManagedClusterTransition is a transition of the managed cluster, the Changes are the fields changed by the
+// transition, e.g. created, deleted, leaf_hub_name, available, joined, accepted, openshift_version and labels.
+type ManagedClusterTransition struct {
+	Time             time.Time         `json:"time"`
+	ClusterName      string            `json:"clusterName"`
+	LeafHubName      string            `json:"leafHubName"`
+	Available        string            `json:"available,omitempty"`
+	Joined           string            `json:"joined,omitempty"`
+	Accepted         string            `json:"accepted,omitempty"`
+	OpenshiftVersion string            `json:"openshiftVersion,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Changes          []string          `json:"changes"`
+}
+
+// ManagedClusterHistory is the transitions of the managed cluster, the latest transition is the first one
+type ManagedClusterHistory struct {
+	ClusterID string                     `json:"clusterId"`
+	Items     []ManagedClusterTransition `json:"items"`
+}
+
+// GetManagedClusterHistory godoc
+// @summary get managed cluster history
+// @description get the transitions of the availability, joined, accepted, openshift version, labels and the
+// @description owning hub of a given managed cluster
+// @accept json
+// @produce json
+// @param        clusterID    path     string  true   "Managed cluster ID"
+// @param        since        query    string  false  "only return the transitions after the time, in RFC3339 format"
+// @param        until        query    string  false  "only return the transitions before the time, in RFC3339 format"
+// @param        change       query    string  false  "only return the transitions changed the field, e.g. available"
+// @param        limit        query    int     false  "maximum transition number to receive"
+// @success      200  {object}  ManagedClusterHistory
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedcluster/{clusterID}/history [get]
+func GetManagedClusterHistory() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		clusterID := ginCtx.Param("clusterID")
+		if _, err := uuid.Parse(clusterID); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid cluster ID %q", clusterID))
+			return
+		}
+
+		// the cluster might be moved between the hubs, only the transitions on the allowed hubs are returned
+		filter, err := authorization.NewFilter(ginCtx, "get", authorization.ManagedClusters, "")
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the managed cluster history: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		query := database.GetGorm().Where("cluster_id = ?", clusterID).Scopes(filter.Scope("leaf_hub_name", ""))
+		for _, param := range []struct {
+			name      string
+			condition string
+		}{{"since", "created_at >= ?"}, {"until", "created_at < ?"}} {
+			value := ginCtx.Query(param.name)
+			if value == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid %s %q, the format should be RFC3339",
+					param.name, value))
+				return
+			}
+			query = query.Where(param.condition, t.UTC())
+		}
+		if change := ginCtx.Query("change"); change != "" {
+			query = query.Where("? = ANY(changes)", change)
+		}
+		if limit := ginCtx.Query("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n <= 0 {
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid limit %q", limit))
+				return
+			}
+			query = query.Limit(n)
+		}
+
+		var histories []models.ManagedClusterHistory
+		if err := query.Order("created_at DESC").Find(&histories).Error; err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying managed cluster history: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		clusterHistory := ManagedClusterHistory{
+			ClusterID: clusterID,
+			Items:     make([]ManagedClusterTransition, 0, len(histories)),
+		}
+		for _, history := range histories {
+			transition := ManagedClusterTransition{
+				Time:             history.CreatedAt,
+				ClusterName:      history.ClusterName,
+				LeafHubName:      history.LeafHubName,
+				Available:        stringValue(history.Available),
+				Joined:           stringValue(history.Joined),
+				Accepted:         stringValue(history.Accepted),
+				OpenshiftVersion: stringValue(history.OpenshiftVersion),
+				Changes:          history.Changes,
+			}
+			if len(history.Labels) > 0 {
+				if err := json.Unmarshal(history.Labels, &transition.Labels); err != nil {
+					fmt.Fprintf(gin.DefaultWriter, "error in unmarshaling the labels: %v\n", err)
+				}
+			}
+			clusterHistory.Items = append(clusterHistory.Items, transition)
+		}
+
+		ginCtx.JSON(http.StatusOK, clusterHistory)
+	}
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedclusters
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authentication"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+// LabelOwnerRequest is the owner of the label key of the managed clusters. The label of the global owner is always
+// kept with the value of the global hub, the one of the hub owner is only changed on the hub, and the shared one is
+// kept with the value of the side changing it last.
+type LabelOwnerRequest struct {
+	Key string `json:"key" binding:"required"`
+	// Owner is global, hub or shared
+	Owner string `json:"owner" binding:"required,oneof=global hub shared"`
+}
+
+// LabelOwner is the owner of the label key, the keys not listed are shared
+type LabelOwner struct {
+	Key       string    `json:"key"`
+	Owner     string    `json:"owner"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// LabelOwnerList is the owners of the label keys of the managed clusters
+type LabelOwnerList struct {
+	Items []LabelOwner `json:"items"`
+}
+
+// ListLabelOwners godoc
+// @summary list the owners of the managed cluster label keys
+// @description list the owners of the label keys of the managed clusters, global, hub or shared. The keys not listed
+// @description are shared.
+// @accept json
+// @produce json
+// @success      200  {object}     LabelOwnerList
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedclusters/labelowners [get]
+func ListLabelOwners() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		if !authorization.AuthorizeOrAbort(ginCtx, "list", authorization.LabelOwners, "", "") {
+			return
+		}
+		rows := []models.ManagedClusterLabelOwner{}
+		if err := database.GetGorm().WithContext(ginCtx.Request.Context()).Order("label_key").
+			Find(&rows).Error; err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the managed cluster label owners: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		resp := LabelOwnerList{Items: make([]LabelOwner, 0, len(rows))}
+		for _, row := range rows {
+			resp.Items = append(resp.Items, LabelOwner{
+				Key:       row.LabelKey,
+				Owner:     row.Owner,
+				UpdatedBy: row.UpdatedBy,
+				UpdatedAt: row.UpdatedAt,
+			})
+		}
+		ginCtx.JSON(http.StatusOK, resp)
+	}
+}
+
+// PutLabelOwner godoc
+// @summary set the owner of the managed cluster label key
+// @description set the owner of the label key of the managed clusters, the labels of the managed clusters of all the
+// @description hubs are sent again with the owners. The label of the hub owner can't be patched by the global hub.
+// @accept json
+// @produce json
+// @param        body    body    LabelOwnerRequest  true    "the label key and its owner"
+// @success      200
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedclusters/labelowners [put]
+func PutLabelOwner() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		if !authorization.AuthorizeOrAbort(ginCtx, "update", authorization.LabelOwners, "", "") {
+			return
+		}
+		request := &LabelOwnerRequest{}
+		if err := ginCtx.ShouldBindJSON(request); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid label owner: %v", err))
+			return
+		}
+		if errs := validation.IsQualifiedName(request.Key); len(errs) > 0 {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid label key %q: %s", request.Key,
+				strings.Join(errs, "; ")))
+			return
+		}
+
+		err := database.GetGorm().WithContext(ginCtx.Request.Context()).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "label_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"owner", "updated_by", "updated_at"}),
+		}).Create(&models.ManagedClusterLabelOwner{
+			LabelKey:  request.Key,
+			Owner:     request.Owner,
+			UpdatedBy: ginCtx.GetString(authentication.UserKey),
+		}).Error
+		util.RecordAudit(ginCtx, "update", authorization.LabelOwners, request, []audit.Object{
+			{Name: request.Key},
+		}, err)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to update the owner of the label %s: %v\n", request.Key, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		ginCtx.String(http.StatusOK, fmt.Sprintf("the owner of the label %s is %s", request.Key, request.Owner))
+	}
+}
+
+// DeleteLabelOwner godoc
+// @summary delete the owner of the managed cluster label key
+// @description delete the owner of the label key of the managed clusters, then the label is shared
+// @accept json
+// @produce json
+// @param        key    query    string    true    "the label key"
+// @success      200
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedclusters/labelowners [delete]
+func DeleteLabelOwner() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		if !authorization.AuthorizeOrAbort(ginCtx, "delete", authorization.LabelOwners, "", "") {
+			return
+		}
+		key := ginCtx.Query("key")
+		if key == "" {
+			ginCtx.String(http.StatusBadRequest, "the label key should be specified")
+			return
+		}
+		result := database.GetGorm().WithContext(ginCtx.Request.Context()).
+			Where("label_key = ?", key).Delete(&models.ManagedClusterLabelOwner{})
+		util.RecordAudit(ginCtx, "delete", authorization.LabelOwners, nil, []audit.Object{{Name: key}}, result.Error)
+		if result.Error != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to delete the owner of the label %s: %v\n", key, result.Error)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		if result.RowsAffected == 0 {
+			ginCtx.String(http.StatusNotFound, fmt.Sprintf("owner of the label %s not found", key))
+			return
+		}
+		ginCtx.String(http.StatusOK, fmt.Sprintf("the owner of the label %s is deleted", key))
+	}
+}
+
+// hubOwnedLabels returns the keys of the patch owned by the hubs, which can't be patched by the global hub
+func hubOwnedLabels(db *gorm.DB, labelsToAdd map[string]string, labelsToRemove map[string]struct{},
+) ([]string, error) {
+	keys := getKeys(labelsToRemove)
+	for key := range labelsToAdd {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	owned := []string{}
+	if err := db.Model(&models.ManagedClusterLabelOwner{}).Where("label_key IN ? AND owner = ?", keys,
+		wiremodels.LabelOwnerHub).Order("label_key").Pluck("label_key", &owned).Error; err != nil {
+		return nil, fmt.Errorf("failed to query the owners of the labels: %w", err)
+	}
+	return owned, nil
+}
+
+// checkLabelOwnersOrAbort returns false and responds the bad request if any label of the patch is owned by the hubs
+func checkLabelOwnersOrAbort(ginCtx *gin.Context, labelsToAdd map[string]string,
+	labelsToRemove map[string]struct{},
+) bool {
+	owned, err := hubOwnedLabels(database.GetGorm().WithContext(ginCtx.Request.Context()), labelsToAdd,
+		labelsToRemove)
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in checking the owners of the labels: %v\n", err)
+		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+		return false
+	}
+	if len(owned) > 0 {
+		ginCtx.String(http.StatusBadRequest, fmt.Sprintf("the labels %v are owned by the managed hubs", owned))
+		return false
+	}
+	return true
+}
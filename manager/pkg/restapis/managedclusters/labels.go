@@ -0,0 +1,428 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedclusters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/schedules"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+const (
+	// labelJobClustersQuery selects the clusters of the bulk label patch, the selector or the IDs are appended
+	labelJobClustersQuery = `SELECT cluster_id, payload -> 'metadata' ->> 'name', leaf_hub_name,
+		payload -> 'metadata' -> 'labels' FROM status.managed_clusters WHERE deleted_at IS NULL`
+	labelJobClusterLabelsQuery = `SELECT cluster_id, payload -> 'metadata' -> 'labels' FROM status.managed_clusters
+		WHERE deleted_at IS NULL AND cluster_id IN ?`
+
+	// the states of the cluster in the label job
+	LabelJobClusterAffected = "affected"
+	LabelJobClusterPatched  = "patched"
+	LabelJobClusterApplied  = "applied"
+	LabelJobClusterPending  = "pending"
+	LabelJobClusterDeferred = "deferred"
+	LabelJobClusterDeleted  = "deleted"
+)
+
+// BulkLabelPatch is the label patch of the clusters selected by the LabelSelector or listed by the ClusterIDs, only
+// one of them can be set.
+type BulkLabelPatch struct {
+	LabelSelector string   `json:"labelSelector,omitempty"`
+	ClusterIDs    []string `json:"clusterIds,omitempty"`
+	Patches       []patch  `json:"patches" binding:"required"`
+}
+
+// LabelJobCluster is the cluster patched by the label job. The State is affected in the dry run and patched once the
+// job is created, then it's applied after the hub reports the labels, pending before that, or deleted if the cluster
+// is removed. The pending cluster is deferred until the DeferredUntil if its hub is deferred by the schedules.
+type LabelJobCluster struct {
+	ClusterID     string     `json:"clusterId"`
+	ClusterName   string     `json:"clusterName"`
+	LeafHubName   string     `json:"leafHubName"`
+	State         string     `json:"state"`
+	DeferredUntil *time.Time `json:"deferredUntil,omitempty"`
+	// labels are the labels before the patch, which are recorded in the audit event
+	labels map[string]string
+}
+
+// LabelJob is the bulk label patch of the clusters, the JobID is empty in the dry run
+type LabelJob struct {
+	JobID         string            `json:"jobId,omitempty"`
+	DryRun        bool              `json:"dryRun,omitempty"`
+	LabelSelector string            `json:"labelSelector,omitempty"`
+	Patches       []patch           `json:"patches"`
+	CreatedAt     *time.Time        `json:"createdAt,omitempty"`
+	Items         []LabelJobCluster `json:"items"`
+}
+
+// PatchManagedClusterLabels godoc
+// @summary patch labels of managed clusters in bulk
+// @description patch the labels of the managed clusters selected by the label selector or listed by the IDs, all
+// @description the clusters are patched or none of them. The dryRun only lists the clusters to patch. The labels
+// @description owned by the managed hubs can't be patched.
+// @accept json
+// @produce json
+// @param        patch     body     BulkLabelPatch  true   "the clusters and the JSON patch of their labels"
+// @param        dryRun    query    string          false  "only list the clusters to patch"
+// @success      200  {object}  LabelJob
+// @success      201  {object}  LabelJob
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedclusters/labels [post]
+func PatchManagedClusterLabels() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		bulkPatch := &BulkLabelPatch{}
+		if err := ginCtx.ShouldBindJSON(bulkPatch); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid bulk label patch: %v", err))
+			return
+		}
+		if (bulkPatch.LabelSelector == "") == (len(bulkPatch.ClusterIDs) == 0) {
+			ginCtx.String(http.StatusBadRequest, "either the labelSelector or the clusterIds should be specified")
+			return
+		}
+		labelsToAdd, labelsToRemove, err := getLabels(ginCtx, bulkPatch.Patches)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to get labels: %s\n", err.Error())
+			return
+		}
+		if len(labelsToAdd) == 0 && len(labelsToRemove) == 0 {
+			ginCtx.String(http.StatusBadRequest, "no label to patch")
+			return
+		}
+		if !checkLabelOwnersOrAbort(ginCtx, labelsToAdd, labelsToRemove) {
+			return
+		}
+
+		// only the clusters of the hubs the user is allowed to patch are selected by the label selector
+		filter, err := authorization.NewFilter(ginCtx, "patch", authorization.ManagedClusters, "")
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the managed clusters: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		clusters, ok := selectLabelJobClusters(ginCtx, bulkPatch, filter)
+		if !ok {
+			return
+		}
+
+		job := &LabelJob{
+			LabelSelector: bulkPatch.LabelSelector,
+			Patches:       bulkPatch.Patches,
+			Items:         clusters,
+		}
+		if _, dryRun := ginCtx.GetQuery("dryRun"); dryRun || len(clusters) == 0 {
+			job.DryRun = dryRun
+			for i := range job.Items {
+				job.Items[i].State = LabelJobClusterAffected
+			}
+			ginCtx.JSON(http.StatusOK, job)
+			return
+		}
+
+		err = applyLabelJob(job, labelsToAdd, labelsToRemove)
+		objects := []audit.Object{}
+		for _, cluster := range job.Items {
+			objects = append(objects, labelsAuditObject(cluster.ClusterID, cluster.ClusterName, cluster.LeafHubName,
+				cluster.labels, labelsToAdd, labelsToRemove))
+		}
+		util.RecordAudit(ginCtx, "patch", authorization.ManagedClusters, bulkPatch, objects, err)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in patching managed cluster labels in bulk: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		fmt.Fprintf(gin.DefaultWriter, "label job %s patched %d managed clusters\n", job.JobID, len(job.Items))
+		ginCtx.JSON(http.StatusCreated, job)
+	}
+}
+
+// selectLabelJobClusters returns the clusters to patch, it writes the response if the clusters can't be selected.
+// The listed clusters must all exist and be allowed, otherwise none of them is patched.
+func selectLabelJobClusters(ginCtx *gin.Context, bulkPatch *BulkLabelPatch, filter *authorization.Filter,
+) ([]LabelJobCluster, bool) {
+	query := labelJobClustersQuery
+	args := []interface{}{}
+	if bulkPatch.LabelSelector != "" {
+		selectorInSql, selectorArgs, err := util.ParseLabelSelector(bulkPatch.LabelSelector)
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid label selector: %v", err))
+			return nil, false
+		}
+		filterInSql, filterArgs := filter.Condition("leaf_hub_name", "")
+		query += selectorInSql + filterInSql
+		args = append(append(args, selectorArgs...), filterArgs...)
+	} else {
+		for _, clusterID := range bulkPatch.ClusterIDs {
+			if _, err := uuid.Parse(clusterID); err != nil {
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid cluster ID %q", clusterID))
+				return nil, false
+			}
+		}
+		query += " AND cluster_id IN ?"
+		args = append(args, bulkPatch.ClusterIDs)
+	}
+	query += " ORDER BY payload -> 'metadata' ->> 'name', cluster_id"
+
+	rows, err := database.GetGorm().Raw(query, args...).Rows()
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in querying the managed clusters to patch: %v\n", err)
+		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+		return nil, false
+	}
+	defer rows.Close()
+
+	clusters := []LabelJobCluster{}
+	found := map[string]bool{}
+	forbidden := []string{}
+	for rows.Next() {
+		cluster := LabelJobCluster{}
+		var labels []byte
+		if err := rows.Scan(&cluster.ClusterID, &cluster.ClusterName, &cluster.LeafHubName, &labels); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in scanning the managed cluster to patch: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return nil, false
+		}
+		if len(labels) > 0 {
+			if err := json.Unmarshal(labels, &cluster.labels); err != nil {
+				fmt.Fprintf(gin.DefaultWriter, "error in unmarshaling the labels of the managed cluster: %v\n", err)
+				ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+				return nil, false
+			}
+		}
+		found[cluster.ClusterID] = true
+		if !filter.AllowsLeafHub(cluster.LeafHubName) {
+			forbidden = append(forbidden, cluster.ClusterID)
+			continue
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	missing := []string{}
+	for _, clusterID := range bulkPatch.ClusterIDs {
+		if !found[clusterID] {
+			missing = append(missing, clusterID)
+		}
+	}
+	if len(missing) > 0 {
+		ginCtx.String(http.StatusNotFound, fmt.Sprintf("managed clusters not found: %s", strings.Join(missing, ", ")))
+		return nil, false
+	}
+	if len(forbidden) > 0 {
+		ginCtx.String(http.StatusForbidden, fmt.Sprintf("not allowed to patch the managed clusters: %s",
+			strings.Join(forbidden, ", ")))
+		return nil, false
+	}
+	return clusters, true
+}
+
+// applyLabelJob merges the labels of all the clusters and records the job in a transaction, the transaction is
+// retried if the labels of a cluster are updated concurrently.
+func applyLabelJob(job *LabelJob, labelsToAdd map[string]string, labelsToRemove map[string]struct{}) error {
+	patches, err := json.Marshal(job.Patches)
+	if err != nil {
+		return err
+	}
+	job.JobID = uuid.New().String()
+	createdAt := time.Now()
+	job.CreatedAt = &createdAt
+	for i := range job.Items {
+		job.Items[i].State = LabelJobClusterPatched
+	}
+	clusters, err := json.Marshal(job.Items)
+	if err != nil {
+		return err
+	}
+
+	conn := database.GetConn()
+	if err := database.Lock(conn); err != nil {
+		return err
+	}
+	defer database.Unlock(conn)
+
+	for retryAttempts := optimisticConcurrencyRetryAttempts; ; retryAttempts-- {
+		err = database.GetGorm().Transaction(func(tx *gorm.DB) error {
+			for _, cluster := range job.Items {
+				if err := mergeLabels(tx, cluster.ClusterID, cluster.LeafHubName, cluster.ClusterName, labelsToAdd,
+					labelsToRemove); err != nil {
+					return err
+				}
+			}
+			return tx.Create(&models.ManagedClusterLabelJob{
+				ID:            job.JobID,
+				LabelSelector: job.LabelSelector,
+				Patches:       patches,
+				Clusters:      clusters,
+				CreatedAt:     createdAt,
+			}).Error
+		})
+		if err == nil || !errors.Is(err, errOptimisticConcurrencyWriteFailed) || retryAttempts <= 1 {
+			return err
+		}
+	}
+}
+
+// GetManagedClusterLabelJob godoc
+// @summary get label job of managed clusters
+// @description get the bulk label patch with the state of each managed cluster, the cluster is applied once the
+// @description labels reported by its hub match the patch
+// @accept json
+// @produce json
+// @param        jobID    path    string    true    "Label job ID"
+// @success      200  {object}  LabelJob
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedclusters/labeljob/{jobID} [get]
+func GetManagedClusterLabelJob() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		jobID := ginCtx.Param("jobID")
+		if _, err := uuid.Parse(jobID); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid job ID %q", jobID))
+			return
+		}
+
+		labelJob := &models.ManagedClusterLabelJob{}
+		err := database.GetGorm().Where("id = ?", jobID).First(labelJob).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ginCtx.String(http.StatusNotFound, fmt.Sprintf("label job %s not found", jobID))
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the label job: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		// only the clusters of the hubs the user is allowed to get are returned
+		filter, err := authorization.NewFilter(ginCtx, "get", authorization.ManagedClusters, "")
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the managed clusters: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		job, err := getLabelJobStates(ginCtx.Request.Context(), labelJob, filter)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in getting the state of the label job: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		ginCtx.JSON(http.StatusOK, job)
+	}
+}
+
+func getLabelJobStates(ctx context.Context, labelJob *models.ManagedClusterLabelJob, filter *authorization.Filter,
+) (*LabelJob, error) {
+	createdAt := labelJob.CreatedAt
+	job := &LabelJob{
+		JobID:         labelJob.ID,
+		LabelSelector: labelJob.LabelSelector,
+		CreatedAt:     &createdAt,
+		Items:         []LabelJobCluster{},
+	}
+	if err := json.Unmarshal(labelJob.Patches, &job.Patches); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the patches: %w", err)
+	}
+	clusters := []LabelJobCluster{}
+	if err := json.Unmarshal(labelJob.Clusters, &clusters); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the clusters: %w", err)
+	}
+	clusterIDs := []string{}
+	for _, cluster := range clusters {
+		if filter.AllowsLeafHub(cluster.LeafHubName) {
+			job.Items = append(job.Items, cluster)
+			clusterIDs = append(clusterIDs, cluster.ClusterID)
+		}
+	}
+	if len(clusterIDs) == 0 {
+		return job, nil
+	}
+
+	rows, err := database.GetGorm().Raw(labelJobClusterLabelsQuery, clusterIDs).Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the labels of the clusters: %w", err)
+	}
+	defer rows.Close()
+	clusterLabels := map[string]map[string]string{}
+	for rows.Next() {
+		var clusterID string
+		var payload []byte
+		if err := rows.Scan(&clusterID, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan the labels of the cluster: %w", err)
+		}
+		labels := map[string]string{}
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &labels); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal the labels of the cluster %s: %w", clusterID, err)
+			}
+		}
+		clusterLabels[clusterID] = labels
+	}
+
+	hubSchedules, err := schedules.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i, cluster := range job.Items {
+		labels, found := clusterLabels[cluster.ClusterID]
+		switch {
+		case !found:
+			job.Items[i].State = LabelJobClusterDeleted
+		case labelsPatched(labels, job.Patches):
+			job.Items[i].State = LabelJobClusterApplied
+		default:
+			job.Items[i].State = LabelJobClusterPending
+			if deferral := hubSchedules.Deferral(cluster.LeafHubName, now); deferral.Deferred {
+				job.Items[i].State = LabelJobClusterDeferred
+				job.Items[i].DeferredUntil = deferral.Until
+			}
+		}
+	}
+	return job, nil
+}
+
+// labelsPatched reports whether the labels are the result of the patches, the patches are applied in order
+func labelsPatched(labels map[string]string, patches []patch) bool {
+	expected := map[string]*string{}
+	for i, aPatch := range patches {
+		label := strings.Replace(strings.TrimPrefix(aPatch.Path, "/metadata/labels/"), "~1", "/", 1)
+		if aPatch.Op == "add" {
+			expected[label] = &patches[i].Value
+		} else {
+			expected[label] = nil
+		}
+	}
+	for key, value := range expected {
+		current, found := labels[key]
+		if value == nil && found || value != nil && (!found || current != *value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedclusters
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabelsPatched(t *testing.T) {
+	patches := []patch{
+		{Op: "add", Path: "/metadata/labels/env", Value: "dev"},
+		{Op: "remove", Path: "/metadata/labels/team"},
+		{Op: "add", Path: "/metadata/labels/example.com~1zone", Value: "east"},
+		// the later patch of the same label wins
+		{Op: "add", Path: "/metadata/labels/owner", Value: "alice"},
+		{Op: "remove", Path: "/metadata/labels/owner"},
+	}
+	cases := []struct {
+		name     string
+		labels   map[string]string
+		expected bool
+	}{
+		{"applied", map[string]string{"env": "dev", "example.com/zone": "east", "vendor": "OpenShift"}, true},
+		{"value not updated", map[string]string{"env": "prod", "example.com/zone": "east"}, false},
+		{"label not added", map[string]string{"env": "dev"}, false},
+		{"label not removed", map[string]string{"env": "dev", "example.com/zone": "east", "team": "a"}, false},
+		{"overridden label not removed", map[string]string{"env": "dev", "example.com/zone": "east", "owner": "a"},
+			false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := labelsPatched(c.labels, patches); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLabelsAuditObject(t *testing.T) {
+	labels := map[string]string{"env": "dev", "team": "a"}
+	object := labelsAuditObject("1", "cluster1", "hub1", labels, map[string]string{"env": "prod", "zone": "east"},
+		map[string]struct{}{"team": {}})
+	expected := map[string]string{"env": "prod", "zone": "east"}
+	if !reflect.DeepEqual(object.After, expected) {
+		t.Errorf("expected the labels %v after the patch, but got %v", expected, object.After)
+	}
+	if !reflect.DeepEqual(object.Before, map[string]string{"env": "dev", "team": "a"}) {
+		t.Errorf("the labels before the patch are changed: %v", object.Before)
+	}
+	if object.ID != "1" || object.Name != "cluster1" || object.LeafHubName != "hub1" {
+		t.Errorf("unexpected object %+v", object)
+	}
+}
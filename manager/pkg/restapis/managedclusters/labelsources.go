@@ -0,0 +1,224 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedclusters
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+// LabelSourcePending means the value of the global hub isn't reported by the hub yet
+const LabelSourcePending = "pending"
+
+// LabelConflict is the latest conflict of the label, the Winner is the side whose value is kept on the hub
+type LabelConflict struct {
+	GlobalValue *string   `json:"globalValue"`
+	HubValue    *string   `json:"hubValue"`
+	Winner      string    `json:"winner"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// LabelSource is the label of the managed cluster with its effective source. The Source is global if the value of
+// the hub is the one of the global hub, hub if the label is owned by the hub, isn't set by the global hub or the hub
+// won the latest conflict, otherwise it's pending until the hub reports the value of the global hub.
+type LabelSource struct {
+	Key string `json:"key"`
+	// Value is the value reported by the hub, it's nil if the label isn't on the cluster
+	Value *string `json:"value"`
+	// GlobalValue is the value set by the global hub, it's nil if the label is deleted or not set by the global hub
+	GlobalValue    *string        `json:"globalValue"`
+	Owner          string         `json:"owner"`
+	Source         string         `json:"source"`
+	LatestConflict *LabelConflict `json:"latestConflict,omitempty"`
+}
+
+// ManagedClusterLabelSources is the labels of the managed cluster with their sources
+type ManagedClusterLabelSources struct {
+	ClusterID   string        `json:"clusterId"`
+	ClusterName string        `json:"clusterName"`
+	LeafHubName string        `json:"leafHubName"`
+	Items       []LabelSource `json:"items"`
+}
+
+// GetManagedClusterLabelSources godoc
+// @summary get the sources of the managed cluster labels
+// @description get the labels of the managed cluster with their owners, the values of the global hub, the effective
+// @description sources and the latest conflicts between the global hub and the hub
+// @accept json
+// @produce json
+// @param        clusterID    path     string  true   "Managed cluster ID"
+// @success      200  {object}  ManagedClusterLabelSources
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedcluster/{clusterID}/labelsources [get]
+func GetManagedClusterLabelSources() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		clusterID := ginCtx.Param("clusterID")
+		if _, err := uuid.Parse(clusterID); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid cluster ID %q", clusterID))
+			return
+		}
+
+		db := database.GetGorm().WithContext(ginCtx.Request.Context())
+		resp := &ManagedClusterLabelSources{ClusterID: clusterID, Items: []LabelSource{}}
+		var labelsPayload []byte
+		err := db.Raw(`SELECT leaf_hub_name, payload->'metadata'->>'name', payload->'metadata'->'labels'
+			FROM status.managed_clusters WHERE cluster_id = ? AND deleted_at IS NULL`, clusterID).Row().Scan(
+			&resp.LeafHubName, &resp.ClusterName, &labelsPayload)
+		if errors.Is(err, sql.ErrNoRows) {
+			ginCtx.String(http.StatusNotFound, fmt.Sprintf("managed cluster %s not found", clusterID))
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the managed cluster %s: %v\n", clusterID, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		if !authorization.AuthorizeOrAbort(ginCtx, "get", authorization.ManagedClusters, resp.LeafHubName, "") {
+			return
+		}
+
+		items, err := labelSources(db, resp.LeafHubName, resp.ClusterName, clusterID, labelsPayload)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the label sources of the managed cluster %s: %v\n",
+				clusterID, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		resp.Items = items
+		ginCtx.JSON(http.StatusOK, resp)
+	}
+}
+
+// labelSources returns the labels reported by the hub or set by the global hub with their sources, sorted by the key
+func labelSources(db *gorm.DB, leafHubName, clusterName, clusterID string, labelsPayload []byte,
+) ([]LabelSource, error) {
+	hubLabels := map[string]string{}
+	if len(labelsPayload) > 0 {
+		if err := json.Unmarshal(labelsPayload, &hubLabels); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the labels of the managed cluster: %w", err)
+		}
+	}
+
+	globalLabels := map[string]string{}
+	deletedKeys := []string{}
+	var globalUpdatedAt time.Time
+	specRows := []models.ManagedClusterLabel{}
+	if err := db.Where("id = ?", clusterID).Find(&specRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query the labels of the global hub: %w", err)
+	}
+	if len(specRows) > 0 {
+		if err := json.Unmarshal(specRows[0].Labels, &globalLabels); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the labels of the global hub: %w", err)
+		}
+		if err := json.Unmarshal(specRows[0].DeletedLabelKeys, &deletedKeys); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the deleted labels of the global hub: %w", err)
+		}
+		globalUpdatedAt = specRows[0].UpdatedAt
+	}
+
+	owners := map[string]string{}
+	ownerRows := []models.ManagedClusterLabelOwner{}
+	if err := db.Find(&ownerRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query the label owners: %w", err)
+	}
+	for _, row := range ownerRows {
+		owners[row.LabelKey] = row.Owner
+	}
+
+	conflicts := map[string]*models.ManagedClusterLabelConflictEvent{}
+	conflictRows := []models.ManagedClusterLabelConflictEvent{}
+	if err := db.Raw(`SELECT DISTINCT ON (label_key) * FROM event.managed_cluster_label_conflicts
+		WHERE leaf_hub_name = ? AND cluster_name = ? ORDER BY label_key, created_at DESC`,
+		leafHubName, clusterName).Scan(&conflictRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query the label conflicts: %w", err)
+	}
+	for i := range conflictRows {
+		conflicts[conflictRows[i].LabelKey] = &conflictRows[i]
+	}
+
+	keys := map[string]bool{}
+	for key := range hubLabels {
+		keys[key] = true
+	}
+	for key := range globalLabels {
+		keys[key] = true
+	}
+	for _, key := range deletedKeys {
+		keys[key] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	deleted := getMap(deletedKeys)
+	items := make([]LabelSource, 0, len(sortedKeys))
+	for _, key := range sortedKeys {
+		item := LabelSource{Key: key, Owner: owners[key]}
+		if item.Owner == "" {
+			item.Owner = wiremodels.LabelOwnerShared
+		}
+		if value, found := hubLabels[key]; found {
+			item.Value = &value
+		}
+		if value, found := globalLabels[key]; found {
+			item.GlobalValue = &value
+		}
+		if conflict, found := conflicts[key]; found {
+			item.LatestConflict = &LabelConflict{
+				GlobalValue: conflict.GlobalValue,
+				HubValue:    conflict.HubValue,
+				Winner:      conflict.Winner,
+				CreatedAt:   conflict.CreatedAt,
+			}
+		}
+		_, deletedByGlobal := deleted[key]
+
+		switch {
+		case item.Owner == wiremodels.LabelOwnerHub || (item.GlobalValue == nil && !deletedByGlobal):
+			item.Source = wiremodels.LabelSourceHub
+		case equalValues(item.Value, item.GlobalValue):
+			item.Source = wiremodels.LabelSourceGlobal
+		case item.LatestConflict != nil && item.LatestConflict.Winner == wiremodels.LabelSourceHub &&
+			!item.LatestConflict.CreatedAt.Before(globalUpdatedAt):
+			item.Source = wiremodels.LabelSourceHub
+		default:
+			item.Source = LabelSourcePending
+		}
+		// the deleted label only shows up if it's still on the cluster
+		if item.Value == nil && item.GlobalValue == nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func equalValues(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
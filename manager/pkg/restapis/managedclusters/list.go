@@ -24,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
 )
@@ -38,12 +39,22 @@ const (
 	crdName                                     = "managedclusters.cluster.open-cluster-management.io"
 )
 
+// managedClusterFields are the fields of the managed clusters which can be selected and sorted by
+var managedClusterFields = util.ListFields{
+	"metadata.name":    "payload -> 'metadata' ->> 'name'",
+	"leafHubName":      "leaf_hub_name",
+	"available":        "history.managed_cluster_condition(payload, 'ManagedClusterConditionAvailable')",
+	"openshiftVersion": "payload -> 'metadata' -> 'labels' ->> 'openshiftVersion'",
+}
+
 // ListManagedClusters godoc
 // @summary list managed clusters
 // @description list managed clusters
 // @accept json
-// @produce json
+// @produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @param        labelSelector    query     string  false  "list managed clusters by label selector"
+// @param        fieldSelector    query     string  false  "list managed clusters by field selector"
+// @param        sortBy           query     string  false  "sort managed clusters by the field, - for descending"
 // @param        limit            query     int     false  "maximum managed cluster number to receive"
 // @param        continue         query     string  false  "continue token to request next request"
 // @success      200  {object}    clusterv1.ManagedClusterList
@@ -63,78 +74,116 @@ func ListManagedClusters() gin.HandlerFunc {
 		labelSelector := ginCtx.Query("labelSelector")
 
 		selectorInSql := ""
+		selectorArgs := []interface{}{}
 		var err error
 
 		if labelSelector != "" {
-			selectorInSql, err = util.ParseLabelSelector(labelSelector)
+			selectorInSql, selectorArgs, err = util.ParseLabelSelector(labelSelector)
 			if err != nil {
 				fmt.Fprintf(gin.DefaultWriter, "failed to parse label selector: %s\n", err.Error())
+				ginCtx.String(http.StatusBadRequest, err.Error())
 				return
 			}
 		}
 
 		fmt.Fprintf(gin.DefaultWriter, "parsed selector: %s\n", selectorInSql)
 
+		fieldSelectorInSql := ""
+		fieldSelectorArgs := []interface{}{}
+		if fieldSelector := ginCtx.Query("fieldSelector"); fieldSelector != "" {
+			fieldSelectorInSql, fieldSelectorArgs, err = util.ParseFieldSelector(fieldSelector, managedClusterFields)
+			if err != nil {
+				ginCtx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		order, err := util.NewListOrder(ginCtx.Query("sortBy"), managedClusterFields,
+			"payload -> 'metadata' ->> 'name'", "cluster_id::text")
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
 		limit := ginCtx.Query("limit")
 		fmt.Fprintf(gin.DefaultWriter, "limit: %v\n", limit)
+		limitInSql, limitArgs, err := util.ParseLimit(limit)
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
 
-		lastManagedClusterName := ""
-		lastManagedClusterUID := uuid.MustParse("00000000-0000-0000-0000-000000000000")
-
+		var lastManagedCluster *util.ContinueToken
 		continueToken := ginCtx.Query("continue")
 		if continueToken != "" {
-			var lastManagedClusterUIDStr string
-			lastManagedClusterName, lastManagedClusterUIDStr, err = util.DecodeContinue(continueToken)
-			if err != nil {
-				fmt.Fprintf(gin.DefaultWriter, "failed to decode continue token: %s\n", err.Error())
-				return
+			lastManagedCluster, err = util.DecodeSortedContinue(continueToken, order.SortBy)
+			if err == nil {
+				_, err = uuid.Parse(lastManagedCluster.LastUID)
 			}
-			lastManagedClusterUID, err = uuid.Parse(lastManagedClusterUIDStr)
 			if err != nil {
-				fmt.Println("Error parsing UUID:", err)
+				fmt.Fprintf(gin.DefaultWriter, "failed to decode continue token: %s\n", err.Error())
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid continue token: %v", err))
 				return
 			}
+			fmt.Fprintf(gin.DefaultWriter,
+				"last returned managed cluster name: %s, last returned managed cluster UID: %s\n",
+				lastManagedCluster.LastName,
+				lastManagedCluster.LastUID)
 		}
 
-		fmt.Fprintf(gin.DefaultWriter,
-			"last returned managed cluster name: %s, last returned managed cluster UID: %s\n",
-			lastManagedClusterName,
-			lastManagedClusterUID)
-
-		// build query condition for paging
-		LastResourceCompareCondition := fmt.Sprintf(
-			"(payload -> 'metadata' ->> 'name', cluster_id) > ('%s', '%s') ",
-			lastManagedClusterName,
-			lastManagedClusterUID)
-
-		// managed cluster list query order by name and uid with limit if set
-		managedClusterListQuery := "SELECT payload FROM status.managed_clusters WHERE deleted_at is NULL AND " +
-			LastResourceCompareCondition +
-			selectorInSql +
-			" ORDER BY (payload -> 'metadata' ->> 'name', cluster_id)"
-
-		// add limit
-		if limit != "" {
-			managedClusterListQuery += fmt.Sprintf(" LIMIT %s", limit)
+		_, watch := ginCtx.GetQuery("watch")
+		verb := "list"
+		if watch {
+			verb = "watch"
+		}
+		// only the managed clusters of the hubs the user is allowed to access are listed
+		filter, err := authorization.NewFilter(ginCtx, verb, authorization.ManagedClusters, "")
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the managed clusters: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		filterInSql, filterArgs := filter.Condition("leaf_hub_name", "")
+
+		// the conditions are applied to both the list and the last managed cluster, so the continue token isn't
+		// returned with the last visible managed cluster
+		conditionsInSql := selectorInSql + fieldSelectorInSql + filterInSql
+		conditionArgs := append(append(append([]interface{}{}, selectorArgs...), fieldSelectorArgs...), filterArgs...)
+
+		// managed cluster list query after the last returned managed cluster, ordered by the sort key, name and uid
+		afterInSql, afterArgs := order.After(lastManagedCluster)
+		managedClusterListQuery := "SELECT payload FROM status.managed_clusters WHERE deleted_at is NULL" +
+			afterInSql +
+			conditionsInSql +
+			order.OrderBy(false) +
+			limitInSql
+		listArgs := append(append(append([]interface{}{}, afterArgs...), conditionArgs...), limitArgs...)
+
+		fmt.Fprintf(gin.DefaultWriter, "managedcluster list query: %v, args: %v\n", managedClusterListQuery, listArgs)
+
+		if watch {
+			handleRowsForWatch(ginCtx, managedClusterListQuery, listArgs)
+			return
 		}
 
-		fmt.Fprintf(gin.DefaultWriter, "managedcluster list query: %v\n", managedClusterListQuery)
-
-		if _, watch := ginCtx.GetQuery("watch"); watch {
-			handleRowsForWatch(ginCtx, managedClusterListQuery)
+		// the export streams all the managed clusters of the list query
+		if contentType := util.ExportContentType(ginCtx); contentType != "" {
+			exportManagedClusters(ginCtx, contentType, managedClusterListQuery, listArgs,
+				customResourceColumnDefinitions)
 			return
 		}
 
-		// last managed cluster query order by name and cluster id
-		lastManagedClusterQuery := "SELECT payload FROM status.managed_clusters WHERE deleted_at is NULL " +
-			"ORDER BY (payload -> 'metadata' ->> 'name', cluster_id) DESC LIMIT 1"
+		lastManagedClusterQuery := "SELECT payload FROM status.managed_clusters WHERE deleted_at is NULL" +
+			conditionsInSql +
+			order.OrderBy(true) +
+			" LIMIT 1"
 
-		handleRows(ginCtx, managedClusterListQuery, lastManagedClusterQuery,
+		handleRows(ginCtx, managedClusterListQuery, lastManagedClusterQuery, listArgs, conditionArgs, order,
 			customResourceColumnDefinitions)
 	}
 }
 
-func handleRowsForWatch(ginCtx *gin.Context, managedClusterListQuery string) {
+func handleRowsForWatch(ginCtx *gin.Context, managedClusterListQuery string, args []interface{}) {
 	writer := ginCtx.Writer
 	header := writer.Header()
 	header.Set("Transfer-Encoding", "chunked")
@@ -165,16 +214,16 @@ func handleRowsForWatch(ginCtx *gin.Context, managedClusterListQuery string) {
 				return
 			}
 
-			doHandleRowsForWatch(writer, managedClusterListQuery, preAddedManagedClusterNames)
+			doHandleRowsForWatch(writer, managedClusterListQuery, args, preAddedManagedClusterNames)
 		}
 	}
 }
 
-func doHandleRowsForWatch(writer io.Writer, managedClusterListQuery string,
+func doHandleRowsForWatch(writer io.Writer, managedClusterListQuery string, args []interface{},
 	preAddedManagedClusterNames set.Set,
 ) {
 	db := database.GetGorm()
-	rows, err := db.Raw(managedClusterListQuery).Rows()
+	rows, err := db.Raw(managedClusterListQuery, args...).Rows()
 	if err != nil {
 		fmt.Fprintf(gin.DefaultWriter, "error in quering managed cluster list: %v\n", err)
 	}
@@ -239,6 +288,7 @@ func doHandleRowsForWatch(writer io.Writer, managedClusterListQuery string,
 }
 
 func handleRows(ginCtx *gin.Context, managedClusterListQuery, lastManagedClusterQuery string,
+	listArgs, lastArgs []interface{}, order *util.ListOrder,
 	customResourceColumnDefinitions []apiextensionsv1.CustomResourceColumnDefinition,
 ) {
 	db := database.GetGorm()
@@ -247,7 +297,7 @@ func handleRows(ginCtx *gin.Context, managedClusterListQuery, lastManagedCluster
 	lastManagedCluster := &clusterv1.ManagedCluster{}
 
 	var payload []byte
-	err := db.Raw(lastManagedClusterQuery).Row().Scan(&payload)
+	err := db.Raw(lastManagedClusterQuery, lastArgs...).Row().Scan(&payload)
 	if err != nil && err != sql.ErrNoRows {
 		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
 		fmt.Fprintf(gin.DefaultWriter, "error in querying row: %v\n", err)
@@ -262,7 +312,7 @@ func handleRows(ginCtx *gin.Context, managedClusterListQuery, lastManagedCluster
 	}
 
 	// get hte managed cluster list
-	rows, err := db.Raw(managedClusterListQuery).Rows()
+	rows, err := db.Raw(managedClusterListQuery, listArgs...).Rows()
 	if err != nil {
 		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
 		fmt.Fprintf(gin.DefaultWriter, "error in querying managed clusters: %v\n", err)
@@ -304,7 +354,8 @@ func handleRows(ginCtx *gin.Context, managedClusterListQuery, lastManagedCluster
 		lastManagedClusterUID != "" &&
 		string(lastManagedCluster.GetUID()) != "" &&
 		lastManagedClusterUID != string(lastManagedCluster.GetUID()) {
-		continueToken, err := util.EncodeContinue(lastManagedClusterName, lastManagedClusterUID)
+		continueToken, err := order.ContinueToken("status.managed_clusters", lastManagedClusterName,
+			lastManagedClusterUID)
 		if err != nil {
 			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
 			fmt.Fprintf(gin.DefaultWriter, "error in encoding the continue token: %v\n", err)
@@ -345,6 +396,46 @@ func handleRows(ginCtx *gin.Context, managedClusterListQuery, lastManagedCluster
 	ginCtx.JSON(http.StatusOK, managedClusterList)
 }
 
+// exportManagedClusters streams the managed clusters as the rows of the csv or xlsx, the columns are the name and the
+// additional printer columns of the managed cluster CRD
+func exportManagedClusters(ginCtx *gin.Context, contentType, managedClusterListQuery string, args []interface{},
+	customResourceColumnDefinitions []apiextensionsv1.CustomResourceColumnDefinition,
+) {
+	rows, err := database.GetGorm().Raw(managedClusterListQuery, args...).Rows()
+	if err != nil {
+		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+		fmt.Fprintf(gin.DefaultWriter, "error in querying managed clusters: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	exporter, err := util.NewExporter(ginCtx, contentType, "managedclusters",
+		util.ExportColumns(false, customResourceColumnDefinitions))
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in exporting managed clusters: %v\n", err)
+		return
+	}
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in scanning a managed cluster: %v\n", err)
+			return
+		}
+		managedCluster := map[string]interface{}{}
+		if err := json.Unmarshal(payload, &managedCluster); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error to unmarshal payload to managedCluster: %v\n", err)
+			continue
+		}
+		if err := exporter.Export(managedCluster); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in exporting managed clusters: %v\n", err)
+			return
+		}
+	}
+	if err := exporter.Close(); err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in exporting managed clusters: %v\n", err)
+	}
+}
+
 func wrapObjectsInList(managedClusters []clusterv1.ManagedCluster) (*corev1.List, error) {
 	list := &corev1.List{
 		TypeMeta: metav1.TypeMeta{
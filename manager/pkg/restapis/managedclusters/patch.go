@@ -13,6 +13,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
 	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
 )
@@ -31,7 +34,7 @@ type patch struct {
 
 // PatchManagedCluster godoc
 // @summary patch managed cluster label
-// @description patch label for a given managed cluster
+// @description patch label for a given managed cluster, the labels owned by the managed hubs can't be patched
 // @accept json
 // @produce json
 // @param        clusterID    path    string    true    "Managed Cluster ID"
@@ -53,8 +56,10 @@ func PatchManagedCluster() gin.HandlerFunc {
 
 		db := database.GetGorm()
 		var leafHubName, managedClusterName string
-		if err := db.Raw(`SELECT leaf_hub_name, payload->'metadata'->>'name' FROM status.managed_clusters 
-			WHERE cluster_id = ?`, clusterID).Row().Scan(&leafHubName, &managedClusterName); err != nil {
+		var labelsPayload []byte
+		if err := db.Raw(`SELECT leaf_hub_name, payload->'metadata'->>'name', payload->'metadata'->'labels'
+			FROM status.managed_clusters WHERE cluster_id = ?`, clusterID).Row().Scan(&leafHubName,
+			&managedClusterName, &labelsPayload); err != nil {
 			fmt.Fprintf(gin.DefaultWriter, "failed to get leaf hub and manged cluster name: %s\n", err.Error())
 			return
 		}
@@ -62,6 +67,10 @@ func PatchManagedCluster() gin.HandlerFunc {
 		fmt.Fprintf(gin.DefaultWriter, "patch for managed cluster: %s -leaf hub: %s\n",
 			managedClusterName, leafHubName)
 
+		if !authorization.AuthorizeOrAbort(ginCtx, "patch", authorization.ManagedClusters, leafHubName, "") {
+			return
+		}
+
 		var patches []patch
 
 		err := ginCtx.BindJSON(&patches)
@@ -75,6 +84,9 @@ func PatchManagedCluster() gin.HandlerFunc {
 			fmt.Fprintf(gin.DefaultWriter, "failed to get labels: %s\n", err.Error())
 			return
 		}
+		if !checkLabelOwnersOrAbort(ginCtx, labelsToAdd, labelsToRemove) {
+			return
+		}
 
 		fmt.Fprintf(gin.DefaultWriter, "labels to add: %v\n", labelsToAdd)
 		fmt.Fprintf(gin.DefaultWriter, "labels to remove: %v\n", labelsToRemove)
@@ -91,9 +103,20 @@ func PatchManagedCluster() gin.HandlerFunc {
 			retryAttempts--
 		}
 
+		labels := map[string]string{}
+		if len(labelsPayload) > 0 {
+			if e := json.Unmarshal(labelsPayload, &labels); e != nil {
+				fmt.Fprintf(gin.DefaultWriter, "failed to unmarshal the labels of the managed cluster: %v\n", e)
+			}
+		}
+		util.RecordAudit(ginCtx, "patch", authorization.ManagedClusters, patches, []audit.Object{
+			labelsAuditObject(clusterID, managedClusterName, leafHubName, labels, labelsToAdd, labelsToRemove),
+		}, err)
+
 		if err != nil {
 			ginCtx.String(http.StatusInternalServerError, "internal error")
 			fmt.Fprintf(gin.DefaultWriter, "error in updating managed cluster labels: %v\n", err)
+			return
 		}
 
 		ginCtx.String(http.StatusOK, "managed cluster label patched")
@@ -106,7 +129,6 @@ func updateLabels(clusterID, leafHubName, managedClusterName string, labelsToAdd
 	if len(labelsToAdd) == 0 && len(labelsToRemove) == 0 {
 		return nil
 	}
-	db := database.GetGorm()
 	conn := database.GetConn()
 
 	err := database.Lock(conn)
@@ -115,8 +137,16 @@ func updateLabels(clusterID, leafHubName, managedClusterName string, labelsToAdd
 	}
 	defer database.Unlock(conn)
 
+	return mergeLabels(database.GetGorm(), clusterID, leafHubName, managedClusterName, labelsToAdd, labelsToRemove)
+}
+
+// mergeLabels merges the labels to add and remove into the row of the cluster, the db is either the connection or the
+// transaction of the bulk patch.
+func mergeLabels(db *gorm.DB, clusterID, leafHubName, managedClusterName string, labelsToAdd map[string]string,
+	labelsToRemove map[string]struct{},
+) error {
 	managedClusterLabels := []models.ManagedClusterLabel{}
-	err = db.Where(models.ManagedClusterLabel{ID: clusterID}).Find(&managedClusterLabels).Error
+	err := db.Where(models.ManagedClusterLabel{ID: clusterID}).Find(&managedClusterLabels).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return fmt.Errorf("failed to read from managed_clusters_labels: %w", err)
 	}
@@ -154,7 +184,7 @@ func updateLabels(clusterID, leafHubName, managedClusterName string, labelsToAdd
 	}
 	existVersion := managedClusterLabels[0].Version
 
-	err = updateRow(clusterID, labelsToAdd, existLabels, labelsToRemove,
+	err = updateRow(db, clusterID, labelsToAdd, existLabels, labelsToRemove,
 		getMap(existLabelsToRemoveSlice), existVersion)
 	if err != nil {
 		return fmt.Errorf("failed to update managed_clusters_labels table: %w", err)
@@ -168,7 +198,7 @@ func updateLabels(clusterID, leafHubName, managedClusterName string, labelsToAdd
 	return nil
 }
 
-func updateRow(clusterID string, labelsToAdd, existLabelsToAdd map[string]string,
+func updateRow(db *gorm.DB, clusterID string, labelsToAdd, existLabelsToAdd map[string]string,
 	labelsToRemove, existLabelsToRemove map[string]struct{}, existVersion int,
 ) error {
 	newLabelsToAdd := make(map[string]string)
@@ -194,7 +224,6 @@ func updateRow(clusterID string, labelsToAdd, existLabelsToAdd map[string]string
 		newLabelsToAdd[key] = value
 	}
 
-	db := database.GetGorm()
 	newLabelsToAddPayload, err := json.Marshal(newLabelsToAdd)
 	if err != nil {
 		return err
@@ -222,6 +251,28 @@ func updateRow(clusterID string, labelsToAdd, existLabelsToAdd map[string]string
 	return nil
 }
 
+// labelsAuditObject returns the audited cluster with its labels before and after the patch
+func labelsAuditObject(clusterID, clusterName, leafHubName string, labels, labelsToAdd map[string]string,
+	labelsToRemove map[string]struct{},
+) audit.Object {
+	patchedLabels := map[string]string{}
+	for key, value := range labels {
+		if _, removed := labelsToRemove[key]; !removed {
+			patchedLabels[key] = value
+		}
+	}
+	for key, value := range labelsToAdd {
+		patchedLabels[key] = value
+	}
+	return audit.Object{
+		ID:          clusterID,
+		Name:        clusterName,
+		LeafHubName: leafHubName,
+		Before:      labels,
+		After:       patchedLabels,
+	}
+}
+
 func getMap(aSlice []string) map[string]struct{} {
 	mapToReturn := make(map[string]struct{}, len(aSlice))
 
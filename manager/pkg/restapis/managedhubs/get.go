@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedhubs
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+)
+
+// GetManagedHub godoc
+// @summary get managed hub
+// @description get the managed hub with the heartbeat, the console and grafana URLs and the number of the clusters
+// @accept json
+// @produce json
+// @param        name    path    string    true    "Managed hub name"
+// @success      200  {object}     ManagedHub
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedhub/{name} [get]
+func GetManagedHub() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		name := ginCtx.Param("name")
+		if !authorization.AuthorizeOrAbort(ginCtx, "get", authorization.ManagedHubs, name, "") {
+			return
+		}
+		hubs, err := queryManagedHubs(managedHubsQuery+" WHERE hubs.leaf_hub_name = ?", name)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, queryManagedHubsFailureMsg, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		if len(hubs) == 0 {
+			ginCtx.String(http.StatusNotFound, fmt.Sprintf("managed hub %s not found", name))
+			return
+		}
+		ginCtx.JSON(http.StatusOK, hubs[0])
+	}
+}
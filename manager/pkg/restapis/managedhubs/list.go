@@ -0,0 +1,160 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedhubs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/schedules"
+	"github.com/stolostron/multicluster-global-hub/pkg/bundle/cluster"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+)
+
+const (
+	serverInternalErrorMsg     = "internal error"
+	queryManagedHubsFailureMsg = "error in querying managed hubs: %v\n"
+	// the hub without the heartbeat hasn't reported the status yet
+	hubStatusUnknown = "unknown"
+
+	// managedHubsQuery joins the hubs reported by the heartbeat or the hub cluster info, the inactive hub is still
+	// listed with the soft deleted cluster info. The clusters are counted from the active managed clusters.
+	managedHubsQuery = `SELECT hubs.leaf_hub_name, lh.cluster_id, lh.payload, hb.status, hb.last_timestamp,
+			COALESCE(mc.total, 0), COALESCE(mc.available, 0)
+		FROM (
+			SELECT leaf_hub_name FROM status.leaf_hub_heartbeats
+			UNION
+			SELECT leaf_hub_name FROM status.leaf_hubs WHERE deleted_at IS NULL
+		) hubs
+		LEFT JOIN status.leaf_hub_heartbeats hb ON hb.leaf_hub_name = hubs.leaf_hub_name
+		LEFT JOIN LATERAL (
+			SELECT cluster_id, payload FROM status.leaf_hubs
+			WHERE leaf_hub_name = hubs.leaf_hub_name
+			ORDER BY deleted_at IS NOT NULL, updated_at DESC
+			LIMIT 1
+		) lh ON TRUE
+		LEFT JOIN (
+			SELECT leaf_hub_name, count(*) AS total,
+				count(*) FILTER (WHERE payload -> 'status' -> 'conditions' @>
+					'[{"type": "ManagedClusterConditionAvailable", "status": "True"}]') AS available
+			FROM status.managed_clusters
+			WHERE deleted_at IS NULL
+			GROUP BY leaf_hub_name
+		) mc ON mc.leaf_hub_name = hubs.leaf_hub_name`
+)
+
+// ManagedHub is the managed hub with the heartbeat and the number of its managed clusters
+type ManagedHub struct {
+	Name       string `json:"name"`
+	ClusterID  string `json:"clusterId,omitempty"`
+	ConsoleURL string `json:"consoleURL,omitempty"`
+	GrafanaURL string `json:"grafanaURL,omitempty"`
+	// Status is active or inactive by the heartbeat, it's unknown if the heartbeat isn't received
+	Status                   string     `json:"status"`
+	LastHeartbeat            *time.Time `json:"lastHeartbeat,omitempty"`
+	ManagedClusters          int64      `json:"managedClusters"`
+	AvailableManagedClusters int64      `json:"availableManagedClusters"`
+	// Deferral is set while the spec changes of the hub are deferred by its maintenance windows or change freezes
+	Deferral *schedules.Deferral `json:"deferral,omitempty"`
+}
+
+type ManagedHubList struct {
+	Items []ManagedHub `json:"items"`
+}
+
+// ListManagedHubs godoc
+// @summary list managed hubs
+// @description list the managed hubs with the heartbeat, the console and grafana URLs and the number of the clusters
+// @accept json
+// @produce json
+// @param        status    query     string  false  "only list the managed hubs of the status, active or inactive"
+// @success      200  {object}    ManagedHubList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedhubs [get]
+func ListManagedHubs() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		filter, err := authorization.NewFilter(ginCtx, "list", authorization.ManagedHubs, "")
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the managed hubs: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		filterInSql, args := filter.Condition("hubs.leaf_hub_name", "")
+
+		query := managedHubsQuery + " WHERE TRUE" + filterInSql
+		if status := ginCtx.Query("status"); status != "" {
+			query += " AND hb.status = ?"
+			args = append(args, status)
+		}
+		query += " ORDER BY hubs.leaf_hub_name"
+
+		hubs, err := queryManagedHubs(query, args...)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, queryManagedHubsFailureMsg, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		ginCtx.JSON(http.StatusOK, ManagedHubList{Items: hubs})
+	}
+}
+
+// Query returns the managed hubs of the condition appended to the managedHubsQuery, e.g. " WHERE hubs.leaf_hub_name IN ?"
+func Query(condition string, args ...interface{}) ([]ManagedHub, error) {
+	return queryManagedHubs(managedHubsQuery+condition, args...)
+}
+
+func queryManagedHubs(query string, args ...interface{}) ([]ManagedHub, error) {
+	rows, err := database.GetGorm().Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hubSchedules, err := schedules.Load(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	hubs := []ManagedHub{}
+	for rows.Next() {
+		var clusterID, status *string
+		var payload []byte
+		hub := ManagedHub{}
+		if err := rows.Scan(&hub.Name, &clusterID, &payload, &status, &hub.LastHeartbeat, &hub.ManagedClusters,
+			&hub.AvailableManagedClusters); err != nil {
+			return nil, err
+		}
+		if clusterID != nil {
+			hub.ClusterID = *clusterID
+		}
+		hub.Status = hubStatusUnknown
+		if status != nil {
+			hub.Status = *status
+		}
+		if len(payload) > 0 {
+			info := &cluster.HubClusterInfo{}
+			if err := json.Unmarshal(payload, info); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal the cluster info of the hub %s: %w", hub.Name, err)
+			}
+			hub.ConsoleURL = info.ConsoleURL
+			hub.GrafanaURL = info.GrafanaURL
+		}
+		if deferral := hubSchedules.Deferral(hub.Name, now); deferral.Deferred {
+			hub.Deferral = &deferral
+		}
+		hubs = append(hubs, hub)
+	}
+	return hubs, rows.Err()
+}
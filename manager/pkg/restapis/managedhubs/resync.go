@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedhubs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/hubmanagement"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
+)
+
+// ResyncManagedHub godoc
+// @summary resync managed hub
+// @description request the managed hub to resend the status of the hub info, managed clusters and local policies
+// @accept json
+// @produce json
+// @param        name    path    string    true    "Managed hub name"
+// @success      202
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedhub/{name}/resync [post]
+func ResyncManagedHub() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		name := ginCtx.Param("name")
+		if !authorization.AuthorizeOrAbort(ginCtx, "create", authorization.Resync, name, "") {
+			return
+		}
+		hubs, err := queryManagedHubs(managedHubsQuery+" WHERE hubs.leaf_hub_name = ?", name)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, queryManagedHubsFailureMsg, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		if len(hubs) == 0 {
+			ginCtx.String(http.StatusNotFound, fmt.Sprintf("managed hub %s not found", name))
+			return
+		}
+
+		err = hubmanagement.Resync(ginCtx.Request.Context(), name)
+		util.RecordAudit(ginCtx, "create", authorization.Resync, nil, []audit.Object{
+			{Name: name, LeafHubName: name},
+		}, err)
+		if errors.Is(err, hubmanagement.ErrHubManagementNotStarted) {
+			ginCtx.String(http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to resync the managed hub %s: %v\n", name, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		ginCtx.String(http.StatusAccepted, fmt.Sprintf("the resync is requested for the managed hub %s", name))
+	}
+}
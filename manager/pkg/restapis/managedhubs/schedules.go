@@ -0,0 +1,217 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package managedhubs
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authentication"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/schedules"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+// HubScheduleRequest is the maintenance window or change freeze of the hub. The spec changes of the hub are only sent
+// while any of its windows is open and none of its freezes is active.
+type HubScheduleRequest struct {
+	// Type is window or freeze
+	Type string `json:"type" binding:"required"`
+	// Cron is the standard cron expression of the starts, e.g. "0 22 * * 6"
+	Cron string `json:"cron" binding:"required"`
+	// Duration is how long the schedule lasts from each start, e.g. "6h"
+	Duration string `json:"duration" binding:"required"`
+	// Timezone is the IANA timezone of the cron expression, it's UTC by default
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// HubSchedule is the schedule of the hub and whether it's active now
+type HubSchedule struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Cron      string    `json:"cron"`
+	Duration  string    `json:"duration"`
+	Timezone  string    `json:"timezone,omitempty"`
+	Active    bool      `json:"active"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// HubScheduleList is the schedules of the hub with the deferral of its spec changes
+type HubScheduleList struct {
+	Items    []HubSchedule      `json:"items"`
+	Deferral schedules.Deferral `json:"deferral"`
+}
+
+// ListHubSchedules godoc
+// @summary list the schedules of the managed hub
+// @description list the maintenance windows and change freezes of the managed hub, and whether its spec changes are
+// @description deferred by them now
+// @accept json
+// @produce json
+// @param        name    path    string    true    "Managed hub name"
+// @success      200  {object}     HubScheduleList
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedhub/{name}/schedules [get]
+func ListHubSchedules() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		name := ginCtx.Param("name")
+		if !authorization.AuthorizeOrAbort(ginCtx, "list", authorization.Schedules, name, "") {
+			return
+		}
+		rows := []models.HubSchedule{}
+		if err := database.GetGorm().WithContext(ginCtx.Request.Context()).Where("leaf_hub_name = ?", name).
+			Order("name").Find(&rows).Error; err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the schedules of the managed hub %s: %v\n", name, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		now := time.Now()
+		parsed := []*schedules.Schedule{}
+		resp := HubScheduleList{Items: make([]HubSchedule, 0, len(rows))}
+		for i := range rows {
+			row := &rows[i]
+			item := HubSchedule{
+				Name:      row.Name,
+				Type:      row.Type,
+				Cron:      row.Cron,
+				Duration:  (time.Duration(row.DurationSeconds) * time.Second).String(),
+				Timezone:  row.Timezone,
+				UpdatedBy: row.UpdatedBy,
+				UpdatedAt: row.UpdatedAt,
+			}
+			// the invalid schedule written to the table directly is listed, but it doesn't defer the changes
+			if schedule, err := schedules.Parse(row); err == nil {
+				item.Active = schedule.ActiveAt(now)
+				parsed = append(parsed, schedule)
+			}
+			resp.Items = append(resp.Items, item)
+		}
+		resp.Deferral = schedules.HubSchedules{name: parsed}.Deferral(name, now)
+		ginCtx.JSON(http.StatusOK, resp)
+	}
+}
+
+// PutHubSchedule godoc
+// @summary create or update the schedule of the managed hub
+// @description create or update the maintenance window or change freeze of the managed hub. The spec changes of the
+// @description hub, including the labels of its managed clusters and the migrations, are deferred until any of its
+// @description windows is open and none of its freezes is active.
+// @accept json
+// @produce json
+// @param        name        path    string              true    "Managed hub name"
+// @param        schedule    path    string              true    "Schedule name"
+// @param        body        body    HubScheduleRequest  true    "the maintenance window or change freeze"
+// @success      200
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedhub/{name}/schedule/{schedule} [put]
+func PutHubSchedule() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		name, scheduleName := ginCtx.Param("name"), ginCtx.Param("schedule")
+		if !authorization.AuthorizeOrAbort(ginCtx, "update", authorization.Schedules, name, "") {
+			return
+		}
+		request := &HubScheduleRequest{}
+		if err := ginCtx.ShouldBindJSON(request); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid schedule: %v", err))
+			return
+		}
+		duration, err := time.ParseDuration(request.Duration)
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid duration %q: %v", request.Duration, err))
+			return
+		}
+		row := &models.HubSchedule{
+			LeafHubName:     name,
+			Name:            scheduleName,
+			Type:            request.Type,
+			Cron:            request.Cron,
+			DurationSeconds: int64(duration.Seconds()),
+			Timezone:        request.Timezone,
+			UpdatedBy:       ginCtx.GetString(authentication.UserKey),
+		}
+		if _, err := schedules.Parse(row); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid schedule: %v", err))
+			return
+		}
+
+		err = database.GetGorm().WithContext(ginCtx.Request.Context()).Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "leaf_hub_name"}, {Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"type", "cron", "duration_seconds", "timezone", "updated_by", "updated_at",
+			}),
+		}).Create(row).Error
+		util.RecordAudit(ginCtx, "update", authorization.Schedules, request, []audit.Object{
+			{Name: scheduleName, LeafHubName: name},
+		}, err)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to update the schedule %s of the managed hub %s: %v\n",
+				scheduleName, name, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		ginCtx.String(http.StatusOK, fmt.Sprintf("the schedule %s is updated for the managed hub %s", scheduleName,
+			name))
+	}
+}
+
+// DeleteHubSchedule godoc
+// @summary delete the schedule of the managed hub
+// @description delete the maintenance window or change freeze of the managed hub, the deferred spec changes are
+// @description released if the hub isn't deferred by its other schedules
+// @accept json
+// @produce json
+// @param        name        path    string    true    "Managed hub name"
+// @param        schedule    path    string    true    "Schedule name"
+// @success      200
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedhub/{name}/schedule/{schedule} [delete]
+func DeleteHubSchedule() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		name, scheduleName := ginCtx.Param("name"), ginCtx.Param("schedule")
+		if !authorization.AuthorizeOrAbort(ginCtx, "delete", authorization.Schedules, name, "") {
+			return
+		}
+		result := database.GetGorm().WithContext(ginCtx.Request.Context()).
+			Where("leaf_hub_name = ? AND name = ?", name, scheduleName).Delete(&models.HubSchedule{})
+		util.RecordAudit(ginCtx, "delete", authorization.Schedules, nil, []audit.Object{
+			{Name: scheduleName, LeafHubName: name},
+		}, result.Error)
+		if result.Error != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to delete the schedule %s of the managed hub %s: %v\n",
+				scheduleName, name, result.Error)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		if result.RowsAffected == 0 {
+			ginCtx.String(http.StatusNotFound, fmt.Sprintf("schedule %s of the managed hub %s not found",
+				scheduleName, name))
+			return
+		}
+		ginCtx.String(http.StatusOK, fmt.Sprintf("the schedule %s is deleted for the managed hub %s", scheduleName,
+			name))
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package policies
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+)
+
+const (
+	complianceHistoryDateFormat = "2006-01-02"
+	// the compliance history of the last defaultComplianceHistoryDays days is returned if the startDate isn't set
+	defaultComplianceHistoryDays = 30
+
+	complianceHistoryQuery = `SELECT h.policy_id, p.payload -> 'metadata' ->> 'name' AS policy_name,
+			p.payload -> 'metadata' ->> 'namespace' AS policy_namespace, h.leaf_hub_name, h.cluster_name,
+			h.compliance_date, h.compliance, h.compliance_changed_frequency
+		FROM history.compliance h
+		LEFT JOIN spec.policies p ON p.id = h.policy_id
+		WHERE h.compliance_date BETWEEN ? AND ?`
+)
+
+// PolicyComplianceHistory is the compliance of the global policy on the managed cluster for a day
+type PolicyComplianceHistory struct {
+	PolicyID         string `json:"policyId"`
+	PolicyName       string `json:"policyName,omitempty"`
+	PolicyNamespace  string `json:"policyNamespace,omitempty"`
+	LeafHubName      string `json:"leafHubName"`
+	ClusterName      string `json:"clusterName"`
+	Date             string `json:"date"`
+	Compliance       string `json:"compliance"`
+	ChangedFrequency int    `json:"changedFrequency"`
+}
+
+// PolicyComplianceHistoryList is the compliance history of the global policies between the start and end date
+type PolicyComplianceHistoryList struct {
+	StartDate string                    `json:"startDate"`
+	EndDate   string                    `json:"endDate"`
+	Items     []PolicyComplianceHistory `json:"items"`
+}
+
+// GetPolicyComplianceHistory godoc
+// @summary get policy compliance history
+// @description get the daily compliance history of a given global policy
+// @accept json
+// @produce json
+// @param        policyID     path     string  true   "Policy ID"
+// @param        startDate    query    string  false  "the first date of the history, in the format of YYYY-MM-DD"
+// @param        endDate      query    string  false  "the last date of the history, in the format of YYYY-MM-DD"
+// @param        hub          query    string  false  "only return the history of the managed hub"
+// @param        cluster      query    string  false  "only return the history of the managed cluster"
+// @success      200  {object}  PolicyComplianceHistoryList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /policy/{policyID}/compliancehistory [get]
+func GetPolicyComplianceHistory() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		policyID := ginCtx.Param("policyID")
+		startDate, endDate, err := parseComplianceHistoryDates(ginCtx)
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filterInSql, filterArgs, ok := complianceHistoryFilter(ginCtx)
+		if !ok {
+			return
+		}
+		query := complianceHistoryQuery + filterInSql + " AND h.policy_id = ?"
+		args := append([]interface{}{startDate, endDate}, filterArgs...)
+		args = append(args, policyID)
+		if hub := ginCtx.Query("hub"); hub != "" {
+			query += " AND h.leaf_hub_name = ?"
+			args = append(args, hub)
+		}
+		if cluster := ginCtx.Query("cluster"); cluster != "" {
+			query += " AND h.cluster_name = ?"
+			args = append(args, cluster)
+		}
+		query += " ORDER BY h.compliance_date, h.leaf_hub_name, h.cluster_name"
+
+		handleComplianceHistory(ginCtx, startDate, endDate, query, args)
+	}
+}
+
+// ListPolicyComplianceChanges godoc
+// @summary list policy compliance changes
+// @description list the compliance of the global policies which are changed between the start and end date
+// @accept json
+// @produce json
+// @param        startDate    query    string  false  "the first date of the changes, in the format of YYYY-MM-DD"
+// @param        endDate      query    string  false  "the last date of the changes, in the format of YYYY-MM-DD"
+// @param        hub          query    string  false  "only return the changes of the managed hub"
+// @success      200  {object}  PolicyComplianceHistoryList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /policies/compliancechanges [get]
+func ListPolicyComplianceChanges() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		startDate, endDate, err := parseComplianceHistoryDates(ginCtx)
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filterInSql, filterArgs, ok := complianceHistoryFilter(ginCtx)
+		if !ok {
+			return
+		}
+		query := complianceHistoryQuery + filterInSql + " AND h.compliance_changed_frequency > 0"
+		args := append([]interface{}{startDate, endDate}, filterArgs...)
+		if hub := ginCtx.Query("hub"); hub != "" {
+			query += " AND h.leaf_hub_name = ?"
+			args = append(args, hub)
+		}
+		query += " ORDER BY h.compliance_date DESC, h.compliance_changed_frequency DESC, h.leaf_hub_name, h.cluster_name"
+
+		handleComplianceHistory(ginCtx, startDate, endDate, query, args)
+	}
+}
+
+// complianceHistoryFilter returns the condition of the history the user is allowed to get, which is either on the
+// allowed hubs or of the policies in the allowed namespaces. It writes the response if the authorization fails.
+func complianceHistoryFilter(ginCtx *gin.Context) (string, []interface{}, bool) {
+	filter, err := authorization.NewFilter(ginCtx, "get", authorization.Policies, policyNamespacesQuery)
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in authorizing the compliance history: %v\n", err)
+		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		return "", nil, false
+	}
+	filterInSql, filterArgs := filter.Condition("h.leaf_hub_name", "p."+policyNamespaceColumn)
+	return filterInSql, filterArgs, true
+}
+
+func handleComplianceHistory(ginCtx *gin.Context, startDate, endDate string, query string, args []interface{}) {
+	fmt.Fprintf(gin.DefaultWriter, "compliance history query: %v, args: %v\n", query, args)
+
+	rows, err := database.GetGorm().Raw(query, args...).Rows()
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, QueryComplianceHistoryFailureFormatMsg, err)
+		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		return
+	}
+	defer rows.Close()
+
+	historyList := PolicyComplianceHistoryList{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Items:     []PolicyComplianceHistory{},
+	}
+	for rows.Next() {
+		var policyName, policyNamespace *string
+		var date time.Time
+		record := PolicyComplianceHistory{}
+		if err := rows.Scan(&record.PolicyID, &policyName, &policyNamespace, &record.LeafHubName,
+			&record.ClusterName, &date, &record.Compliance, &record.ChangedFrequency); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, QueryComplianceHistoryFailureFormatMsg, err)
+			ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+			return
+		}
+		if policyName != nil {
+			record.PolicyName = *policyName
+		}
+		if policyNamespace != nil {
+			record.PolicyNamespace = *policyNamespace
+		}
+		record.Date = date.Format(complianceHistoryDateFormat)
+		historyList.Items = append(historyList.Items, record)
+	}
+
+	ginCtx.JSON(http.StatusOK, historyList)
+}
+
+// parseComplianceHistoryDates returns the startDate and endDate of the request, the endDate is today by default, and
+// the startDate is defaultComplianceHistoryDays days before the endDate by default.
+func parseComplianceHistoryDates(ginCtx *gin.Context) (string, string, error) {
+	endDate := time.Now()
+	if value := ginCtx.Query("endDate"); value != "" {
+		date, err := time.Parse(complianceHistoryDateFormat, value)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid endDate %q, the format should be YYYY-MM-DD", value)
+		}
+		endDate = date
+	}
+
+	startDate := endDate.AddDate(0, 0, -defaultComplianceHistoryDays)
+	if value := ginCtx.Query("startDate"); value != "" {
+		date, err := time.Parse(complianceHistoryDateFormat, value)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid startDate %q, the format should be YYYY-MM-DD", value)
+		}
+		startDate = date
+	}
+
+	if startDate.After(endDate) {
+		return "", "", fmt.Errorf("the startDate %s is after the endDate %s",
+			startDate.Format(complianceHistoryDateFormat), endDate.Format(complianceHistoryDateFormat))
+	}
+	return startDate.Format(complianceHistoryDateFormat), endDate.Format(complianceHistoryDateFormat), nil
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package policies
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+)
+
+const (
+	// complianceTimelineQuery counts the (policy, cluster) pairs of each period by their worst compliance in the
+	// period, so a cluster which is non compliant for a day is counted as non compliant for the week and month. The
+	// worst compliance follows the history.update_history_compliance_by_event: pending > unknown > non_compliant >
+	// compliant. The %s is replaced with the filter of the policy, cluster or hub.
+	complianceTimelineQuery = `WITH periods AS (
+			SELECT date_trunc(?, h.compliance_date)::date AS period, h.policy_id, h.cluster_id,
+				max(CASE h.compliance WHEN 'compliant' THEN 0 WHEN 'non_compliant' THEN 1 WHEN 'unknown' THEN 2
+					ELSE 3 END) AS severity,
+				sum(h.compliance_changed_frequency) AS changes
+			FROM history.local_compliance h
+			WHERE h.compliance_date BETWEEN ? AND ? AND %s
+			GROUP BY 1, 2, 3
+		)
+		SELECT period,
+			count(*) FILTER (WHERE severity = 0),
+			count(*) FILTER (WHERE severity = 3),
+			count(*) FILTER (WHERE severity = 2),
+			count(*) FILTER (WHERE severity = 1),
+			coalesce(sum(changes), 0)
+		FROM periods
+		GROUP BY period
+		ORDER BY period`
+
+	complianceTimelineCSV = "csv"
+)
+
+// complianceTimelineIntervals are the supported aggregations, they're the field names of the date_trunc
+var complianceTimelineIntervals = map[string]bool{"day": true, "week": true, "month": true}
+
+// CompliancePeriod is the compliance of the (policy, cluster) pairs in a period, which begins with the date. The
+// week begins on Monday.
+type CompliancePeriod struct {
+	Date         string  `json:"date"`
+	Compliant    int     `json:"compliant"`
+	Pending      int     `json:"pending"`
+	Unknown      int     `json:"unknown"`
+	NonCompliant int     `json:"nonCompliant"`
+	Changes      int     `json:"changes"`
+	Rate         float64 `json:"complianceRate"`
+}
+
+// ComplianceTimeline is the compliance of the local policies between the start and end date, aggregated by the
+// interval
+type ComplianceTimeline struct {
+	StartDate string             `json:"startDate"`
+	EndDate   string             `json:"endDate"`
+	Interval  string             `json:"interval"`
+	Items     []CompliancePeriod `json:"items"`
+}
+
+// GetPolicyComplianceTimeline godoc
+// @summary get local policy compliance timeline
+// @description get the compliance timeline of the policy on the managed clusters, aggregated by day, week or month
+// @accept json
+// @produce json,text/csv
+// @param        policyID     path     string  true   "Policy ID"
+// @param        startDate    query    string  false  "the first date of the timeline, in the format of YYYY-MM-DD"
+// @param        endDate      query    string  false  "the last date of the timeline, in the format of YYYY-MM-DD"
+// @param        interval     query    string  false  "the aggregation: day, week or month, it's day by default"
+// @param        format       query    string  false  "the format of the response: json or csv, it's json by default"
+// @success      200  {object}  ComplianceTimeline
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /localpolicy/{policyID}/compliancetimeline [get]
+func GetPolicyComplianceTimeline() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		policyID := ginCtx.Param("policyID")
+		if _, err := uuid.Parse(policyID); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid policy ID %q", policyID))
+			return
+		}
+		handleComplianceTimeline(ginCtx, "h.policy_id = ?", policyID, "policy-"+policyID)
+	}
+}
+
+// GetClusterComplianceTimeline godoc
+// @summary get managed cluster compliance timeline
+// @description get the compliance timeline of the local policies on the managed cluster, aggregated by day, week or
+// @description month
+// @accept json
+// @produce json,text/csv
+// @param        clusterID    path     string  true   "Managed cluster ID"
+// @param        startDate    query    string  false  "the first date of the timeline, in the format of YYYY-MM-DD"
+// @param        endDate      query    string  false  "the last date of the timeline, in the format of YYYY-MM-DD"
+// @param        interval     query    string  false  "the aggregation: day, week or month, it's day by default"
+// @param        format       query    string  false  "the format of the response: json or csv, it's json by default"
+// @success      200  {object}  ComplianceTimeline
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedcluster/{clusterID}/compliancetimeline [get]
+func GetClusterComplianceTimeline() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		clusterID := ginCtx.Param("clusterID")
+		if _, err := uuid.Parse(clusterID); err != nil {
+			ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid cluster ID %q", clusterID))
+			return
+		}
+		handleComplianceTimeline(ginCtx, "h.cluster_id = ?", clusterID, "cluster-"+clusterID)
+	}
+}
+
+// GetHubComplianceTimeline godoc
+// @summary get managed hub compliance timeline
+// @description get the compliance timeline of the local policies on the clusters of the managed hub, aggregated by
+// @description day, week or month
+// @accept json
+// @produce json,text/csv
+// @param        name         path     string  true   "Managed hub name"
+// @param        startDate    query    string  false  "the first date of the timeline, in the format of YYYY-MM-DD"
+// @param        endDate      query    string  false  "the last date of the timeline, in the format of YYYY-MM-DD"
+// @param        interval     query    string  false  "the aggregation: day, week or month, it's day by default"
+// @param        format       query    string  false  "the format of the response: json or csv, it's json by default"
+// @success      200  {object}  ComplianceTimeline
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /managedhub/{name}/compliancetimeline [get]
+func GetHubComplianceTimeline() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		name := ginCtx.Param("name")
+		if !authorization.AuthorizeOrAbort(ginCtx, "get", authorization.Policies, name, "") {
+			return
+		}
+		handleComplianceTimeline(ginCtx, "h.leaf_hub_name = ?", name, "hub-"+name)
+	}
+}
+
+func handleComplianceTimeline(ginCtx *gin.Context, filter string, value string, fileName string) {
+	startDate, endDate, err := parseComplianceHistoryDates(ginCtx)
+	if err != nil {
+		ginCtx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	interval := ginCtx.DefaultQuery("interval", "day")
+	if !complianceTimelineIntervals[interval] {
+		ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid interval %q, it should be day, week or month", interval))
+		return
+	}
+	format := ginCtx.DefaultQuery("format", "json")
+	if format != "json" && format != complianceTimelineCSV {
+		ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid format %q, it should be json or csv", format))
+		return
+	}
+
+	// only the local policies on the allowed hubs are counted
+	authorizationFilter, err := authorization.NewFilter(ginCtx, "get", authorization.Policies, "")
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in authorizing the compliance timeline: %v\n", err)
+		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		return
+	}
+	filterInSql, filterArgs := authorizationFilter.Condition("h.leaf_hub_name", "")
+
+	query := fmt.Sprintf(complianceTimelineQuery, filter+filterInSql)
+	args := append([]interface{}{interval, startDate, endDate, value}, filterArgs...)
+	fmt.Fprintf(gin.DefaultWriter, "compliance timeline query: %v, args: %v\n", query, args)
+
+	rows, err := database.GetGorm().Raw(query, args...).Rows()
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, QueryComplianceHistoryFailureFormatMsg, err)
+		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		return
+	}
+	defer rows.Close()
+
+	timeline := ComplianceTimeline{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Interval:  interval,
+		Items:     []CompliancePeriod{},
+	}
+	for rows.Next() {
+		var date time.Time
+		period := CompliancePeriod{}
+		if err := rows.Scan(&date, &period.Compliant, &period.Pending, &period.Unknown, &period.NonCompliant,
+			&period.Changes); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, QueryComplianceHistoryFailureFormatMsg, err)
+			ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+			return
+		}
+		period.Date = date.Format(complianceHistoryDateFormat)
+		if total := period.Compliant + period.Pending + period.Unknown + period.NonCompliant; total > 0 {
+			period.Rate = float64(period.Compliant) / float64(total)
+		}
+		timeline.Items = append(timeline.Items, period)
+	}
+
+	if format != complianceTimelineCSV {
+		ginCtx.JSON(http.StatusOK, timeline)
+		return
+	}
+	data, err := complianceTimelineToCSV(timeline)
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in writing the compliance timeline to csv: %v\n", err)
+		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		return
+	}
+	ginCtx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("compliance-%s-%s-%s.csv", fileName, startDate, endDate)))
+	ginCtx.Data(http.StatusOK, "text/csv", data)
+}
+
+func complianceTimelineToCSV(timeline ComplianceTimeline) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	if err := writer.Write([]string{
+		"date", "compliant", "pending", "unknown", "non_compliant", "changes", "compliance_rate",
+	}); err != nil {
+		return nil, err
+	}
+	for _, period := range timeline.Items {
+		if err := writer.Write([]string{
+			period.Date,
+			strconv.Itoa(period.Compliant),
+			strconv.Itoa(period.Pending),
+			strconv.Itoa(period.Unknown),
+			strconv.Itoa(period.NonCompliant),
+			strconv.Itoa(period.Changes),
+			strconv.FormatFloat(period.Rate, 'f', 4, 64),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
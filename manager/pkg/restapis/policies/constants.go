@@ -4,15 +4,22 @@
 package policies
 
 const (
-	ServerInternalErrorMsg                = "internal error"
-	QueryPolicyFailureFormatMsg           = "error in querying policy: %v\n"
-	QueryPoliciesFailureFormatMsg         = "error in querying policies: %v\n"
-	QueryPolicyComplianceFailureFormatMsg = "error in querying compliance status of a policy with UID: %v\n"
-	QueryPolicyMappingFailureFormatMsg    = "error in querying policy&placementbinding&placementrule mapping: %v\n"
+	ServerInternalErrorMsg                 = "internal error"
+	QueryPolicyFailureFormatMsg            = "error in querying policy: %v\n"
+	QueryPoliciesFailureFormatMsg          = "error in querying policies: %v\n"
+	QueryPolicyComplianceFailureFormatMsg  = "error in querying compliance status of a policy with UID: %v\n"
+	QueryPolicyMappingFailureFormatMsg     = "error in querying policy&placementbinding&placementrule mapping: %v\n"
+	QueryComplianceHistoryFailureFormatMsg = "error in querying compliance history: %v\n"
 )
 
 const (
-	policyQuery           = `SELECT payload FROM spec.policies WHERE deleted = FALSE AND id = ?`
+	policyQuery          = `SELECT payload FROM spec.policies WHERE deleted = FALSE AND id = ?`
+	policyNamespaceQuery = `SELECT payload -> 'metadata' ->> 'namespace' FROM spec.policies
+		WHERE deleted = FALSE AND id = ?`
+	// policyNamespacesQuery lists the namespaces of the policies to authorize the list by namespace
+	policyNamespacesQuery = `SELECT DISTINCT payload -> 'metadata' ->> 'namespace' FROM spec.policies
+		WHERE deleted = FALSE`
+	policyNamespaceColumn = "payload -> 'metadata' ->> 'namespace'"
 	policyComplianceQuery = `SELECT cluster_name,leaf_hub_name,compliance FROM status.compliance
 		WHERE policy_id = ? ORDER BY leaf_hub_name, cluster_name`
 	policyMappingQuery = `SELECT p.payload -> 'metadata' ->> 'name' AS policy,
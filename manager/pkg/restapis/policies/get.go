@@ -5,7 +5,9 @@ package policies
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -19,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
 )
@@ -27,7 +30,7 @@ import (
 // @summary get policy status
 // @description get status with a given policy
 // @accept json
-// @produce json
+// @produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @param        policyID    path    string    true    "Policy ID"
 // @success      200  {object}  policyv1.Policy
 // @failure      400
@@ -42,6 +45,26 @@ func GetPolicyStatus() gin.HandlerFunc {
 	return func(ginCtx *gin.Context) {
 		policyID := ginCtx.Param("policyID")
 		fmt.Fprintf(gin.DefaultWriter, "getting status for policy: %s\n", policyID)
+
+		var namespace string
+		err := database.GetGorm().Raw(policyNamespaceQuery, policyID).Row().Scan(&namespace)
+		if errors.Is(err, sql.ErrNoRows) {
+			ginCtx.String(http.StatusNotFound, "policy not found")
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, QueryPolicyFailureFormatMsg, err)
+			ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+			return
+		}
+		verb := "get"
+		if _, watch := ginCtx.GetQuery("watch"); watch {
+			verb = "watch"
+		}
+		if !authorization.AuthorizeOrAbort(ginCtx, verb, authorization.Policies, "", namespace) {
+			return
+		}
+
 		fmt.Fprintf(gin.DefaultWriter, "policy query with policy ID: %s\n", policyQuery)
 		fmt.Fprintf(gin.DefaultWriter, "policy compliance query with policy ID: %v\n", policyComplianceQuery)
 		fmt.Fprintf(gin.DefaultWriter, "policy&placementbinding&placementrule mapping query: %v\n", policyMappingQuery)
@@ -142,11 +165,17 @@ func handlePolicy(ginCtx *gin.Context, policyID, policyQuery, policyMappingQuery
 		policyQuery, policyMappingQuery, policyComplianceQuery)
 	if err != nil {
 		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		return
 	}
 
 	// no need to return unstrPolicy spec
 	delete(unstrPolicy.Object, "spec")
 
+	if contentType := util.ExportContentType(ginCtx); contentType != "" {
+		exportPolicyStatus(ginCtx, contentType, unstrPolicy, customResourceColumnDefinitions)
+		return
+	}
+
 	if util.ShouldReturnAsTable(ginCtx) {
 		fmt.Fprintf(gin.DefaultWriter, "returning policy as table...\n")
 
@@ -172,6 +201,41 @@ func handlePolicy(ginCtx *gin.Context, policyID, policyQuery, policyMappingQuery
 	ginCtx.JSON(http.StatusOK, unstrPolicy)
 }
 
+// exportPolicyStatus writes the compliance of the policy on each cluster as the rows of the csv or xlsx, the columns
+// of the policy are followed by the cluster and its compliance
+func exportPolicyStatus(ginCtx *gin.Context, contentType string, unstrPolicy *unstructured.Unstructured,
+	customResourceColumnDefinitions []apiextensionsv1.CustomResourceColumnDefinition,
+) {
+	statuses, _, err := unstructured.NestedSlice(unstrPolicy.Object, "status", "status")
+	if err != nil {
+		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		fmt.Fprintf(gin.DefaultWriter, "error in getting the cluster status of the policy: %v\n", err)
+		return
+	}
+
+	exporter, err := util.NewExporter(ginCtx, contentType, "policy-"+unstrPolicy.GetName(),
+		util.ExportColumns(true, customResourceColumnDefinitions), "Cluster", "Cluster Compliance")
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in exporting the policy status: %v\n", err)
+		return
+	}
+	for _, status := range statuses {
+		clusterStatus, ok := status.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		clusterName, _, _ := unstructured.NestedString(clusterStatus, "clustername")
+		compliance, _, _ := unstructured.NestedString(clusterStatus, "compliant")
+		if err := exporter.Export(unstrPolicy, clusterName, compliance); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in exporting the policy status: %v\n", err)
+			return
+		}
+	}
+	if err := exporter.Close(); err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in exporting the policy status: %v\n", err)
+	}
+}
+
 func queryPolicyStatus(policyID, policyQuery, policyMappingQuery,
 	policyComplianceQuery string,
 ) (*unstructured.Unstructured, error) {
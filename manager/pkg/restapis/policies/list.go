@@ -24,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
 	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
@@ -50,12 +51,26 @@ var (
 	customResourceColumnDefinitions = util.GetCustomResourceColumnDefinitions(crdName, policyv1.GroupVersion.Version)
 )
 
+// policyFields are the fields of the policies which can be selected and sorted by, the status.compliant is the
+// compliance state of the policy status, which is NonCompliant if any cluster is non compliant
+var policyFields = util.ListFields{
+	"metadata.name":      "payload -> 'metadata' ->> 'name'",
+	"metadata.namespace": "payload -> 'metadata' ->> 'namespace'",
+	"status.compliant": `CASE
+		WHEN EXISTS (SELECT 1 FROM status.compliance c WHERE c.policy_id = spec.policies.id
+			AND c.compliance = 'non_compliant') THEN 'NonCompliant'
+		WHEN EXISTS (SELECT 1 FROM status.compliance c WHERE c.policy_id = spec.policies.id) THEN 'Compliant'
+		END`,
+}
+
 // ListPolicies godoc
 // @summary list policies
 // @description list policies
 // @accept json
-// @produce json
+// @produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @param        labelSelector    query     string  false  "list policies by label selector"
+// @param        fieldSelector    query     string  false  "list policies by field selector"
+// @param        sortBy           query     string  false  "sort policies by the field, - for descending"
 // @param        limit            query     int     false  "maximum policy number to receive"
 // @param        continue         query     string  false  "continue token to request next request"
 // @success      200  {object}    policyv1.PolicyList
@@ -72,76 +87,117 @@ func ListPolicies() gin.HandlerFunc {
 		labelSelector := ginCtx.Query("labelSelector")
 
 		selectorInSql := ""
+		selectorArgs := []interface{}{}
+		var err error
 		if labelSelector != "" {
-			var err error
-			selectorInSql, err = util.ParseLabelSelector(labelSelector)
+			selectorInSql, selectorArgs, err = util.ParseLabelSelector(labelSelector)
 			if err != nil {
 				fmt.Fprintf(gin.DefaultWriter, "failed to parse label selector: %s\n", err.Error())
+				ginCtx.String(http.StatusBadRequest, err.Error())
 				return
 			}
 		}
 
 		fmt.Fprintf(gin.DefaultWriter, "parsed selector: %s\n", selectorInSql)
 
+		fieldSelectorInSql := ""
+		fieldSelectorArgs := []interface{}{}
+		if fieldSelector := ginCtx.Query("fieldSelector"); fieldSelector != "" {
+			fieldSelectorInSql, fieldSelectorArgs, err = util.ParseFieldSelector(fieldSelector, policyFields)
+			if err != nil {
+				ginCtx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		// the continue token is the policy ID, which is the uid of the policy
+		order, err := util.NewListOrder(ginCtx.Query("sortBy"), policyFields,
+			"payload -> 'metadata' ->> 'name'", "id::text")
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
 		limit := ginCtx.Query("limit")
 		fmt.Fprintf(gin.DefaultWriter, "limit: %v\n", limit)
+		limitInSql, limitArgs, err := util.ParseLimit(limit)
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
 
+		var lastPolicy *util.ContinueToken
 		continueToken := ginCtx.Query("continue")
-
-		lastPolicyName, lastPolicyUID := "", ""
 		if continueToken != "" {
 			fmt.Fprintf(gin.DefaultWriter, "continue: %v\n", continueToken)
 
-			var err error
-			lastPolicyName, lastPolicyUID, err = util.DecodeContinue(continueToken)
+			lastPolicy, err = util.DecodeSortedContinue(continueToken, order.SortBy)
 			if err != nil {
 				fmt.Fprintf(gin.DefaultWriter, "failed to decode continue token: %s\n", err.Error())
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid continue token: %v", err))
 				return
 			}
+			fmt.Fprintf(gin.DefaultWriter,
+				"last returned policy name: %s, last returned policy UID: %s\n",
+				lastPolicy.LastName,
+				lastPolicy.LastUID)
 		}
 
-		fmt.Fprintf(gin.DefaultWriter,
-			"last returned policy name: %s, last returned policy] UID: %s\n",
-			lastPolicyName,
-			lastPolicyUID)
-
-		// build query condition for paging
-		LastResourceCompareCondition := fmt.Sprintf(
-			"(payload -> 'metadata' ->> 'name', payload -> 'metadata' ->> 'uid') > ('%s', '%s') ",
-			lastPolicyName,
-			lastPolicyUID)
-
-		// policy list query order by name and uid
-		policyListQuery := "SELECT id, payload FROM spec.policies WHERE deleted = FALSE AND " +
-			LastResourceCompareCondition +
-			selectorInSql +
-			" ORDER BY (payload -> 'metadata' ->> 'name', payload -> 'metadata' ->> 'uid')"
-
-		// add limit
-		if limit != "" {
-			policyListQuery += fmt.Sprintf(" LIMIT %s", limit)
+		_, watch := ginCtx.GetQuery("watch")
+		verb := "list"
+		if watch {
+			verb = "watch"
 		}
-
-		// last policy order by name and uid query
-		lastPolicyQuery := "SELECT id, payload FROM spec.policies WHERE deleted = FALSE " +
-			"ORDER BY (payload -> 'metadata' ->> 'name', payload -> 'metadata' ->> 'uid') DESC LIMIT 1"
+		// only the policies in the namespaces the user is allowed to access are listed
+		filter, err := authorization.NewFilter(ginCtx, verb, authorization.Policies, policyNamespacesQuery)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the policies: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+			return
+		}
+		filterInSql, filterArgs := filter.Condition("", policyNamespaceColumn)
+
+		// the conditions are applied to both the list and the last policy, so the continue token isn't returned with
+		// the last visible policy
+		conditionsInSql := selectorInSql + fieldSelectorInSql + filterInSql
+		conditionArgs := append(append(append([]interface{}{}, selectorArgs...), fieldSelectorArgs...), filterArgs...)
+
+		// policy list query after the last returned policy, ordered by the sort key, name and uid
+		afterInSql, afterArgs := order.After(lastPolicy)
+		policyListQuery := "SELECT id, payload FROM spec.policies WHERE deleted = FALSE" +
+			afterInSql +
+			conditionsInSql +
+			order.OrderBy(false) +
+			limitInSql
+		listArgs := append(append(append([]interface{}{}, afterArgs...), conditionArgs...), limitArgs...)
+
+		lastPolicyQuery := "SELECT id, payload FROM spec.policies WHERE deleted = FALSE" +
+			conditionsInSql +
+			order.OrderBy(true) +
+			" LIMIT 1"
 
 		fmt.Fprintf(gin.DefaultWriter, "last policy query: %v\n", lastPolicyQuery)
-		fmt.Fprintf(gin.DefaultWriter, "policy list query: %v\n", policyListQuery)
+		fmt.Fprintf(gin.DefaultWriter, "policy list query: %v, args: %v\n", policyListQuery, listArgs)
 		fmt.Fprintf(gin.DefaultWriter, "policy compliance query with policy ID: %v\n", policyComplianceQuery)
 		fmt.Fprintf(gin.DefaultWriter, "policy&placementbinding&placementrule mapping query: %v\n", policyMappingQuery)
 
-		if _, watch := ginCtx.GetQuery("watch"); watch {
-			handlePoliciesForWatch(ginCtx, policyListQuery, policyMappingQuery, policyComplianceQuery)
+		if watch {
+			handlePoliciesForWatch(ginCtx, policyListQuery, listArgs, policyMappingQuery, policyComplianceQuery)
 			return
 		}
 
-		handlePolicies(ginCtx, policyListQuery, lastPolicyQuery, policyMappingQuery,
+		// the export streams all the policies of the list query
+		if contentType := util.ExportContentType(ginCtx); contentType != "" {
+			exportPolicies(ginCtx, contentType, policyListQuery, listArgs, policyMappingQuery, policyComplianceQuery)
+			return
+		}
+
+		handlePolicies(ginCtx, policyListQuery, lastPolicyQuery, listArgs, conditionArgs, order, policyMappingQuery,
 			policyComplianceQuery, customResourceColumnDefinitions)
 	}
 }
 
-func handlePoliciesForWatch(ginCtx *gin.Context, policyListQuery, policyMappingQuery,
+func handlePoliciesForWatch(ginCtx *gin.Context, policyListQuery string, args []interface{}, policyMappingQuery,
 	policyComplianceQuery string,
 ) {
 	writer := ginCtx.Writer
@@ -172,14 +228,15 @@ func handlePoliciesForWatch(ginCtx *gin.Context, policyListQuery, policyMappingQ
 				return
 			}
 
-			doHandlePoliciesForWatch(ctx, writer, policyListQuery, policyMappingQuery,
+			doHandlePoliciesForWatch(ctx, writer, policyListQuery, args, policyMappingQuery,
 				policyComplianceQuery, preAddedPolicies)
 		}
 	}
 }
 
 func doHandlePoliciesForWatch(ctx context.Context, writer gin.ResponseWriter,
-	policyListQuery, policyMappingQuery, policyComplianceQuery string, preAddedPolicies set.Set,
+	policyListQuery string, args []interface{}, policyMappingQuery, policyComplianceQuery string,
+	preAddedPolicies set.Set,
 ) {
 	var err error
 	policyMatches, err = getPolicyMatches(policyMappingQuery)
@@ -187,7 +244,7 @@ func doHandlePoliciesForWatch(ctx context.Context, writer gin.ResponseWriter,
 		fmt.Fprintf(gin.DefaultWriter, QueryPolicyMappingFailureFormatMsg, err)
 	}
 	db := database.GetGorm()
-	policyRows, err := db.Raw(policyListQuery).Rows()
+	policyRows, err := db.Raw(policyListQuery, args...).Rows()
 	if err != nil {
 		fmt.Fprintf(gin.DefaultWriter, QueryPoliciesFailureFormatMsg, err)
 	}
@@ -292,15 +349,15 @@ func sendPolicyWatchEvent(writer io.Writer, policy *policyv1.Policy, eventType,
 	}, writer)
 }
 
-func handlePolicies(ginCtx *gin.Context, policyListQuery, lastPolicyQuery,
-	policyMappingQuery, policyComplianceQuery string,
+func handlePolicies(ginCtx *gin.Context, policyListQuery, lastPolicyQuery string,
+	listArgs, lastArgs []interface{}, order *util.ListOrder, policyMappingQuery, policyComplianceQuery string,
 	customResourceColumnDefinitions []apiextensionsv1.CustomResourceColumnDefinition,
 ) {
 	db := database.GetGorm()
 	lastPolicy := &policyv1.Policy{}
 	lastPolicyID := ""
 	var lastPolicyPayload []byte
-	err := db.Raw(lastPolicyQuery).Row().Scan(&lastPolicyID, &lastPolicyPayload)
+	err := db.Raw(lastPolicyQuery, lastArgs...).Row().Scan(&lastPolicyID, &lastPolicyPayload)
 	if err != nil && err != sql.ErrNoRows {
 		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
 		fmt.Fprintf(gin.DefaultWriter, "error in querying last policy: %v\n", err)
@@ -320,7 +377,7 @@ func handlePolicies(ginCtx *gin.Context, policyListQuery, lastPolicyQuery,
 		fmt.Fprintf(gin.DefaultWriter, QueryPolicyMappingFailureFormatMsg, err)
 	}
 
-	policyRows, err := db.Raw(policyListQuery).Rows()
+	policyRows, err := db.Raw(policyListQuery, listArgs...).Rows()
 	if err != nil {
 		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
 		fmt.Fprintf(gin.DefaultWriter, QueryPoliciesFailureFormatMsg, err)
@@ -370,7 +427,7 @@ func handlePolicies(ginCtx *gin.Context, policyListQuery, lastPolicyQuery,
 		policyName != "" &&
 		lastPolicy.GetName() != "" &&
 		policyName != lastPolicy.GetName() {
-		continueToken, err := util.EncodeContinue(policyName, policyUID)
+		continueToken, err := order.ContinueToken("spec.policies", policyName, policyUID)
 		if err != nil {
 			fmt.Fprintf(gin.DefaultWriter, "error in encoding the continue token: %v\n", err)
 			return
@@ -410,6 +467,66 @@ func handlePolicies(ginCtx *gin.Context, policyListQuery, lastPolicyQuery,
 	ginCtx.JSON(http.StatusOK, unstrPolicyList)
 }
 
+// exportPolicies streams the policies with their status as the rows of the csv or xlsx, the columns are the name,
+// namespace and the additional printer columns of the policy CRD
+func exportPolicies(ginCtx *gin.Context, contentType, policyListQuery string, args []interface{},
+	policyMappingQuery, policyComplianceQuery string,
+) {
+	matches, err := getPolicyMatches(policyMappingQuery)
+	if err != nil {
+		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		fmt.Fprintf(gin.DefaultWriter, QueryPolicyMappingFailureFormatMsg, err)
+		return
+	}
+
+	policyRows, err := database.GetGorm().Raw(policyListQuery, args...).Rows()
+	if err != nil {
+		ginCtx.String(http.StatusInternalServerError, ServerInternalErrorMsg)
+		fmt.Fprintf(gin.DefaultWriter, QueryPoliciesFailureFormatMsg, err)
+		return
+	}
+	defer policyRows.Close()
+
+	exporter, err := util.NewExporter(ginCtx, contentType, "policies",
+		util.ExportColumns(true, customResourceColumnDefinitions))
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in exporting policies: %v\n", err)
+		return
+	}
+	for policyRows.Next() {
+		var policyUID string
+		var policyPayload []byte
+		if err := policyRows.Scan(&policyUID, &policyPayload); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in scanning a policy: %v\n", err)
+			return
+		}
+		policy := &policyv1.Policy{}
+		if err := json.Unmarshal(policyPayload, policy); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in Unmarshal a policyPayload : %v\n", err)
+			continue
+		}
+
+		compliancePerClusterStatuses, hasNonCompliantClusters, err := getComplianceStatus(policyComplianceQuery, policyUID)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, QueryPolicyComplianceFailureFormatMsg, err)
+			return
+		}
+		unstrPolicy, err := assemblePolicyStatus(policy, matches, compliancePerClusterStatuses,
+			hasNonCompliantClusters)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in assemble status: %v\n", err)
+			continue
+		}
+		if err := exporter.Export(&unstrPolicy); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in exporting policies: %v\n", err)
+			return
+		}
+	}
+	if err := exporter.Close(); err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in exporting policies: %v\n", err)
+	}
+}
+
 // getPolicyMatches returns array of policy & placementbinding & placementrule mapping and error.
 func getPolicyMatches(policyMappingQuery string) ([]*policyMatch, error) {
 	policyMatches := []*policyMatch{}
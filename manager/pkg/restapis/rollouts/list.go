@@ -0,0 +1,110 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package rollouts
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+const serverInternalErrorMsg = "internal error"
+
+// Rollout is the rollout of a version of the global resource to the waves of the managed hubs, the Wave is the index
+// of the latest released wave of the rollout strategy annotation, and the hubs not selected by any wave are the last
+// one.
+type Rollout struct {
+	UID           string    `json:"uid"`
+	Kind          string    `json:"kind"`
+	Namespace     string    `json:"namespace,omitempty"`
+	Name          string    `json:"name"`
+	State         string    `json:"state"`
+	Wave          int       `json:"wave"`
+	WaveStartedAt time.Time `json:"waveStartedAt"`
+	Message       string    `json:"message,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// RolloutList is the rollouts ordered by the kind, namespace and name
+type RolloutList struct {
+	Items []Rollout `json:"items"`
+}
+
+// ListRollouts godoc
+// @summary list the rollouts of the global resources
+// @description list the rollouts of the global resources with the rollout strategy annotation
+// @accept json
+// @produce json
+// @param        kind       query     string  false  "only list the rollouts of the kind, e.g. Policy"
+// @param        namespace  query     string  false  "only list the rollouts of the namespace"
+// @param        state      query     string  false  "only list the rollouts of the state, progressing, paused, aborted or completed"
+// @success      200  {object}    RolloutList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /rollouts [get]
+func ListRollouts() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		namespace := ginCtx.Query("namespace")
+		if !authorization.AuthorizeOrAbort(ginCtx, "list", authorization.Rollouts, "", namespace) {
+			return
+		}
+
+		db := database.GetGorm()
+		if namespace != "" {
+			db = db.Where("namespace = ?", namespace)
+		}
+		if kind := ginCtx.Query("kind"); kind != "" {
+			db = db.Where("kind = ?", kind)
+		}
+		if state := ginCtx.Query("state"); state != "" {
+			switch state {
+			case models.RolloutProgressing, models.RolloutPaused, models.RolloutAborted, models.RolloutCompleted:
+				db = db.Where("state = ?", state)
+			default:
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf(
+					"invalid state %q, it should be %s, %s, %s or %s", state, models.RolloutProgressing,
+					models.RolloutPaused, models.RolloutAborted, models.RolloutCompleted))
+				return
+			}
+		}
+
+		rows := []models.SpecRollout{}
+		if err := db.Omit("payload", "previous_payload").Order("kind, namespace, name").
+			Find(&rows).Error; err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the rollouts: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		list := RolloutList{Items: make([]Rollout, 0, len(rows))}
+		for i := range rows {
+			list.Items = append(list.Items, rolloutOf(&rows[i]))
+		}
+		ginCtx.JSON(http.StatusOK, list)
+	}
+}
+
+func rolloutOf(row *models.SpecRollout) Rollout {
+	return Rollout{
+		UID:           row.UID,
+		Kind:          row.Kind,
+		Namespace:     row.Namespace,
+		Name:          row.Name,
+		State:         row.State,
+		Wave:          row.Wave,
+		WaveStartedAt: row.WaveStartedAt,
+		Message:       row.Message,
+		UpdatedAt:     row.UpdatedAt,
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package rollouts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authentication"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+// PauseRollout godoc
+// @summary pause the rollout
+// @description pause the progressing rollout of the global resource, the released waves keep the version and the
+// @description others keep the previous one
+// @accept json
+// @produce json
+// @param        uid    path    string    true    "UID of the global resource"
+// @success      200  {object}    Rollout
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      409
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /rollout/{uid}/pause [post]
+func PauseRollout() gin.HandlerFunc {
+	return updateRollout("pause", []string{models.RolloutProgressing}, models.RolloutPaused)
+}
+
+// ResumeRollout godoc
+// @summary resume the rollout
+// @description resume the paused rollout of the global resource, the next wave is released once the released one
+// @description passes the health checks
+// @accept json
+// @produce json
+// @param        uid    path    string    true    "UID of the global resource"
+// @success      200  {object}    Rollout
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      409
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /rollout/{uid}/resume [post]
+func ResumeRollout() gin.HandlerFunc {
+	return updateRollout("resume", []string{models.RolloutPaused}, models.RolloutProgressing)
+}
+
+// AbortRollout godoc
+// @summary abort the rollout
+// @description abort the rollout of the global resource, all the hubs are delivered the previous version, or the
+// @description global resource is deleted from them if there is no previous version
+// @accept json
+// @produce json
+// @param        uid    path    string    true    "UID of the global resource"
+// @success      200  {object}    Rollout
+// @failure      401
+// @failure      403
+// @failure      404
+// @failure      409
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /rollout/{uid}/abort [post]
+func AbortRollout() gin.HandlerFunc {
+	return updateRollout("abort", []string{models.RolloutProgressing, models.RolloutPaused}, models.RolloutAborted)
+}
+
+// updateRollout changes the state of the rollout from one of the states, the spec syncer delivers the global resource
+// by the new state on its next sync. The rollout is changed only if it isn't changed since it's read, e.g. by a new
+// version of the global resource.
+func updateRollout(action string, from []string, to string) gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		uid := ginCtx.Param("uid")
+		db := database.GetGorm().WithContext(ginCtx.Request.Context())
+		row := &models.SpecRollout{}
+		err := db.Omit("payload", "previous_payload").Where("uid = ?", uid).First(row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ginCtx.String(http.StatusNotFound, fmt.Sprintf("rollout %s not found", uid))
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the rollout %s: %v\n", uid, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		if !authorization.AuthorizeOrAbort(ginCtx, "update", authorization.Rollouts, "", row.Namespace) {
+			return
+		}
+		if !contains(from, row.State) {
+			ginCtx.String(http.StatusConflict, fmt.Sprintf("the %s rollout %s can't be %s", row.State, uid,
+				pastTense(action)))
+			return
+		}
+
+		updated := *row
+		updated.State = to
+		updated.Message = ""
+		if to != models.RolloutProgressing {
+			updated.Message = fmt.Sprintf("%s by %s", pastTense(action), ginCtx.GetString(authentication.UserKey))
+		}
+		updated.UpdatedAt = time.Now()
+		result := db.Model(&models.SpecRollout{}).
+			Where("uid = ? AND digest = ? AND state = ? AND wave = ?", row.UID, row.Digest, row.State, row.Wave).
+			Updates(map[string]interface{}{
+				"state":      updated.State,
+				"message":    updated.Message,
+				"updated_at": updated.UpdatedAt,
+			})
+		err = result.Error
+		if err == nil && result.RowsAffected == 0 {
+			err = fmt.Errorf("the rollout %s is changed, please try again", uid)
+		}
+		util.RecordAudit(ginCtx, action, authorization.Rollouts, nil, []audit.Object{
+			{ID: uid, Name: row.Name, Namespace: row.Namespace, Before: rolloutOf(row), After: rolloutOf(&updated)},
+		}, err)
+		if result.Error != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to %s the rollout %s: %v\n", action, uid, result.Error)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		if err != nil {
+			ginCtx.String(http.StatusConflict, err.Error())
+			return
+		}
+		ginCtx.JSON(http.StatusOK, rolloutOf(&updated))
+	}
+}
+
+func pastTense(action string) string {
+	switch action {
+	case "pause":
+		return "paused"
+	case "resume":
+		return "resumed"
+	default:
+		return action + "ed"
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package security
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+const (
+	serverInternalErrorMsg = "internal error"
+
+	// the severities of the alerts, which are the columns of the security.alert_counts
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// AlertCounts is the number of the alerts by the severity
+type AlertCounts struct {
+	Low      int `json:"low"`
+	Medium   int `json:"medium"`
+	High     int `json:"high"`
+	Critical int `json:"critical"`
+	Total    int `json:"total"`
+}
+
+func (c *AlertCounts) add(counts *models.SecurityAlertCounts) {
+	c.Low += counts.Low
+	c.Medium += counts.Medium
+	c.High += counts.High
+	c.Critical += counts.Critical
+	c.Total += counts.Low + counts.Medium + counts.High + counts.Critical
+}
+
+// HubAlertCounts is the alert counts of the Central instance of the managed hub, the DetailURL is the violations page
+// of the Central console.
+type HubAlertCounts struct {
+	HubName   string    `json:"hubName"`
+	Source    string    `json:"source"`
+	DetailURL string    `json:"detailURL"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	AlertCounts
+}
+
+// AlertCountsList is the alert counts of the managed hubs, the Total is the sum of the listed items
+type AlertCountsList struct {
+	Total AlertCounts      `json:"total"`
+	Items []HubAlertCounts `json:"items"`
+}
+
+// ListAlertCounts godoc
+// @summary list the security alert counts
+// @description list the security alert counts of the Central instances on the managed hubs with the fleet totals
+// @accept json
+// @produce json
+// @param        hub       query     string  false  "only list the alert counts of the managed hub"
+// @param        source    query     string  false  "only list the alert counts of the Central instance, namespace/name"
+// @param        severity  query     string  false  "only list the counts with the alerts of the severities, e.g. high,critical"
+// @success      200  {object}    AlertCountsList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /security/alertcounts [get]
+func ListAlertCounts() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		filter, err := authorization.NewFilter(ginCtx, "list", authorization.SecurityAlerts, "")
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the security alerts: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		db := database.GetGorm().Scopes(filter.Scope("hub_name", ""))
+		if hub := ginCtx.Query("hub"); hub != "" {
+			db = db.Where("hub_name = ?", hub)
+		}
+		if source := ginCtx.Query("source"); source != "" {
+			db = db.Where("source = ?", source)
+		}
+		if severity := ginCtx.Query("severity"); severity != "" {
+			condition, err := severityCondition(severity)
+			if err != nil {
+				ginCtx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			db = db.Where(condition)
+		}
+
+		alertCounts := []models.SecurityAlertCounts{}
+		if err := db.Order("hub_name, source").Find(&alertCounts).Error; err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the security alert counts: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		ginCtx.JSON(http.StatusOK, newAlertCountsList(alertCounts))
+	}
+}
+
+// severityCondition returns the condition of the rows with the alerts of any of the severities, the severities are
+// the columns so they're checked against the known severities instead of the arguments.
+func severityCondition(severity string) (string, error) {
+	conditions := []string{}
+	for _, s := range strings.Split(severity, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		found := false
+		for _, known := range severities {
+			if s == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("invalid severity %q, it should be one of %s", s, strings.Join(severities, ", "))
+		}
+		conditions = append(conditions, s+" > 0")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", nil
+}
+
+func newAlertCountsList(alertCounts []models.SecurityAlertCounts) *AlertCountsList {
+	list := &AlertCountsList{Items: []HubAlertCounts{}}
+	for i := range alertCounts {
+		counts := &alertCounts[i]
+		item := HubAlertCounts{
+			HubName:   counts.HubName,
+			Source:    counts.Source,
+			DetailURL: counts.DetailURL,
+			UpdatedAt: counts.UpdatedAt,
+		}
+		item.add(counts)
+		list.Total.add(counts)
+		list.Items = append(list.Items, item)
+	}
+	return list
+}
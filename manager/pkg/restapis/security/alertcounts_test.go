@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package security
+
+import (
+	"testing"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+func TestSeverityCondition(t *testing.T) {
+	condition, err := severityCondition("High, critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if condition != "(high > 0 OR critical > 0)" {
+		t.Errorf("unexpected condition %q", condition)
+	}
+	if _, err := severityCondition("high; DROP TABLE security.alert_counts"); err == nil {
+		t.Errorf("expected the error for the invalid severity")
+	}
+}
+
+func TestNewAlertCountsList(t *testing.T) {
+	list := newAlertCountsList([]models.SecurityAlertCounts{
+		{HubName: "hub1", Source: "stackrox/central", Low: 1, Medium: 2, High: 3, Critical: 4, DetailURL: "url1"},
+		{HubName: "hub2", Source: "stackrox/central", Low: 5, Critical: 1, DetailURL: "url2"},
+	})
+	if len(list.Items) != 2 {
+		t.Fatalf("expected 2 items, but got %d", len(list.Items))
+	}
+	if list.Items[0].Total != 10 || list.Items[1].Total != 6 || list.Items[1].DetailURL != "url2" {
+		t.Errorf("unexpected items %+v", list.Items)
+	}
+	expected := AlertCounts{Low: 6, Medium: 2, High: 3, Critical: 5, Total: 16}
+	if list.Total != expected {
+		t.Errorf("expected the total %+v, but got %+v", expected, list.Total)
+	}
+}
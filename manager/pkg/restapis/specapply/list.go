@@ -0,0 +1,145 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package specapply
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+const (
+	serverInternalErrorMsg = "internal error"
+	// the results in a response are limited to protect the database
+	defaultLimit = 500
+	maxLimit     = 5000
+)
+
+// SpecApplyResult is the result of applying the global resource on the managed hub, the UID is the global resource
+// and it's empty once the global resource is deleted.
+type SpecApplyResult struct {
+	HubName         string    `json:"hubName"`
+	UID             string    `json:"uid"`
+	APIVersion      string    `json:"apiVersion"`
+	Kind            string    `json:"kind"`
+	Namespace       string    `json:"namespace,omitempty"`
+	Name            string    `json:"name"`
+	Result          string    `json:"result"`
+	Error           string    `json:"error,omitempty"`
+	ResourceVersion string    `json:"resourceVersion,omitempty"`
+	Deleted         bool      `json:"deleted"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// SpecApplyResultList is the apply results ordered by the hub, kind, namespace and name
+type SpecApplyResultList struct {
+	Items []SpecApplyResult `json:"items"`
+}
+
+// ListSpecApplyResults godoc
+// @summary list the apply results of the global resources
+// @description list the results of applying the global resources on the managed hubs reported by the hubs
+// @accept json
+// @produce json
+// @param        hub        query     string  false  "only list the results of the managed hub"
+// @param        kind       query     string  false  "only list the results of the kind, e.g. Policy"
+// @param        namespace  query     string  false  "only list the results of the namespace"
+// @param        name       query     string  false  "only list the results of the name"
+// @param        uid        query     string  false  "only list the results of the global resource"
+// @param        result     query     string  false  "only list the results of the result, applied, failed, skipped or rejected"
+// @param        limit      query     int     false  "maximum number of the results, 500 by default and 5000 at most"
+// @success      200  {object}    SpecApplyResultList
+// @failure      400
+// @failure      401
+// @failure      403
+// @failure      500
+// @failure      503
+// @security     ApiKeyAuth
+// @router /specapplyresults [get]
+func ListSpecApplyResults() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		filter, err := authorization.NewFilter(ginCtx, "list", authorization.SpecApplyResults, "")
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the spec apply results: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		db, limit, err := specApplyResultsQuery(ginCtx, database.GetGorm().Scopes(filter.Scope("leaf_hub_name", "")))
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rows := []models.SpecApplyResult{}
+		if err := db.Order("leaf_hub_name, kind, namespace, name").Limit(limit).Find(&rows).Error; err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying the spec apply results: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+
+		list := SpecApplyResultList{Items: make([]SpecApplyResult, 0, len(rows))}
+		for _, row := range rows {
+			list.Items = append(list.Items, SpecApplyResult{
+				HubName:         row.LeafHubName,
+				UID:             row.UID,
+				APIVersion:      row.APIVersion,
+				Kind:            row.Kind,
+				Namespace:       row.Namespace,
+				Name:            row.Name,
+				Result:          row.Result,
+				Error:           row.Error,
+				ResourceVersion: row.ResourceVersion,
+				Deleted:         row.Deleted,
+				UpdatedAt:       row.UpdatedAt,
+			})
+		}
+		ginCtx.JSON(http.StatusOK, list)
+	}
+}
+
+// specApplyResultsQuery adds the filters of the query parameters to the db and returns the limit of the results
+func specApplyResultsQuery(ginCtx *gin.Context, db *gorm.DB) (*gorm.DB, int, error) {
+	for param, column := range map[string]string{
+		"hub":       "leaf_hub_name",
+		"kind":      "kind",
+		"namespace": "namespace",
+		"name":      "name",
+		"uid":       "uid",
+	} {
+		if value := ginCtx.Query(param); value != "" {
+			db = db.Where(column+" = ?", value)
+		}
+	}
+	if result := ginCtx.Query("result"); result != "" {
+		switch result {
+		case wiremodels.SpecApplied, wiremodels.SpecApplyFailed, wiremodels.SpecApplySkipped,
+			wiremodels.SpecApplyRejected:
+			db = db.Where("result = ?", result)
+		default:
+			return nil, 0, fmt.Errorf("invalid result %q, it should be %s, %s, %s or %s", result,
+				wiremodels.SpecApplied, wiremodels.SpecApplyFailed, wiremodels.SpecApplySkipped,
+				wiremodels.SpecApplyRejected)
+		}
+	}
+
+	limit := defaultLimit
+	if value := ginCtx.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxLimit {
+			return nil, 0, fmt.Errorf("invalid limit %q, it should be between 1 and %d", value, maxLimit)
+		}
+		limit = n
+	}
+	return db, limit, nil
+}
@@ -5,7 +5,9 @@ package subscriptions
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -17,6 +19,7 @@ import (
 	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1"
 	appsv1alpha1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1alpha1"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
 )
@@ -28,6 +31,9 @@ const (
 		FROM spec.subscriptions WHERE deleted = FALSE AND id = ?`
 	subscriptionReportQuery = `SELECT payload FROM status.subscription_reports
 		WHERE payload->'metadata'->>'name'= ? AND payload->'metadata'->>'namespace' = ?`
+	// subscriptionNamespacesQuery lists the namespaces of the subscriptions to authorize the list by namespace
+	subscriptionNamespacesQuery = `SELECT DISTINCT payload -> 'metadata' ->> 'namespace' FROM spec.subscriptions
+		WHERE deleted = FALSE`
 )
 
 var subReportCustomResourceColumnDefinitions = util.GetCustomResourceColumnDefinitions(subscriptionRepostCRDName,
@@ -56,6 +62,22 @@ func GetSubscriptionReport() gin.HandlerFunc {
 		fmt.Fprintf(gin.DefaultWriter, "subscription report query with subscription name and namespace: %v\n",
 			subscriptionReportQuery)
 
+		var subName, subNamespace string
+		err := database.GetGorm().Raw(subscriptionQuery, subscriptionID).Row().Scan(&subName, &subNamespace)
+		if errors.Is(err, sql.ErrNoRows) {
+			ginCtx.String(http.StatusNotFound, "subscription not found")
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in querying subscription with subscription ID(%s): %v\n",
+				subscriptionID, err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		if !authorization.AuthorizeOrAbort(ginCtx, "get", authorization.Subscriptions, "", subNamespace) {
+			return
+		}
+
 		handleSubscriptionReport(ginCtx, subscriptionID,
 			subscriptionQuery, subscriptionReportQuery,
 			subReportCustomResourceColumnDefinitions)
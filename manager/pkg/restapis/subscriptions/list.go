@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	appsv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
 )
@@ -36,12 +37,20 @@ const (
 var customResourceColumnDefinitions = util.GetCustomResourceColumnDefinitions(crdName,
 	appsv1.SchemeGroupVersion.Version)
 
+// subscriptionFields are the fields of the subscriptions which can be selected and sorted by
+var subscriptionFields = util.ListFields{
+	"metadata.name":      "payload -> 'metadata' ->> 'name'",
+	"metadata.namespace": "payload -> 'metadata' ->> 'namespace'",
+}
+
 // ListSubscriptions godoc
 // @summary list application subscriptions
 // @description list application subscriptions
 // @accept json
-// @produce json
+// @produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @param        labelSelector    query     string  false  "list application subscriptions by label selector"
+// @param        fieldSelector    query     string  false  "list application subscriptions by field selector"
+// @param        sortBy           query     string  false  "sort subscriptions by the field, - for descending"
 // @param        limit            query     int     false  "maximum application subscription number to receive"
 // @param        continue         query     string  false  "continue token to request next request"
 // @success      200  {object}    appsv1.SubscriptionList
@@ -58,73 +67,113 @@ func ListSubscriptions() gin.HandlerFunc {
 		labelSelector := ginCtx.Query("labelSelector")
 
 		selectorInSql := ""
-
+		selectorArgs := []interface{}{}
+		var err error
 		if labelSelector != "" {
-			var err error
-			selectorInSql, err = util.ParseLabelSelector(labelSelector)
+			selectorInSql, selectorArgs, err = util.ParseLabelSelector(labelSelector)
 			if err != nil {
 				fmt.Fprintf(gin.DefaultWriter, "failed to parse label selector: %s\n", err.Error())
+				ginCtx.String(http.StatusBadRequest, err.Error())
 				return
 			}
 		}
 
 		fmt.Fprintf(gin.DefaultWriter, "parsed selector: %s\n", selectorInSql)
 
+		fieldSelectorInSql := ""
+		fieldSelectorArgs := []interface{}{}
+		if fieldSelector := ginCtx.Query("fieldSelector"); fieldSelector != "" {
+			fieldSelectorInSql, fieldSelectorArgs, err = util.ParseFieldSelector(fieldSelector, subscriptionFields)
+			if err != nil {
+				ginCtx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		order, err := util.NewListOrder(ginCtx.Query("sortBy"), subscriptionFields,
+			"payload -> 'metadata' ->> 'name'", "payload -> 'metadata' ->> 'uid'")
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
 		limit := ginCtx.Query("limit")
 		fmt.Fprintf(gin.DefaultWriter, "limit: %v\n", limit)
+		limitInSql, limitArgs, err := util.ParseLimit(limit)
+		if err != nil {
+			ginCtx.String(http.StatusBadRequest, err.Error())
+			return
+		}
 
-		lastSubscriptionName, lastSubscriptionUID := "", ""
-
+		var lastSubscription *util.ContinueToken
 		continueToken := ginCtx.Query("continue")
 		if continueToken != "" {
 			fmt.Fprintf(gin.DefaultWriter, "continue: %v\n", continueToken)
 
-			var err error
-			lastSubscriptionName, lastSubscriptionUID, err = util.DecodeContinue(continueToken)
+			lastSubscription, err = util.DecodeSortedContinue(continueToken, order.SortBy)
 			if err != nil {
 				fmt.Fprintf(gin.DefaultWriter, "failed to decode continue token: %s\n", err.Error())
+				ginCtx.String(http.StatusBadRequest, fmt.Sprintf("invalid continue token: %v", err))
 				return
 			}
+			fmt.Fprintf(gin.DefaultWriter,
+				"last returned subscription name: %s, last returned subscription UID: %s\n",
+				lastSubscription.LastName,
+				lastSubscription.LastUID)
 		}
 
-		fmt.Fprintf(gin.DefaultWriter,
-			"last returned subscription name: %s, last returned subscription UID: %s\n",
-			lastSubscriptionName,
-			lastSubscriptionUID)
-
-		// build query condition for paging
-		LastResourceCompareCondition := fmt.Sprintf(
-			"(payload -> 'metadata' ->> 'name', payload -> 'metadata' ->> 'uid') > ('%s', '%s') ",
-			lastSubscriptionName,
-			lastSubscriptionUID)
-
-		// the last subscription query order by subscription name and uid
-		lastSubscriptionQuery := "SELECT payload FROM spec.subscriptions WHERE deleted = FALSE " +
-			"ORDER BY (payload -> 'metadata' ->> 'name', payload -> 'metadata' ->> 'uid') DESC LIMIT 1"
-
-		// subscrition list query
-		subscriptionListQuery := "SELECT payload FROM spec.subscriptions WHERE deleted = FALSE AND " +
-			LastResourceCompareCondition +
-			selectorInSql +
-			" ORDER BY (payload -> 'metadata' ->> 'name', payload -> 'metadata' ->> 'uid')"
-
-		// add limit
-		if limit != "" {
-			subscriptionListQuery += fmt.Sprintf(" LIMIT %s", limit)
+		_, watch := ginCtx.GetQuery("watch")
+		verb := "list"
+		if watch {
+			verb = "watch"
+		}
+		// only the subscriptions in the namespaces the user is allowed to access are listed
+		filter, err := authorization.NewFilter(ginCtx, verb, authorization.Subscriptions, subscriptionNamespacesQuery)
+		if err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in authorizing the subscriptions: %v\n", err)
+			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+			return
+		}
+		filterInSql, filterArgs := filter.Condition("", "payload -> 'metadata' ->> 'namespace'")
+
+		// the conditions are applied to both the list and the last subscription, so the continue token isn't
+		// returned with the last visible subscription
+		conditionsInSql := selectorInSql + fieldSelectorInSql + filterInSql
+		conditionArgs := append(append(append([]interface{}{}, selectorArgs...), fieldSelectorArgs...), filterArgs...)
+
+		lastSubscriptionQuery := "SELECT payload FROM spec.subscriptions WHERE deleted = FALSE" +
+			conditionsInSql +
+			order.OrderBy(true) +
+			" LIMIT 1"
+
+		// subscrition list query after the last returned subscription, ordered by the sort key, name and uid
+		afterInSql, afterArgs := order.After(lastSubscription)
+		subscriptionListQuery := "SELECT payload FROM spec.subscriptions WHERE deleted = FALSE" +
+			afterInSql +
+			conditionsInSql +
+			order.OrderBy(false) +
+			limitInSql
+		listArgs := append(append(append([]interface{}{}, afterArgs...), conditionArgs...), limitArgs...)
+
+		fmt.Fprintf(gin.DefaultWriter, "subscription list query: %v, args: %v\n", subscriptionListQuery, listArgs)
+
+		if watch {
+			handleSubscriptionListForWatch(ginCtx, subscriptionListQuery, listArgs)
+			return
 		}
 
-		fmt.Fprintf(gin.DefaultWriter, "subscription list query: %v\n", subscriptionListQuery)
-
-		if _, watch := ginCtx.GetQuery("watch"); watch {
-			handleSubscriptionListForWatch(ginCtx, subscriptionListQuery)
+		// the export streams all the subscriptions of the list query
+		if contentType := util.ExportContentType(ginCtx); contentType != "" {
+			exportSubscriptions(ginCtx, contentType, subscriptionListQuery, listArgs)
 			return
 		}
 
-		handleRows(ginCtx, subscriptionListQuery, lastSubscriptionQuery, customResourceColumnDefinitions)
+		handleRows(ginCtx, subscriptionListQuery, lastSubscriptionQuery, listArgs, conditionArgs, order,
+			customResourceColumnDefinitions)
 	}
 }
 
-func handleSubscriptionListForWatch(ginCtx *gin.Context, subscriptionListQuery string) {
+func handleSubscriptionListForWatch(ginCtx *gin.Context, subscriptionListQuery string, args []interface{}) {
 	writer := ginCtx.Writer
 	header := writer.Header()
 
@@ -154,16 +203,16 @@ func handleSubscriptionListForWatch(ginCtx *gin.Context, subscriptionListQuery s
 				return
 			}
 
-			doHandleRowsForWatch(ctx, writer, subscriptionListQuery, preAddedSubscriptions)
+			doHandleRowsForWatch(ctx, writer, subscriptionListQuery, args, preAddedSubscriptions)
 		}
 	}
 }
 
-func doHandleRowsForWatch(ctx context.Context, writer io.Writer, subscriptionListQuery string,
+func doHandleRowsForWatch(ctx context.Context, writer io.Writer, subscriptionListQuery string, args []interface{},
 	preAddedSubscriptions set.Set,
 ) {
 	db := database.GetGorm()
-	rows, err := db.Raw(subscriptionListQuery).Rows()
+	rows, err := db.Raw(subscriptionListQuery, args...).Rows()
 	if err != nil {
 		fmt.Fprintf(gin.DefaultWriter, "error in quering subscription list: %v\n", err)
 	}
@@ -227,12 +276,13 @@ func doHandleRowsForWatch(ctx context.Context, writer io.Writer, subscriptionLis
 }
 
 func handleRows(ginCtx *gin.Context, subscriptionListQuery, lastSubscriptionQuery string,
+	listArgs, lastArgs []interface{}, order *util.ListOrder,
 	customResourceColumnDefinitions []apiextensionsv1.CustomResourceColumnDefinition,
 ) {
 	db := database.GetGorm()
 	lastSubscription := &appsv1.Subscription{}
 	var payload []byte
-	err := db.Raw(lastSubscriptionQuery).Row().Scan(&payload)
+	err := db.Raw(lastSubscriptionQuery, lastArgs...).Row().Scan(&payload)
 	if err != nil && err != sql.ErrNoRows {
 		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
 		fmt.Fprintf(gin.DefaultWriter, "error in querying last subscription: %v\n", err)
@@ -248,7 +298,7 @@ func handleRows(ginCtx *gin.Context, subscriptionListQuery, lastSubscriptionQuer
 		}
 	}
 
-	rows, err := db.Raw(subscriptionListQuery).Rows()
+	rows, err := db.Raw(subscriptionListQuery, listArgs...).Rows()
 	if err != nil {
 		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
 		fmt.Fprintf(gin.DefaultWriter, "error in querying subscriptions: %v\n", err)
@@ -286,7 +336,7 @@ func handleRows(ginCtx *gin.Context, subscriptionListQuery, lastSubscriptionQuer
 		lastSubscriptionUID != "" &&
 		string(lastSubscription.GetUID()) != "" &&
 		lastSubscriptionUID != string(lastSubscription.GetUID()) {
-		continueToken, err := util.EncodeContinue(lastSubscriptionName, lastSubscriptionUID)
+		continueToken, err := order.ContinueToken("spec.subscriptions", lastSubscriptionName, lastSubscriptionUID)
 		if err != nil {
 			ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
 			fmt.Fprintf(gin.DefaultWriter, "error in encoding the continue token: %v\n", err)
@@ -327,6 +377,44 @@ func handleRows(ginCtx *gin.Context, subscriptionListQuery, lastSubscriptionQuer
 	ginCtx.JSON(http.StatusOK, subscriptionList)
 }
 
+// exportSubscriptions streams the subscriptions as the rows of the csv or xlsx, the columns are the name, namespace and
+// the additional printer columns of the subscription CRD
+func exportSubscriptions(ginCtx *gin.Context, contentType, subscriptionListQuery string, args []interface{}) {
+	rows, err := database.GetGorm().Raw(subscriptionListQuery, args...).Rows()
+	if err != nil {
+		ginCtx.String(http.StatusInternalServerError, serverInternalErrorMsg)
+		fmt.Fprintf(gin.DefaultWriter, "error in querying subscriptions: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	exporter, err := util.NewExporter(ginCtx, contentType, "subscriptions",
+		util.ExportColumns(true, customResourceColumnDefinitions))
+	if err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in exporting subscriptions: %v\n", err)
+		return
+	}
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in scanning a subscription: %v\n", err)
+			return
+		}
+		subscription := map[string]interface{}{}
+		if err := json.Unmarshal(payload, &subscription); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in scanning a subscription payload: %v\n", err)
+			continue
+		}
+		if err := exporter.Export(subscription); err != nil {
+			fmt.Fprintf(gin.DefaultWriter, "error in exporting subscriptions: %v\n", err)
+			return
+		}
+	}
+	if err := exporter.Close(); err != nil {
+		fmt.Fprintf(gin.DefaultWriter, "error in exporting subscriptions: %v\n", err)
+	}
+}
+
 func wrapObjectsInList(subscriptions []appsv1.Subscription) (*corev1.List, error) {
 	list := &corev1.List{
 		TypeMeta: metav1.TypeMeta{
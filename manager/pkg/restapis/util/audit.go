@@ -0,0 +1,45 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authentication"
+)
+
+// RecordAudit records the audit event of the request by the authenticated user, the body is passed since it has been
+// read by the handler. The failure of the recording is only logged, it doesn't fail the request.
+func RecordAudit(ginCtx *gin.Context, action, resource string, body interface{}, objects []audit.Object, err error) {
+	request := &audit.Request{
+		Method: ginCtx.Request.Method,
+		Path:   ginCtx.Request.URL.Path,
+		Query:  ginCtx.Request.URL.RawQuery,
+	}
+	if body != nil {
+		data, e := json.Marshal(body)
+		if e != nil {
+			fmt.Fprintf(gin.DefaultWriter, "failed to marshal the body of the audit event: %v\n", e)
+		}
+		request.Body = data
+	}
+	result, message := audit.Result(err)
+	if e := audit.Record(ginCtx.Request.Context(), &audit.Event{
+		Source:   audit.SourceRESTAPI,
+		User:     ginCtx.GetString(authentication.UserKey),
+		Groups:   ginCtx.GetStringSlice(authentication.GroupsKey),
+		Action:   action,
+		Resource: resource,
+		Request:  request,
+		Objects:  objects,
+		Result:   result,
+		Message:  message,
+	}); e != nil {
+		fmt.Fprintf(gin.DefaultWriter, "failed to record the audit event: %v\n", e)
+	}
+}
@@ -6,13 +6,17 @@ package util
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 )
 
-// continueToken is a simple structured object for encoding the state of a continue token
-// since the resource name may not be unique, resource uid is combined to check last returned resource
-type continueToken struct {
+// ContinueToken is a simple structured object for encoding the state of a continue token
+// since the resource name may not be unique, resource uid is combined to check last returned resource.
+// The SortKey is the value of the SortBy field of the last returned resource if the list is sorted.
+type ContinueToken struct {
 	LastName string `json:"lastName"`
 	LastUID  string `json:"lastUID"`
+	SortBy   string `json:"sortBy,omitempty"`
+	SortKey  string `json:"sortKey,omitempty"`
 }
 
 // DecodeContinue decodes the continue token and get last resoource name and uid
@@ -23,7 +27,7 @@ func DecodeContinue(continueStr string) (string, string, error) {
 		return lastName, lastUID, err
 	}
 
-	ct := &continueToken{}
+	ct := &ContinueToken{}
 	if err := json.Unmarshal(decodedContinue, ct); err != nil {
 		return lastName, lastUID, err
 	}
@@ -31,9 +35,33 @@ func DecodeContinue(continueStr string) (string, string, error) {
 	return ct.LastName, ct.LastUID, nil
 }
 
+// DecodeSortedContinue decodes the continue token of the list sorted by the sortBy, the token of the list sorted by
+// another field is invalid.
+func DecodeSortedContinue(continueStr, sortBy string) (*ContinueToken, error) {
+	decodedContinue, err := base64.RawURLEncoding.DecodeString(continueStr)
+	if err != nil {
+		return nil, err
+	}
+
+	ct := &ContinueToken{}
+	if err := json.Unmarshal(decodedContinue, ct); err != nil {
+		return nil, err
+	}
+	if ct.SortBy != sortBy {
+		return nil, fmt.Errorf("the continue token is sorted by %q instead of %q", ct.SortBy, sortBy)
+	}
+
+	return ct, nil
+}
+
 // EncodeContinue encodes the continue token with last resource name and uid
 func EncodeContinue(lastName, lastUID string) (string, error) {
-	ct, err := json.Marshal(&continueToken{LastName: lastName, LastUID: lastUID})
+	return EncodeSortedContinue(&ContinueToken{LastName: lastName, LastUID: lastUID})
+}
+
+// EncodeSortedContinue encodes the continue token with last resource name, uid and sort key
+func EncodeSortedContinue(token *ContinueToken) (string, error) {
+	ct, err := json.Marshal(token)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,303 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	ContentTypeCSV  = "text/csv"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// the rows are flushed to the client in batches, so the export of the whole fleet isn't buffered in the memory
+	exportFlushRows = 100
+)
+
+// ExportContentType returns the spreadsheet content type accepted by the request, it's empty if the request doesn't
+// accept the csv or xlsx, and the response is returned as json.
+func ExportContentType(ginCtx *gin.Context) string {
+	for _, accepted := range strings.Split(ginCtx.GetHeader("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.Split(accepted, ";")[0])
+		if mediaType == ContentTypeCSV || mediaType == ContentTypeXLSX {
+			return mediaType
+		}
+	}
+	return ""
+}
+
+// ExportColumn is the column of the exported resource, the value is the JSONPath over the resource.
+type ExportColumn struct {
+	Name     string
+	JSONPath string
+}
+
+// ExportColumns returns the name and namespace of the resource followed by the additional printer columns of its CRD,
+// the cluster scoped resource doesn't have the namespace column.
+func ExportColumns(namespaced bool,
+	customResourceColumnDefinitions []apiextensionsv1.CustomResourceColumnDefinition,
+) []ExportColumn {
+	columns := []ExportColumn{{Name: "Name", JSONPath: ".metadata.name"}}
+	if namespaced {
+		columns = append(columns, ExportColumn{Name: "Namespace", JSONPath: ".metadata.namespace"})
+	}
+	for _, definition := range customResourceColumnDefinitions {
+		columns = append(columns, ExportColumn{Name: definition.Name, JSONPath: definition.JSONPath})
+	}
+	return columns
+}
+
+// Exporter streams the resources to the response as the rows of the csv or xlsx, each resource is flattened into the
+// values of the columns.
+type Exporter struct {
+	ginCtx *gin.Context
+	writer tableWriter
+	paths  []*jsonpath.JSONPath
+	rows   int
+}
+
+// tableWriter writes the rows of the spreadsheet
+type tableWriter interface {
+	write(row []string) error
+	flush() error
+	close() error
+}
+
+// NewExporter writes the response header and the header row of the columns, the additional columns are appended to
+// the header and their values are passed to the Export.
+func NewExporter(ginCtx *gin.Context, contentType, fileName string, columns []ExportColumn,
+	additionalColumns ...string,
+) (*Exporter, error) {
+	exporter := &Exporter{ginCtx: ginCtx}
+	for _, column := range columns {
+		path := jsonpath.New(column.Name).AllowMissingKeys(true)
+		if err := path.Parse(fmt.Sprintf("{%s}", column.JSONPath)); err != nil {
+			return nil, fmt.Errorf("failed to parse the JSONPath %s of the column %s: %w", column.JSONPath,
+				column.Name, err)
+		}
+		exporter.paths = append(exporter.paths, path)
+	}
+
+	extension := "csv"
+	if contentType == ContentTypeXLSX {
+		extension = "xlsx"
+	}
+	ginCtx.Header("Content-Type", contentType)
+	ginCtx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName+"."+extension))
+	ginCtx.Status(http.StatusOK)
+
+	if contentType == ContentTypeXLSX {
+		writer, err := newXLSXWriter(ginCtx.Writer)
+		if err != nil {
+			return nil, err
+		}
+		exporter.writer = writer
+	} else {
+		exporter.writer = &csvWriter{csv.NewWriter(ginCtx.Writer)}
+	}
+
+	header := []string{}
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+	return exporter, exporter.WriteRow(append(header, additionalColumns...))
+}
+
+// Export writes the row of the resource, the values of the additional columns are appended to the row
+func (e *Exporter) Export(obj interface{}, additionalValues ...string) error {
+	var object map[string]interface{}
+	switch o := obj.(type) {
+	case *unstructured.Unstructured:
+		object = o.Object
+	case map[string]interface{}:
+		object = o
+	default:
+		var err error
+		if object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return err
+		}
+	}
+
+	row := make([]string, 0, len(e.paths)+len(additionalValues))
+	for _, path := range e.paths {
+		value, err := cellValue(path, object)
+		if err != nil {
+			return err
+		}
+		row = append(row, value)
+	}
+	return e.WriteRow(append(row, additionalValues...))
+}
+
+// WriteRow writes the row and flushes the rows to the client in batches
+func (e *Exporter) WriteRow(row []string) error {
+	if err := e.writer.write(row); err != nil {
+		return err
+	}
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *Exporter) flush() error {
+	if err := e.writer.flush(); err != nil {
+		return err
+	}
+	e.ginCtx.Writer.Flush()
+	return nil
+}
+
+// Close completes the spreadsheet and flushes the remaining rows
+func (e *Exporter) Close() error {
+	if err := e.writer.close(); err != nil {
+		return err
+	}
+	e.ginCtx.Writer.Flush()
+	return nil
+}
+
+// cellValue returns the values of the JSONPath joined by the comma, the value other than the string is formatted as
+// json
+func cellValue(path *jsonpath.JSONPath, object map[string]interface{}) (string, error) {
+	results, err := path.FindResults(object)
+	if err != nil {
+		return "", err
+	}
+	values := []string{}
+	for _, result := range results {
+		for _, value := range result {
+			switch v := value.Interface().(type) {
+			case string:
+				values = append(values, v)
+			case nil:
+			default:
+				data, err := json.Marshal(v)
+				if err != nil {
+					return "", err
+				}
+				values = append(values, string(data))
+			}
+		}
+	}
+	return strings.Join(values, ","), nil
+}
+
+type csvWriter struct {
+	*csv.Writer
+}
+
+func (w *csvWriter) write(row []string) error {
+	return w.Write(row)
+}
+
+func (w *csvWriter) flush() error {
+	w.Flush()
+	return w.Error()
+}
+
+func (w *csvWriter) close() error {
+	return w.flush()
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" ` +
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" ` +
+		`Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" ` +
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" ` +
+		`Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes the workbook of a single sheet, the sheet is the last entry of the zip archive so its rows are
+// streamed with the inline strings instead of the shared strings table.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+}
+
+func newXLSXWriter(writer io.Writer) (*xlsxWriter, error) {
+	archive := zip.NewWriter(writer)
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRelationships},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelationships},
+	} {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetHeader); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{archive: archive, sheet: sheet}, nil
+}
+
+func (w *xlsxWriter) write(row []string) error {
+	builder := &strings.Builder{}
+	builder.WriteString("<row>")
+	for _, cell := range row {
+		builder.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(builder, []byte(cell)); err != nil {
+			return err
+		}
+		builder.WriteString("</t></is></c>")
+	}
+	builder.WriteString("</row>")
+	_, err := io.WriteString(w.sheet, builder.String())
+	return err
+}
+
+func (w *xlsxWriter) flush() error {
+	return w.archive.Flush()
+}
+
+func (w *xlsxWriter) close() error {
+	if _, err := io.WriteString(w.sheet, xlsxSheetFooter); err != nil {
+		return err
+	}
+	return w.archive.Close()
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var testColumnDefinitions = []apiextensionsv1.CustomResourceColumnDefinition{
+	{Name: "Available", JSONPath: `.status.conditions[?(@.type=="Available")].status`},
+	{Name: "Labels", JSONPath: ".metadata.labels"},
+}
+
+var testCluster = map[string]interface{}{
+	"metadata": map[string]interface{}{"name": "cluster1", "labels": map[string]interface{}{"env": "dev"}},
+	"status": map[string]interface{}{"conditions": []interface{}{
+		map[string]interface{}{"type": "Joined", "status": "True"},
+		map[string]interface{}{"type": "Available", "status": "False"},
+	}},
+}
+
+func exportTestCluster(t *testing.T, contentType string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodGet, "/managedclusters", nil)
+
+	exporter, err := NewExporter(ginCtx, contentType, "managedclusters",
+		ExportColumns(false, testColumnDefinitions), "Hub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Export(testCluster, "hub1"); err != nil {
+		t.Fatal(err)
+	}
+	// the missing fields are empty
+	if err := exporter.Export(map[string]interface{}{"metadata": map[string]interface{}{"name": "cluster2"}},
+		"hub2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return recorder
+}
+
+func TestExportContentType(t *testing.T) {
+	for accept, expected := range map[string]string{
+		"":                                   "",
+		"application/json":                   "",
+		"application/json, text/csv;q=0.9":   ContentTypeCSV,
+		ContentTypeXLSX + ";q=1.0":           ContentTypeXLSX,
+		"application/json;as=Table;g=meta.k": "",
+	} {
+		ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ginCtx.Request = httptest.NewRequest(http.MethodGet, "/policies", nil)
+		ginCtx.Request.Header.Set("Accept", accept)
+		if contentType := ExportContentType(ginCtx); contentType != expected {
+			t.Errorf("expected %q for %q, but got %q", expected, accept, contentType)
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	recorder := exportTestCluster(t, ContentTypeCSV)
+	if contentType := recorder.Header().Get("Content-Type"); contentType != ContentTypeCSV {
+		t.Errorf("unexpected content type %s", contentType)
+	}
+	if disposition := recorder.Header().Get("Content-Disposition"); !strings.Contains(disposition,
+		`"managedclusters.csv"`) {
+		t.Errorf("unexpected content disposition %s", disposition)
+	}
+	records, err := csv.NewReader(recorder.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"Name", "Available", "Labels", "Hub"},
+		{"cluster1", "False", `{"env":"dev"}`, "hub1"},
+		{"cluster2", "", "", "hub2"},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected %v, but got %v", expected, records)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	recorder := exportTestCluster(t, ContentTypeXLSX)
+	body := recorder.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	sheet := ""
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(data)
+	}
+	expectedNames := []string{
+		"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels",
+		"xl/worksheets/sheet1.xml",
+	}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("expected the parts %v, but got %v", expectedNames, names)
+	}
+	for _, cell := range []string{">cluster1</t>", ">{&#34;env&#34;:&#34;dev&#34;}</t>", ">hub2</t>"} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("expected the cell %s in the sheet %s", cell, sheet)
+		}
+	}
+	if !strings.HasSuffix(sheet, "</sheetData></worksheet>") {
+		t.Errorf("the sheet isn't completed: %s", sheet)
+	}
+}
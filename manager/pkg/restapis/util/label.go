@@ -12,37 +12,42 @@ const (
 	invalidLabelSelectorFormatMsg = "invalid equality based label selector: %s\n"
 )
 
-func ParseLabelSelector(labelSelector string) (string, error) {
+// ParseLabelSelector translates the equality based label selector into the condition appended to the WHERE clause,
+// the keys and values are the arguments of the query.
+func ParseLabelSelector(labelSelector string) (string, []interface{}, error) {
 	selectorInSql := ""
+	args := []interface{}{}
 	selectors := strings.Split(labelSelector, ",")
 	for _, selector := range selectors {
 		switch {
 		case strings.Contains(selector, "!="):
 			keyValPair := strings.Split(selector, "!=")
 			if len(keyValPair) != 2 {
-				return "", fmt.Errorf(invalidLabelSelectorFormatMsg, selector)
+				return "", nil, fmt.Errorf(invalidLabelSelectorFormatMsg, selector)
 			}
 
 			key, val := strings.TrimSpace(keyValPair[0]), strings.TrimSpace(keyValPair[1])
-			selectorInSql += fmt.Sprintf(" AND NOT (payload -> 'metadata' -> 'labels' @> '{\"%s\": \"%s\"}')", key, val)
+			selectorInSql += " AND NOT (payload -> 'metadata' -> 'labels' @> jsonb_build_object(?::text, ?::text))"
+			args = append(args, key, val)
 		case strings.Contains(selector, "==") || strings.Contains(selector, "="):
 			var keyValPair []string
 			if strings.Contains(selector, "==") {
-				keyValPair = strings.Split(selector, "=")
+				keyValPair = strings.Split(selector, "==")
 			} else if strings.Contains(selector, "=") {
 				keyValPair = strings.Split(selector, "=")
 			}
 
 			if len(keyValPair) != 2 {
-				return "", fmt.Errorf(invalidLabelSelectorFormatMsg, selector)
+				return "", nil, fmt.Errorf(invalidLabelSelectorFormatMsg, selector)
 			}
 
 			key, val := strings.TrimSpace(keyValPair[0]), strings.TrimSpace(keyValPair[1])
-			selectorInSql += fmt.Sprintf(" AND payload -> 'metadata' -> 'labels' @> '{\"%s\": \"%s\"}'", key, val)
+			selectorInSql += " AND payload -> 'metadata' -> 'labels' @> jsonb_build_object(?::text, ?::text)"
+			args = append(args, key, val)
 		// case strings.Contains(selector, "notin"):
 		// 	keyValSetPair := strings.Split(selector, "notin")
 		// 	if len(keyValSetPair) != 2 {
-		// 		return "", fmt.Errorf(invalidLabelSelectorFormatMsg, selector)
+		// 		return "", nil, fmt.Errorf(invalidLabelSelectorFormatMsg, selector)
 		// 	}
 
 		// 	key, valSet := strings.TrimSpace(keyValSetPair[0]), strings.TrimSpace(keyValSetPair[1])
@@ -60,7 +65,7 @@ func ParseLabelSelector(labelSelector string) (string, error) {
 		// case strings.Contains(selector, "in"):
 		// 	keyValSetPair := strings.Split(selector, "in")
 		// 	if len(keyValSetPair) != 2 {
-		// 		return "", fmt.Errorf(invalidLabelSelectorFormatMsg, selector)
+		// 		return "", nil, fmt.Errorf(invalidLabelSelectorFormatMsg, selector)
 		// 	}
 
 		// 	key, valSet := strings.TrimSpace(keyValSetPair[0]), strings.TrimSpace(keyValSetPair[1])
@@ -75,14 +80,17 @@ func ParseLabelSelector(labelSelector string) (string, error) {
 		// 		}
 		// 	}
 		// 	selectorInSql += ")"
+		// the jsonb_exists is the ? operator, which would be taken as the placeholder of the query arguments
 		case strings.HasPrefix(selector, "!"):
 			key := strings.TrimSpace(strings.TrimPrefix(selector, "!"))
-			selectorInSql += fmt.Sprintf(" AND NOT (payload -> 'metadata' -> 'labels' ? '%s')", key)
+			selectorInSql += " AND NOT jsonb_exists(payload -> 'metadata' -> 'labels', ?)"
+			args = append(args, key)
 		default:
 			key := strings.TrimSpace(selector)
-			selectorInSql += fmt.Sprintf(" AND payload -> 'metadata' -> 'labels' ? '%s'", key)
+			selectorInSql += " AND jsonb_exists(payload -> 'metadata' -> 'labels', ?)"
+			args = append(args, key)
 		}
 	}
 
-	return selectorInSql, nil
+	return selectorInSql, args, nil
 }
@@ -0,0 +1,153 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+)
+
+const invalidFieldSelectorFormatMsg = "invalid field selector: %s"
+
+// ListFields are the fields of the list which can be selected by the fieldSelector and sorted by the sortBy, the value
+// is the SQL expression of the field over the row of the resource. The field is compared as text, and the missing
+// field is taken as the empty string like the field selector of the kubernetes.
+type ListFields map[string]string
+
+// names returns the supported fields for the error message
+func (f ListFields) names() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func (f ListFields) expression(field string) (string, error) {
+	expression, found := f[field]
+	if !found {
+		return "", fmt.Errorf("unsupported field %q, it should be one of %s", field, f.names())
+	}
+	return "coalesce(" + expression + ", '')", nil
+}
+
+// ParseFieldSelector translates the field selector, e.g. metadata.name=foo,leafHubName!=hub1, into the condition
+// appended to the WHERE clause, the values are the arguments of the query.
+func ParseFieldSelector(fieldSelector string, fields ListFields) (string, []interface{}, error) {
+	selectorInSql := ""
+	args := []interface{}{}
+	for _, selector := range strings.Split(fieldSelector, ",") {
+		operator := "="
+		keyValPair := []string{}
+		switch {
+		case strings.Contains(selector, "!="):
+			operator = "<>"
+			keyValPair = strings.Split(selector, "!=")
+		case strings.Contains(selector, "=="):
+			keyValPair = strings.Split(selector, "==")
+		case strings.Contains(selector, "="):
+			keyValPair = strings.Split(selector, "=")
+		}
+		if len(keyValPair) != 2 {
+			return "", nil, fmt.Errorf(invalidFieldSelectorFormatMsg, selector)
+		}
+
+		expression, err := fields.expression(strings.TrimSpace(keyValPair[0]))
+		if err != nil {
+			return "", nil, err
+		}
+		selectorInSql += fmt.Sprintf(" AND %s %s ?", expression, operator)
+		args = append(args, strings.TrimSpace(keyValPair[1]))
+	}
+	return selectorInSql, args, nil
+}
+
+// ParseLimit returns the LIMIT clause of the query and its argument, the clause is empty if the limit isn't set
+func ParseLimit(limit string) (string, []interface{}, error) {
+	if limit == "" {
+		return "", nil, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return "", nil, fmt.Errorf("invalid limit %q", limit)
+	}
+	return " LIMIT ?", []interface{}{n}, nil
+}
+
+// ListOrder is the order of the list. The rows are ordered by the sort key, then the name and uid, so the order is
+// stable and the continue token is the position of the last returned row in the order.
+type ListOrder struct {
+	// SortBy is the field to sort by, the field prefixed with "-" is sorted in the descending order
+	SortBy string
+
+	sortKey    string
+	name       string
+	uid        string
+	descending bool
+}
+
+// NewListOrder returns the order of the list sorted by the sortBy field, the name and uid are the SQL expressions of
+// the resource name and uid.
+func NewListOrder(sortBy string, fields ListFields, name, uid string) (*ListOrder, error) {
+	order := &ListOrder{SortBy: sortBy, name: name, uid: uid}
+	if sortBy == "" {
+		return order, nil
+	}
+	field := strings.TrimPrefix(sortBy, "-")
+	order.descending = field != sortBy
+	sortKey, err := fields.expression(field)
+	if err != nil {
+		return nil, err
+	}
+	order.sortKey = sortKey
+	return order, nil
+}
+
+func (o *ListOrder) columns() string {
+	if o.sortKey == "" {
+		return fmt.Sprintf("(%s, %s)", o.name, o.uid)
+	}
+	return fmt.Sprintf("(%s, %s, %s)", o.sortKey, o.name, o.uid)
+}
+
+// After returns the condition of the rows after the continue token and its arguments, it's empty for the first page
+func (o *ListOrder) After(token *ContinueToken) (string, []interface{}) {
+	if token == nil {
+		return "", nil
+	}
+	operator := ">"
+	if o.descending {
+		operator = "<"
+	}
+	if o.sortKey == "" {
+		return fmt.Sprintf(" AND %s %s (?, ?)", o.columns(), operator), []interface{}{token.LastName, token.LastUID}
+	}
+	return fmt.Sprintf(" AND %s %s (?, ?, ?)", o.columns(), operator),
+		[]interface{}{token.SortKey, token.LastName, token.LastUID}
+}
+
+// OrderBy returns the ORDER BY clause of the list, the reversed order returns the last row of the list first
+func (o *ListOrder) OrderBy(reversed bool) string {
+	if o.descending != reversed {
+		return " ORDER BY " + o.columns() + " DESC"
+	}
+	return " ORDER BY " + o.columns()
+}
+
+// ContinueToken returns the token of the row, the sort key of the sorted list is queried from the table by the uid
+func (o *ListOrder) ContinueToken(table, lastName, lastUID string) (string, error) {
+	token := &ContinueToken{LastName: lastName, LastUID: lastUID, SortBy: o.SortBy}
+	if o.sortKey != "" {
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? LIMIT 1", o.sortKey, table, o.uid)
+		if err := database.GetGorm().Raw(query, lastUID).Row().Scan(&token.SortKey); err != nil {
+			return "", fmt.Errorf("failed to query the sort key of %s: %w", lastName, err)
+		}
+	}
+	return EncodeSortedContinue(token)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testFields = ListFields{
+	"metadata.name": "payload -> 'metadata' ->> 'name'",
+	"leafHubName":   "leaf_hub_name",
+}
+
+func TestParseFieldSelector(t *testing.T) {
+	sql, args, err := ParseFieldSelector("metadata.name=foo,leafHubName!=hub1,leafHubName==hub2", testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSql := " AND coalesce(payload -> 'metadata' ->> 'name', '') = ?" +
+		" AND coalesce(leaf_hub_name, '') <> ? AND coalesce(leaf_hub_name, '') = ?"
+	if sql != expectedSql {
+		t.Errorf("expected %q, but got %q", expectedSql, sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"foo", "hub1", "hub2"}) {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	for _, selector := range []string{"metadata.uid=foo", "metadata.name", "a=b=c"} {
+		if _, _, err := ParseFieldSelector(selector, testFields); err == nil {
+			t.Errorf("expected the error for the field selector %q", selector)
+		}
+	}
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	sql, args, err := ParseLabelSelector("env=prod,tier!=db,vendor,!cloud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSql := " AND payload -> 'metadata' -> 'labels' @> jsonb_build_object(?::text, ?::text)" +
+		" AND NOT (payload -> 'metadata' -> 'labels' @> jsonb_build_object(?::text, ?::text))" +
+		" AND jsonb_exists(payload -> 'metadata' -> 'labels', ?)" +
+		" AND NOT jsonb_exists(payload -> 'metadata' -> 'labels', ?)"
+	if sql != expectedSql {
+		t.Errorf("expected %q, but got %q", expectedSql, sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"env", "prod", "tier", "db", "vendor", "cloud"}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	sql, args, err := ParseLimit("10")
+	if err != nil || sql != " LIMIT ?" || !reflect.DeepEqual(args, []interface{}{10}) {
+		t.Errorf("unexpected limit %q, %v, %v", sql, args, err)
+	}
+	if sql, _, err := ParseLimit(""); err != nil || sql != "" {
+		t.Errorf("expected no limit, but got %q, %v", sql, err)
+	}
+	for _, limit := range []string{"0", "-1", "1; DROP TABLE spec.policies"} {
+		if _, _, err := ParseLimit(limit); err == nil {
+			t.Errorf("expected the error for the limit %q", limit)
+		}
+	}
+}
+
+func TestListOrder(t *testing.T) {
+	order, err := NewListOrder("", testFields, "name", "uid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orderBy := order.OrderBy(false); orderBy != " ORDER BY (name, uid)" {
+		t.Errorf("unexpected order %q", orderBy)
+	}
+	if after, _ := order.After(nil); after != "" {
+		t.Errorf("expected no condition for the first page, but got %q", after)
+	}
+	after, args := order.After(&ContinueToken{LastName: "foo", LastUID: "1"})
+	if after != " AND (name, uid) > (?, ?)" || !reflect.DeepEqual(args, []interface{}{"foo", "1"}) {
+		t.Errorf("unexpected condition %q, %v", after, args)
+	}
+
+	order, err = NewListOrder("-leafHubName", testFields, "name", "uid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orderBy := order.OrderBy(false); orderBy != " ORDER BY (coalesce(leaf_hub_name, ''), name, uid) DESC" {
+		t.Errorf("unexpected order %q", orderBy)
+	}
+	if orderBy := order.OrderBy(true); orderBy != " ORDER BY (coalesce(leaf_hub_name, ''), name, uid)" {
+		t.Errorf("unexpected reversed order %q", orderBy)
+	}
+	after, args = order.After(&ContinueToken{LastName: "foo", LastUID: "1", SortKey: "hub1"})
+	if after != " AND (coalesce(leaf_hub_name, ''), name, uid) < (?, ?, ?)" ||
+		!reflect.DeepEqual(args, []interface{}{"hub1", "foo", "1"}) {
+		t.Errorf("unexpected condition %q, %v", after, args)
+	}
+
+	if _, err := NewListOrder("metadata.uid", testFields, "name", "uid"); err == nil {
+		t.Errorf("expected the error for the unsupported field")
+	}
+}
+
+func TestSortedContinue(t *testing.T) {
+	token, err := EncodeSortedContinue(&ContinueToken{LastName: "foo", LastUID: "1", SortBy: "-leafHubName"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSortedContinue(token, "-leafHubName")
+	if err != nil || decoded.LastName != "foo" || decoded.LastUID != "1" {
+		t.Errorf("unexpected token %v, %v", decoded, err)
+	}
+	if _, err := DecodeSortedContinue(token, ""); err == nil {
+		t.Errorf("expected the error for the token sorted by another field")
+	}
+
+	// the token of the unsorted list is still decoded by the DecodeContinue
+	token, err = EncodeContinue("foo", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, uid, err := DecodeContinue(token)
+	if err != nil || name != "foo" || uid != "1" {
+		t.Errorf("unexpected token %s, %s, %v", name, uid, err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/specdb"
 	"github.com/stolostron/multicluster-global-hub/pkg/constants"
 )
@@ -59,7 +60,9 @@ func (r *genericSpecController) Reconcile(ctx context.Context, request ctrl.Requ
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// the instance on hub was deleted, update all the matching instances in the database as deleted
-			if err := r.specDB.DeleteSpecObject(ctx, r.tableName, request.Name, request.Namespace); err != nil {
+			err := r.specDB.DeleteSpecObject(ctx, r.tableName, request.Name, request.Namespace)
+			r.recordAudit(ctx, "delete", request.Name, request.Namespace, nil, nil, err)
+			if err != nil {
 				return ctrl.Result{Requeue: true, RequeueAfter: requeueDuration}, err
 			}
 			return ctrl.Result{}, nil
@@ -88,7 +91,9 @@ func (r *genericSpecController) Reconcile(ctx context.Context, request ctrl.Requ
 	if !r.areEqual(instance, instanceInDatabase) {
 		reqLogger.Info("Mismatch between hub and the database, updating the database")
 
-		if err := r.specDB.UpdateSpecObject(ctx, r.tableName, string(instance.GetUID()), &instance); err != nil {
+		err := r.specDB.UpdateSpecObject(ctx, r.tableName, string(instance.GetUID()), &instance)
+		r.recordAudit(ctx, "update", instance.GetName(), instance.GetNamespace(), instanceInDatabase, instance, err)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
 	}
@@ -106,7 +111,9 @@ func (r *genericSpecController) removeFinalizerAndDelete(ctx context.Context, in
 	log.Info("Removing an instance from the database")
 
 	// the policy is being deleted, update all the matching policies in the database as deleted
-	if err := r.specDB.DeleteSpecObject(ctx, r.tableName, instance.GetName(), instance.GetNamespace()); err != nil {
+	err := r.specDB.DeleteSpecObject(ctx, r.tableName, instance.GetName(), instance.GetNamespace())
+	r.recordAudit(ctx, "delete", instance.GetName(), instance.GetNamespace(), r.cleanInstance(instance), nil, err)
+	if err != nil {
 		return fmt.Errorf("failed to delete an instance from the database: %w", err)
 	}
 
@@ -144,7 +151,9 @@ func (r *genericSpecController) insertInstanceToDatabase(ctx context.Context, in
 	if errors.Is(err, sql.ErrNoRows) {
 		log.Debug("The instance with the current UID does not exist in the database, inserting...")
 
-		if err := r.specDB.InsertSpecObject(ctx, r.tableName, instanceUID, &instance); err != nil {
+		err := r.specDB.InsertSpecObject(ctx, r.tableName, instanceUID, &instance)
+		r.recordAudit(ctx, "create", instance.GetName(), instance.GetNamespace(), nil, instance, err)
+		if err != nil {
 			return nil, err
 		}
 
@@ -160,6 +169,39 @@ func (r *genericSpecController) insertInstanceToDatabase(ctx context.Context, in
 	return instanceInTheDatabase, nil
 }
 
+// recordAudit records the change of the global resource by the user of its identity annotations, the before is nil for
+// the created instance and the after is nil for the deleted one. The failure of the recording is only logged.
+func (r *genericSpecController) recordAudit(ctx context.Context, action, name, namespace string,
+	before, after client.Object, err error,
+) {
+	object := audit.Object{Name: name, Namespace: namespace}
+	annotations := map[string]string{}
+	if before != nil {
+		object.ID = string(before.GetUID())
+		object.Before = before
+		annotations = before.GetAnnotations()
+	}
+	if after != nil {
+		object.ID = string(after.GetUID())
+		object.After = after
+		annotations = after.GetAnnotations()
+	}
+	user, groups := audit.UserFromAnnotations(annotations)
+	result, message := audit.Result(err)
+	if e := audit.Record(ctx, &audit.Event{
+		Source:   audit.SourceSpec,
+		User:     user,
+		Groups:   groups,
+		Action:   action,
+		Resource: r.tableName,
+		Objects:  []audit.Object{object},
+		Result:   result,
+		Message:  message,
+	}); e != nil {
+		r.log.Errorw("failed to record the audit event", "error", e)
+	}
+}
+
 func (r *genericSpecController) cleanInstance(originInstance client.Object) client.Object {
 	instance := originInstance.DeepCopyObject().(client.Object)
 
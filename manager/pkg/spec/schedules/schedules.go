@@ -0,0 +1,199 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package schedules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+)
+
+const (
+	// releaseHorizon limits the search of the time the deferred changes are released
+	releaseHorizon = 366 * 24 * time.Hour
+	// maxReleaseSteps limits the boundaries of the schedules checked for the release
+	maxReleaseSteps = 1000
+)
+
+var log = logger.ZapLogger("hub-schedules")
+
+// Schedule is the parsed maintenance window or change freeze of the hub
+type Schedule struct {
+	Name     string
+	Type     string
+	cron     cron.Schedule
+	location *time.Location
+	duration time.Duration
+}
+
+// Parse validates the schedule, the cron expression is the standard one with 5 fields, e.g. "0 22 * * 6".
+func Parse(row *models.HubSchedule) (*Schedule, error) {
+	if row.Type != models.HubScheduleWindow && row.Type != models.HubScheduleFreeze {
+		return nil, fmt.Errorf("invalid type %q, it should be %s or %s", row.Type, models.HubScheduleWindow,
+			models.HubScheduleFreeze)
+	}
+	if row.DurationSeconds <= 0 {
+		return nil, fmt.Errorf("invalid duration %ds, it should be positive", row.DurationSeconds)
+	}
+	schedule, err := cron.ParseStandard(row.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", row.Cron, err)
+	}
+	timezone := row.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", row.Timezone, err)
+	}
+	return &Schedule{
+		Name:     row.Name,
+		Type:     row.Type,
+		cron:     schedule,
+		location: location,
+		duration: time.Duration(row.DurationSeconds) * time.Second,
+	}, nil
+}
+
+// ActiveAt reports whether the schedule is active at the time, it's active from each start of the cron expression
+// for the duration.
+func (s *Schedule) ActiveAt(t time.Time) bool {
+	_, active := s.activeEnd(t)
+	return active
+}
+
+// activeEnd returns the end of the occurrence active at the time
+func (s *Schedule) activeEnd(t time.Time) (time.Time, bool) {
+	// the first start after the earliest start of the occurrence still active at the time
+	start := s.cron.Next(t.Add(-s.duration).In(s.location))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}, false
+	}
+	return start.Add(s.duration), true
+}
+
+// nextBoundary returns the first time after the time when the schedule starts or ends
+func (s *Schedule) nextBoundary(t time.Time) time.Time {
+	next := s.cron.Next(t.In(s.location))
+	if end, active := s.activeEnd(t); active && (next.IsZero() || end.Before(next)) {
+		return end
+	}
+	return next
+}
+
+// Deferral is the state of the spec changes of the hub deferred by its schedules, the changes are released at the
+// Until, which is empty if the release isn't found within a year.
+type Deferral struct {
+	Deferred bool       `json:"deferred"`
+	Reason   string     `json:"reason,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+// HubSchedules is the schedules of each hub
+type HubSchedules map[string][]*Schedule
+
+// Load returns the schedules of all the hubs, the invalid ones written to the table directly are ignored.
+func Load(ctx context.Context) (HubSchedules, error) {
+	db := database.GetGorm().WithContext(ctx)
+	// the table doesn't exist until the database is migrated, no hub has schedules then
+	var exists bool
+	if err := db.Raw("SELECT to_regclass(?) IS NOT NULL", models.HubSchedule{}.TableName()).Row().
+		Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check the hub schedules table - %w", err)
+	}
+	if !exists {
+		return HubSchedules{}, nil
+	}
+	rows := []models.HubSchedule{}
+	if err := db.Order("leaf_hub_name, name").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list the hub schedules - %w", err)
+	}
+	hubSchedules := HubSchedules{}
+	for i := range rows {
+		schedule, err := Parse(&rows[i])
+		if err != nil {
+			log.Warnw("ignore the invalid schedule", "hub", rows[i].LeafHubName, "name", rows[i].Name, "error", err)
+			continue
+		}
+		hubSchedules[rows[i].LeafHubName] = append(hubSchedules[rows[i].LeafHubName], schedule)
+	}
+	return hubSchedules, nil
+}
+
+// Deferral returns whether the spec changes of the hub are deferred at the time, and when they're released.
+func (h HubSchedules) Deferral(hub string, now time.Time) Deferral {
+	return deferral(h[hub], now)
+}
+
+// Release returns the earliest time the deferred changes of any hub are released, it's zero if none is deferred.
+func (h HubSchedules) Release(now time.Time) time.Time {
+	release := time.Time{}
+	for _, schedules := range h {
+		if d := deferral(schedules, now); d.Deferred && d.Until != nil &&
+			(release.IsZero() || d.Until.Before(release)) {
+			release = *d.Until
+		}
+	}
+	return release
+}
+
+func deferral(schedules []*Schedule, now time.Time) Deferral {
+	reason := blockedBy(schedules, now)
+	if reason == "" {
+		return Deferral{}
+	}
+	d := Deferral{Deferred: true, Reason: reason}
+	// the changes are released at the first boundary of the schedules when none of them blocks the changes
+	t := now
+	for i := 0; i < maxReleaseSteps; i++ {
+		next := time.Time{}
+		for _, schedule := range schedules {
+			if boundary := schedule.nextBoundary(t); !boundary.IsZero() && (next.IsZero() || boundary.Before(next)) {
+				next = boundary
+			}
+		}
+		if next.IsZero() || next.Sub(now) > releaseHorizon {
+			break
+		}
+		if blockedBy(schedules, next) == "" {
+			until := next.UTC()
+			d.Until = &until
+			break
+		}
+		t = next
+	}
+	return d
+}
+
+// blockedBy returns the reason the changes are blocked at the time, it's empty if they aren't blocked
+func blockedBy(schedules []*Schedule, t time.Time) string {
+	windows := []string{}
+	for _, schedule := range schedules {
+		if schedule.Type == models.HubScheduleFreeze && schedule.ActiveAt(t) {
+			return fmt.Sprintf("the change freeze %s is active", schedule.Name)
+		}
+	}
+	for _, schedule := range schedules {
+		if schedule.Type != models.HubScheduleWindow {
+			continue
+		}
+		if schedule.ActiveAt(t) {
+			return ""
+		}
+		windows = append(windows, schedule.Name)
+	}
+	if len(windows) == 0 {
+		return ""
+	}
+	sort.Strings(windows)
+	return fmt.Sprintf("none of the maintenance windows %v is open", windows)
+}
@@ -0,0 +1,93 @@
+package schedules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name  string
+		row   models.HubSchedule
+		valid bool
+	}{
+		{"the window", models.HubSchedule{Type: "window", Cron: "0 22 * * 6", DurationSeconds: 3600,
+			Timezone: "Europe/Paris"}, true},
+		{"the freeze without the timezone", models.HubSchedule{Type: "freeze", Cron: "0 0 24 12 *",
+			DurationSeconds: 3600}, true},
+		{"the invalid type", models.HubSchedule{Type: "pause", Cron: "0 22 * * 6", DurationSeconds: 3600}, false},
+		{"the invalid cron", models.HubSchedule{Type: "window", Cron: "0 22 * *", DurationSeconds: 3600}, false},
+		{"the invalid duration", models.HubSchedule{Type: "window", Cron: "0 22 * * 6"}, false},
+		{"the invalid timezone", models.HubSchedule{Type: "window", Cron: "0 22 * * 6", DurationSeconds: 3600,
+			Timezone: "Mars/Olympus"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse(&c.row)
+			if c.valid && err != nil {
+				t.Errorf("expected the valid schedule, but got %v", err)
+			}
+			if !c.valid && err == nil {
+				t.Error("expected the invalid schedule")
+			}
+		})
+	}
+}
+
+func TestDeferral(t *testing.T) {
+	parse := func(name, scheduleType, cron string, duration time.Duration, timezone string) *Schedule {
+		schedule, err := Parse(&models.HubSchedule{
+			Name: name, Type: scheduleType, Cron: cron, DurationSeconds: int64(duration.Seconds()), Timezone: timezone,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return schedule
+	}
+	// the window opens at 22:00 of every Saturday in Paris (UTC+2 in June) for 6 hours
+	window := parse("weekend", models.HubScheduleWindow, "0 22 * * 6", 6*time.Hour, "Europe/Paris")
+	// the freeze is active on the whole day of 2024-06-15, which is a Saturday
+	freeze := parse("release", models.HubScheduleFreeze, "0 0 15 6 *", 24*time.Hour, "UTC")
+
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	cases := []struct {
+		name      string
+		schedules []*Schedule
+		now       time.Time
+		deferred  bool
+		until     time.Time
+	}{
+		{"no schedule", nil, at("2024-06-12T10:00:00Z"), false, time.Time{}},
+		{"outside the window", []*Schedule{window}, at("2024-06-12T10:00:00Z"), true,
+			at("2024-06-15T20:00:00Z")},
+		{"inside the window", []*Schedule{window}, at("2024-06-15T21:00:00Z"), false, time.Time{}},
+		{"the freeze is active", []*Schedule{freeze}, at("2024-06-15T10:00:00Z"), true,
+			at("2024-06-16T00:00:00Z")},
+		{"the window opens in the freeze", []*Schedule{window, freeze}, at("2024-06-15T21:00:00Z"), true,
+			at("2024-06-16T00:00:00Z")},
+		{"the window is closed after the freeze", []*Schedule{window, freeze}, at("2024-06-16T03:00:00Z"), true,
+			at("2024-06-22T20:00:00Z")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := HubSchedules{"hub1": c.schedules}.Deferral("hub1", c.now)
+			if d.Deferred != c.deferred {
+				t.Fatalf("expected the deferred %v, but got %v: %s", c.deferred, d.Deferred, d.Reason)
+			}
+			if !c.deferred {
+				return
+			}
+			if d.Until == nil || !d.Until.Equal(c.until) {
+				t.Errorf("expected the changes to be released at %s, but got %v", c.until, d.Until)
+			}
+		})
+	}
+}
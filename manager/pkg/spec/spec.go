@@ -78,12 +78,17 @@ func AddDatabaseSyncers(mgr ctrl.Manager, config *configs.ManagerConfig, produce
 		syncers.AddPlacementsDBToTransportSyncer,
 		syncers.AddManagedClusterSetsDBToTransportSyncer,
 		syncers.AddManagedClusterSetBindingsDBToTransportSyncer,
+		syncers.AddDryRunsDBToTransportSyncer,
 	}
 	for _, addDBSyncerFunction := range addDBSyncerFunctions {
 		if err := addDBSyncerFunction(mgr, specDB, producer, specSyncInterval); err != nil {
 			return fmt.Errorf("failed to add DB Syncer: %w", err)
 		}
 	}
+	// the syncers are woken by the changes of their tables besides polling
+	if err := syncers.AddSpecNotifier(mgr); err != nil {
+		return fmt.Errorf("failed to add spec notifier: %w", err)
+	}
 	return nil
 }
 
@@ -26,14 +26,15 @@ func AddApplicationsDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.SpecDB,
 	specSyncInterval time.Duration,
 ) error {
 	createObjFunc := func() metav1.Object { return &applicationv1beta1.Application{} }
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-application"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		notifications:  notifier.subscribe(applicationsTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, applicationsMsgKey, specDB, applicationsTableName,
-				createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add applications db to transport syncer - %w", err)
@@ -26,14 +26,15 @@ func AddChannelsDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.SpecDB, prod
 	specSyncInterval time.Duration,
 ) error {
 	createObjFunc := func() metav1.Object { return &channelv1.Channel{} }
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-channels"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		notifications:  notifier.subscribe(channelsTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, channelsMsgKey, specDB, channelsTableName,
-				createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add channels db to transport syncer - %w", err)
@@ -27,14 +27,15 @@ func AddHoHConfigDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.SpecDB, pro
 	specSyncInterval time.Duration,
 ) error {
 	createObjFunc := func() metav1.Object { return &corev1.ConfigMap{} }
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
+	// the config table has no trigger, so it's only synced by polling
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-configmap"),
 		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, configMsgKey, specDB, configTableName,
-				createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add config db to transport syncer - %w", err)
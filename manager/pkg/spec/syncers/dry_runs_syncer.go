@@ -0,0 +1,180 @@
+package syncers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	subscriptionv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/specdb"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/syncers/interval"
+	"github.com/stolostron/multicluster-global-hub/pkg/bundle/spec"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
+	"github.com/stolostron/multicluster-global-hub/pkg/utils"
+)
+
+const dryRunsTableName = "dry_runs"
+
+// AddDryRunsDBToTransportSyncer adds the syncer sending the dry runs requested by the REST API to the hubs.
+func AddDryRunsDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.SpecDB, producer transport.Producer,
+	specSyncInterval time.Duration,
+) error {
+	delivery := newHubBundles(mgr.GetClient())
+	if err := mgr.Add(&genericDBToTransportSyncer{
+		log:            logger.ZapLogger("db-to-transport-syncer-dryrun"),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		notifications:  notifier.subscribe(dryRunsTableName),
+		syncBundleFunc: func(ctx context.Context) (bool, error) {
+			return syncDryRuns(ctx, producer, delivery)
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to add dry runs db to transport syncer - %w", err)
+	}
+	return nil
+}
+
+// syncDryRuns sends the objects of each unsent dry run to the hubs they target, the same as the global resources. The
+// dry run is sent again on the next sync if it fails to be sent to any hub.
+func syncDryRuns(ctx context.Context, producer transport.Producer, delivery *hubBundles) (bool, error) {
+	db := database.GetGorm().WithContext(ctx)
+	dryRuns := []models.SpecDryRun{}
+	if err := db.Where("sent_at IS NULL").Order("created_at").Find(&dryRuns).Error; err != nil {
+		return false, fmt.Errorf("failed to list the dry runs - %w", err)
+	}
+	if len(dryRuns) == 0 {
+		return false, nil
+	}
+	targets, err := newHubTargets(ctx, delivery.log, delivery.client)
+	if err != nil {
+		return false, fmt.Errorf("unable to sync the dry runs - %w", err)
+	}
+
+	synced := false
+	errs := []error{}
+	for _, dryRun := range dryRuns {
+		hubObjects, err := dryRunTargets(ctx, targets, &dryRun)
+		if err != nil {
+			// the invalid dry run is sent to no hub, so it isn't retried forever
+			delivery.log.Warnw("the dry run isn't sent to any hub", "id", dryRun.ID, "error", err)
+			hubObjects = map[string][]*unstructured.Unstructured{}
+		}
+		if err := sendDryRun(ctx, producer, dryRun.ID, hubObjects); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		hubs := sortedKeys(toSet(hubObjects))
+		targetHubs, err := json.Marshal(hubs)
+		if err != nil {
+			return synced, err
+		}
+		if err := db.Model(&models.SpecDryRun{}).Where("id = ?", dryRun.ID).Updates(map[string]interface{}{
+			"target_hubs": targetHubs,
+			"sent_at":     time.Now(),
+		}).Error; err != nil {
+			errs = append(errs, fmt.Errorf("failed to update the dry run %s - %w", dryRun.ID, err))
+			continue
+		}
+		delivery.log.Infow("sent the dry run", "id", dryRun.ID, "hubs", hubs)
+		synced = true
+	}
+	return synced, errors.Join(errs...)
+}
+
+// dryRunTargets returns the objects of the dry run sent to each hub, which is limited by the hubs of the dry run
+func dryRunTargets(ctx context.Context, targets *hubTargets,
+	dryRun *models.SpecDryRun,
+) (map[string][]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	if err := json.Unmarshal(dryRun.Objects, &objects); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the objects - %w", err)
+	}
+	limited := map[string]bool{}
+	if len(dryRun.Hubs) > 0 {
+		hubs := []string{}
+		if err := json.Unmarshal(dryRun.Hubs, &hubs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the hubs - %w", err)
+		}
+		for _, hub := range hubs {
+			limited[hub] = true
+		}
+	}
+
+	hubObjects := map[string][]*unstructured.Unstructured{}
+	for _, obj := range objects {
+		typed, err := typedObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		hubs, err := targets.of(ctx, typed)
+		if err != nil {
+			return nil, err
+		}
+		for _, hub := range hubs {
+			if len(limited) == 0 || limited[hub] {
+				hubObjects[hub] = append(hubObjects[hub], obj)
+			}
+		}
+	}
+	return hubObjects, nil
+}
+
+// typedObject converts the object of the kinds whose targets depend on their fields, the others are delivered by
+// their annotations only
+func typedObject(obj *unstructured.Unstructured) (metav1.Object, error) {
+	var typed metav1.Object
+	switch obj.GroupVersionKind().GroupKind() {
+	case clusterv1beta1.SchemeGroupVersion.WithKind(placementKind).GroupKind():
+		typed = &clusterv1beta1.Placement{}
+	case policyv1.SchemeGroupVersion.WithKind("PlacementBinding").GroupKind():
+		typed = &policyv1.PlacementBinding{}
+	case policyv1.SchemeGroupVersion.WithKind(policyKind).GroupKind():
+		typed = &policyv1.Policy{}
+	case subscriptionv1.SchemeGroupVersion.WithKind("Subscription").GroupKind():
+		typed = &subscriptionv1.Subscription{}
+	default:
+		return obj, nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed); err != nil {
+		return nil, fmt.Errorf("failed to convert the %s %s - %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return typed, nil
+}
+
+func sendDryRun(ctx context.Context, producer transport.Producer, id string,
+	hubObjects map[string][]*unstructured.Unstructured,
+) error {
+	errs := []error{}
+	for hub, objects := range hubObjects {
+		payloadBytes, err := json.Marshal(&spec.DryRunSpecBundle{ID: id, Objects: objects})
+		if err != nil {
+			return fmt.Errorf("failed to marshal the dry run %s - %w", id, err)
+		}
+		evt := utils.ToCloudEvent(constants.SpecDryRunMsgKey, constants.CloudEventSourceGlobalHub, hub, payloadBytes)
+		if err := producer.SendEvent(ctx, evt); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send the dry run %s to the hub %s - %w", id, hub, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func toSet(hubObjects map[string][]*unstructured.Unstructured) map[string]bool {
+	set := make(map[string]bool, len(hubObjects))
+	for hub := range hubObjects {
+		set[hub] = true
+	}
+	return set
+}
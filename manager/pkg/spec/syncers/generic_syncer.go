@@ -2,16 +2,29 @@ package syncers
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/controllers/bundle"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/schedules"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/specdb"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/syncers/interval"
 	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
 	"github.com/stolostron/multicluster-global-hub/pkg/transport"
 	"github.com/stolostron/multicluster-global-hub/pkg/utils"
 )
@@ -20,6 +33,9 @@ type genericDBToTransportSyncer struct {
 	log            *zap.SugaredLogger
 	intervalPolicy interval.IntervalPolicy
 	syncBundleFunc func(ctx context.Context) (bool, error)
+	// notifications wakes the syncer when its tables change, the polling by the interval policy is the safety net of
+	// the missed notifications, and the only way to sync the changes out of the tables
+	notifications <-chan struct{}
 }
 
 func (syncer *genericDBToTransportSyncer) Start(ctx context.Context) error {
@@ -46,16 +62,13 @@ func (syncer *genericDBToTransportSyncer) periodicSync(ctx context.Context) {
 			ticker.Stop()
 			return
 
-		case <-ticker.C:
-			// define timeout of max sync interval on the sync function
-			ctxWithTimeout, cancelFunc := context.WithTimeout(ctx, syncer.intervalPolicy.GetMaxInterval())
-
-			synced, err := syncer.syncBundleFunc(ctxWithTimeout)
-			if err != nil {
-				syncer.log.Error(err, "failed to sync bundle")
-			}
+		case <-syncer.notifications:
+			// the changes are synced now, so the next polling is postponed
+			ticker.Reset(syncer.intervalPolicy.GetInterval())
+			syncer.sync(ctx)
 
-			cancelFunc() // cancel child ctx and is used to cleanup resources once context expires or sync is done.
+		case <-ticker.C:
+			synced := syncer.sync(ctx)
 
 			// get current sync interval
 			currentInterval := syncer.intervalPolicy.GetInterval()
@@ -79,42 +92,273 @@ func (syncer *genericDBToTransportSyncer) periodicSync(ctx context.Context) {
 	}
 }
 
-// syncObjectsBundle performs the actual sync logic and returns true if bundle was committed to transport,
-// otherwise false.
+func (syncer *genericDBToTransportSyncer) sync(ctx context.Context) bool {
+	// define timeout of max sync interval on the sync function
+	ctxWithTimeout, cancelFunc := context.WithTimeout(ctx, syncer.intervalPolicy.GetMaxInterval())
+	defer cancelFunc() // cancel child ctx and is used to cleanup resources once context expires or sync is done.
+
+	synced, err := syncer.syncBundleFunc(ctxWithTimeout)
+	if err != nil {
+		syncer.log.Error(err, "failed to sync bundle")
+	}
+	return synced
+}
+
+// syncObjectsBundle sends the bundle of the objects targeted at each managed hub, the objects not targeted at the hub
+// are only sent as their identities to be deleted. It returns true if any bundle was committed to transport, otherwise
+// false.
 func syncObjectsBundle(ctx context.Context, producer transport.Producer, eventType string,
 	specDB specdb.SpecDB, dbTableName string, createObjFunc bundle.CreateObjectFunction,
-	createBundleFunc bundle.CreateBundleFunction, lastSyncTimestampPtr *time.Time,
+	createBundleFunc bundle.CreateBundleFunction, delivery *hubBundles,
 ) (bool, error) {
-	lastUpdateTimestamp, err := specDB.GetLastUpdateTimestamp(ctx, dbTableName, true) // filter local resources
+	// the targets depend on the hubs, their labels and clusters besides the objects, so the bundles are rebuilt once
+	// any of them changes, and only the changed ones are sent
+	hubSchedules, err := schedules.Load(ctx)
 	if err != nil {
 		return false, fmt.Errorf("unable to sync bundle - %w", err)
 	}
-
-	if !lastUpdateTimestamp.After(*lastSyncTimestampPtr) { // sync only if something has changed
+	now := time.Now()
+	inputs, err := delivery.inputsDigest(ctx, dbTableName, hubSchedules, now)
+	if err != nil {
+		return false, fmt.Errorf("unable to sync bundle - %w", err)
+	}
+	// the progressing rollouts are moved forward by the time and the health of the hubs, so they're always rebuilt
+	if !delivery.progressing && delivery.inputs != nil && *delivery.inputs == inputs {
 		return false, nil
 	}
+	delivery.inputs = nil
 
-	// if we got here, then the last update timestamp from db is after what we have in memory.
-	// this means something has changed in db, syncing all the objects to transport.
-	bundleResult := createBundleFunc()
-	lastUpdateTimestamp, err = specDB.GetObjectsBundle(ctx, dbTableName, createObjFunc, bundleResult)
+	objects := &objectsCollector{}
+	if _, err := specDB.GetObjectsBundle(ctx, dbTableName, createObjFunc, objects); err != nil {
+		return false, fmt.Errorf("unable to sync bundle - %w", err)
+	}
+	targets, err := newHubTargets(ctx, delivery.log, delivery.client)
 	if err != nil {
 		return false, fmt.Errorf("unable to sync bundle - %w", err)
 	}
+	objectHubs := make([]map[string]bool, len(objects.objects))
+	for i, obj := range objects.objects {
+		hubs, err := targets.of(ctx, obj.object)
+		if err != nil {
+			return false, fmt.Errorf("unable to resolve the hubs of the object %s/%s - %w",
+				obj.object.GetNamespace(), obj.object.GetName(), err)
+		}
+		objectHubs[i] = map[string]bool{}
+		for _, hub := range hubs {
+			objectHubs[i][hub] = true
+		}
+	}
+
+	// the objects with the rollout strategy are released to the hubs wave by wave
+	rollouts, err := resolveRollouts(ctx, delivery.log, targets, objects, objectHubs, createObjFunc)
+	if err != nil {
+		return false, fmt.Errorf("unable to resolve the rollouts - %w", err)
+	}
+
+	delivery.progressing = false
+	for _, rollout := range rollouts {
+		if rollout != nil && rollout.progressing {
+			delivery.progressing = true
+		}
+	}
+	delivery.labelTargeted = targets.hubLabels != nil
+	delivery.placementTargeted = len(targets.placementHubs) > 0
+
+	synced := false
+	errs := []error{}
+	digests := map[string][sha256.Size]byte{}
+	for _, hub := range targets.hubs {
+		// the changes of the hubs are deferred by their maintenance windows and change freezes
+		if deferral := hubSchedules.Deferral(hub, now); deferral.Deferred {
+			// the last delivered bundle is kept, so the changes are sent once they're released
+			if lastDigest, found := delivery.digests[hub]; found {
+				digests[hub] = lastDigest
+			}
+			deferHub(delivery.log, eventType, hub, deferral)
+			continue
+		}
+		hubBundle := createBundleFunc()
+		for i, obj := range objects.objects {
+			if !objectHubs[i][hub] {
+				hubBundle.AddDeletedObject(identityOf(obj.object, obj.uid))
+				continue
+			}
+			if rollout := rollouts[i]; rollout != nil && !rollout.isReleased(hub) {
+				switch {
+				case rollout.previous != nil:
+					hubBundle.AddObject(rollout.previous, obj.uid)
+				case rollout.aborted:
+					hubBundle.AddDeletedObject(identityOf(obj.object, obj.uid))
+				}
+				continue
+			}
+			hubBundle.AddObject(obj.object, obj.uid)
+		}
+		for _, deletedObject := range objects.deletedObjects {
+			hubBundle.AddDeletedObject(identityOf(deletedObject, ""))
+		}
+
+		payloadBytes, err := json.Marshal(hubBundle)
+		if err != nil {
+			return false, fmt.Errorf("failed to sync marshal bundle(%s)", eventType)
+		}
+		digest := sha256.Sum256(payloadBytes)
+		if lastDigest, found := delivery.digests[hub]; found && lastDigest == digest {
+			digests[hub] = digest
+			continue
+		}
+
+		evt := utils.ToCloudEvent(eventType, constants.CloudEventSourceGlobalHub, hub, payloadBytes)
+		if err := producer.SendEvent(ctx, evt); err != nil {
+			// the bundle is sent again on the next sync
+			errs = append(errs, fmt.Errorf("failed to sync message(%s) from table(%s) to destination(%s) - %w",
+				eventType, dbTableName, hub, err))
+			continue
+		}
+		digests[hub] = digest
+		synced = true
+	}
+	// the removed hubs are forgotten, so the bundle is sent again if the hub joins back
+	delivery.digests = digests
+	if len(errs) > 0 {
+		return synced, errors.Join(errs...)
+	}
+	delivery.inputs = &inputs
+	return synced, nil
+}
+
+// deferHub wakes the syncers once the deferred changes of the hub are released, the changes are also released by
+// the polling if the release isn't found.
+func deferHub(log *zap.SugaredLogger, eventType, hub string, deferral schedules.Deferral) {
+	log.Debugw("the spec changes are deferred", "type", eventType, "hub", hub, "reason", deferral.Reason,
+		"until", deferral.Until)
+	if deferral.Until != nil {
+		notifier.wakeAt(*deferral.Until)
+	}
+}
+
+// hubBundles is the state of the bundles delivered from a table to the managed hubs
+type hubBundles struct {
+	log     *zap.SugaredLogger
+	client  client.Client
+	digests map[string][sha256.Size]byte
+	// inputs is the digest of the inputs the bundles were built from, it's nil if they weren't all delivered
+	inputs *[sha256.Size]byte
+	// the labels and the clusters of the hubs are only the inputs of the bundles if they targeted any object
+	labelTargeted     bool
+	placementTargeted bool
+	progressing       bool
+}
+
+// bundleInputs is the inputs of the bundles of a table besides the objects, which are checked on each sync
+type bundleInputs struct {
+	// the count of the rows tells the deleted ones, which don't change the last update time
+	TableUpdatedAt              sql.NullTime
+	TableCount                  int64
+	PlacementsUpdatedAt         sql.NullTime
+	PlacementBindingsUpdatedAt  sql.NullTime
+	ClusterSetBindingsUpdatedAt sql.NullTime
+	RolloutsUpdatedAt           sql.NullTime
+	RolloutsCount               int64
+	Hubs                        []string
+	HubLabels                   map[string]map[string]string `json:",omitempty"`
+	HubClusterSets              []hubClusterSet              `json:",omitempty"`
+	DeferredHubs                []string
+}
+
+type hubClusterSet struct {
+	LeafHubName string
+	ClusterSet  string
+}
 
-	// send message to transport
-	payloadBytes, err := json.Marshal(bundleResult)
+// inputsDigest returns the digest of the inputs of the bundles of the table at the time, they're cheap to check
+// compared to rebuilding the bundles
+func (d *hubBundles) inputsDigest(ctx context.Context, dbTableName string, hubSchedules schedules.HubSchedules,
+	now time.Time,
+) ([sha256.Size]byte, error) {
+	db := database.GetGorm().WithContext(ctx)
+	inputs := bundleInputs{}
+	if err := db.Raw(fmt.Sprintf(`SELECT (SELECT MAX(updated_at) FROM spec.%s), (SELECT COUNT(*) FROM spec.%s),
+		(SELECT MAX(updated_at) FROM spec.%s), (SELECT MAX(updated_at) FROM spec.%s),
+		(SELECT MAX(updated_at) FROM spec.%s), (SELECT MAX(updated_at) FROM spec.rollouts),
+		(SELECT COUNT(*) FROM spec.rollouts)`, dbTableName, dbTableName, placementsTableName,
+		placementBindingsTableName, managedClusterSetBindingsTableName)).Row().Scan(&inputs.TableUpdatedAt,
+		&inputs.TableCount, &inputs.PlacementsUpdatedAt, &inputs.PlacementBindingsUpdatedAt,
+		&inputs.ClusterSetBindingsUpdatedAt, &inputs.RolloutsUpdatedAt, &inputs.RolloutsCount); err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to check the tables of the bundles - %w", err)
+	}
+	if err := db.Model(&models.LeafHubHeartbeat{}).Order("leaf_hub_name").
+		Pluck("leaf_hub_name", &inputs.Hubs).Error; err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to list the managed hubs - %w", err)
+	}
+	if d.labelTargeted {
+		clusters := &clusterv1.ManagedClusterList{}
+		if err := d.client.List(ctx, clusters); err != nil {
+			return [sha256.Size]byte{}, fmt.Errorf("failed to list the managed hubs - %w", err)
+		}
+		inputs.HubLabels = map[string]map[string]string{}
+		for _, cluster := range clusters.Items {
+			inputs.HubLabels[cluster.Name] = cluster.Labels
+		}
+	}
+	if d.placementTargeted {
+		if err := db.Raw(fmt.Sprintf(`SELECT DISTINCT leaf_hub_name,
+			COALESCE(payload -> 'metadata' -> 'labels' ->> '%s', '') AS cluster_set FROM status.managed_clusters
+			WHERE deleted_at IS NULL ORDER BY leaf_hub_name, cluster_set`, clusterv1beta2.ClusterSetLabel)).
+			Scan(&inputs.HubClusterSets).Error; err != nil {
+			return [sha256.Size]byte{}, fmt.Errorf("failed to list the cluster sets of the hubs - %w", err)
+		}
+	}
+	for _, hub := range inputs.Hubs {
+		if hubSchedules.Deferral(hub, now).Deferred {
+			inputs.DeferredHubs = append(inputs.DeferredHubs, hub)
+		}
+	}
+	inputsBytes, err := json.Marshal(inputs)
 	if err != nil {
-		return false, fmt.Errorf("failed to sync marshal bundle(%s)", eventType)
+		return [sha256.Size]byte{}, err
 	}
+	return sha256.Sum256(inputsBytes), nil
+}
 
-	evt := utils.ToCloudEvent(eventType, constants.CloudEventSourceGlobalHub, transport.Broadcast, payloadBytes)
-	if err := producer.SendEvent(ctx, evt); err != nil {
-		return false, fmt.Errorf("failed to sync message(%s) from table(%s) to destination(%s) - %w",
-			eventType, dbTableName, transport.Broadcast, err)
+func newHubBundles(c client.Client) *hubBundles {
+	return &hubBundles{
+		log:     logger.ZapLogger("spec-hub-targets"),
+		client:  c,
+		digests: map[string][sha256.Size]byte{},
 	}
+}
+
+type collectedObject struct {
+	object metav1.Object
+	uid    string
+}
 
-	// updating value to retain same ptr between calls
-	*lastSyncTimestampPtr = *lastUpdateTimestamp
-	return true, nil
+// objectsCollector collects the objects of the table to build the bundle of each hub
+type objectsCollector struct {
+	objects        []collectedObject
+	deletedObjects []metav1.Object
+}
+
+func (c *objectsCollector) AddObject(object metav1.Object, objectUID string) {
+	c.objects = append(c.objects, collectedObject{object: object, uid: objectUID})
+}
+
+func (c *objectsCollector) AddDeletedObject(object metav1.Object) {
+	c.deletedObjects = append(c.deletedObjects, object)
+}
+
+// identityOf returns the kind, name and namespace of the object, so the hub deletes the object without receiving its
+// content. The origin annotation restricts the deletion to the object delivered by the global hub.
+func identityOf(object metav1.Object, objectUID string) *unstructured.Unstructured {
+	identity := &unstructured.Unstructured{}
+	if runtimeObject, ok := object.(runtime.Object); ok {
+		identity.SetGroupVersionKind(runtimeObject.GetObjectKind().GroupVersionKind())
+	}
+	identity.SetName(object.GetName())
+	identity.SetNamespace(object.GetNamespace())
+	if objectUID != "" {
+		identity.SetAnnotations(map[string]string{constants.OriginOwnerReferenceAnnotation: objectUID})
+	}
+	return identity
 }
@@ -5,11 +5,13 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"time"
 
 	"gorm.io/gorm"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/schedules"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/specdb"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/syncers/interval"
 	"github.com/stolostron/multicluster-global-hub/pkg/bundle/spec"
@@ -21,21 +23,29 @@ import (
 	"github.com/stolostron/multicluster-global-hub/pkg/utils"
 )
 
-const managedClusterLabelsDBTableName = "managed_clusters_labels"
+const (
+	managedClusterLabelsDBTableName      = "managed_clusters_labels"
+	managedClusterLabelOwnersDBTableName = "managed_cluster_label_owners"
+)
 
 // AddManagedClusterLabelsDBToTransportSyncer adds managed-cluster labels db to transport syncer to the manager.
 func AddManagedClusterLabelsDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.SpecDB, producer transport.Producer,
 	specSyncInterval time.Duration,
 ) error {
 	lastSyncTimestampPtr := &time.Time{}
+	// the labels of the deferred hubs are sent once they're released even if they aren't changed since then
+	deferredHubs := map[string]bool{}
+	// the labels of all the hubs are sent once the owners of the label keys are changed
+	lastOwners := map[string]string{}
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-managedclusterlabel"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		notifications:  notifier.subscribe(managedClusterLabelsDBTableName, managedClusterLabelOwnersDBTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncManagedClusterLabelsBundles(ctx, producer,
 				constants.ManagedClustersLabelsMsgKey, specDB,
-				managedClusterLabelsDBTableName, lastSyncTimestampPtr)
+				managedClusterLabelsDBTableName, lastSyncTimestampPtr, deferredHubs, lastOwners)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add managed-cluster labels db to transport syncer - %w", err)
@@ -47,26 +57,51 @@ func AddManagedClusterLabelsDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.
 // syncManagedClusterLabelsBundles performs the actual sync logic and returns true if bundle was committed to transport,
 // otherwise false.
 func syncManagedClusterLabelsBundles(ctx context.Context, producer transport.Producer, transportBundleKey string,
-	specDB specdb.SpecDB, dbTableName string, lastSyncTimestampPtr *time.Time,
+	specDB specdb.SpecDB, dbTableName string, lastSyncTimestampPtr *time.Time, deferredHubs map[string]bool,
+	lastOwners map[string]string,
 ) (bool, error) {
 	lastUpdateTimestamp, err := specDB.GetLastUpdateTimestamp(ctx, dbTableName, false) // no resources in table
 	if err != nil {
 		return false, fmt.Errorf("unable to sync bundle - %w", err)
 	}
+	owners, err := getManagedClusterLabelOwners(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to sync bundle - %w", err)
+	}
+	ownersChanged := !maps.Equal(owners, lastOwners)
 
-	if !lastUpdateTimestamp.After(*lastSyncTimestampPtr) { // sync only if something has changed
+	// sync only if something has changed or deferred
+	if !lastUpdateTimestamp.After(*lastSyncTimestampPtr) && len(deferredHubs) == 0 && !ownersChanged {
 		return false, nil
 	}
 
 	// if we got here, then the last update timestamp from db is after what we have in memory.
 	// this means something has changed in db, syncing to transport.
-	leafHubToLabelsSpecBundleMap, err := getUpdatedManagedClusterLabelsBundles(lastSyncTimestampPtr)
+	updatedSince := lastSyncTimestampPtr
+	if ownersChanged {
+		updatedSince = &time.Time{}
+	}
+	leafHubToLabelsSpecBundleMap, err := getUpdatedManagedClusterLabelsBundles(updatedSince,
+		sortedKeys(deferredHubs))
 	if err != nil {
 		return false, fmt.Errorf("unable to sync bundle - %w", err)
 	}
+	hubSchedules, err := schedules.Load(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to sync bundle - %w", err)
+	}
+	now := time.Now()
+	log := logger.ZapLogger("db-to-transport-syncer-managedclusterlabel")
 
 	// sync bundle per leaf hub
+	synced := false
 	for leafHubName, managedClusterLabelsBundle := range leafHubToLabelsSpecBundleMap {
+		if deferral := hubSchedules.Deferral(leafHubName, now); deferral.Deferred {
+			deferredHubs[leafHubName] = true
+			deferHub(log, transportBundleKey, leafHubName, deferral)
+			continue
+		}
+		managedClusterLabelsBundle.Owners = owners
 		payloadBytes, err := json.Marshal(managedClusterLabelsBundle)
 		if err != nil {
 			return false, fmt.Errorf("failed to sync marshal bundle(%s)", transportBundleKey)
@@ -77,23 +112,41 @@ func syncManagedClusterLabelsBundles(ctx context.Context, producer transport.Pro
 			return false, fmt.Errorf("failed to sync message(%s) from table(%s) to destination(%s) - %w",
 				leafHubName, dbTableName, transport.Broadcast, err)
 		}
+		delete(deferredHubs, leafHubName)
+		synced = true
 	}
 
 	// updating value to retain same ptr between calls
 	*lastSyncTimestampPtr = *lastUpdateTimestamp
+	clear(lastOwners)
+	maps.Copy(lastOwners, owners)
+
+	return synced, nil
+}
 
-	return true, nil
+// getManagedClusterLabelOwners returns a map of label key -> owner of the keys with the owners.
+func getManagedClusterLabelOwners(ctx context.Context) (map[string]string, error) {
+	rows := []models.ManagedClusterLabelOwner{}
+	if err := database.GetGorm().WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list the managed cluster label owners - %w", err)
+	}
+	owners := make(map[string]string, len(rows))
+	for _, row := range rows {
+		owners[row.LabelKey] = row.Owner
+	}
+	return owners, nil
 }
 
 // getUpdatedManagedClusterLabelsBundles returns a map of leaf-hub -> ManagedClusterLabelsSpecBundle of objects
-// belonging to a leaf-hub that had at least once update since the given timestamp, from a specific table.
-func getUpdatedManagedClusterLabelsBundles(timestamp *time.Time,
+// belonging to a leaf-hub that had at least once update since the given timestamp, or to the deferred leaf-hubs, from
+// a specific table.
+func getUpdatedManagedClusterLabelsBundles(timestamp *time.Time, deferredHubs []string,
 ) (map[string]*spec.ManagedClusterLabelsSpecBundle, error) {
 	db := database.GetGorm()
 	// select ManagedClusterLabelsSpec entries information from DB
-	rows, err := db.Raw(fmt.Sprintf(`SELECT * FROM spec.%[1]s WHERE leaf_hub_name IN (SELECT DISTINCT(leaf_hub_name) 
-		from spec.%[1]s WHERE updated_at::timestamp > timestamp '%[2]s') AND leaf_hub_name <> ''`,
-		managedClusterLabelsDBTableName, timestamp.Format(time.RFC3339Nano))).Rows()
+	rows, err := db.Raw(fmt.Sprintf(`SELECT * FROM spec.%[1]s WHERE (leaf_hub_name IN (SELECT DISTINCT(leaf_hub_name) 
+		from spec.%[1]s WHERE updated_at::timestamp > timestamp '%[2]s') OR leaf_hub_name IN ?) AND leaf_hub_name <> ''`,
+		managedClusterLabelsDBTableName, timestamp.Format(time.RFC3339Nano)), deferredHubs).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -29,14 +29,15 @@ func AddManagedClusterSetBindingsDBToTransportSyncer(mgr ctrl.Manager, specDB sp
 	createObjFunc := func() metav1.Object {
 		return &clusterv1beta2.ManagedClusterSetBinding{}
 	}
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-managedclustersetbinding"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		notifications:  notifier.subscribe(managedClusterSetBindingsTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, managedClusterSetBindingsMsgKey, specDB,
-				managedClusterSetBindingsTableName, createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				managedClusterSetBindingsTableName, createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add managed-cluster-set-bindings db to transport syncer - %w", err)
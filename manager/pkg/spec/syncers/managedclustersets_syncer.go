@@ -26,14 +26,15 @@ func AddManagedClusterSetsDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.Sp
 	specSyncInterval time.Duration,
 ) error {
 	createObjFunc := func() metav1.Object { return &clusterv1beta2.ManagedClusterSet{} }
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-managedclusterset"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		notifications:  notifier.subscribe(managedClusterSetsTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, managedClusterSetsMsgKey, specDB, managedClusterSetsTableName,
-				createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add managed-cluster-sets db to transport syncer - %w", err)
@@ -0,0 +1,164 @@
+package syncers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+)
+
+const (
+	// specChangesChannel is notified by the triggers of the spec tables with the name of the changed table
+	specChangesChannel = "spec_changes"
+	// hubSchedulesTableName is the schedules deferring the changes of the hubs, which wakes all the syncers
+	hubSchedulesTableName = "hub_schedules"
+	// notifiedSyncInterval is the interval of polling the tables that only change by the notified statements, the
+	// polling is the safety net of the missed notifications
+	notifiedSyncInterval = time.Minute
+
+	listenerMinReconnectInterval = time.Second
+	listenerMaxReconnectInterval = time.Minute
+	// listenerPingInterval is the interval of checking the connection of the listener, which isn't aware of the lost
+	// connection until it's used
+	listenerPingInterval = 90 * time.Second
+)
+
+// notifiedInterval returns the polling interval of the syncer whose tables notify their changes, the polling is the
+// safety net of the missed notifications, and it picks up the changes of the hubs, which are cheaply checked by the
+// inputs of the bundles
+func notifiedInterval(specSyncInterval time.Duration) time.Duration {
+	if specSyncInterval > notifiedSyncInterval {
+		return specSyncInterval
+	}
+	return notifiedSyncInterval
+}
+
+// notifier is shared by the syncers of the manager, they're only woken by the notifications once it's added to the
+// manager.
+var notifier = newSpecNotifier()
+
+// specNotifier listens to the changes of the spec tables, and wakes the syncers subscribing to the changed tables.
+type specNotifier struct {
+	log         *zap.SugaredLogger
+	mutex       sync.Mutex
+	subscribers map[string][]chan struct{}
+	// release wakes all the subscribers when the deferred changes of the hubs are released
+	release   *time.Timer
+	releaseAt time.Time
+}
+
+func newSpecNotifier() *specNotifier {
+	return &specNotifier{
+		log:         logger.ZapLogger("spec-notifier"),
+		subscribers: map[string][]chan struct{}{},
+	}
+}
+
+// AddSpecNotifier adds the listener of the changes of the spec tables to the manager.
+func AddSpecNotifier(mgr ctrl.Manager) error {
+	return mgr.Add(notifier)
+}
+
+// subscribe returns the channel notified when any of the tables changes. The notifications are folded into one until
+// the subscriber receives it, so the busy syncer syncs the changes once.
+func (n *specNotifier) subscribe(tables ...string) <-chan struct{} {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	notifications := make(chan struct{}, 1)
+	for _, table := range tables {
+		n.subscribers[table] = append(n.subscribers[table], notifications)
+	}
+	return notifications
+}
+
+// notify wakes the subscribers of the table, or all the subscribers if the table is empty
+func (n *specNotifier) notify(table string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for subscribedTable, subscribers := range n.subscribers {
+		if table != "" && table != subscribedTable {
+			continue
+		}
+		for _, notifications := range subscribers {
+			select {
+			case notifications <- struct{}{}:
+			default: // the subscriber hasn't received the previous notification yet
+			}
+		}
+	}
+}
+
+// wakeAt wakes all the subscribers at the time, e.g. the deferred changes are released, only the earliest time is
+// kept until it's passed.
+func (n *specNotifier) wakeAt(t time.Time) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	now := time.Now()
+	if n.release != nil && n.releaseAt.After(now) && !t.Before(n.releaseAt) {
+		return
+	}
+	if n.release != nil {
+		n.release.Stop()
+	}
+	n.releaseAt = t
+	n.release = time.AfterFunc(t.Sub(now), func() { n.notify("") })
+}
+
+func (n *specNotifier) Start(ctx context.Context) error {
+	listener, err := database.NewListener(listenerMinReconnectInterval, listenerMaxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				n.log.Warnw("the connection of the spec listener is changed", "event", event, "error", err)
+			}
+		})
+	if err != nil {
+		// the syncers still sync the changes by polling
+		n.log.Errorw("failed to create the spec listener", "error", err)
+		<-ctx.Done()
+		return nil
+	}
+	defer func() {
+		if err := listener.Close(); err != nil {
+			n.log.Warnw("failed to close the spec listener", "error", err)
+		}
+	}()
+	if err := listener.Listen(specChangesChannel); err != nil {
+		n.log.Errorw("failed to listen to the spec changes", "error", err)
+		<-ctx.Done()
+		return nil
+	}
+	n.log.Info("listening to the spec changes")
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			n.log.Info("stopped listening to the spec changes")
+			return nil
+		case notification := <-listener.Notify:
+			if notification == nil {
+				// the connection is reestablished, the changes might be missed while it was lost
+				n.notify("")
+				continue
+			}
+			if notification.Extra == hubSchedulesTableName {
+				n.notify("")
+				continue
+			}
+			n.notify(notification.Extra)
+		case <-ticker.C:
+			go func() {
+				if err := listener.Ping(); err != nil {
+					n.log.Warnw("failed to ping the spec listener", "error", err)
+				}
+			}()
+		}
+	}
+}
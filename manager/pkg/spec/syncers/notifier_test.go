@@ -0,0 +1,56 @@
+package syncers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/syncers/interval"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+)
+
+func TestSpecNotifier(t *testing.T) {
+	n := newSpecNotifier()
+	policies := n.subscribe(policiesTableName, placementsTableName)
+	labels := n.subscribe(managedClusterLabelsDBTableName)
+
+	// the notifications are folded into one until it's received
+	n.notify(placementsTableName)
+	n.notify(policiesTableName)
+	if len(policies) != 1 || len(labels) != 0 {
+		t.Fatalf("expected only the policies syncer to be notified once, but got %d and %d", len(policies),
+			len(labels))
+	}
+	<-policies
+
+	// all the subscribers are notified once the connection is reestablished
+	n.notify("")
+	if len(policies) != 1 || len(labels) != 1 {
+		t.Fatalf("expected all the syncers to be notified, but got %d and %d", len(policies), len(labels))
+	}
+}
+
+func TestSyncerNotified(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifications := make(chan struct{}, 1)
+	synced := make(chan struct{}, 10)
+	syncer := &genericDBToTransportSyncer{
+		log:            logger.ZapLogger("test-syncer"),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(time.Hour),
+		notifications:  notifications,
+		syncBundleFunc: func(ctx context.Context) (bool, error) {
+			synced <- struct{}{}
+			return true, nil
+		},
+	}
+	go syncer.periodicSync(ctx)
+
+	notifications <- struct{}{}
+	select {
+	case <-synced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the syncer to sync on the notification before the polling")
+	}
+}
@@ -26,14 +26,17 @@ func AddPlacementBindingsDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.Spe
 	specSyncInterval time.Duration,
 ) error {
 	createObjFunc := func() metav1.Object { return &policyv1.PlacementBinding{} }
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-placementrulebiding"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		// the hubs of the objects are resolved by their placements and the bound cluster sets
+		notifications: notifier.subscribe(placementBindingsTableName, placementsTableName,
+			managedClusterSetBindingsTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, placementBindingsMsgKey, specDB, placementBindingsTableName,
-				createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add placement bindings db to transport syncer - %w", err)
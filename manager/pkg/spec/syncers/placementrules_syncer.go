@@ -26,14 +26,15 @@ func AddPlacementRulesDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.SpecDB
 	specSyncInterval time.Duration,
 ) error {
 	createObjFunc := func() metav1.Object { return &placementrulev1.PlacementRule{} }
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-placementrule"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		notifications:  notifier.subscribe(placementRulesTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, placementRulesMsgKey, specDB, placementRulesTableName,
-				createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add placement rules db to transport syncer - %w", err)
@@ -26,14 +26,17 @@ func AddPoliciesDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.SpecDB, prod
 	specSyncInterval time.Duration,
 ) error {
 	createObjFunc := func() metav1.Object { return &policyv1.Policy{} }
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-policy"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		// the hubs of the objects are resolved by their placements and the bound cluster sets
+		notifications: notifier.subscribe(policiesTableName, placementBindingsTableName, placementsTableName,
+			managedClusterSetBindingsTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, policiesMsgKey, specDB, policiesTableName,
-				createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add policies db to transport syncer - %w", err)
@@ -0,0 +1,493 @@
+package syncers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	subscriptionv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1"
+	appsv1alpha1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1alpha1"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/controllers/bundle"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+// rolloutStrategy is the value of the rollout strategy annotation. The hubs are released wave by wave, and the next
+// wave is released once the soak time of the released one has passed and it's healthy.
+type rolloutStrategy struct {
+	Waves    []rolloutWave `json:"waves"`
+	SoakTime string        `json:"soakTime,omitempty"`
+
+	soakTime  time.Duration
+	selectors []labels.Selector
+}
+
+type rolloutWave struct {
+	Name        string `json:"name"`
+	HubSelector string `json:"hubSelector"`
+}
+
+func parseRolloutStrategy(value string) (*rolloutStrategy, error) {
+	strategy := &rolloutStrategy{}
+	if err := json.Unmarshal([]byte(value), strategy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the rollout strategy - %w", err)
+	}
+	if len(strategy.Waves) == 0 {
+		return nil, errors.New("the rollout strategy has no waves")
+	}
+	if strategy.SoakTime != "" {
+		soakTime, err := time.ParseDuration(strategy.SoakTime)
+		if err != nil || soakTime < 0 {
+			return nil, fmt.Errorf("invalid soak time %q of the rollout strategy", strategy.SoakTime)
+		}
+		strategy.soakTime = soakTime
+	}
+	for _, wave := range strategy.Waves {
+		selector, err := labels.Parse(wave.HubSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hub selector %q of the wave %s - %w", wave.HubSelector, wave.Name, err)
+		}
+		strategy.selectors = append(strategy.selectors, selector)
+	}
+	return strategy, nil
+}
+
+// waves returns the hubs of each wave, the hub belongs to the first wave selecting it, and the hubs not selected by
+// any wave are the last wave
+func (s *rolloutStrategy) waves(hubs []string, hubLabels map[string]labels.Set) [][]string {
+	waves := make([][]string, len(s.selectors)+1)
+	for _, hub := range hubs {
+		wave := len(s.selectors)
+		for i, selector := range s.selectors {
+			if selector.Matches(hubLabels[hub]) {
+				wave = i
+				break
+			}
+		}
+		waves[wave] = append(waves[wave], hub)
+	}
+	return waves
+}
+
+// objectRollout is the version of the object delivered to each hub
+type objectRollout struct {
+	// all means the version is released to all the hubs
+	all      bool
+	released map[string]bool
+	// previous is the last completed version kept on the hubs not released, they're left untouched if there is no
+	// completed version, unless the rollout is aborted and then the object is deleted from them
+	previous metav1.Object
+	aborted  bool
+	// progressing means the next waves are released by the time and the health of the released ones
+	progressing bool
+}
+
+func (r *objectRollout) isReleased(hub string) bool {
+	return r.all || r.released[hub]
+}
+
+// rolloutGate checks the health of the version on the hubs of the released wave since it's released. The pending is
+// the reason of waiting for the wave, and the failure is the reason of pausing the rollout.
+type rolloutGate func(ctx context.Context, object metav1.Object, uid string, hubs []string,
+	since time.Time) (pending string, failure string, err error)
+
+// resolveRollouts moves forward the rollouts of the objects with the rollout strategy annotation, and returns the
+// rollout of each object, which is nil for the object without the annotation
+func resolveRollouts(ctx context.Context, log *zap.SugaredLogger, targets *hubTargets, objects *objectsCollector,
+	objectHubs []map[string]bool, createObjFunc bundle.CreateObjectFunction,
+) ([]*objectRollout, error) {
+	db := database.GetGorm().WithContext(ctx)
+	uids, forgotten := []string{}, []string{}
+	for _, obj := range objects.objects {
+		if _, found := obj.object.GetAnnotations()[constants.RolloutStrategyAnnotation]; found {
+			uids = append(uids, obj.uid)
+		} else {
+			forgotten = append(forgotten, obj.uid)
+		}
+	}
+	for _, obj := range objects.deletedObjects {
+		if obj.GetUID() != "" {
+			forgotten = append(forgotten, string(obj.GetUID()))
+		}
+	}
+
+	rollouts := make([]*objectRollout, len(objects.objects))
+	if len(uids) == 0 && len(forgotten) == 0 {
+		return rollouts, nil
+	}
+	rows := []models.SpecRollout{}
+	if err := db.Where("uid IN ?", append(uids, forgotten...)).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list the rollouts - %w", err)
+	}
+	existing := map[string]*models.SpecRollout{}
+	for i := range rows {
+		existing[rows[i].UID] = &rows[i]
+	}
+	// the rollout is dropped once the object is deleted or the annotation is removed
+	stale := []string{}
+	for _, uid := range forgotten {
+		if _, found := existing[uid]; found {
+			stale = append(stale, uid)
+		}
+	}
+	if len(stale) > 0 {
+		if err := db.Where("uid IN ?", stale).Delete(&models.SpecRollout{}).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete the rollouts - %w", err)
+		}
+	}
+	if len(uids) == 0 {
+		return rollouts, nil
+	}
+	hubLabels, err := targets.labels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, obj := range objects.objects {
+		value, found := obj.object.GetAnnotations()[constants.RolloutStrategyAnnotation]
+		if !found {
+			continue
+		}
+		hubs := []string{}
+		for _, hub := range targets.hubs {
+			if objectHubs[i][hub] {
+				hubs = append(hubs, hub)
+			}
+		}
+		row, err := syncRollout(ctx, obj, existing[obj.uid], value, hubs, hubLabels, createObjFunc, checkRolloutGate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync the rollout of the object %s/%s - %w", obj.object.GetNamespace(),
+				obj.object.GetName(), err)
+		}
+		rollout, err := rolloutOf(row, value, hubs, hubLabels, createObjFunc)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugw("rollout", "namespace", row.Namespace, "name", row.Name, "state", row.State, "wave", row.Wave,
+			"message", row.Message)
+		rollouts[i] = rollout
+	}
+	return rollouts, nil
+}
+
+// syncRollout starts the rollout of the new version of the object, and releases the next waves of the progressing
+// rollout. The rollout changed by the REST API in the meantime is returned as it is.
+func syncRollout(ctx context.Context, obj collectedObject, row *models.SpecRollout, value string, hubs []string,
+	hubLabels map[string]labels.Set, createObjFunc bundle.CreateObjectFunction, gate rolloutGate,
+) (*models.SpecRollout, error) {
+	db := database.GetGorm().WithContext(ctx)
+	digest, payload, err := rolloutDigest(obj.object)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if row == nil || row.Digest != digest {
+		started := newRollout(obj, row, digest, payload, now)
+		if err := completeIfUnchanged(started, createObjFunc); err != nil {
+			return nil, err
+		}
+		if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(started).Error; err != nil {
+			return nil, err
+		}
+		row = started
+	}
+
+	if row.State != models.RolloutProgressing {
+		return row, nil
+	}
+	strategy, err := parseRolloutStrategy(value)
+	if err != nil {
+		return updateRollout(ctx, row, func(r *models.SpecRollout) {
+			r.State = models.RolloutPaused
+			r.Message = err.Error()
+		})
+	}
+	advanced, err := advanceRollout(ctx, obj, row, strategy, strategy.waves(hubs, hubLabels), now, gate)
+	if err != nil {
+		return nil, err
+	}
+	if advanced.State == row.State && advanced.Message == row.Message && advanced.Wave == row.Wave {
+		return row, nil
+	}
+	return updateRollout(ctx, row, func(r *models.SpecRollout) {
+		r.State, r.Message, r.Wave, r.WaveStartedAt = advanced.State, advanced.Message, advanced.Wave,
+			advanced.WaveStartedAt
+		r.PreviousPayload = advanced.PreviousPayload
+	})
+}
+
+// advanceRollout releases the next waves once the released one is soaked and passes the gate, the empty waves are
+// passed at once. The rollout is completed once the last wave passes, and it's paused once the gate fails.
+func advanceRollout(ctx context.Context, obj collectedObject, row *models.SpecRollout, strategy *rolloutStrategy,
+	waves [][]string, now time.Time, gate rolloutGate,
+) (*models.SpecRollout, error) {
+	advanced := *row
+	advanced.Message = ""
+	for advanced.Wave < len(waves) {
+		wave := advanced.Wave
+		if len(waves[wave]) > 0 {
+			if now.Sub(advanced.WaveStartedAt) < strategy.soakTime {
+				advanced.Message = fmt.Sprintf("soaking the wave %s until %s", waveName(strategy, wave),
+					advanced.WaveStartedAt.Add(strategy.soakTime).Format(time.RFC3339))
+				break
+			}
+			pending, failure, err := gate(ctx, obj.object, obj.uid, waves[wave], advanced.WaveStartedAt)
+			if err != nil {
+				return nil, err
+			}
+			if failure != "" {
+				advanced.State = models.RolloutPaused
+				advanced.Message = fmt.Sprintf("the wave %s is unhealthy: %s", waveName(strategy, wave), failure)
+				break
+			}
+			if pending != "" {
+				advanced.Message = fmt.Sprintf("waiting for the wave %s: %s", waveName(strategy, wave), pending)
+				break
+			}
+		}
+		if wave == len(waves)-1 {
+			advanced.State = models.RolloutCompleted
+			advanced.PreviousPayload = advanced.Payload
+			break
+		}
+		advanced.Wave, advanced.WaveStartedAt = wave+1, now
+	}
+	return &advanced, nil
+}
+
+// newRollout starts the rollout of the version from the first wave, the previous version is the last completed one
+func newRollout(obj collectedObject, row *models.SpecRollout, digest string, payload []byte,
+	now time.Time,
+) *models.SpecRollout {
+	kind := ""
+	if runtimeObject, ok := obj.object.(runtime.Object); ok {
+		kind = runtimeObject.GetObjectKind().GroupVersionKind().Kind
+	}
+	started := &models.SpecRollout{
+		UID:           obj.uid,
+		Kind:          kind,
+		Namespace:     obj.object.GetNamespace(),
+		Name:          obj.object.GetName(),
+		Digest:        digest,
+		Payload:       payload,
+		State:         models.RolloutProgressing,
+		WaveStartedAt: now,
+	}
+	if row != nil {
+		started.PreviousPayload = row.PreviousPayload
+		if row.State == models.RolloutCompleted {
+			started.PreviousPayload = row.Payload
+		}
+	}
+	return started
+}
+
+// completeIfUnchanged completes the rollout of the version which is the same as the previous one, e.g. the change is
+// reverted, since the hubs don't report the results of the version they have already applied
+func completeIfUnchanged(rollout *models.SpecRollout, createObjFunc bundle.CreateObjectFunction) error {
+	if len(rollout.PreviousPayload) == 0 {
+		return nil
+	}
+	previous := createObjFunc()
+	if err := json.Unmarshal(rollout.PreviousPayload, previous); err != nil {
+		return fmt.Errorf("failed to unmarshal the previous version - %w", err)
+	}
+	digest, _, err := rolloutDigest(previous)
+	if err != nil {
+		return err
+	}
+	if digest == rollout.Digest {
+		rollout.State = models.RolloutCompleted
+		rollout.PreviousPayload = rollout.Payload
+	}
+	return nil
+}
+
+// updateRollout updates the rollout unless it's changed by the REST API or a new version since it was read, the
+// changed rollout is returned then
+func updateRollout(ctx context.Context, row *models.SpecRollout,
+	update func(r *models.SpecRollout),
+) (*models.SpecRollout, error) {
+	db := database.GetGorm().WithContext(ctx)
+	updated := *row
+	update(&updated)
+	result := db.Model(&models.SpecRollout{}).
+		Where("uid = ? AND digest = ? AND state = ? AND wave = ?", row.UID, row.Digest, row.State, row.Wave).
+		Updates(map[string]interface{}{
+			"state":            updated.State,
+			"wave":             updated.Wave,
+			"wave_started_at":  updated.WaveStartedAt,
+			"message":          updated.Message,
+			"previous_payload": updated.PreviousPayload,
+			"updated_at":       time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return &updated, nil
+	}
+	latest := &models.SpecRollout{}
+	if err := db.Where("uid = ?", row.UID).First(latest).Error; err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// rolloutOf returns the version of the object delivered to each hub by the state of the rollout
+func rolloutOf(row *models.SpecRollout, value string, hubs []string, hubLabels map[string]labels.Set,
+	createObjFunc bundle.CreateObjectFunction,
+) (*objectRollout, error) {
+	rollout := &objectRollout{released: map[string]bool{}}
+	switch row.State {
+	case models.RolloutCompleted:
+		rollout.all = true
+		return rollout, nil
+	case models.RolloutAborted:
+		rollout.aborted = true
+	case models.RolloutProgressing:
+		rollout.progressing = true
+		fallthrough
+	default:
+		// the paused rollout with the invalid strategy releases no hub
+		if strategy, err := parseRolloutStrategy(value); err == nil {
+			waves := strategy.waves(hubs, hubLabels)
+			for wave := 0; wave <= row.Wave && wave < len(waves); wave++ {
+				for _, hub := range waves[wave] {
+					rollout.released[hub] = true
+				}
+			}
+		}
+	}
+	if len(row.PreviousPayload) > 0 {
+		previous := createObjFunc()
+		if err := json.Unmarshal(row.PreviousPayload, previous); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the previous version of the object %s/%s - %w",
+				row.Namespace, row.Name, err)
+		}
+		rollout.previous = previous
+	}
+	return rollout, nil
+}
+
+// rolloutDigest returns the digest and payload of the version of the object, the status and the rollout strategy
+// aren't a part of the version, so changing the strategy doesn't restart the rollout
+func rolloutDigest(object metav1.Object) (string, []byte, error) {
+	payload, err := json.Marshal(object)
+	if err != nil {
+		return "", nil, err
+	}
+	version := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &version); err != nil {
+		return "", nil, err
+	}
+	delete(version, "status")
+	if metadata, ok := version["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, constants.RolloutStrategyAnnotation)
+			delete(annotations, constants.OriginOwnerReferenceAnnotation)
+		}
+	}
+	versionBytes, err := json.Marshal(version)
+	if err != nil {
+		return "", nil, err
+	}
+	digest := sha256.Sum256(versionBytes)
+	return hex.EncodeToString(digest[:]), payload, nil
+}
+
+func waveName(strategy *rolloutStrategy, wave int) string {
+	if wave < len(strategy.Waves) && strategy.Waves[wave].Name != "" {
+		return strategy.Waves[wave].Name
+	}
+	if wave == len(strategy.Waves) {
+		return "of the remaining hubs"
+	}
+	return strconv.Itoa(wave)
+}
+
+// checkRolloutGate requires the version to be applied on all the hubs of the wave, besides the policy to be compliant
+// on their clusters and the subscription to have no failed clusters
+func checkRolloutGate(ctx context.Context, object metav1.Object, uid string, hubs []string,
+	since time.Time,
+) (string, string, error) {
+	db := database.GetGorm().WithContext(ctx)
+	results := []models.SpecApplyResult{}
+	if err := db.Where("uid = ? AND leaf_hub_name IN ?", uid, hubs).Find(&results).Error; err != nil {
+		return "", "", fmt.Errorf("failed to list the apply results - %w", err)
+	}
+	applied := map[string]bool{}
+	for _, result := range results {
+		if result.UpdatedAt.Before(since) {
+			continue
+		}
+		switch result.Result {
+		case wiremodels.SpecApplyFailed:
+			return "", fmt.Sprintf("failed to apply on the hub %s: %s", result.LeafHubName, result.Error), nil
+		case wiremodels.SpecApplyRejected:
+			return "", fmt.Sprintf("rejected by the hub %s: %s", result.LeafHubName, result.Error), nil
+		case wiremodels.SpecApplied, wiremodels.SpecApplySkipped:
+			applied[result.LeafHubName] = true
+		}
+	}
+	waiting := []string{}
+	for _, hub := range hubs {
+		if !applied[hub] {
+			waiting = append(waiting, hub)
+		}
+	}
+	if len(waiting) > 0 {
+		return fmt.Sprintf("not applied on the hubs %s yet", strings.Join(waiting, ", ")), "", nil
+	}
+
+	switch obj := object.(type) {
+	case *policyv1.Policy:
+		clusters := []string{}
+		if err := db.Model(&models.StatusCompliance{}).
+			Where("policy_id = ? AND leaf_hub_name IN ? AND compliance = ?", uid, hubs, database.NonCompliant).
+			Order("leaf_hub_name, cluster_name").Limit(10).
+			Pluck("leaf_hub_name || '/' || cluster_name", &clusters).Error; err != nil {
+			return "", "", fmt.Errorf("failed to list the compliance of the policy - %w", err)
+		}
+		if len(clusters) > 0 {
+			return "", fmt.Sprintf("the policy is non compliant on the clusters %s", strings.Join(clusters, ", ")),
+				nil
+		}
+	case *subscriptionv1.Subscription:
+		reports := []models.SubscriptionReport{}
+		if err := db.Where(
+			"leaf_hub_name IN ? AND payload->'metadata'->>'name' = ? AND payload->'metadata'->>'namespace' = ?",
+			hubs, obj.Name, obj.Namespace).Order("leaf_hub_name").Find(&reports).Error; err != nil {
+			return "", "", fmt.Errorf("failed to list the reports of the subscription - %w", err)
+		}
+		for _, row := range reports {
+			report := &appsv1alpha1.SubscriptionReport{}
+			if err := json.Unmarshal(row.Payload, report); err != nil {
+				return "", "", fmt.Errorf("failed to unmarshal the report of the subscription - %w", err)
+			}
+			failed, _ := strconv.Atoi(report.Summary.Failed)
+			propagationFailed, _ := strconv.Atoi(report.Summary.PropagationFailed)
+			if failed+propagationFailed > 0 {
+				return "", fmt.Sprintf("the subscription failed on %d clusters of the hub %s",
+					failed+propagationFailed, row.LeafHubName), nil
+			}
+		}
+	}
+	return "", "", nil
+}
@@ -0,0 +1,147 @@
+package syncers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+func TestParseRolloutStrategy(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    string
+		soakTime time.Duration
+		valid    bool
+	}{
+		{"the waves with the soak time", `{"waves":[{"name":"canary","hubSelector":"env=dev"}],"soakTime":"30m"}`,
+			30 * time.Minute, true},
+		{"the waves without the soak time", `{"waves":[{"name":"canary","hubSelector":"env=dev"}]}`, 0, true},
+		{"no waves", `{"soakTime":"30m"}`, 0, false},
+		{"the invalid soak time", `{"waves":[{"hubSelector":"env=dev"}],"soakTime":"-1m"}`, 0, false},
+		{"the invalid hub selector", `{"waves":[{"hubSelector":"env in dev"}]}`, 0, false},
+		{"the invalid json", `waves`, 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			strategy, err := parseRolloutStrategy(c.value)
+			if !c.valid {
+				if err == nil {
+					t.Errorf("expected the invalid strategy, but got %v", strategy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strategy.soakTime != c.soakTime {
+				t.Errorf("expected the soak time %s, but got %s", c.soakTime, strategy.soakTime)
+			}
+		})
+	}
+}
+
+func TestRolloutWaves(t *testing.T) {
+	strategy, err := parseRolloutStrategy(`{"waves":[{"name":"canary","hubSelector":"env=dev"},
+		{"name":"staging","hubSelector":"env in (dev,staging)"},{"name":"empty","hubSelector":"env=none"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hubLabels := map[string]labels.Set{
+		"hub1": {"env": "dev"},
+		"hub2": {"env": "staging"},
+		"hub3": {"env": "prod"},
+	}
+	waves := strategy.waves([]string{"hub1", "hub2", "hub3", "hub4"}, hubLabels)
+	expected := [][]string{{"hub1"}, {"hub2"}, nil, {"hub3", "hub4"}}
+	if !reflect.DeepEqual(waves, expected) {
+		t.Errorf("expected %v, but got %v", expected, waves)
+	}
+}
+
+func TestAdvanceRollout(t *testing.T) {
+	strategy, err := parseRolloutStrategy(`{"waves":[{"name":"canary","hubSelector":"env=dev"},
+		{"name":"empty","hubSelector":"env=none"}],"soakTime":"10m"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waves := [][]string{{"hub1"}, {}, {"hub2"}}
+	now := time.Now()
+	obj := collectedObject{object: &policyv1.Policy{}, uid: "uid1"}
+
+	cases := []struct {
+		name    string
+		started time.Time
+		wave    int
+		pending string
+		failure string
+		state   string
+		newWave int
+	}{
+		{"soaking the wave", now.Add(-time.Minute), 0, "", "", models.RolloutProgressing, 0},
+		{"waiting for the wave", now.Add(-time.Hour), 0, "not applied", "", models.RolloutProgressing, 0},
+		{"the unhealthy wave", now.Add(-time.Hour), 0, "", "non compliant", models.RolloutPaused, 0},
+		{"passing the empty wave", now.Add(-time.Hour), 0, "", "", models.RolloutProgressing, 2},
+		{"the completed rollout", now.Add(-time.Hour), 2, "", "", models.RolloutCompleted, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			row := &models.SpecRollout{
+				State: models.RolloutProgressing, Wave: c.wave, WaveStartedAt: c.started, Payload: []byte(`{}`),
+			}
+			gate := func(ctx context.Context, object metav1.Object, uid string, hubs []string,
+				since time.Time,
+			) (string, string, error) {
+				// the released wave is soaked before the gate
+				if since != c.started {
+					return "not applied", "", nil
+				}
+				return c.pending, c.failure, nil
+			}
+			advanced, err := advanceRollout(context.Background(), obj, row, strategy, waves, now, gate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if advanced.State != c.state || advanced.Wave != c.newWave {
+				t.Errorf("expected the state %s of the wave %d, but got %s of the wave %d: %s", c.state, c.newWave,
+					advanced.State, advanced.Wave, advanced.Message)
+			}
+			if c.state == models.RolloutCompleted && string(advanced.PreviousPayload) != string(row.Payload) {
+				t.Errorf("expected the completed version to be the previous one, but got %s", advanced.PreviousPayload)
+			}
+		})
+	}
+}
+
+func TestRolloutDigest(t *testing.T) {
+	policy := &policyv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "policy1",
+			Namespace:   "default",
+			Annotations: map[string]string{constants.RolloutStrategyAnnotation: `{"waves":[]}`},
+		},
+		Spec: policyv1.PolicySpec{RemediationAction: "inform"},
+	}
+	digest, _, err := rolloutDigest(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy.Annotations[constants.RolloutStrategyAnnotation] = `{"waves":[{"hubSelector":"env=dev"}]}`
+	policy.Status.ComplianceState = policyv1.NonCompliant
+	if unchanged, _, err := rolloutDigest(policy); err != nil || unchanged != digest {
+		t.Errorf("expected the same version with the changed strategy and status, but got %s, %v", unchanged, err)
+	}
+
+	policy.Spec.RemediationAction = "enforce"
+	if changed, _, err := rolloutDigest(policy); err != nil || changed == digest {
+		t.Errorf("expected the new version with the changed spec, but got %s, %v", changed, err)
+	}
+}
@@ -26,14 +26,17 @@ func AddSubscriptionsDBToTransportSyncer(mgr ctrl.Manager, specDB specdb.SpecDB,
 	specSyncInterval time.Duration,
 ) error {
 	createObjFunc := func() metav1.Object { return &subscriptionv1.Subscription{} }
-	lastSyncTimestampPtr := &time.Time{}
+	delivery := newHubBundles(mgr.GetClient())
 
 	if err := mgr.Add(&genericDBToTransportSyncer{
 		log:            logger.ZapLogger("db-to-transport-syncer-subscriptions"),
-		intervalPolicy: interval.NewExponentialBackoffPolicy(specSyncInterval),
+		intervalPolicy: interval.NewExponentialBackoffPolicy(notifiedInterval(specSyncInterval)),
+		// the hubs of the objects are resolved by their placements and the bound cluster sets
+		notifications: notifier.subscribe(subscriptionsTableName, placementsTableName,
+			managedClusterSetBindingsTableName),
 		syncBundleFunc: func(ctx context.Context) (bool, error) {
 			return syncObjectsBundle(ctx, producer, subscriptionMsgKey, specDB, subscriptionsTableName,
-				createObjFunc, bundle.NewBaseObjectsBundle, lastSyncTimestampPtr)
+				createObjFunc, bundle.NewBaseObjectsBundle, delivery)
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to add subscriptions db to transport syncer - %w", err)
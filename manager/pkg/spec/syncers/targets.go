@@ -0,0 +1,251 @@
+package syncers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	subscriptionv1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+const (
+	placementKind = "Placement"
+	policyKind    = "Policy"
+	// the global cluster set selects all the managed clusters
+	globalClusterSetName = "global"
+)
+
+// hubTargets resolves the managed hubs each global object is delivered to. The hub selector annotation of the object
+// selects the hubs by the labels of their managed clusters on the global hub cluster. Otherwise the placement is
+// delivered to the hubs of the clusters in its bound cluster sets, and the placement bindings, policies and
+// subscriptions follow their placements. The other objects are delivered to all the hubs.
+type hubTargets struct {
+	log    *zap.SugaredLogger
+	client client.Client
+	hubs   []string
+	// the labels of the hubs, the bound cluster sets of the namespaces and the targets of the placements are loaded
+	// on demand
+	hubLabels          map[string]labels.Set
+	clusterSetBindings map[string][]string
+	placementHubs      map[string][]string
+}
+
+func newHubTargets(ctx context.Context, log *zap.SugaredLogger, c client.Client) (*hubTargets, error) {
+	hubs := []string{}
+	if err := database.GetGorm().WithContext(ctx).Model(&models.LeafHubHeartbeat{}).
+		Order("leaf_hub_name").Pluck("leaf_hub_name", &hubs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list the managed hubs - %w", err)
+	}
+	return &hubTargets{
+		log:                log,
+		client:             c,
+		hubs:               hubs,
+		clusterSetBindings: map[string][]string{},
+		placementHubs:      map[string][]string{},
+	}, nil
+}
+
+// of returns the hubs the object is delivered to
+func (t *hubTargets) of(ctx context.Context, object metav1.Object) ([]string, error) {
+	if selector, found := object.GetAnnotations()[constants.HubSelectorAnnotation]; found {
+		return t.selectedHubs(ctx, object, selector)
+	}
+
+	switch obj := object.(type) {
+	case *clusterv1beta1.Placement:
+		return t.placementTargets(ctx, obj.Namespace, obj.Name)
+	case *policyv1.PlacementBinding:
+		if obj.PlacementRef.Kind == placementKind {
+			return t.placementTargets(ctx, obj.Namespace, obj.PlacementRef.Name)
+		}
+	case *policyv1.Policy:
+		return t.policyTargets(ctx, obj)
+	case *subscriptionv1.Subscription:
+		if obj.Spec.Placement != nil && obj.Spec.Placement.PlacementRef != nil &&
+			obj.Spec.Placement.PlacementRef.Kind == placementKind {
+			return t.placementTargets(ctx, obj.Namespace, obj.Spec.Placement.PlacementRef.Name)
+		}
+	}
+	return t.hubs, nil
+}
+
+// selectedHubs returns the hubs matching the selector, the object isn't delivered to any hub if the selector is
+// invalid, so a typo never delivers it to the excluded hubs
+func (t *hubTargets) selectedHubs(ctx context.Context, object metav1.Object, selector string) ([]string, error) {
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		t.log.Warnw("the object isn't delivered to any hub due to the invalid hub selector", "name",
+			object.GetName(), "namespace", object.GetNamespace(), "selector", selector, "error", err)
+		return []string{}, nil
+	}
+	hubLabels, err := t.labels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hubs := []string{}
+	for _, hub := range t.hubs {
+		if labelSelector.Matches(hubLabels[hub]) {
+			hubs = append(hubs, hub)
+		}
+	}
+	return hubs, nil
+}
+
+// labels returns the labels of the managed clusters of the hubs on the global hub cluster
+func (t *hubTargets) labels(ctx context.Context) (map[string]labels.Set, error) {
+	if t.hubLabels != nil {
+		return t.hubLabels, nil
+	}
+	clusters := &clusterv1.ManagedClusterList{}
+	if err := t.client.List(ctx, clusters); err != nil {
+		return nil, fmt.Errorf("failed to list the managed hubs - %w", err)
+	}
+	t.hubLabels = map[string]labels.Set{}
+	for _, cluster := range clusters.Items {
+		t.hubLabels[cluster.Name] = cluster.Labels
+	}
+	return t.hubLabels, nil
+}
+
+// placementTargets returns the hubs of the clusters in the cluster sets the placement selects from the bound ones
+func (t *hubTargets) placementTargets(ctx context.Context, namespace, name string) ([]string, error) {
+	key := namespace + "/" + name
+	if hubs, found := t.placementHubs[key]; found {
+		return hubs, nil
+	}
+
+	db := database.GetGorm().WithContext(ctx)
+	placement := &clusterv1beta1.Placement{}
+	payloads := []string{}
+	if err := db.Raw(fmt.Sprintf(`SELECT payload FROM spec.placements WHERE deleted = false AND
+		payload -> 'metadata' ->> 'namespace' = ? AND payload -> 'metadata' ->> 'name' = ? AND
+		payload -> 'metadata' -> 'labels' -> '%s' IS NOT NULL`, constants.GlobalHubGlobalResourceLabel),
+		namespace, name).Scan(&payloads).Error; err != nil {
+		return nil, fmt.Errorf("failed to query the placement %s - %w", key, err)
+	}
+	if len(payloads) == 0 {
+		// the placement isn't a global resource, the hubs may own it
+		t.placementHubs[key] = t.hubs
+		return t.hubs, nil
+	}
+	if err := json.Unmarshal([]byte(payloads[0]), placement); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the placement %s - %w", key, err)
+	}
+
+	boundClusterSets, err := t.boundClusterSets(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	clusterSets := selectedClusterSets(placement.Spec.ClusterSets, boundClusterSets)
+
+	hubs := []string{}
+	if len(clusterSets) > 0 {
+		query := db.Model(&models.ManagedCluster{}).Distinct("leaf_hub_name").Where("leaf_hub_name IN ?", t.hubs)
+		if !contains(clusterSets, globalClusterSetName) {
+			query = query.Where(fmt.Sprintf("payload -> 'metadata' -> 'labels' ->> '%s' IN ?",
+				clusterv1beta2.ClusterSetLabel), clusterSets)
+		}
+		if err := query.Order("leaf_hub_name").Pluck("leaf_hub_name", &hubs).Error; err != nil {
+			return nil, fmt.Errorf("failed to query the hubs of the placement %s - %w", key, err)
+		}
+	}
+	t.placementHubs[key] = hubs
+	return hubs, nil
+}
+
+// boundClusterSets returns the cluster sets bound to the namespace by the global cluster set bindings
+func (t *hubTargets) boundClusterSets(ctx context.Context, namespace string) ([]string, error) {
+	if clusterSets, found := t.clusterSetBindings[namespace]; found {
+		return clusterSets, nil
+	}
+	clusterSets := []string{}
+	if err := database.GetGorm().WithContext(ctx).Raw(fmt.Sprintf(`SELECT payload -> 'spec' ->> 'clusterSet'
+		FROM spec.managedclustersetbindings WHERE deleted = false AND payload -> 'metadata' ->> 'namespace' = ? AND
+		payload -> 'metadata' -> 'labels' -> '%s' IS NOT NULL`, constants.GlobalHubGlobalResourceLabel),
+		namespace).Scan(&clusterSets).Error; err != nil {
+		return nil, fmt.Errorf("failed to query the cluster set bindings of the namespace %s - %w", namespace, err)
+	}
+	t.clusterSetBindings[namespace] = clusterSets
+	return clusterSets, nil
+}
+
+// policyTargets returns the hubs of the placements bound to the policy, the policy without the placement binding
+// is delivered to all the hubs
+func (t *hubTargets) policyTargets(ctx context.Context, policy *policyv1.Policy) ([]string, error) {
+	payloads := []string{}
+	if err := database.GetGorm().WithContext(ctx).Raw(fmt.Sprintf(`SELECT payload FROM spec.placementbindings
+		WHERE deleted = false AND payload -> 'metadata' ->> 'namespace' = ? AND
+		payload -> 'metadata' -> 'labels' -> '%s' IS NOT NULL AND
+		payload -> 'subjects' @> jsonb_build_array(jsonb_build_object('kind', ?::text, 'name', ?::text))`,
+		constants.GlobalHubGlobalResourceLabel), policy.Namespace, policyKind, policy.Name).
+		Scan(&payloads).Error; err != nil {
+		return nil, fmt.Errorf("failed to query the placement bindings of the policy %s/%s - %w",
+			policy.Namespace, policy.Name, err)
+	}
+	if len(payloads) == 0 {
+		return t.hubs, nil
+	}
+
+	hubs := map[string]bool{}
+	for _, payload := range payloads {
+		binding := &policyv1.PlacementBinding{}
+		if err := json.Unmarshal([]byte(payload), binding); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the placement binding of the policy %s/%s - %w",
+				policy.Namespace, policy.Name, err)
+		}
+		bindingHubs, err := t.of(ctx, binding)
+		if err != nil {
+			return nil, err
+		}
+		for _, hub := range bindingHubs {
+			hubs[hub] = true
+		}
+	}
+	return sortedKeys(hubs), nil
+}
+
+// selectedClusterSets returns the cluster sets the placement selects, which are all the bound cluster sets if the
+// placement doesn't specify any
+func selectedClusterSets(placementClusterSets, boundClusterSets []string) []string {
+	if len(placementClusterSets) == 0 {
+		return boundClusterSets
+	}
+	clusterSets := []string{}
+	for _, clusterSet := range placementClusterSets {
+		if contains(boundClusterSets, clusterSet) {
+			clusterSets = append(clusterSets, clusterSet)
+		}
+	}
+	return clusterSets
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
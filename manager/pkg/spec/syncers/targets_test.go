@@ -0,0 +1,63 @@
+package syncers
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+)
+
+func TestSelectedClusterSets(t *testing.T) {
+	cases := []struct {
+		name                 string
+		placementClusterSets []string
+		boundClusterSets     []string
+		expected             []string
+	}{
+		{"all the bound cluster sets", nil, []string{"set1", "set2"}, []string{"set1", "set2"}},
+		{"the bound cluster sets of the placement", []string{"set2", "set3"}, []string{"set1", "set2"},
+			[]string{"set2"}},
+		{"no bound cluster set", []string{"set1"}, []string{}, []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := selectedClusterSets(c.placementClusterSets, c.boundClusterSets); !reflect.DeepEqual(
+				actual, c.expected) {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIdentityOf(t *testing.T) {
+	policy := &policyv1.Policy{
+		TypeMeta: metav1.TypeMeta{Kind: "Policy", APIVersion: "policy.open-cluster-management.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "policy1",
+			Namespace:   "default",
+			Annotations: map[string]string{"policy.open-cluster-management.io/standards": "NIST SP 800-53"},
+		},
+		Spec: policyv1.PolicySpec{RemediationAction: "enforce"},
+	}
+
+	identity := identityOf(policy, "uid1")
+	expected := map[string]interface{}{
+		"apiVersion": "policy.open-cluster-management.io/v1",
+		"kind":       "Policy",
+		"metadata": map[string]interface{}{
+			"name":        "policy1",
+			"namespace":   "default",
+			"annotations": map[string]interface{}{constants.OriginOwnerReferenceAnnotation: "uid1"},
+		},
+	}
+	if !reflect.DeepEqual(identity.Object, expected) {
+		t.Errorf("expected %v, but got %v", expected, identity.Object)
+	}
+
+	if identity := identityOf(policy, ""); identity.GetAnnotations() != nil {
+		t.Errorf("expected no annotation of the deleted object, but got %v", identity.GetAnnotations())
+	}
+}
@@ -27,13 +27,16 @@ type ConflationCommitter struct {
 	log                  *zap.SugaredLogger
 	retrieveMetadataFunc MetadataFunc
 	committedPositions   map[string]int64
+	// member is the shard member of the replica, the replicas commit the offsets of their own consumer groups
+	member string
 }
 
-func NewKafkaConflationCommitter(metadataFunc MetadataFunc) *ConflationCommitter {
+func NewKafkaConflationCommitter(metadataFunc MetadataFunc, member string) *ConflationCommitter {
 	return &ConflationCommitter{
 		log:                  logger.DefaultZapLogger(),
 		retrieveMetadataFunc: metadataFunc,
 		committedPositions:   map[string]int64{},
+		member:               member,
 	}
 }
 
@@ -85,7 +88,7 @@ func (k *ConflationCommitter) commit() error {
 			return err
 		}
 		databaseTransports = append(databaseTransports, models.Transport{
-			Name:    transPosition.Topic,
+			Name:    transport.OffsetName(transPosition.Topic, k.member),
 			Payload: payload,
 		})
 		k.committedPositions[key] = int64(transPosition.Offset)
@@ -21,6 +21,9 @@ type ConflationManager struct {
 	readyQueue    *ConflationReadyQueue
 	lock          sync.Mutex
 	statistics    *statistics.Statistics
+	// hubFilter decides whether the events from the leaf hub are processed by the current replica, all the hubs are
+	// accepted if it isn't specified
+	hubFilter func(leafHubName string) bool
 }
 
 // NewConflationManager creates a new instance of ConflationManager.
@@ -45,6 +48,23 @@ func (cm *ConflationManager) Register(registration *ConflationRegistration) {
 	cm.statistics.Register(registration.eventType)
 }
 
+// SetHubFilter only keeps the conflation units of the leaf hubs accepted by the filter, the events from the other
+// hubs will be discarded. It's invoked when the leaf hubs are reassigned between the manager replicas.
+func (cm *ConflationManager) SetHubFilter(filter func(leafHubName string) bool) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+
+	cm.hubFilter = filter
+	for leafHubName := range cm.conflationUnits {
+		if filter(leafHubName) {
+			continue
+		}
+		cm.log.Infow("release the conflation unit", "leafHub", leafHubName)
+		delete(cm.conflationUnits, leafHubName)
+		cm.statistics.DecrementNumberOfConflations()
+	}
+}
+
 // Insert function inserts the bundle to the appropriate conflation unit.
 func (cm *ConflationManager) Insert(evt *cloudevents.Event) {
 	// validate the event
@@ -52,6 +72,10 @@ func (cm *ConflationManager) Insert(evt *cloudevents.Event) {
 		cm.log.Infow("event type hasn't been registered", "type", evt.Type())
 		return
 	}
+	if !cm.accept(evt.Source()) {
+		cm.log.Debugw("skip the event from the hub owned by other replica", "type", evt.Type(), "source", evt.Source())
+		return
+	}
 	// metadata
 	conflationMetadata := metadata.NewThresholdMetadata(consumer.TransportID(), 3, evt)
 	if conflationMetadata == nil {
@@ -63,6 +87,9 @@ func (cm *ConflationManager) Insert(evt *cloudevents.Event) {
 
 // GetTransportMetadatas provides collections of the CU's bundle transport-metadata.
 func (cm *ConflationManager) GetMetadatas() []ConflationMetadata {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+
 	metadata := make([]ConflationMetadata, 0)
 	for _, cu := range cm.conflationUnits {
 		metadata = append(metadata, cu.getMetadatas()...)
@@ -85,6 +112,12 @@ func (cm *ConflationManager) getConflationUnit(leafHubName string) *ConflationUn
 	return conflationUnit
 }
 
+func (cm *ConflationManager) accept(leafHubName string) bool {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	return cm.hubFilter == nil || cm.hubFilter(leafHubName)
+}
+
 func (cm *ConflationManager) GetReadyQueue() *ConflationReadyQueue {
 	return cm.readyQueue
 }
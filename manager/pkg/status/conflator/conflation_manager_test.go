@@ -0,0 +1,44 @@
+package conflator
+
+import (
+	"context"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/statistics"
+)
+
+func TestConflationManagerHubFilter(t *testing.T) {
+	cm := NewConflationManager(statistics.NewStatistics(&statistics.StatisticsConfig{}))
+	cm.Register(NewConflationRegistration(ConflationPriority(0), enum.CompleteStateMode, "test.type",
+		func(ctx context.Context, evt *cloudevents.Event) error { return nil }))
+
+	// all the hubs are accepted without the filter
+	assert.True(t, cm.accept("hub1"))
+	cm.getConflationUnit("hub1")
+	cm.getConflationUnit("hub2")
+
+	// the units of the hubs owned by the other replica are released
+	owned := map[string]bool{"hub1": true}
+	cm.SetHubFilter(func(leafHubName string) bool { return owned[leafHubName] })
+	assert.Contains(t, cm.conflationUnits, "hub1")
+	assert.NotContains(t, cm.conflationUnits, "hub2")
+	assert.True(t, cm.accept("hub1"))
+	assert.False(t, cm.accept("hub2"))
+
+	// the events from the hubs owned by the other replica are dropped
+	evt := cloudevents.NewEvent()
+	evt.SetType("test.type")
+	evt.SetSource("hub2")
+	cm.Insert(&evt)
+	assert.NotContains(t, cm.conflationUnits, "hub2")
+
+	// the hub is accepted again once it's reassigned to the replica
+	owned["hub2"] = true
+	cm.SetHubFilter(func(leafHubName string) bool { return owned[leafHubName] })
+	assert.True(t, cm.accept("hub2"))
+	assert.Len(t, cm.conflationUnits, 1)
+}
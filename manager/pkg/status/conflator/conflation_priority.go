@@ -30,4 +30,10 @@ const (
 
 	SubscriptionStatusPriority ConflationPriority = iota
 	SubscriptionReportPriority ConflationPriority = iota
+
+	SpecApplyResultsPriority  ConflationPriority = iota
+	SpecDriftPriority         ConflationPriority = iota
+	SpecDryRunResultsPriority ConflationPriority = iota
+
+	ManagedClusterLabelConflictPriority ConflationPriority = iota
 )
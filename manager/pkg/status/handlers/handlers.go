@@ -10,10 +10,14 @@ import (
 
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/conflator"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/generic"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/labelconflict"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/managedcluster"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/managedhub"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/policy"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/security"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/specapply"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/specdrift"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers/specdryrun"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
 	"github.com/stolostron/multicluster-global-hub/pkg/enum"
 )
@@ -88,5 +92,14 @@ func RegisterHandlers(mgr ctrl.Manager, cmr *conflator.ConflationManager, enable
 			conflator.SubscriptionStatusPriority,
 			enum.CompleteStateMode,
 			fmt.Sprintf("%s.%s", database.StatusSchema, database.SubscriptionStatusesTableName))
+
+		// the apply results of the global resources
+		specapply.RegisterSpecApplyResultsHandler(mgr, cmr)
+		// the changes of the global resources made on the hubs
+		specdrift.RegisterSpecDriftHandler(cmr)
+		// the results of the dry runs of the global resources on the hubs
+		specdryrun.RegisterSpecDryRunResultsHandler(cmr)
+		// the conflicts of the managed cluster labels between the global hub and the hubs
+		labelconflict.RegisterLabelConflictHandler(cmr)
 	}
 }
@@ -0,0 +1,87 @@
+package labelconflict
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/conflator"
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+const batchSize = 500
+
+type labelConflictHandler struct {
+	log           *zap.SugaredLogger
+	eventType     string
+	eventSyncMode enum.EventSyncMode
+	eventPriority conflator.ConflationPriority
+}
+
+func RegisterLabelConflictHandler(conflationManager *conflator.ConflationManager) {
+	eventType := string(enum.ManagedClusterLabelConflictType)
+	logName := strings.Replace(eventType, enum.EventTypePrefix, "", -1)
+	h := &labelConflictHandler{
+		log:           logger.ZapLogger(logName),
+		eventType:     eventType,
+		eventSyncMode: enum.DeltaStateMode,
+		eventPriority: conflator.ManagedClusterLabelConflictPriority,
+	}
+	conflationManager.Register(conflator.NewConflationRegistration(
+		h.eventPriority,
+		h.eventSyncMode,
+		h.eventType,
+		h.handleEvent,
+	))
+}
+
+func (h *labelConflictHandler) handleEvent(ctx context.Context, evt *cloudevents.Event) error {
+	version := evt.Extensions()[eventversion.ExtVersion]
+	leafHubName := evt.Source()
+	h.log.Debugw("handler start", "type", evt.Type(), "LH", evt.Source(), "version", version)
+
+	conflicts := wiremodels.ManagedClusterLabelConflicts{}
+	if err := evt.DataAs(&conflicts); err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		h.log.Info("empty managed cluster label conflict event payload", "event", evt)
+		return nil
+	}
+
+	rows := make([]models.ManagedClusterLabelConflictEvent, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		rows = append(rows, models.ManagedClusterLabelConflictEvent{
+			LeafHubName: leafHubName,
+			ClusterName: conflict.ClusterName,
+			LabelKey:    conflict.Key,
+			Owner:       conflict.Owner,
+			GlobalValue: conflict.GlobalValue,
+			HubValue:    conflict.HubValue,
+			Winner:      conflict.Winner,
+			CreatedAt:   conflict.CreatedAt,
+		})
+	}
+
+	err := database.GetGorm().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "leaf_hub_name"}, {Name: "cluster_name"}, {Name: "label_key"}, {Name: "created_at"},
+		},
+		DoNothing: true,
+	}).CreateInBatches(rows, batchSize).Error
+	if err != nil {
+		return fmt.Errorf("failed handling leaf hub managed cluster label conflict event - %w", err)
+	}
+
+	h.log.Debugw("handler finished", "type", evt.Type(), "LH", evt.Source(), "version", version)
+	return nil
+}
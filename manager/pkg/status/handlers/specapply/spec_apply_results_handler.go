@@ -0,0 +1,247 @@
+package specapply
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/conflator"
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+const (
+	// AppliedConditionType is the condition of the global resource summarizing its apply results on the hubs
+	AppliedConditionType = "GlobalHubSpecApplied"
+
+	reasonApplied     = "Applied"
+	reasonApplyFailed = "ApplyFailed"
+)
+
+type specApplyResultsHandler struct {
+	log           *zap.SugaredLogger
+	eventType     string
+	eventSyncMode enum.EventSyncMode
+	eventPriority conflator.ConflationPriority
+	client        client.Client
+}
+
+func RegisterSpecApplyResultsHandler(mgr ctrl.Manager, conflationManager *conflator.ConflationManager) {
+	eventType := string(enum.SpecApplyResultsType)
+	logName := strings.Replace(eventType, enum.EventTypePrefix, "", -1)
+	h := &specApplyResultsHandler{
+		log:           logger.ZapLogger(logName),
+		eventType:     eventType,
+		eventSyncMode: enum.CompleteStateMode,
+		eventPriority: conflator.SpecApplyResultsPriority,
+		client:        mgr.GetClient(),
+	}
+	conflationManager.Register(conflator.NewConflationRegistration(
+		h.eventPriority,
+		h.eventSyncMode,
+		h.eventType,
+		h.handleEvent,
+	))
+}
+
+// handleEvent replaces the results of the hub with the reported ones, and then updates the condition of the global
+// resources whose results are changed
+func (h *specApplyResultsHandler) handleEvent(ctx context.Context, evt *cloudevents.Event) error {
+	version := evt.Extensions()[eventversion.ExtVersion]
+	leafHubName := evt.Source()
+	h.log.Debugw("handler start", "type", evt.Type(), "LH", evt.Source(), "version", version)
+
+	wireModel := &wiremodels.SpecApplyResults{}
+	if err := evt.DataAs(wireModel); err != nil {
+		return err
+	}
+	results := make([]models.SpecApplyResult, 0, len(wireModel.Results))
+	for _, result := range wireModel.Results {
+		results = append(results, models.SpecApplyResult{
+			LeafHubName:     leafHubName,
+			APIVersion:      result.APIVersion,
+			Kind:            result.Kind,
+			Namespace:       result.Namespace,
+			Name:            result.Name,
+			UID:             result.UID,
+			Result:          result.Result,
+			Error:           result.Error,
+			ResourceVersion: result.ResourceVersion,
+			Deleted:         result.Deleted,
+			UpdatedAt:       result.UpdatedAt,
+		})
+	}
+
+	db := database.GetGorm()
+	previous := []models.SpecApplyResult{}
+	if err := db.WithContext(ctx).Where("leaf_hub_name = ?", leafHubName).Find(&previous).Error; err != nil {
+		return err
+	}
+	if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("leaf_hub_name = ?", leafHubName).Delete(&models.SpecApplyResult{}).Error; err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(results, 500).Error
+	}); err != nil {
+		return err
+	}
+
+	for _, uid := range changedUIDs(previous, results) {
+		if err := h.updateCondition(ctx, uid); err != nil {
+			// the condition is updated again when the results are changed
+			h.log.Warnw("failed to update the applied condition", "uid", uid, "error", err)
+		}
+	}
+
+	h.log.Debugw("handler finished", "type", evt.Type(), "LH", evt.Source(), "version", version)
+	return nil
+}
+
+// updateCondition sets the applied condition on the status of the global resource by its results on all the hubs.
+// The condition isn't kept on the resource whose status has no conditions, e.g. the policy, and the results of it are
+// listed by the REST API.
+func (h *specApplyResultsHandler) updateCondition(ctx context.Context, uid string) error {
+	results := []models.SpecApplyResult{}
+	if err := database.GetGorm().WithContext(ctx).Where("uid = ?", uid).Order("leaf_hub_name").
+		Find(&results).Error; err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return nil
+	}
+
+	condition := appliedCondition(results)
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(results[0].APIVersion, results[0].Kind))
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if err := h.client.Get(ctx, client.ObjectKey{Namespace: results[0].Namespace, Name: results[0].Name},
+			obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if string(obj.GetUID()) != uid {
+			return nil
+		}
+
+		items, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		if err != nil {
+			return err
+		}
+		conditions := []metav1.Condition{}
+		for _, item := range items {
+			c := metav1.Condition{}
+			if m, ok := item.(map[string]interface{}); ok {
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &c); err != nil {
+					return err
+				}
+			}
+			conditions = append(conditions, c)
+		}
+		if !meta.SetStatusCondition(&conditions, condition) {
+			return nil
+		}
+
+		items = make([]interface{}, 0, len(conditions))
+		for i := range conditions {
+			m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&conditions[i])
+			if err != nil {
+				return err
+			}
+			items = append(items, m)
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, items, "status", "conditions"); err != nil {
+			return err
+		}
+		return h.client.Status().Update(ctx, obj)
+	})
+}
+
+// appliedCondition summarizes the results of the global resource on the hubs
+func appliedCondition(results []models.SpecApplyResult) metav1.Condition {
+	applied, skipped := 0, 0
+	failures := []string{}
+	for _, result := range results {
+		switch result.Result {
+		case wiremodels.SpecApplied:
+			applied++
+		case wiremodels.SpecApplySkipped:
+			skipped++
+		case wiremodels.SpecApplyFailed, wiremodels.SpecApplyRejected:
+			failures = append(failures, fmt.Sprintf("%s: %s", result.LeafHubName, result.Error))
+		}
+	}
+	message := fmt.Sprintf("applied on %d hubs, skipped on %d hubs", applied, skipped)
+	if len(failures) == 0 {
+		return metav1.Condition{
+			Type:    AppliedConditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonApplied,
+			Message: message,
+		}
+	}
+	return metav1.Condition{
+		Type:   AppliedConditionType,
+		Status: metav1.ConditionFalse,
+		Reason: reasonApplyFailed,
+		Message: fmt.Sprintf("failed on %d hubs, %s; %s", len(failures), message,
+			strings.Join(failures, "; ")),
+	}
+}
+
+// changedUIDs returns the global resources whose results of the hub are changed
+func changedUIDs(previous, current []models.SpecApplyResult) []string {
+	key := func(result models.SpecApplyResult) string {
+		return fmt.Sprintf("%s/%s/%s", result.UID, result.Result, result.Error)
+	}
+	previousKeys := map[string]bool{}
+	for _, result := range previous {
+		previousKeys[key(result)] = true
+	}
+	currentKeys := map[string]bool{}
+	for _, result := range current {
+		currentKeys[key(result)] = true
+	}
+
+	changed := map[string]bool{}
+	for _, result := range previous {
+		if !currentKeys[key(result)] {
+			changed[result.UID] = true
+		}
+	}
+	for _, result := range current {
+		if !previousKeys[key(result)] {
+			changed[result.UID] = true
+		}
+	}
+	delete(changed, "")
+
+	uids := make([]string, 0, len(changed))
+	for uid := range changed {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
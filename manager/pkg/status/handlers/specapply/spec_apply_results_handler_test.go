@@ -0,0 +1,80 @@
+package specapply
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+func TestAppliedCondition(t *testing.T) {
+	cases := []struct {
+		name            string
+		results         []models.SpecApplyResult
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name: "applied on all the hubs",
+			results: []models.SpecApplyResult{
+				{LeafHubName: "hub1", Result: wiremodels.SpecApplied},
+				{LeafHubName: "hub2", Result: wiremodels.SpecApplySkipped},
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  reasonApplied,
+			expectedMessage: "applied on 1 hubs, skipped on 1 hubs",
+		},
+		{
+			name: "failed on a hub",
+			results: []models.SpecApplyResult{
+				{LeafHubName: "hub1", Result: wiremodels.SpecApplied},
+				{LeafHubName: "hub2", Result: wiremodels.SpecApplyFailed, Error: "forbidden"},
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  reasonApplyFailed,
+			expectedMessage: "failed on 1 hubs, applied on 1 hubs, skipped on 0 hubs; hub2: forbidden",
+		},
+		{
+			name: "rejected by a hub",
+			results: []models.SpecApplyResult{
+				{LeafHubName: "hub1", Result: wiremodels.SpecApplied},
+				{LeafHubName: "hub2", Result: wiremodels.SpecApplyRejected, Error: "not allowed"},
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  reasonApplyFailed,
+			expectedMessage: "failed on 1 hubs, applied on 1 hubs, skipped on 0 hubs; hub2: not allowed",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			condition := appliedCondition(c.results)
+			if condition.Type != AppliedConditionType || condition.Status != c.expectedStatus ||
+				condition.Reason != c.expectedReason || condition.Message != c.expectedMessage {
+				t.Errorf("expected %s/%s/%s, but got %v", c.expectedStatus, c.expectedReason, c.expectedMessage,
+					condition)
+			}
+		})
+	}
+}
+
+func TestChangedUIDs(t *testing.T) {
+	previous := []models.SpecApplyResult{
+		{UID: "uid1", Result: wiremodels.SpecApplied},
+		{UID: "uid2", Result: wiremodels.SpecApplied},
+		{UID: "uid3", Result: wiremodels.SpecApplied},
+	}
+	current := []models.SpecApplyResult{
+		{UID: "uid1", Result: wiremodels.SpecApplied},
+		{UID: "uid2", Result: wiremodels.SpecApplyFailed, Error: "forbidden"},
+		{UID: "uid4", Result: wiremodels.SpecApplied},
+		{Result: wiremodels.SpecApplied},
+	}
+	expected := []string{"uid2", "uid3", "uid4"}
+	if actual := changedUIDs(previous, current); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, but got %v", expected, actual)
+	}
+}
@@ -0,0 +1,96 @@
+package specdrift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/conflator"
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+const batchSize = 500
+
+type specDriftHandler struct {
+	log           *zap.SugaredLogger
+	eventType     string
+	eventSyncMode enum.EventSyncMode
+	eventPriority conflator.ConflationPriority
+}
+
+func RegisterSpecDriftHandler(conflationManager *conflator.ConflationManager) {
+	eventType := string(enum.SpecDriftType)
+	logName := strings.Replace(eventType, enum.EventTypePrefix, "", -1)
+	h := &specDriftHandler{
+		log:           logger.ZapLogger(logName),
+		eventType:     eventType,
+		eventSyncMode: enum.DeltaStateMode,
+		eventPriority: conflator.SpecDriftPriority,
+	}
+	conflationManager.Register(conflator.NewConflationRegistration(
+		h.eventPriority,
+		h.eventSyncMode,
+		h.eventType,
+		h.handleEvent,
+	))
+}
+
+func (h *specDriftHandler) handleEvent(ctx context.Context, evt *cloudevents.Event) error {
+	version := evt.Extensions()[eventversion.ExtVersion]
+	leafHubName := evt.Source()
+	h.log.Debugw("handler start", "type", evt.Type(), "LH", evt.Source(), "version", version)
+
+	driftEvents := wiremodels.SpecDriftEvents{}
+	if err := evt.DataAs(&driftEvents); err != nil {
+		return err
+	}
+	if len(driftEvents) == 0 {
+		h.log.Info("empty spec drift event payload", "event", evt)
+		return nil
+	}
+
+	rows := make([]models.SpecDriftEvent, 0, len(driftEvents))
+	for _, driftEvent := range driftEvents {
+		fields, err := json.Marshal(driftEvent.Fields)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, models.SpecDriftEvent{
+			LeafHubName: leafHubName,
+			UID:         driftEvent.UID,
+			APIVersion:  driftEvent.APIVersion,
+			Kind:        driftEvent.Kind,
+			Namespace:   driftEvent.Namespace,
+			Name:        driftEvent.Name,
+			DriftType:   driftEvent.DriftType,
+			Mode:        driftEvent.Mode,
+			Action:      driftEvent.Action,
+			Fields:      fields,
+			Message:     driftEvent.Message,
+			CreatedAt:   driftEvent.CreatedAt,
+		})
+	}
+
+	err := database.GetGorm().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "leaf_hub_name"}, {Name: "kind"}, {Name: "namespace"}, {Name: "name"}, {Name: "created_at"},
+		},
+		DoNothing: true,
+	}).CreateInBatches(rows, batchSize).Error
+	if err != nil {
+		return fmt.Errorf("failed handling leaf hub spec drift event - %w", err)
+	}
+
+	h.log.Debugw("handler finished", "type", evt.Type(), "LH", evt.Source(), "version", version)
+	return nil
+}
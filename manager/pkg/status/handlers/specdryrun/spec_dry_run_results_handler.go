@@ -0,0 +1,100 @@
+package specdryrun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/conflator"
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+const batchSize = 500
+
+type specDryRunResultsHandler struct {
+	log           *zap.SugaredLogger
+	eventType     string
+	eventSyncMode enum.EventSyncMode
+	eventPriority conflator.ConflationPriority
+}
+
+// RegisterSpecDryRunResultsHandler handles the results of each dry run, which are delta events, so the results of
+// the dry runs sent to the hub at the same time aren't conflated
+func RegisterSpecDryRunResultsHandler(conflationManager *conflator.ConflationManager) {
+	eventType := string(enum.SpecDryRunResultsType)
+	logName := strings.Replace(eventType, enum.EventTypePrefix, "", -1)
+	h := &specDryRunResultsHandler{
+		log:           logger.ZapLogger(logName),
+		eventType:     eventType,
+		eventSyncMode: enum.DeltaStateMode,
+		eventPriority: conflator.SpecDryRunResultsPriority,
+	}
+	conflationManager.Register(conflator.NewConflationRegistration(
+		h.eventPriority,
+		h.eventSyncMode,
+		h.eventType,
+		h.handleEvent,
+	))
+}
+
+// handleEvent saves the results of the dry run on the hub, the results reported again replace the previous ones
+func (h *specDryRunResultsHandler) handleEvent(ctx context.Context, evt *cloudevents.Event) error {
+	version := evt.Extensions()[eventversion.ExtVersion]
+	leafHubName := evt.Source()
+	h.log.Debugw("handler start", "type", evt.Type(), "LH", evt.Source(), "version", version)
+
+	dryRunResults := &wiremodels.SpecDryRunResults{}
+	if err := evt.DataAs(dryRunResults); err != nil {
+		return err
+	}
+	if len(dryRunResults.Results) == 0 {
+		h.log.Infow("empty dry run results", "LH", leafHubName, "id", dryRunResults.ID)
+		return nil
+	}
+
+	rows := make([]models.SpecDryRunResult, 0, len(dryRunResults.Results))
+	for _, result := range dryRunResults.Results {
+		var diff []byte
+		if len(result.Diff) > 0 {
+			var err error
+			if diff, err = json.Marshal(result.Diff); err != nil {
+				return err
+			}
+		}
+		rows = append(rows, models.SpecDryRunResult{
+			DryRunID:    dryRunResults.ID,
+			LeafHubName: leafHubName,
+			APIVersion:  result.APIVersion,
+			Kind:        result.Kind,
+			Namespace:   result.Namespace,
+			Name:        result.Name,
+			Result:      result.Result,
+			Diff:        diff,
+			Message:     result.Message,
+			ReportedAt:  result.ReportedAt,
+		})
+	}
+
+	err := database.GetGorm().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "dry_run_id"}, {Name: "leaf_hub_name"}, {Name: "kind"}, {Name: "namespace"}, {Name: "name"},
+		},
+		UpdateAll: true,
+	}).CreateInBatches(rows, batchSize).Error
+	if err != nil {
+		return fmt.Errorf("failed handling leaf hub dry run results - %w", err)
+	}
+
+	h.log.Debugw("handler finished", "type", evt.Type(), "LH", evt.Source(), "version", version)
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package sharding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// DefaultVirtualNodes is the number of points each member owns on the ring, it's used to spread the leaf hubs evenly
+// even there are only a few manager replicas.
+const DefaultVirtualNodes = 100
+
+// HashRing assigns the leaf hubs to the manager replicas with consistent hashing, so that only the leaf hubs owned
+// by the joining or leaving replica are moved when the members are changed.
+type HashRing struct {
+	members      []string
+	points       []uint32
+	pointToOwner map[uint32]string
+}
+
+// NewHashRing creates the ring from the members, each member is placed on the ring with the virtualNodes points.
+func NewHashRing(members []string, virtualNodes int) *HashRing {
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+	ring := &HashRing{
+		members:      append([]string{}, members...),
+		points:       make([]uint32, 0, len(members)*virtualNodes),
+		pointToOwner: make(map[uint32]string, len(members)*virtualNodes),
+	}
+	sort.Strings(ring.members)
+	for _, member := range ring.members {
+		for i := 0; i < virtualNodes; i++ {
+			point := hashKey(member + "#" + strconv.Itoa(i))
+			// skip the collision point, the owner is decided by the sorted members
+			if _, found := ring.pointToOwner[point]; found {
+				continue
+			}
+			ring.pointToOwner[point] = member
+			ring.points = append(ring.points, point)
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+// Owner returns the member owns the key, return empty if there is no member in the ring.
+func (r *HashRing) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	point := hashKey(key)
+	idx := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= point })
+	if idx == len(r.points) {
+		idx = 0
+	}
+	return r.pointToOwner[r.points[idx]]
+}
+
+// Members returns the sorted members of the ring.
+func (r *HashRing) Members() []string {
+	return append([]string{}, r.members...)
+}
+
+func hashKey(key string) uint32 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
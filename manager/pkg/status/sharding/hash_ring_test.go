@@ -0,0 +1,44 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRing(t *testing.T) {
+	hubs := []string{}
+	for i := 0; i < 300; i++ {
+		hubs = append(hubs, fmt.Sprintf("hub%d", i))
+	}
+
+	// empty ring doesn't own any hub
+	assert.Equal(t, "", NewHashRing(nil, DefaultVirtualNodes).Owner("hub1"))
+
+	// the order of the members doesn't affect the assignment
+	ring := NewHashRing([]string{"manager-a", "manager-b", "manager-c"}, DefaultVirtualNodes)
+	reordered := NewHashRing([]string{"manager-c", "manager-a", "manager-b"}, DefaultVirtualNodes)
+	owned := map[string]int{}
+	for _, hub := range hubs {
+		owner := ring.Owner(hub)
+		assert.Equal(t, owner, reordered.Owner(hub))
+		owned[owner]++
+	}
+
+	// the hubs are spread between all the members
+	assert.Len(t, owned, 3)
+	for member, count := range owned {
+		assert.Greater(t, count, 50, "member %s owns too few hubs", member)
+	}
+
+	// only the hubs of the leaving member are moved
+	shrunk := NewHashRing([]string{"manager-a", "manager-c"}, DefaultVirtualNodes)
+	for _, hub := range hubs {
+		if ring.Owner(hub) != "manager-b" {
+			assert.Equal(t, ring.Owner(hub), shrunk.Owner(hub))
+		} else {
+			assert.NotEqual(t, "manager-b", shrunk.Owner(hub))
+		}
+	}
+}
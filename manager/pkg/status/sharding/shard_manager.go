@@ -0,0 +1,410 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/configs"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/hubmanagement"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
+)
+
+const (
+	// ShardMemberLabelKey is used to list the member leases of the status shards
+	ShardMemberLabelKey = "global-hub.open-cluster-management.io/manager-shard"
+	shardLeasePrefix    = "multicluster-global-hub-manager-"
+	shardMemberPrefix   = "shard-"
+)
+
+var (
+	shardManager *ShardManager
+	// errMemberLost means the member lease is taken by the other replica, the replica must stop processing the status
+	errMemberLost = errors.New("the shard member is lost")
+)
+
+// RebalanceFunc is invoked once the members of the shards are changed, the owns reports whether the leaf hub is
+// owned by the current replica.
+type RebalanceFunc func(owns func(leafHubName string) bool)
+
+// ShardManager runs on every manager replica, it maintains a member lease for the current replica and assigns the
+// leaf hubs to the live members with the consistent hashing. The replica only processes the status of the leaf hubs
+// it owns, and requests the hubs to resync once it acquires them from the other replicas.
+//
+// The member is one of the MaxMembers slots claimed by AcquireMember, instead of the pod name, so the consumer group
+// and the offsets of the member are reused by the replica which replaces it in the next rollout.
+type ShardManager struct {
+	log           *zap.SugaredLogger
+	client        client.Client
+	reader        client.Reader
+	producer      transport.Producer
+	namespace     string
+	identity      string
+	holder        string
+	leaseDuration time.Duration
+	renewPeriod   time.Duration
+	virtualNodes  int
+
+	mutex      sync.RWMutex
+	ring       *HashRing
+	rebalances []RebalanceFunc
+}
+
+// AddShardManager adds the shard manager to the runtime manager, it only takes effect when the sharding is enabled.
+func AddShardManager(mgr ctrl.Manager, managerConfig *configs.ManagerConfig, producer transport.Producer) error {
+	if shardManager != nil || managerConfig.ShardingConfig == nil || !managerConfig.ShardingConfig.Enabled {
+		return nil
+	}
+	instance, err := NewShardManager(mgr.GetClient(), mgr.GetAPIReader(), producer,
+		managerConfig.ManagerNamespace, managerConfig.ShardingConfig)
+	if err != nil {
+		return err
+	}
+	if err := mgr.Add(instance); err != nil {
+		return fmt.Errorf("failed to add the shard manager: %w", err)
+	}
+	shardManager = instance
+	return nil
+}
+
+// GetShardManager returns the shard manager of the current replica, it's nil if the sharding is disabled
+func GetShardManager() *ShardManager {
+	return shardManager
+}
+
+func NewShardManager(c client.Client, reader client.Reader, producer transport.Producer, namespace string,
+	shardingConfig *configs.ShardingConfig,
+) (*ShardManager, error) {
+	if shardingConfig.Identity == "" {
+		return nil, fmt.Errorf("the identity of the shard member must be acquired")
+	}
+	return &ShardManager{
+		log:           logger.ZapLogger("shard-manager"),
+		client:        c,
+		reader:        reader,
+		producer:      producer,
+		namespace:     namespace,
+		identity:      shardingConfig.Identity,
+		holder:        shardingConfig.PodName,
+		leaseDuration: shardingConfig.LeaseDuration,
+		renewPeriod:   shardingConfig.RenewPeriod,
+		virtualNodes:  shardingConfig.VirtualNodes,
+	}, nil
+}
+
+// AcquireMember claims a member slot for the replica, and sets it to the identity of the sharding config. A slot is
+// free if its lease doesn't exist or is expired, or it's held by the same pod before the restart. It waits for a slot
+// to be released if all of them are held by the live replicas.
+func AcquireMember(ctx context.Context, c client.Client, reader client.Reader, namespace string,
+	shardingConfig *configs.ShardingConfig,
+) error {
+	if shardingConfig.PodName == "" {
+		return fmt.Errorf("the pod name of the shard member must be specified")
+	}
+	if shardingConfig.MaxMembers <= 0 {
+		return fmt.Errorf("the max members of the shards must be positive, but got %d", shardingConfig.MaxMembers)
+	}
+	log := logger.ZapLogger("shard-manager")
+	ticker := time.NewTicker(shardingConfig.RenewPeriod)
+	defer ticker.Stop()
+	for {
+		for i := 0; i < shardingConfig.MaxMembers; i++ {
+			member := fmt.Sprintf("%s%d", shardMemberPrefix, i)
+			acquired, err := acquireMemberLease(ctx, c, reader, namespace, member, shardingConfig)
+			if err != nil {
+				log.Warnw("failed to acquire the shard member", "member", member, "error", err)
+				continue
+			}
+			if acquired {
+				log.Infow("acquired the shard member", "member", member, "holder", shardingConfig.PodName)
+				shardingConfig.Identity = member
+				return nil
+			}
+		}
+		log.Infow("all the shard members are held, waiting for the members to be released",
+			"maxMembers", shardingConfig.MaxMembers)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// acquireMemberLease takes the lease of the member if it's free, the conflict means the lease is taken by the other
+// replica at the same time.
+func acquireMemberLease(ctx context.Context, c client.Client, reader client.Reader, namespace, member string,
+	shardingConfig *configs.ShardingConfig,
+) (bool, error) {
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{}
+	err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: shardLeasePrefix + member}, lease)
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      shardLeasePrefix + member,
+				Namespace: namespace,
+				Labels:    map[string]string{ShardMemberLabelKey: member},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(shardingConfig.PodName),
+				LeaseDurationSeconds: ptr.To(int32(shardingConfig.LeaseDuration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		err = c.Create(ctx, lease)
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return err == nil, err
+	} else if err != nil {
+		return false, err
+	}
+
+	if ptr.Deref(lease.Spec.HolderIdentity, "") != shardingConfig.PodName &&
+		!isLeaseExpired(lease, shardingConfig.LeaseDuration, now.Time) {
+		return false, nil
+	}
+	lease.Spec.HolderIdentity = ptr.To(shardingConfig.PodName)
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(shardingConfig.LeaseDuration.Seconds()))
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	err = c.Update(ctx, lease)
+	if apierrors.IsConflict(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Identity returns the member of the current replica
+func (s *ShardManager) Identity() string {
+	return s.identity
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface, the shards run on all the replicas.
+func (s *ShardManager) NeedLeaderElection() bool {
+	return false
+}
+
+// OnRebalance registers the function to be invoked after the leaf hubs are reassigned.
+func (s *ShardManager) OnRebalance(fn RebalanceFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rebalances = append(s.rebalances, fn)
+}
+
+// Owns reports whether the leaf hub is assigned to the current replica. It doesn't own any hub until the members
+// are observed for the first time.
+func (s *ShardManager) Owns(leafHubName string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.ring == nil {
+		return false
+	}
+	return s.ring.Owner(leafHubName) == s.identity
+}
+
+// Start renews the lease of the member periodically. It returns error once the member is taken by the other replica
+// after the lease is expired, the manager exits then and acquires a member again after the restart.
+func (s *ShardManager) Start(ctx context.Context) error {
+	s.log.Infow("start the status shard", "identity", s.identity, "holder", s.holder, "renewPeriod", s.renewPeriod)
+	if err := s.sync(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.renewPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// release the lease so the other replicas can take over the hubs without waiting it expired
+			if err := s.releaseLease(); err != nil {
+				s.log.Warnw("failed to release the shard lease", "error", err)
+			}
+			s.log.Info("stopped the status shard")
+			return nil
+		case <-ticker.C:
+			if err := s.sync(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sync renews the lease and rebalances the leaf hubs if the live members are changed, it only returns the error
+// that the member is lost, the other errors are retried in the next period.
+func (s *ShardManager) sync(ctx context.Context) error {
+	if err := s.renewLease(ctx); errors.Is(err, errMemberLost) {
+		return err
+	} else if err != nil {
+		s.log.Warnw("failed to renew the shard lease", "error", err)
+		return nil
+	}
+	members, err := s.liveMembers(ctx)
+	if err != nil {
+		s.log.Warnw("failed to list the shard members", "error", err)
+		return nil
+	}
+
+	s.mutex.Lock()
+	previous := s.ring
+	if previous != nil && reflect.DeepEqual(previous.Members(), members) {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.ring = NewHashRing(members, s.virtualNodes)
+	rebalances := append([]RebalanceFunc{}, s.rebalances...)
+	s.mutex.Unlock()
+
+	s.log.Infow("rebalance the leaf hubs", "members", members)
+	for _, rebalance := range rebalances {
+		rebalance(s.Owns)
+	}
+	s.resyncAcquiredHubs(ctx, previous)
+	return nil
+}
+
+// resyncAcquiredHubs requests the hubs moved to the current replica to send their status again, since the events
+// of them might be discarded before the ownership is changed.
+func (s *ShardManager) resyncAcquiredHubs(ctx context.Context, previous *HashRing) {
+	if s.producer == nil {
+		return
+	}
+	var heartbeats []models.LeafHubHeartbeat
+	if err := database.GetGorm().Where("status = ?", hubmanagement.HubActive).Find(&heartbeats).Error; err != nil {
+		s.log.Warnw("failed to list the leaf hubs for resync", "error", err)
+		return
+	}
+	for _, hub := range heartbeats {
+		if !s.Owns(hub.Name) {
+			continue
+		}
+		if previous != nil && previous.Owner(hub.Name) == s.identity {
+			continue
+		}
+		if err := hubmanagement.ResyncHub(ctx, s.producer, hub.Name); err != nil {
+			s.log.Warnw("failed to resync the acquired hub", "hub", hub.Name, "error", err)
+		}
+	}
+}
+
+// renewLease renews the lease of the member, it returns errMemberLost if the lease is deleted or held by the other
+// replica, which means the lease was expired and the member might have been acquired by the other replica.
+func (s *ShardManager) renewLease(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{}
+	err := s.reader.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.leaseName()}, lease)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%w: the lease %s is deleted", errMemberLost, s.leaseName())
+	} else if err != nil {
+		return err
+	}
+	if holder := ptr.Deref(lease.Spec.HolderIdentity, ""); holder != s.holder {
+		return fmt.Errorf("%w: the lease %s is held by %s", errMemberLost, s.leaseName(), holder)
+	}
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.leaseDuration.Seconds()))
+	lease.Spec.RenewTime = &now
+	err = s.client.Update(ctx, lease)
+	if apierrors.IsConflict(err) {
+		// retry in the next period, the holder is checked again then
+		return nil
+	}
+	return err
+}
+
+// releaseLease deletes the lease of the member only if it's still held by the current replica
+func (s *ShardManager) releaseLease() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lease := &coordinationv1.Lease{}
+	err := s.reader.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.leaseName()}, lease)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if ptr.Deref(lease.Spec.HolderIdentity, "") != s.holder {
+		return nil
+	}
+	return client.IgnoreNotFound(s.client.Delete(ctx, lease,
+		client.Preconditions{ResourceVersion: ptr.To(lease.ResourceVersion)}))
+}
+
+// liveMembers returns the sorted identities of the replicas which renewed their lease within the lease duration.
+func (s *ShardManager) liveMembers(ctx context.Context) ([]string, error) {
+	leases := &coordinationv1.LeaseList{}
+	if err := s.reader.List(ctx, leases, client.InNamespace(s.namespace),
+		client.HasLabels{ShardMemberLabelKey}); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	members := []string{}
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if lease.Spec.HolderIdentity == nil || isLeaseExpired(lease, s.leaseDuration, now) {
+			continue
+		}
+		members = append(members, strings.TrimPrefix(lease.Name, shardLeasePrefix))
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func (s *ShardManager) leaseName() string {
+	return shardLeasePrefix + s.identity
+}
+
+// isLeaseExpired reports whether the lease isn't renewed within its duration, the defaultDuration is used if the
+// lease doesn't specify it
+func isLeaseExpired(lease *coordinationv1.Lease, defaultDuration time.Duration, now time.Time) bool {
+	if lease.Spec.RenewTime == nil {
+		return true
+	}
+	duration := defaultDuration
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return lease.Spec.RenewTime.Add(duration).Before(now)
+}
+
+// everyReplicaRunnable runs the wrapped runnable on all the replicas instead of only on the leader
+type everyReplicaRunnable struct {
+	manager.Runnable
+}
+
+func (everyReplicaRunnable) NeedLeaderElection() bool {
+	return false
+}
+
+// EveryReplica wraps the runnable so that it's started on the replica without waiting for the leader election.
+func EveryReplica(runnable manager.Runnable) manager.Runnable {
+	return everyReplicaRunnable{runnable}
+}
+
+// Manager overrides the Add of the runtime manager, the added runnables run on every replica.
+type Manager struct {
+	ctrl.Manager
+}
+
+func (m *Manager) Add(runnable manager.Runnable) error {
+	return m.Manager.Add(EveryReplica(runnable))
+}
@@ -0,0 +1,184 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/configs"
+)
+
+const testNamespace = "multicluster-global-hub"
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	require.NoError(t, coordinationv1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func newShardingConfig(podName string) *configs.ShardingConfig {
+	return &configs.ShardingConfig{
+		Enabled:       true,
+		PodName:       podName,
+		MaxMembers:    2,
+		LeaseDuration: 30 * time.Second,
+		RenewPeriod:   10 * time.Millisecond,
+	}
+}
+
+func memberLease(member, holder string, renewTime time.Time) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      shardLeasePrefix + member,
+			Namespace: testNamespace,
+			Labels:    map[string]string{ShardMemberLabelKey: member},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(holder),
+			LeaseDurationSeconds: ptr.To(int32(30)),
+			RenewTime:            ptr.To(metav1.NewMicroTime(renewTime)),
+		},
+	}
+}
+
+func newTestShardManager(t *testing.T, c client.Client, podName string) *ShardManager {
+	shardingConfig := newShardingConfig(podName)
+	require.NoError(t, AcquireMember(context.Background(), c, c, testNamespace, shardingConfig))
+	s, err := NewShardManager(c, c, nil, testNamespace, shardingConfig)
+	require.NoError(t, err)
+	return s
+}
+
+func TestAcquireMember(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t, memberLease("shard-0", "manager-a", time.Now()))
+
+	// the live member is skipped
+	configB := newShardingConfig("manager-b")
+	require.NoError(t, AcquireMember(ctx, c, c, testNamespace, configB))
+	assert.Equal(t, "shard-1", configB.Identity)
+
+	// the same pod takes its member back after the restart
+	configA := newShardingConfig("manager-a")
+	require.NoError(t, AcquireMember(ctx, c, c, testNamespace, configA))
+	assert.Equal(t, "shard-0", configA.Identity)
+
+	// all the members are held, it waits until the context is done
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err := AcquireMember(timeoutCtx, c, c, testNamespace, newShardingConfig("manager-c"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the expired member is taken over by the new pod, e.g. the replica of the next rollout
+	expired := memberLease("shard-0", "manager-a", time.Now().Add(-time.Minute))
+	c = newFakeClient(t, expired, memberLease("shard-1", "manager-b", time.Now()))
+	configC := newShardingConfig("manager-c")
+	require.NoError(t, AcquireMember(ctx, c, c, testNamespace, configC))
+	assert.Equal(t, "shard-0", configC.Identity)
+	lease := &coordinationv1.Lease{}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(expired), lease))
+	assert.Equal(t, "manager-c", *lease.Spec.HolderIdentity)
+}
+
+func TestLiveMembers(t *testing.T) {
+	noHolder := memberLease("shard-3", "", time.Now())
+	noHolder.Spec.HolderIdentity = nil
+	c := newFakeClient(t,
+		memberLease("shard-1", "manager-b", time.Now()),
+		memberLease("shard-0", "manager-a", time.Now().Add(-10*time.Second)),
+		// the lease isn't renewed within the duration
+		memberLease("shard-2", "manager-c", time.Now().Add(-time.Minute)),
+		noHolder,
+		// the lease isn't a shard member
+		&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "leader", Namespace: testNamespace},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: ptr.To("manager-a")},
+		},
+	)
+	s := &ShardManager{reader: c, namespace: testNamespace, leaseDuration: 30 * time.Second}
+	members, err := s.liveMembers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shard-0", "shard-1"}, members)
+
+	// the default duration is used if the lease doesn't specify it
+	lease := memberLease("shard-0", "manager-a", time.Now().Add(-10*time.Second))
+	lease.Spec.LeaseDurationSeconds = nil
+	assert.False(t, isLeaseExpired(lease, 30*time.Second, time.Now()))
+	assert.True(t, isLeaseExpired(lease, 5*time.Second, time.Now()))
+}
+
+func TestShardManagerSync(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t)
+	hubs := []string{"hub1", "hub2", "hub3", "hub4", "hub5", "hub6", "hub7", "hub8"}
+
+	managerA := newTestShardManager(t, c, "manager-a")
+	rebalances := 0
+	ownedByA := map[string]bool{}
+	managerA.OnRebalance(func(owns func(string) bool) {
+		rebalances++
+		for _, hub := range hubs {
+			ownedByA[hub] = owns(hub)
+		}
+	})
+	// it doesn't own any hub before the members are observed
+	assert.False(t, managerA.Owns("hub1"))
+
+	require.NoError(t, managerA.sync(ctx))
+	assert.Equal(t, 1, rebalances)
+	for _, hub := range hubs {
+		assert.True(t, ownedByA[hub], "the only member should own %s", hub)
+	}
+
+	// the rebalance isn't invoked if the members aren't changed
+	require.NoError(t, managerA.sync(ctx))
+	assert.Equal(t, 1, rebalances)
+
+	// the hubs are split once the other replica joins
+	managerB := newTestShardManager(t, c, "manager-b")
+	require.NoError(t, managerB.sync(ctx))
+	require.NoError(t, managerA.sync(ctx))
+	assert.Equal(t, 2, rebalances)
+	for _, hub := range hubs {
+		assert.Equal(t, ownedByA[hub], managerA.Owns(hub))
+		assert.NotEqual(t, managerA.Owns(hub), managerB.Owns(hub), "%s should be owned by one replica", hub)
+	}
+
+	// the hubs are taken back once the other replica releases its member
+	require.NoError(t, managerB.releaseLease())
+	require.NoError(t, managerA.sync(ctx))
+	assert.Equal(t, 3, rebalances)
+	for _, hub := range hubs {
+		assert.True(t, managerA.Owns(hub))
+	}
+}
+
+func TestShardManagerMemberLost(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t)
+	s := newTestShardManager(t, c, "manager-a")
+	require.NoError(t, s.sync(ctx))
+
+	// the expired member is taken by the other replica
+	lease := &coordinationv1.Lease{}
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: s.leaseName()}, lease))
+	lease.Spec.HolderIdentity = ptr.To("manager-b")
+	require.NoError(t, c.Update(ctx, lease))
+	err := s.sync(ctx)
+	assert.True(t, errors.Is(err, errMemberLost), "unexpected error: %v", err)
+
+	// the lease held by the other replica isn't released
+	require.NoError(t, s.releaseLease())
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(lease), lease))
+	assert.Equal(t, "manager-b", *lease.Spec.HolderIdentity)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/conflator"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/dispatcher"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/handlers"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/status/sharding"
 	"github.com/stolostron/multicluster-global-hub/pkg/statistics"
 	"github.com/stolostron/multicluster-global-hub/pkg/transport"
 )
@@ -21,6 +22,12 @@ func AddStatusSyncers(mgr ctrl.Manager, consumer transport.Consumer, managerConf
 	if statusCtrlStarted {
 		return nil
 	}
+	// the status runnables are started on every replica when the leaf hubs are sharded between the replicas
+	shards := sharding.GetShardManager()
+	if shards != nil {
+		mgr = &sharding.Manager{Manager: mgr}
+	}
+
 	// create statistics
 	stats := statistics.NewStatistics(managerConfig.StatisticsConfig)
 	if err := mgr.Add(stats); err != nil {
@@ -30,6 +37,10 @@ func AddStatusSyncers(mgr ctrl.Manager, consumer transport.Consumer, managerConf
 	// manage all Conflation Units and handlers
 	conflationManager := conflator.NewConflationManager(stats)
 	handlers.RegisterHandlers(mgr, conflationManager, managerConfig.EnableGlobalResource)
+	if shards != nil {
+		conflationManager.SetHubFilter(shards.Owns)
+		shards.OnRebalance(conflationManager.SetHubFilter)
+	}
 
 	// start consume message from transport to conflation manager
 	if err := dispatcher.AddTransportDispatcher(mgr, consumer, managerConfig, conflationManager, stats); err != nil {
@@ -42,7 +53,11 @@ func AddStatusSyncers(mgr ctrl.Manager, consumer transport.Consumer, managerConf
 	}
 
 	// add kafka offset to the database periodically
-	committer := conflator.NewKafkaConflationCommitter(conflationManager.GetMetadatas)
+	member := ""
+	if shards != nil {
+		member = shards.Identity()
+	}
+	committer := conflator.NewKafkaConflationCommitter(conflationManager.GetMetadatas, member)
 	if err := mgr.Add(committer); err != nil {
 		return fmt.Errorf("failed to start the offset committer: %w", err)
 	}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +operator-sdk:csv:customresourcedefinitions:resources={{Deployment,v1,multicluster-global-hub-agent}}
+// GlobalResourceAllowList is created by the admin of the managed hub to restrict the global resources the global hub
+// agent applies on the hub. Once any allow list exists, the agent only applies or deletes the objects allowed by any
+// rule of the allow lists, and reports the others as rejected to the global hub.
+type GlobalResourceAllowList struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GlobalResourceAllowListSpec `json:"spec,omitempty"`
+}
+
+// GlobalResourceAllowListSpec defines the objects allowed to be applied by the global hub agent
+type GlobalResourceAllowListSpec struct {
+	// Rules allow the objects matching any of them
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:MinItems=1
+	Rules []GlobalResourceAllowRule `json:"rules"`
+}
+
+// GlobalResourceAllowRule allows the objects matching all of its fields
+type GlobalResourceAllowRule struct {
+	// APIGroups are the API groups of the objects, "" is the core group and "*" is all the groups
+	// +kubebuilder:validation:MinItems=1
+	APIGroups []string `json:"apiGroups"`
+	// Versions are the API versions of the objects, all the versions are allowed if it's empty
+	// +optional
+	Versions []string `json:"versions,omitempty"`
+	// Kinds are the kinds of the objects, "*" is all the kinds
+	// +kubebuilder:validation:MinItems=1
+	Kinds []string `json:"kinds"`
+	// Namespaces are the namespaces of the objects, "" is the cluster scoped objects. All the namespaces and the
+	// cluster scoped objects are allowed if it's empty
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// LabelSelector selects the objects by their labels, all the objects are allowed if it's empty
+	// +optional
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// GlobalResourceAllowListList contains a list of GlobalResourceAllowList
+type GlobalResourceAllowListList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GlobalResourceAllowList `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GlobalResourceAllowList{}, &GlobalResourceAllowListList{})
+}
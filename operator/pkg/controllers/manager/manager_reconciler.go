@@ -240,10 +240,15 @@ func (r *ManagerReconciler) Reconcile(ctx context.Context,
 		months = 1
 	}
 
+	// the replicas share the status path of the leaf hubs with the high availability
 	replicas := int32(1)
+	enableStatusSharding := false
 	if mgh.Spec.AvailabilityConfig == v1alpha4.HAHigh {
 		replicas = 2
+		enableStatusSharding = true
 	}
+	// the rollout surges the new replicas before the old ones are terminated, they need the spare shard members
+	shardMaxMembers := replicas * 2
 
 	transportConn := config.GetTransporterConn()
 	if transportConn == nil || transportConn.BootstrapServer == "" {
@@ -278,12 +283,14 @@ func (r *ManagerReconciler) Reconcile(ctx context.Context,
 
 	managerObjects, err := hohRenderer.Render("manifests", "", func(profile string) (interface{}, error) {
 		return ManagerVariables{
-			Image:              config.GetImage(config.GlobalHubManagerImageKey),
-			Replicas:           replicas,
-			ProxyImage:         config.GetImage(config.OauthProxyImageKey),
-			ImagePullSecret:    mgh.Spec.ImagePullSecret,
-			ImagePullPolicy:    string(imagePullPolicy),
-			ProxySessionSecret: proxySessionSecret,
+			Image:                config.GetImage(config.GlobalHubManagerImageKey),
+			Replicas:             replicas,
+			EnableStatusSharding: enableStatusSharding,
+			ShardMaxMembers:      shardMaxMembers,
+			ProxyImage:           config.GetImage(config.OauthProxyImageKey),
+			ImagePullSecret:      mgh.Spec.ImagePullSecret,
+			ImagePullPolicy:      string(imagePullPolicy),
+			ProxySessionSecret:   proxySessionSecret,
 			DatabaseURL: base64.StdEncoding.EncodeToString(
 				[]byte(storageConn.SuperuserDatabaseURI)),
 			PostgresCACert:            base64.StdEncoding.EncodeToString(storageConn.CACert),
@@ -439,6 +446,8 @@ func setMiddlewareCache(transportConn *transport.KafkaConfig, storageConn *confi
 type ManagerVariables struct {
 	Image                     string
 	Replicas                  int32
+	EnableStatusSharding      bool
+	ShardMaxMembers           int32
 	ProxyImage                string
 	ImagePullSecret           string
 	ImagePullPolicy           string
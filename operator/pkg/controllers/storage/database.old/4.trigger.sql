@@ -71,3 +71,36 @@ CREATE TRIGGER update_compliance_cluster_id_trigger
 AFTER INSERT ON status.managed_clusters
 FOR EACH ROW
 EXECUTE FUNCTION public.update_compliance_cluster_id();
+
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.applications;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.applications FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.channels;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.channels FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.managed_clusters_labels;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.managed_clusters_labels FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.managedclustersetbindings;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.managedclustersetbindings FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.managedclustersets;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.managedclustersets FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.placementbindings;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.placementbindings FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.placementrules;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.placementrules FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.placements;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.placements FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.policies;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.policies FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.subscriptions;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.subscriptions FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+
+-- the function is created by the migration, which is applied after the database.old, so the trigger is created here
+-- once the global resources are enabled after the database is migrated
+DO $$
+BEGIN
+    IF to_regprocedure('history.update_history_compliance_by_status()') IS NOT NULL THEN
+        DROP TRIGGER IF EXISTS trg_update_history_compliance_by_status ON status.compliance;
+        CREATE TRIGGER trg_update_history_compliance_by_status AFTER INSERT OR UPDATE ON status.compliance
+        FOR EACH ROW EXECUTE FUNCTION history.update_history_compliance_by_status();
+    END IF;
+END;
+$$;
@@ -11,6 +11,10 @@ CREATE SCHEMA IF NOT EXISTS event;
 
 CREATE SCHEMA IF NOT EXISTS security;
 
+-- the spec tables of the global resources are created by the database.old, but the migrations create the other spec
+-- tables, so the schema is always created
+CREATE SCHEMA IF NOT EXISTS spec;
+
 CREATE EXTENSION IF NOT EXISTS pg_stat_statements;
 
 DO $$ BEGIN
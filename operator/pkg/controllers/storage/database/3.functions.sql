@@ -189,4 +189,16 @@ BEGIN
 
     RETURN NEW;
 END;
-$$ LANGUAGE plpgsql;
\ No newline at end of file
+$$ LANGUAGE plpgsql;
+
+--- trigger function to notify the spec_changes channel with the table name once for each changed statement, so the
+--- spec syncers sync the changes to the hubs without waiting for the next polling. The notifications of a transaction
+--- are sent when it commits, and the duplicated ones are folded into one.
+CREATE OR REPLACE FUNCTION public.notify_spec_change() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  PERFORM pg_notify('spec_changes', TG_TABLE_NAME);
+  RETURN NULL;
+END;
+$$;
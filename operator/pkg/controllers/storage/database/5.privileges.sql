@@ -7,6 +7,7 @@ DO $$ BEGIN
         GRANT USAGE ON SCHEMA local_spec TO "$1";
         GRANT USAGE ON SCHEMA local_status TO "$1";
         GRANT USAGE ON SCHEMA security TO "$1";
+        GRANT USAGE ON SCHEMA audit TO "$1";
 
         GRANT SELECT ON ALL TABLES IN SCHEMA status TO "$1";
         GRANT SELECT ON ALL TABLES IN SCHEMA event TO "$1";
@@ -14,5 +15,6 @@ DO $$ BEGIN
         GRANT SELECT ON ALL TABLES IN SCHEMA local_spec TO "$1";
         GRANT SELECT ON ALL TABLES IN SCHEMA local_status TO "$1";
         GRANT SELECT ON ALL TABLES IN SCHEMA security TO "$1";
+        GRANT SELECT ON ALL TABLES IN SCHEMA audit TO "$1";
    END IF;
 END $$;
@@ -8,10 +8,12 @@ import (
 	iofs "io/fs"
 	"math/big"
 	"net/url"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -31,6 +33,7 @@ import (
 	"github.com/stolostron/multicluster-global-hub/operator/pkg/utils"
 	"github.com/stolostron/multicluster-global-hub/pkg/constants"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/migration"
 	"github.com/stolostron/multicluster-global-hub/pkg/logger"
 	commonutils "github.com/stolostron/multicluster-global-hub/pkg/utils"
 )
@@ -51,9 +54,6 @@ var databaseFS embed.FS
 //go:embed database.old
 var databaseOldFS embed.FS
 
-//go:embed upgrade
-var upgradeFS embed.FS
-
 //go:embed manifests.sts
 var stsPostgresFS embed.FS
 
@@ -370,9 +370,12 @@ func (r *StorageReconciler) applyGlobalHubInitSQL(ctx context.Context, conn *pgx
 	}
 
 	if !r.upgrade {
-		err = applySQL(ctx, conn, upgradeFS, "upgrade", readonlyUsername)
-		if err != nil {
-			return fmt.Errorf("failed to apply the upgrade sql: %v", err)
+		if err = applyMigrations(ctx, conn); err != nil {
+			return fmt.Errorf("failed to apply the schema migrations: %v", err)
+		}
+		// grant the readonly user to the tables created by the migrations
+		if err = applySQL(ctx, conn, databaseFS, "database/5.privileges.sql", readonlyUsername); err != nil {
+			return fmt.Errorf("failed to apply the privileges sql: %v", err)
 		}
 		r.upgrade = true
 	}
@@ -380,6 +383,28 @@ func (r *StorageReconciler) applyGlobalHubInitSQL(ctx context.Context, conn *pgx
 	return nil
 }
 
+// applyMigrations applies the versioned migrations after the baseline schema is created
+func applyMigrations(ctx context.Context, conn *pgx.Conn) error {
+	db := stdlib.OpenDB(*conn.Config())
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Warnf("failed to close the migration database: %v", err)
+		}
+	}()
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range applied {
+		log.Infof("applied the schema migration %d_%s", m.Version, m.Name)
+	}
+	return nil
+}
+
 func applySQL(ctx context.Context, conn *pgx.Conn, databaseFS embed.FS, rootDir, username string) error {
 	err := iofs.WalkDir(databaseFS, rootDir, func(file string, d iofs.DirEntry, beforeError error) error {
 		if beforeError != nil {
@@ -392,7 +417,7 @@ func applySQL(ctx context.Context, conn *pgx.Conn, databaseFS embed.FS, rootDir,
 		if err != nil {
 			return fmt.Errorf("failed to read %s: %w", file, err)
 		}
-		if file == rootDir+"/5.privileges.sql" {
+		if path.Base(file) == "5.privileges.sql" {
 			if username != "" {
 				_, err = conn.Exec(ctx, strings.ReplaceAll(string(sqlBytes), "$1", username))
 			}
@@ -0,0 +1,11 @@
+package spec
+
+import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+// Manger to Agent: DryRunSpecBundle is the objects applied by the server-side dry run on the hub, and the results are
+// reported back with the ID. The objects aren't in the "objects" field of the GenericSpecBundle, so they're never
+// applied by the agent which dispatches the unknown bundle to the generic syncer.
+type DryRunSpecBundle struct {
+	ID      string                       `json:"id"`
+	Objects []*unstructured.Unstructured `json:"dryRunObjects"`
+}
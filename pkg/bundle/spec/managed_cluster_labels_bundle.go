@@ -15,4 +15,6 @@ type ManagedClusterLabelsSpec struct {
 type ManagedClusterLabelsSpecBundle struct {
 	Objects     []*ManagedClusterLabelsSpec `json:"objects"`
 	LeafHubName string                      `json:"leafHubName"`
+	// Owners are the owners of the label keys, global, hub or shared. The keys not listed are shared.
+	Owners map[string]string `json:"owners,omitempty"`
 }
@@ -115,6 +115,19 @@ const (
 	ManagedClusterManagedByAnnotation = "global-hub.open-cluster-management.io/managed-by"
 	// identify the resource is from the global hub cluster
 	OriginOwnerReferenceAnnotation = "global-hub.open-cluster-management.io/origin-ownerreference-uid"
+	// the label selector of the managed hubs the global resource is delivered to, e.g. "compliance!=regulated"
+	HubSelectorAnnotation = "global-hub.open-cluster-management.io/hub-selector"
+	// the drift mode of the global resource on the managed hubs, enforce reverts the changes of the hub and report only
+	// reports them, it's report by default
+	DriftModeAnnotation = "global-hub.open-cluster-management.io/drift-mode"
+	// the global resource applied by the agent, the changes of the hub are detected against it
+	LastAppliedAnnotation = "global-hub.open-cluster-management.io/last-applied"
+	// the waves the new versions of the global resource are rolled out to the managed hubs in, e.g.
+	// {"waves":[{"name":"canary","hubSelector":"env=dev"}],"soakTime":"30m"}
+	RolloutStrategyAnnotation = "global-hub.open-cluster-management.io/rollout-strategy"
+	// the labels of the managed cluster applied by the agent from the global hub, e.g. {"env":"prod"}, the changes of
+	// the labels made on the hub are detected against it
+	LastAppliedLabelsAnnotation = "global-hub.open-cluster-management.io/last-applied-labels"
 	// identy the kafka is upgrade from zookeeper mode
 	UpgradeKafkaFromZookeeperAnnotation = "global-hub.open-cluster-management.io/upgrade-from-zookeeper"
 	// resync the kafka client secret in agent
@@ -158,6 +171,9 @@ const (
 
 	// GenericSpecMsgKey is the generic spec message key for the bundle
 	GenericSpecMsgKey = "Generic"
+
+	// SpecDryRunMsgKey is the message key of the bundle applied by the server-side dry run
+	SpecDryRunMsgKey = "SpecDryRun"
 )
 
 // event exporter reference object label keys
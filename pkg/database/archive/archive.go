@@ -0,0 +1,345 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+)
+
+const (
+	// FormatCSV is the postgres CSV format with the header, it can be loaded by the COPY FROM directly
+	FormatCSV         = "csv"
+	CompressionGzip   = "gzip"
+	dataSuffix        = ".csv.gz"
+	manifestSuffix    = ".manifest.json"
+	DefaultPrefix     = "data-retention"
+	errMsgNoSuchFile  = "no such file or directory"
+	manifestMediaType = "application/json"
+)
+
+var log = logger.ZapLogger("data-archive")
+
+// Config is the S3 compatible object storage to keep the expired partitions, e.g. AWS S3 or MinIO
+type Config struct {
+	Endpoint string
+	Bucket   string
+	Region   string
+	// Prefix is the parent "directory" of the archives in the bucket
+	Prefix string
+	// Insecure connects the endpoint with plain HTTP, it's only used for testing
+	Insecure bool
+	// AccessKeyID and SecretAccessKey are optional, the credentials are read from the AWS/MinIO environment variables
+	// or the IAM role if they are empty
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Enabled returns whether the expired partitions should be archived before they are dropped
+func (c *Config) Enabled() bool {
+	return c != nil && c.Endpoint != ""
+}
+
+// Column is a column of the archived partition
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Manifest describes an archived partition, it's uploaded after the data object, so the archive is complete once the
+// manifest exists.
+type Manifest struct {
+	Table       string    `json:"table"`
+	Partition   string    `json:"partition"`
+	Columns     []Column  `json:"columns"`
+	Format      string    `json:"format"`
+	Compression string    `json:"compression"`
+	Object      string    `json:"object"`
+	Rows        int64     `json:"rows"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	ArchivedAt  time.Time `json:"archivedAt"`
+	// Key is the object key of the manifest itself
+	Key string `json:"-"`
+}
+
+// Archiver exports the partitions of the database to the object storage, and loads them back for the audit.
+type Archiver struct {
+	client      *minio.Client
+	bucket      string
+	region      string
+	prefix      string
+	databaseURL string
+	caCertPath  string
+}
+
+// NewArchiver doesn't connect the object storage, so the unavailable storage only fails the archive instead of the
+// caller.
+func NewArchiver(config *Config, databaseURL, caCertPath string) (*Archiver, error) {
+	var creds *credentials.Credentials
+	if config.AccessKeyID != "" {
+		creds = credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, "")
+	} else {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.IAM{},
+		})
+	}
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !config.Insecure,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the object storage client: %w", err)
+	}
+
+	prefix := config.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &Archiver{
+		client:      client,
+		bucket:      config.Bucket,
+		region:      config.Region,
+		prefix:      strings.Trim(prefix, "/"),
+		databaseURL: databaseURL,
+		caCertPath:  caCertPath,
+	}, nil
+}
+
+// Archive exports the partition of the table as the gzip compressed CSV, then uploads the manifest of it. The
+// partition isn't changed, the caller should drop it after the archive succeeds.
+func (a *Archiver) Archive(ctx context.Context, table, partition string) (*Manifest, error) {
+	if err := a.ensureBucket(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	columns, err := partitionColumns(ctx, conn, partition)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, manifestKey := a.objectKeys(table, partition)
+
+	// stream the rows from the COPY to the object storage, the partition might be larger than the memory
+	reader, writer := io.Pipe()
+	rowsChan := make(chan int64, 1)
+	go func() {
+		rows, err := copyTo(ctx, conn, partition, writer)
+		rowsChan <- rows
+		_ = writer.CloseWithError(err)
+	}()
+	hash := sha256.New()
+	info, err := a.client.PutObject(ctx, a.bucket, dataKey, io.TeeReader(reader, hash), -1,
+		minio.PutObjectOptions{ContentType: "application/gzip"})
+	// unblock the COPY if the upload is interrupted
+	_ = reader.CloseWithError(err)
+	rows := <-rowsChan
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload the partition %s: %w", partition, err)
+	}
+
+	manifest := &Manifest{
+		Table:       table,
+		Partition:   partition,
+		Columns:     columns,
+		Format:      FormatCSV,
+		Compression: CompressionGzip,
+		Object:      dataKey,
+		Rows:        rows,
+		Size:        info.Size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		ArchivedAt:  time.Now().UTC(),
+		Key:         manifestKey,
+	}
+	payload, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = a.client.PutObject(ctx, a.bucket, manifestKey, bytes.NewReader(payload), int64(len(payload)),
+		minio.PutObjectOptions{ContentType: manifestMediaType}); err != nil {
+		return nil, fmt.Errorf("failed to upload the manifest of the partition %s: %w", partition, err)
+	}
+	log.Infow("archived the partition", "partition", partition, "rows", rows, "object", dataKey)
+	return manifest, nil
+}
+
+// Restore loads the archive of the manifest into a new table of the schema, the table is named as the partition
+// without the schema, e.g. <schema>.local_policies_2023_01. The table isn't attached to the partitioned table, so it
+// can be queried and dropped without touching the production data.
+func (a *Archiver) Restore(ctx context.Context, manifestKey, schema string) (string, error) {
+	manifest, err := a.getManifest(ctx, manifestKey)
+	if err != nil {
+		return "", err
+	}
+	if manifest.Format != FormatCSV || manifest.Compression != CompressionGzip {
+		return "", fmt.Errorf("unsupported archive format %s/%s", manifest.Format, manifest.Compression)
+	}
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(ctx)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, partitionName := splitTableName(manifest.Partition)
+	identifier := pgx.Identifier{schema, partitionName}.Sanitize()
+	schemaSQL := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pgx.Identifier{schema}.Sanitize())
+	if _, err = tx.Exec(ctx, schemaSQL); err != nil {
+		return "", fmt.Errorf("failed to create the schema %s: %w", schema, err)
+	}
+	if _, err = tx.Exec(ctx, createTableSQL(identifier, manifest.Columns)); err != nil {
+		return "", fmt.Errorf("failed to create the table %s: %w", identifier, err)
+	}
+
+	object, err := a.client.GetObject(ctx, a.bucket, manifest.Object, minio.GetObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the archive %s: %w", manifest.Object, err)
+	}
+	defer object.Close()
+	hash := sha256.New()
+	gzipReader, err := gzip.NewReader(io.TeeReader(object, hash))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the archive %s: %w", manifest.Object, err)
+	}
+	tag, err := conn.PgConn().CopyFrom(ctx, gzipReader,
+		fmt.Sprintf("COPY %s FROM STDIN WITH (FORMAT csv, HEADER true)", identifier))
+	if err != nil {
+		return "", fmt.Errorf("failed to load the archive %s: %w", manifest.Object, err)
+	}
+	// drain the reader to verify the checksum of the whole object
+	if _, err = io.Copy(io.Discard, object); err != nil {
+		return "", fmt.Errorf("failed to read the archive %s: %w", manifest.Object, err)
+	}
+	if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != manifest.SHA256 {
+		return "", fmt.Errorf("the checksum of the archive %s is %s, expected %s", manifest.Object, checksum,
+			manifest.SHA256)
+	}
+	if tag.RowsAffected() != manifest.Rows {
+		return "", fmt.Errorf("loaded %d rows from the archive %s, expected %d", tag.RowsAffected(), manifest.Object,
+			manifest.Rows)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	log.Infow("restored the archive", "manifest", manifestKey, "table", identifier, "rows", manifest.Rows)
+	return identifier, nil
+}
+
+func (a *Archiver) ensureBucket(ctx context.Context) error {
+	exists, err := a.client.BucketExists(ctx, a.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check the bucket %s: %w", a.bucket, err)
+	}
+	if exists {
+		return nil
+	}
+	if err := a.client.MakeBucket(ctx, a.bucket, minio.MakeBucketOptions{Region: a.region}); err != nil {
+		return fmt.Errorf("failed to create the bucket %s: %w", a.bucket, err)
+	}
+	log.Infow("created the archive bucket", "bucket", a.bucket)
+	return nil
+}
+
+func (a *Archiver) getManifest(ctx context.Context, manifestKey string) (*Manifest, error) {
+	object, err := a.client.GetObject(ctx, a.bucket, manifestKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the manifest %s: %w", manifestKey, err)
+	}
+	defer object.Close()
+	manifest := &Manifest{Key: manifestKey}
+	if err := json.NewDecoder(object).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode the manifest %s: %w", manifestKey, err)
+	}
+	return manifest, nil
+}
+
+// objectKeys returns the keys of the data and the manifest, e.g.
+// <prefix>/event.local_policies/event.local_policies_2023_01.csv.gz
+func (a *Archiver) objectKeys(table, partition string) (string, string) {
+	return path.Join(a.prefix, table, partition+dataSuffix), path.Join(a.prefix, table, partition+manifestSuffix)
+}
+
+func (a *Archiver) connect(ctx context.Context) (*pgx.Conn, error) {
+	cert, err := os.ReadFile(a.caCertPath) // #nosec G304
+	if err != nil && !strings.Contains(err.Error(), errMsgNoSuchFile) {
+		return nil, fmt.Errorf("failed to read the database cert file: %w", err)
+	}
+	return database.PostgresConnection(ctx, a.databaseURL, cert)
+}
+
+func copyTo(ctx context.Context, conn *pgx.Conn, partition string, writer io.Writer) (int64, error) {
+	gzipWriter := gzip.NewWriter(writer)
+	schema, name := splitTableName(partition)
+	tag, err := conn.PgConn().CopyTo(ctx, gzipWriter, fmt.Sprintf(
+		"COPY (SELECT * FROM %s) TO STDOUT WITH (FORMAT csv, HEADER true)", pgx.Identifier{schema, name}.Sanitize()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to export the partition %s: %w", partition, err)
+	}
+	return tag.RowsAffected(), gzipWriter.Close()
+}
+
+// partitionColumns returns the columns with the types, it's used to create the table when restoring the archive
+func partitionColumns(ctx context.Context, conn *pgx.Conn, partition string) ([]Column, error) {
+	rows, err := conn.Query(ctx, `SELECT attname, format_type(atttypid, atttypmod) FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped ORDER BY attnum`, partition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the columns of %s: %w", partition, err)
+	}
+	defer rows.Close()
+	columns := []Column{}
+	for rows.Next() {
+		column := Column{}
+		if err := rows.Scan(&column.Name, &column.Type); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+func createTableSQL(identifier string, columns []Column) string {
+	definitions := make([]string, 0, len(columns))
+	for _, column := range columns {
+		definitions = append(definitions, fmt.Sprintf("%s %s", pgx.Identifier{column.Name}.Sanitize(), column.Type))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s)", identifier, strings.Join(definitions, ", "))
+}
+
+// splitTableName splits the schema and the name, the schema is public if the table isn't schema-qualified
+func splitTableName(table string) (string, string) {
+	if schema, name, found := strings.Cut(table, "."); found {
+		return schema, name
+	}
+	return "public", table
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package archive
+
+import (
+	"testing"
+)
+
+func TestObjectKeys(t *testing.T) {
+	archiver, err := NewArchiver(&Config{Endpoint: "localhost:9000", Bucket: "test", Prefix: "/audit/"}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, manifestKey := archiver.objectKeys("event.local_policies", "event.local_policies_2023_01")
+	if dataKey != "audit/event.local_policies/event.local_policies_2023_01.csv.gz" {
+		t.Errorf("unexpected data key: %s", dataKey)
+	}
+	if manifestKey != "audit/event.local_policies/event.local_policies_2023_01.manifest.json" {
+		t.Errorf("unexpected manifest key: %s", manifestKey)
+	}
+
+	archiver, err = NewArchiver(&Config{Endpoint: "localhost:9000", Bucket: "test"}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, _ = archiver.objectKeys("history.compliance", "history.compliance_2023_01")
+	if dataKey != "data-retention/history.compliance/history.compliance_2023_01.csv.gz" {
+		t.Errorf("unexpected data key with the default prefix: %s", dataKey)
+	}
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	sql := createTableSQL(`"archive_restore"."compliance_2023_01"`, []Column{
+		{Name: "policy_id", Type: "uuid"},
+		{Name: "compliance", Type: "status.compliance_type"},
+		{Name: "compliance_date", Type: "date"},
+	})
+	expected := `CREATE TABLE "archive_restore"."compliance_2023_01" ("policy_id" uuid, ` +
+		`"compliance" status.compliance_type, "compliance_date" date)`
+	if sql != expected {
+		t.Errorf("expected %s, but got %s", expected, sql)
+	}
+}
+
+func TestSplitTableName(t *testing.T) {
+	cases := []struct {
+		table  string
+		schema string
+		name   string
+	}{
+		{"event.local_policies_2023_01", "event", "local_policies_2023_01"},
+		{"local_policies", "public", "local_policies"},
+	}
+	for _, c := range cases {
+		schema, name := splitTableName(c.table)
+		if schema != c.schema || name != c.name {
+			t.Errorf("%s: expected %s.%s, but got %s.%s", c.table, c.schema, c.name, schema, name)
+		}
+	}
+}
+
+func TestConfigEnabled(t *testing.T) {
+	var config *Config
+	if config.Enabled() {
+		t.Error("the nil config shouldn't be enabled")
+	}
+	if (&Config{Bucket: "test"}).Enabled() {
+		t.Error("the config without the endpoint shouldn't be enabled")
+	}
+	if !(&Config{Endpoint: "localhost:9000"}).Enabled() {
+		t.Error("the config with the endpoint should be enabled")
+	}
+}
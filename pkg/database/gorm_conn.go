@@ -32,6 +32,8 @@ var (
 	log      = logger.ZapLogger("database-controller")
 	lockConn *sql.Conn
 	ctx      = context.Background()
+	// the url of the database connection, which is used by the listeners of the notifications
+	connURL string
 )
 
 type DatabaseConfig struct {
@@ -55,6 +57,11 @@ func InitGormInstance(config *DatabaseConfig) error {
 			return err
 		}
 		sqlDB.SetMaxOpenConns(config.PoolSize)
+		urlObj, err := completePostgres(config.URL, config.CaCertPath)
+		if err != nil {
+			return err
+		}
+		connURL = urlObj.String()
 	}
 	return nil
 }
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NewListener returns the listener of the notifications by a dedicated connection out of the pool, the connection is
+// reestablished with the interval between the min and max reconnect intervals if it's lost.
+func NewListener(minReconnectInterval, maxReconnectInterval time.Duration,
+	eventCallback pq.EventCallbackType,
+) (*pq.Listener, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if connURL == "" {
+		return nil, fmt.Errorf("database connection is not initialized")
+	}
+	return pq.NewListener(connURL, minReconnectInterval, maxReconnectInterval, eventCallback), nil
+}
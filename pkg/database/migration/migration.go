@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package migration
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	iofs "io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed sql
+var migrationFS embed.FS
+
+const migrationDir = "sql"
+
+// the migration file is named as <version>_<description>.up.sql, e.g. 0002_add_compliance_history.up.sql
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.up\.sql$`)
+
+// Migration is an ordered up-migration of the global hub database schema.
+type Migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+// Migrations returns the migrations embedded in the binary, ordered by the version.
+func Migrations() ([]Migration, error) {
+	return Load(migrationFS, migrationDir)
+}
+
+// LatestVersion returns the newest schema version known by the binary.
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Load reads the migrations from the dir of the file system, ordered by the version.
+func Load(fsys iofs.FS, dir string) ([]Migration, error) {
+	entries, err := iofs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the migrations: %w", err)
+	}
+	migrations := []Migration{}
+	versions := map[int]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version of %s: %w", entry.Name(), err)
+		}
+		if version <= 0 {
+			return nil, fmt.Errorf("the migration version must be positive: %s", entry.Name())
+		}
+		if existing, found := versions[version]; found {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, existing, entry.Name())
+		}
+		versions[version] = entry.Name()
+
+		sqlBytes, err := iofs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		sum := sha256.Sum256(sqlBytes)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     matches[2],
+			SQL:      string(sqlBytes),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
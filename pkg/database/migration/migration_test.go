@@ -0,0 +1,65 @@
+package migration
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_add_table.up.sql": {Data: []byte("CREATE TABLE foo();")},
+		"sql/0010_add_index.up.sql": {Data: []byte("CREATE INDEX bar ON foo();")},
+		"sql/0001_baseline.up.sql":  {Data: []byte("SELECT 1;")},
+	}
+	migrations, err := Load(fsys, "sql")
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	assert.Equal(t, []int{1, 2, 10},
+		[]int{migrations[0].Version, migrations[1].Version, migrations[2].Version})
+	assert.Equal(t, "add_table", migrations[1].Name)
+	assert.NotEmpty(t, migrations[0].Checksum)
+	assert.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+
+	_, err = Load(fstest.MapFS{
+		"sql/0001_baseline.up.sql": {Data: []byte("SELECT 1;")},
+		"sql/1_duplicate.up.sql":   {Data: []byte("SELECT 2;")},
+	}, "sql")
+	assert.ErrorContains(t, err, "duplicate migration version 1")
+
+	_, err = Load(fstest.MapFS{"sql/baseline.sql": {Data: []byte("SELECT 1;")}}, "sql")
+	assert.ErrorContains(t, err, "invalid migration file name")
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].Version)
+}
+
+func TestPending(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "baseline", Checksum: "a"},
+		{Version: 2, Name: "add_table", Checksum: "b"},
+	}
+	migrator := NewMigratorWithMigrations(nil, migrations)
+
+	pending, err := migrator.pending(nil)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	pending, err = migrator.pending([]AppliedMigration{{Version: 1, Name: "baseline", Checksum: "a"}})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 2, pending[0].Version)
+
+	_, err = migrator.pending([]AppliedMigration{{Version: 1, Name: "baseline", Checksum: "changed"}})
+	assert.ErrorContains(t, err, "checksum")
+
+	_, err = migrator.pending([]AppliedMigration{{Version: 3, Name: "from_newer_release", Checksum: "c"}})
+	assert.True(t, errors.Is(err, ErrNewerSchema))
+}
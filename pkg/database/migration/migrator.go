@@ -0,0 +1,207 @@
+// Copyright (c) 2024 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/logger"
+)
+
+const (
+	// MigrationTable records the applied migrations of the database
+	MigrationTable = "public.schema_migrations"
+	// the advisory lock is different from the constants.LockId, which is held by the backup and offset committer
+	migrationLockId = 20240101
+)
+
+// ErrNewerSchema indicates the database has been migrated by a newer version of the global hub.
+var ErrNewerSchema = errors.New("the database schema is newer than the known migrations")
+
+// AppliedMigration is the record of the migration table
+type AppliedMigration struct {
+	Version  int
+	Name     string
+	Checksum string
+}
+
+// Migrator applies the embedded migrations to the database in order, each migration is applied in a transaction and
+// recorded with its checksum, so the modified migration can be detected before it's applied again.
+type Migrator struct {
+	log        *zap.SugaredLogger
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return NewMigratorWithMigrations(db, migrations), nil
+}
+
+func NewMigratorWithMigrations(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		log:        logger.ZapLogger("schema-migrator"),
+		db:         db,
+		migrations: migrations,
+	}
+}
+
+// Applied returns the migrations recorded in the migration table, it's empty if the table doesn't exist.
+func (m *Migrator) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	return applied(ctx, m.db)
+}
+
+// Pending returns the migrations haven't been applied to the database. It returns error if the applied migration is
+// modified or the database has been migrated by a newer version.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	appliedMigrations, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.pending(appliedMigrations)
+}
+
+// CheckCompatible returns ErrNewerSchema if the database contains the migrations unknown by the binary.
+func (m *Migrator) CheckCompatible(ctx context.Context) error {
+	_, err := m.Pending(ctx)
+	return err
+}
+
+// Up applies the pending migrations, the advisory lock makes sure only one process migrates the database at a time.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the database connection: %w", err)
+	}
+	defer func() {
+		if e := conn.Close(); e != nil {
+			m.log.Warnw("failed to close the migration connection", "error", e)
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockId); err != nil {
+		return nil, fmt.Errorf("failed to lock the database for migration: %w", err)
+	}
+	defer func() {
+		if _, e := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockId); e != nil {
+			m.log.Warnw("failed to unlock the database after migration", "error", e)
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version integer PRIMARY KEY,
+		name text NOT NULL,
+		checksum text NOT NULL,
+		applied_at timestamp without time zone DEFAULT now() NOT NULL
+	)`, MigrationTable)); err != nil {
+		return nil, fmt.Errorf("failed to create the migration table: %w", err)
+	}
+
+	// read the applied migrations after holding the lock, the other process might have migrated the database
+	appliedMigrations, err := applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	pendingMigrations, err := m.pending(appliedMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range pendingMigrations {
+		if err := m.apply(ctx, conn, migration); err != nil {
+			return nil, err
+		}
+		m.log.Infow("applied the migration", "version", migration.Version, "name", migration.Name)
+	}
+	return pendingMigrations, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin the migration %d: %w", migration.Version, err)
+	}
+	if _, err = tx.ExecContext(ctx, migration.SQL); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to apply the migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err = tx.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (version, name, checksum) VALUES ($1, $2, $3)", MigrationTable),
+		migration.Version, migration.Name, migration.Checksum); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to record the migration %d: %w", migration.Version, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit the migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+func (m *Migrator) pending(appliedMigrations []AppliedMigration) ([]Migration, error) {
+	known := map[int]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+	applied := map[int]bool{}
+	for _, appliedMigration := range appliedMigrations {
+		migration, found := known[appliedMigration.Version]
+		if !found {
+			return nil, fmt.Errorf("%w: the migration %d_%s isn't known", ErrNewerSchema, appliedMigration.Version,
+				appliedMigration.Name)
+		}
+		if migration.Checksum != appliedMigration.Checksum {
+			return nil, fmt.Errorf("the checksum of the applied migration %d_%s is changed", migration.Version,
+				migration.Name)
+		}
+		applied[appliedMigration.Version] = true
+	}
+
+	pendingMigrations := []Migration{}
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pendingMigrations = append(pendingMigrations, migration)
+		}
+	}
+	return pendingMigrations, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func applied(ctx context.Context, q queryer) ([]AppliedMigration, error) {
+	var table sql.NullString
+	if err := q.QueryRowContext(ctx, "SELECT to_regclass($1)::text", MigrationTable).Scan(&table); err != nil {
+		return nil, fmt.Errorf("failed to check the migration table: %w", err)
+	}
+	if !table.Valid {
+		return []AppliedMigration{}, nil
+	}
+
+	rows, err := q.QueryContext(ctx,
+		fmt.Sprintf("SELECT version, name, checksum FROM %s ORDER BY version", MigrationTable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	appliedMigrations := []AppliedMigration{}
+	for rows.Next() {
+		migration := AppliedMigration{}
+		if err := rows.Scan(&migration.Version, &migration.Name, &migration.Checksum); err != nil {
+			return nil, err
+		}
+		appliedMigrations = append(appliedMigrations, migration)
+	}
+	return appliedMigrations, rows.Err()
+}
@@ -0,0 +1,48 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/migration"
+	"github.com/stolostron/multicluster-global-hub/test/integration/utils/testpostgres"
+)
+
+// the migrations must be applied on the default installation, which doesn't create the global resource tables
+func TestMigrationsWithoutGlobalResource(t *testing.T) {
+	testPostgres, err := testpostgres.NewTestPostgres()
+	require.NoError(t, err)
+	defer func() {
+		_ = testPostgres.Stop()
+	}()
+
+	require.NoError(t, testpostgres.InitDatabaseWithoutGlobalResource(testPostgres.URI))
+
+	migrator, err := migration.NewMigrator(database.GetSqlDb())
+	require.NoError(t, err)
+	pending, err := migrator.Pending(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	for _, table := range []string{
+		"spec.managed_clusters_label_jobs",
+		"spec.rollouts",
+		"spec.dry_runs",
+		"status.spec_dry_run_results",
+		"spec.hub_schedules",
+		"spec.managed_cluster_label_owners",
+		"event.managed_cluster_label_conflicts",
+	} {
+		var name sql.NullString
+		require.NoError(t, database.GetSqlDb().QueryRow("SELECT to_regclass($1)::text", table).Scan(&name))
+		assert.True(t, name.Valid, "the table %s doesn't exist", table)
+	}
+	var globalTable sql.NullString
+	require.NoError(t, database.GetSqlDb().QueryRow("SELECT to_regclass('spec.policies')::text").Scan(&globalTable))
+	assert.False(t, globalTable.Valid, "the global resource tables shouldn't be created")
+}
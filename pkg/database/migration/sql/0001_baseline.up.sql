@@ -0,0 +1,4 @@
+-- The baseline schema is created by the idempotent database/*.sql of the operator, this migration only marks the
+-- database is managed by the versioned migrations. The schema changes after the baseline must be added as a new
+-- migration, e.g. 0002_<description>.up.sql, and the applied migrations must never be modified.
+SELECT 1;
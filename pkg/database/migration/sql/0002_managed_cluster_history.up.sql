@@ -0,0 +1,89 @@
+-- record the transitions of the managed clusters: availability, joined, accepted, openshift version, labels and the
+-- owning hub. The records are partitioned by month and dropped by the data retention job.
+CREATE TABLE IF NOT EXISTS history.managed_clusters (
+    cluster_id uuid NOT NULL,
+    cluster_name character varying(254),
+    leaf_hub_name character varying(254) NOT NULL,
+    available character varying(16),
+    joined character varying(16),
+    accepted character varying(16),
+    openshift_version character varying(64),
+    labels jsonb,
+    changes text[] NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
+) PARTITION BY RANGE (created_at);
+CREATE INDEX IF NOT EXISTS managed_clusters_history_cluster_idx ON history.managed_clusters (cluster_id, created_at);
+
+SELECT create_monthly_range_partitioned_table('history.managed_clusters', to_char(current_date, 'YYYY-MM-DD'));
+SELECT create_monthly_range_partitioned_table('history.managed_clusters', to_char(current_date + interval '1 month', 'YYYY-MM-DD'));
+
+-- the status of the condition in the managed cluster payload, e.g. 'True', 'False' or 'Unknown'
+CREATE OR REPLACE FUNCTION history.managed_cluster_condition(payload jsonb, condition_type text)
+RETURNS text AS $$
+    SELECT c ->> 'status'
+    FROM jsonb_array_elements(
+        CASE WHEN jsonb_typeof(payload -> 'status' -> 'conditions') = 'array'
+            THEN payload -> 'status' -> 'conditions'
+            ELSE '[]'::jsonb
+        END) c
+    WHERE c ->> 'type' = condition_type
+    LIMIT 1
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION history.record_managed_cluster_transition()
+RETURNS TRIGGER AS $$
+DECLARE
+    transitions text[] := '{}';
+    new_available text := history.managed_cluster_condition(NEW.payload, 'ManagedClusterConditionAvailable');
+    new_joined text := history.managed_cluster_condition(NEW.payload, 'ManagedClusterJoined');
+    new_accepted text := history.managed_cluster_condition(NEW.payload, 'HubAcceptedManagedCluster');
+    new_version text := NEW.payload -> 'metadata' -> 'labels' ->> 'openshiftVersion';
+    new_labels jsonb := COALESCE(NEW.payload -> 'metadata' -> 'labels', '{}'::jsonb);
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        transitions := ARRAY['created'];
+    ELSE
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            transitions := array_append(transitions, 'deleted');
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            transitions := array_append(transitions, 'created');
+        END IF;
+        IF OLD.leaf_hub_name IS DISTINCT FROM NEW.leaf_hub_name THEN
+            transitions := array_append(transitions, 'leaf_hub_name');
+        END IF;
+        IF history.managed_cluster_condition(OLD.payload, 'ManagedClusterConditionAvailable') IS DISTINCT FROM new_available THEN
+            transitions := array_append(transitions, 'available');
+        END IF;
+        IF history.managed_cluster_condition(OLD.payload, 'ManagedClusterJoined') IS DISTINCT FROM new_joined THEN
+            transitions := array_append(transitions, 'joined');
+        END IF;
+        IF history.managed_cluster_condition(OLD.payload, 'HubAcceptedManagedCluster') IS DISTINCT FROM new_accepted THEN
+            transitions := array_append(transitions, 'accepted');
+        END IF;
+        IF (OLD.payload -> 'metadata' -> 'labels' ->> 'openshiftVersion') IS DISTINCT FROM new_version THEN
+            transitions := array_append(transitions, 'openshift_version');
+        END IF;
+        IF COALESCE(OLD.payload -> 'metadata' -> 'labels', '{}'::jsonb) IS DISTINCT FROM new_labels THEN
+            transitions := array_append(transitions, 'labels');
+        END IF;
+        IF cardinality(transitions) = 0 THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+
+    -- the failure of the history, e.g. the partition is missing, mustn't block updating the managed cluster
+    BEGIN
+        INSERT INTO history.managed_clusters (cluster_id, cluster_name, leaf_hub_name, available, joined, accepted,
+            openshift_version, labels, changes)
+        VALUES (NEW.cluster_id, NEW.payload -> 'metadata' ->> 'name', NEW.leaf_hub_name, new_available, new_joined,
+            new_accepted, new_version, new_labels, transitions);
+    EXCEPTION WHEN OTHERS THEN
+        RAISE WARNING 'failed to record the transition of the managed cluster %: %', NEW.cluster_id, SQLERRM;
+    END;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_record_managed_cluster_transition ON status.managed_clusters;
+CREATE TRIGGER trg_record_managed_cluster_transition AFTER INSERT OR UPDATE ON status.managed_clusters
+FOR EACH ROW EXECUTE FUNCTION history.record_managed_cluster_transition();
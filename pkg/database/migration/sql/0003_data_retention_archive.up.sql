@@ -0,0 +1,4 @@
+-- the expired partitions are archived to the object storage before they are dropped by the data retention job, the
+-- manifest is the object key of the archive, it's the input of the restore.
+ALTER TABLE event.data_retention_job_log ADD COLUMN IF NOT EXISTS archive_manifest varchar(1024);
+ALTER TABLE event.data_retention_job_log ADD COLUMN IF NOT EXISTS archived_rows bigint;
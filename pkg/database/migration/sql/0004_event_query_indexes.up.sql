@@ -0,0 +1,6 @@
+-- the events are queried by the REST API in the order of the created time, and filtered by the managed cluster
+CREATE INDEX IF NOT EXISTS managed_clusters_event_created_at_idx ON event.managed_clusters (created_at);
+CREATE INDEX IF NOT EXISTS managed_clusters_event_cluster_idx ON event.managed_clusters (cluster_name, created_at);
+CREATE INDEX IF NOT EXISTS local_policies_event_created_at_idx ON event.local_policies (created_at);
+CREATE INDEX IF NOT EXISTS local_policies_event_cluster_idx ON event.local_policies (cluster_name, created_at);
+CREATE INDEX IF NOT EXISTS local_root_policies_event_created_at_idx ON event.local_root_policies (created_at);
@@ -0,0 +1,16 @@
+-- the events are inserted in the order they're received from the hubs, not the order of the created time. The watch of
+-- the REST API streams the events by the inserted time, so the late events of a slower hub aren't skipped. The default
+-- is set after adding the column, so the existing partitions aren't rewritten and their inserted_at stays NULL.
+ALTER TABLE event.managed_clusters ADD COLUMN IF NOT EXISTS inserted_at timestamp without time zone;
+ALTER TABLE event.managed_clusters ALTER COLUMN inserted_at SET DEFAULT now();
+ALTER TABLE event.local_policies ADD COLUMN IF NOT EXISTS inserted_at timestamp without time zone;
+ALTER TABLE event.local_policies ALTER COLUMN inserted_at SET DEFAULT now();
+ALTER TABLE event.local_root_policies ADD COLUMN IF NOT EXISTS inserted_at timestamp without time zone;
+ALTER TABLE event.local_root_policies ALTER COLUMN inserted_at SET DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS managed_clusters_event_ingested_at_idx
+    ON event.managed_clusters ((coalesce(inserted_at, created_at)));
+CREATE INDEX IF NOT EXISTS local_policies_event_ingested_at_idx
+    ON event.local_policies ((coalesce(inserted_at, created_at)));
+CREATE INDEX IF NOT EXISTS local_root_policies_event_ingested_at_idx
+    ON event.local_root_policies ((coalesce(inserted_at, created_at)));
@@ -0,0 +1,73 @@
+-- record the daily compliance of the global policies on the managed clusters. The compliance of a day is the worst one
+-- of the day: pending > unknown > non_compliant > compliant, the same as the history.local_compliance. The records are
+-- partitioned by month and dropped by the data retention job.
+CREATE TABLE IF NOT EXISTS history.compliance (
+    policy_id uuid NOT NULL,
+    cluster_name character varying(254) NOT NULL,
+    leaf_hub_name character varying(254) NOT NULL,
+    cluster_id uuid,
+    compliance_date DATE DEFAULT CURRENT_DATE NOT NULL,
+    compliance status.compliance_type NOT NULL,
+    compliance_changed_frequency integer NOT NULL DEFAULT 0,
+    CONSTRAINT compliance_history_unique_constraint UNIQUE (leaf_hub_name, policy_id, cluster_name, compliance_date)
+) PARTITION BY RANGE (compliance_date);
+CREATE INDEX IF NOT EXISTS compliance_history_policy_date_idx ON history.compliance (policy_id, compliance_date);
+
+SELECT create_monthly_range_partitioned_table('history.compliance', to_char(current_date, 'YYYY-MM-DD'));
+SELECT create_monthly_range_partitioned_table('history.compliance', to_char(current_date + interval '1 month', 'YYYY-MM-DD'));
+
+CREATE OR REPLACE FUNCTION history.update_history_compliance_by_status()
+RETURNS TRIGGER AS $$
+BEGIN
+    -- skip the updates which neither change the compliance nor set the cluster_id
+    IF TG_OP = 'UPDATE' AND OLD.compliance = NEW.compliance AND OLD.cluster_id IS NOT DISTINCT FROM NEW.cluster_id THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO history.compliance (
+        policy_id,
+        cluster_name,
+        leaf_hub_name,
+        cluster_id,
+        compliance,
+        compliance_date,
+        compliance_changed_frequency
+    ) VALUES (
+        NEW.policy_id,
+        NEW.cluster_name,
+        NEW.leaf_hub_name,
+        NEW.cluster_id,
+        NEW.compliance,
+        CURRENT_DATE,
+        0
+    ) ON CONFLICT (leaf_hub_name, policy_id, cluster_name, compliance_date)
+    DO UPDATE SET
+        cluster_id = COALESCE(EXCLUDED.cluster_id, history.compliance.cluster_id),
+        compliance =
+            CASE
+                WHEN history.compliance.compliance = 'pending' OR EXCLUDED.compliance = 'pending' THEN 'pending'::status.compliance_type
+                WHEN history.compliance.compliance = 'unknown' OR EXCLUDED.compliance = 'unknown' THEN 'unknown'::status.compliance_type
+                WHEN history.compliance.compliance = 'non_compliant' OR EXCLUDED.compliance = 'non_compliant' THEN 'non_compliant'::status.compliance_type
+                ELSE 'compliant'::status.compliance_type
+            END,
+        compliance_changed_frequency =
+            CASE
+                WHEN history.compliance.compliance <> EXCLUDED.compliance THEN history.compliance.compliance_changed_frequency + 1
+                ELSE history.compliance.compliance_changed_frequency
+            END;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- the status.compliance of the global policies is created only when the global resources are enabled, which are
+-- applied before the migrations
+DO $$
+BEGIN
+    IF to_regclass('status.compliance') IS NOT NULL THEN
+        DROP TRIGGER IF EXISTS trg_update_history_compliance_by_status ON status.compliance;
+        CREATE TRIGGER trg_update_history_compliance_by_status AFTER INSERT OR UPDATE ON status.compliance
+        FOR EACH ROW EXECUTE FUNCTION history.update_history_compliance_by_status();
+    END IF;
+END;
+$$;
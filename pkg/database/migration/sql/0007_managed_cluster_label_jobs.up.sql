@@ -0,0 +1,9 @@
+-- the bulk label patches of the managed clusters. The clusters are the ones selected when the job is created, the
+-- state of each cluster is compared with the status.managed_clusters when the job is queried.
+CREATE TABLE IF NOT EXISTS spec.managed_clusters_label_jobs (
+    id uuid PRIMARY KEY,
+    label_selector text,
+    patches jsonb NOT NULL,
+    clusters jsonb NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
+);
@@ -0,0 +1,24 @@
+CREATE SCHEMA IF NOT EXISTS audit;
+
+-- the audit events of the REST API mutations and the changes of the global resources. The username is the authenticated
+-- user of the REST API or the user-identity annotation of the global resource, and each of the objects carries its
+-- state before and after the change. The events are partitioned by month and dropped by the data retention job.
+CREATE TABLE IF NOT EXISTS audit.events (
+    id uuid NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    source character varying(63) NOT NULL,
+    username text NOT NULL,
+    groups jsonb,
+    action character varying(63) NOT NULL,
+    resource character varying(254) NOT NULL,
+    request jsonb,
+    objects jsonb NOT NULL,
+    result character varying(63) NOT NULL,
+    message text,
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit.events (created_at);
+CREATE INDEX IF NOT EXISTS audit_events_username_idx ON audit.events (username, created_at);
+
+SELECT create_monthly_range_partitioned_table('audit.events', to_char(current_date, 'YYYY-MM-DD'));
+SELECT create_monthly_range_partitioned_table('audit.events', to_char(current_date + interval '1 month', 'YYYY-MM-DD'));
@@ -0,0 +1,19 @@
+-- the latest results of applying the global resources on the managed hubs, each hub reports all of its results at
+-- once, so the rows of the hub are replaced by the reported ones.
+CREATE TABLE IF NOT EXISTS status.spec_apply_results (
+    leaf_hub_name character varying(254) NOT NULL,
+    api_version text NOT NULL,
+    kind text NOT NULL,
+    namespace text NOT NULL DEFAULT '',
+    name text NOT NULL,
+    uid text NOT NULL DEFAULT '',
+    result text NOT NULL,
+    error text NOT NULL DEFAULT '',
+    resource_version text NOT NULL DEFAULT '',
+    deleted boolean NOT NULL DEFAULT false,
+    updated_at timestamp without time zone NOT NULL,
+    PRIMARY KEY (leaf_hub_name, kind, namespace, name)
+);
+
+CREATE INDEX IF NOT EXISTS spec_apply_results_uid_idx ON status.spec_apply_results (uid);
+CREATE INDEX IF NOT EXISTS spec_apply_results_result_idx ON status.spec_apply_results (result);
@@ -0,0 +1,24 @@
+-- the changes of the global resources made on the managed hubs. The drift_type is modified or deleted, the mode is the
+-- drift mode annotation of the global resource, and the action is reverted, reported or failed. The uid is the global
+-- resource, and the fields are the changed top level fields of the object. The events are partitioned by month and
+-- dropped by the data retention job.
+CREATE TABLE IF NOT EXISTS event.spec_drifts (
+    leaf_hub_name character varying(254) NOT NULL,
+    uid text DEFAULT '' NOT NULL,
+    api_version text NOT NULL,
+    kind character varying(254) NOT NULL,
+    namespace text DEFAULT '' NOT NULL,
+    name text NOT NULL,
+    drift_type character varying(63) NOT NULL,
+    mode character varying(63) NOT NULL,
+    action character varying(63) NOT NULL,
+    fields jsonb,
+    message text,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    CONSTRAINT spec_drifts_unique_constraint UNIQUE (leaf_hub_name, kind, namespace, name, created_at)
+) PARTITION BY RANGE (created_at);
+CREATE INDEX IF NOT EXISTS spec_drifts_created_at_idx ON event.spec_drifts (created_at);
+CREATE INDEX IF NOT EXISTS spec_drifts_uid_idx ON event.spec_drifts (uid, created_at);
+
+SELECT create_monthly_range_partitioned_table('event.spec_drifts', to_char(current_date, 'YYYY-MM-DD'));
+SELECT create_monthly_range_partitioned_table('event.spec_drifts', to_char(current_date + interval '1 month', 'YYYY-MM-DD'));
@@ -0,0 +1,19 @@
+-- the rollouts of the global resources with the rollout strategy annotation. The payload is the version being rolled
+-- out and the previous_payload is the last completed version, which is kept on the hubs of the waves not released yet.
+-- The state is progressing, paused, aborted or completed, and the wave is the index of the latest released wave.
+CREATE TABLE IF NOT EXISTS spec.rollouts (
+    uid text PRIMARY KEY,
+    kind text NOT NULL,
+    namespace text NOT NULL DEFAULT '',
+    name text NOT NULL,
+    digest text NOT NULL,
+    payload jsonb NOT NULL,
+    previous_payload jsonb,
+    state character varying(63) NOT NULL,
+    wave integer NOT NULL DEFAULT 0,
+    wave_started_at timestamp without time zone NOT NULL,
+    message text NOT NULL DEFAULT '',
+    updated_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rollouts_state_idx ON spec.rollouts (state);
@@ -0,0 +1,30 @@
+-- the dry runs of the global resources requested by the REST API. The objects are sent to the hubs they target, the
+-- hubs limit the targets if it isn't null, and the target_hubs are the hubs the objects are sent to. The dry run is
+-- sent by the spec syncer once, and the sent_at is null until then.
+CREATE TABLE IF NOT EXISTS spec.dry_runs (
+    id uuid PRIMARY KEY,
+    objects jsonb NOT NULL,
+    hubs jsonb,
+    target_hubs jsonb,
+    created_by text NOT NULL DEFAULT '',
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    sent_at timestamp without time zone
+);
+
+CREATE INDEX IF NOT EXISTS dry_runs_unsent_idx ON spec.dry_runs (created_at) WHERE sent_at IS NULL;
+
+-- the results of the dry runs reported by the hubs, the result is would-create, would-update, unchanged or would-fail,
+-- and the diff is the changed fields of the updated object
+CREATE TABLE IF NOT EXISTS status.spec_dry_run_results (
+    dry_run_id uuid NOT NULL,
+    leaf_hub_name character varying(254) NOT NULL,
+    api_version text NOT NULL,
+    kind text NOT NULL,
+    namespace text NOT NULL DEFAULT '',
+    name text NOT NULL,
+    result text NOT NULL,
+    diff jsonb,
+    message text NOT NULL DEFAULT '',
+    reported_at timestamp without time zone NOT NULL,
+    PRIMARY KEY (dry_run_id, leaf_hub_name, kind, namespace, name)
+);
@@ -0,0 +1,4 @@
+-- the spec tables notify the spec_changes channel by the public.notify_spec_change(), the triggers of the global
+-- resource tables are created with them by the database.old, since they don't exist on the default installation
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.dry_runs;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.dry_runs FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
@@ -0,0 +1,20 @@
+-- the maintenance windows and the change freezes of the hubs. Each of them starts by the cron expression in the
+-- timezone and lasts for the duration_seconds, the spec changes are only sent to the hub while one of its windows is
+-- open, if it has any, and none of its freezes is active.
+CREATE TABLE IF NOT EXISTS spec.hub_schedules (
+    leaf_hub_name character varying(254) NOT NULL,
+    name text NOT NULL,
+    type text NOT NULL,
+    cron text NOT NULL,
+    duration_seconds bigint NOT NULL,
+    timezone text NOT NULL DEFAULT 'UTC',
+    updated_by text NOT NULL DEFAULT '',
+    updated_at timestamp without time zone DEFAULT now() NOT NULL,
+    PRIMARY KEY (leaf_hub_name, name),
+    CONSTRAINT hub_schedules_type_check CHECK (type IN ('window', 'freeze')),
+    CONSTRAINT hub_schedules_duration_check CHECK (duration_seconds > 0)
+);
+
+-- the spec syncers are woken to send or defer the changes once the schedules change
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.hub_schedules;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.hub_schedules FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
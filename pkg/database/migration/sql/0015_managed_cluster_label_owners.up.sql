@@ -0,0 +1,36 @@
+-- the owners of the label keys of the managed clusters. The label of the global owner is always kept with the value
+-- of the global hub, the one of the hub owner is only changed on the hub, and the shared one is kept with the value
+-- of the side changing it last. The keys not listed are shared.
+CREATE TABLE IF NOT EXISTS spec.managed_cluster_label_owners (
+    label_key text PRIMARY KEY,
+    owner text NOT NULL,
+    updated_by text NOT NULL DEFAULT '',
+    updated_at timestamp without time zone DEFAULT now() NOT NULL,
+    CONSTRAINT managed_cluster_label_owners_owner_check CHECK (owner IN ('global', 'hub', 'shared'))
+);
+
+-- the labels of the managed clusters are sent again once the owners change
+DROP TRIGGER IF EXISTS notify_spec_change ON spec.managed_cluster_label_owners;
+CREATE TRIGGER notify_spec_change AFTER INSERT OR UPDATE OR DELETE ON spec.managed_cluster_label_owners FOR EACH STATEMENT EXECUTE FUNCTION public.notify_spec_change();
+
+-- the conflicts of the labels of the managed clusters reported by the hubs, which are the labels whose values on the
+-- hub differ from the ones of the global hub. The winner is the side whose value is kept on the hub, and the values
+-- are null if the label is deleted by that side. The events are partitioned by month and dropped by the data retention
+-- job.
+CREATE TABLE IF NOT EXISTS event.managed_cluster_label_conflicts (
+    leaf_hub_name character varying(254) NOT NULL,
+    cluster_name character varying(254) NOT NULL,
+    label_key text NOT NULL,
+    owner text NOT NULL,
+    global_value text,
+    hub_value text,
+    winner text NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    CONSTRAINT managed_cluster_label_conflicts_unique_constraint UNIQUE (leaf_hub_name, cluster_name, label_key,
+        created_at)
+) PARTITION BY RANGE (created_at);
+CREATE INDEX IF NOT EXISTS managed_cluster_label_conflicts_cluster_idx ON event.managed_cluster_label_conflicts
+    (leaf_hub_name, cluster_name, created_at);
+
+SELECT create_monthly_range_partitioned_table('event.managed_cluster_label_conflicts', to_char(current_date, 'YYYY-MM-DD'));
+SELECT create_monthly_range_partitioned_table('event.managed_cluster_label_conflicts', to_char(current_date + interval '1 month', 'YYYY-MM-DD'));
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AuditEvent records who changed what by the REST API or the global resources, the Objects carry the state of the
+// affected objects before and after the change.
+type AuditEvent struct {
+	ID        string         `gorm:"column:id;primaryKey"`
+	CreatedAt time.Time      `gorm:"column:created_at;primaryKey"`
+	Source    string         `gorm:"column:source;not null"`
+	Username  string         `gorm:"column:username;not null"`
+	Groups    datatypes.JSON `gorm:"column:groups;type:jsonb"`
+	Action    string         `gorm:"column:action;not null"`
+	Resource  string         `gorm:"column:resource;not null"`
+	Request   datatypes.JSON `gorm:"column:request;type:jsonb"`
+	Objects   datatypes.JSON `gorm:"column:objects;type:jsonb;not null"`
+	Result    string         `gorm:"column:result;not null"`
+	Message   string         `gorm:"column:message"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit.events"
+}
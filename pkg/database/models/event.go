@@ -45,6 +45,9 @@ type DataRetentionJobLog struct {
 	MaxPartition string    `gorm:"column:max_partition"`
 	MinDeletion  time.Time `gorm:"column:min_deletion"`
 	Error        string    `gorm:"column:error"`
+	// ArchiveManifest and ArchivedRows are only set when the expired partition is archived before dropping it
+	ArchiveManifest *string `gorm:"column:archive_manifest"`
+	ArchivedRows    *int64  `gorm:"column:archived_rows"`
 }
 
 func (DataRetentionJobLog) TableName() string {
@@ -68,3 +71,41 @@ type ManagedClusterEvent struct {
 func (ManagedClusterEvent) TableName() string {
 	return "event.managed_clusters"
 }
+
+// SpecDriftEvent is a change of the global resource made on the managed hub, the Fields is the changed top level
+// fields of the object.
+type SpecDriftEvent struct {
+	LeafHubName string         `gorm:"column:leaf_hub_name;not null"`
+	UID         string         `gorm:"column:uid;not null"`
+	APIVersion  string         `gorm:"column:api_version;not null"`
+	Kind        string         `gorm:"column:kind;not null"`
+	Namespace   string         `gorm:"column:namespace;not null"`
+	Name        string         `gorm:"column:name;not null"`
+	DriftType   string         `gorm:"column:drift_type;not null"`
+	Mode        string         `gorm:"column:mode;not null"`
+	Action      string         `gorm:"column:action;not null"`
+	Fields      datatypes.JSON `gorm:"column:fields;type:jsonb"`
+	Message     string         `gorm:"column:message"`
+	CreatedAt   time.Time      `gorm:"column:created_at;not null"`
+}
+
+func (SpecDriftEvent) TableName() string {
+	return "event.spec_drifts"
+}
+
+// ManagedClusterLabelConflictEvent is a label of the managed cluster whose value on the hub differs from the one of the
+// global hub, the Winner is the side whose value is kept. The values are nil if the label is deleted by that side.
+type ManagedClusterLabelConflictEvent struct {
+	LeafHubName string    `gorm:"column:leaf_hub_name;not null"`
+	ClusterName string    `gorm:"column:cluster_name;not null"`
+	LabelKey    string    `gorm:"column:label_key;not null"`
+	Owner       string    `gorm:"column:owner;not null"`
+	GlobalValue *string   `gorm:"column:global_value"`
+	HubValue    *string   `gorm:"column:hub_value"`
+	Winner      string    `gorm:"column:winner;not null"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null"`
+}
+
+func (ManagedClusterLabelConflictEvent) TableName() string {
+	return "event.managed_cluster_label_conflicts"
+}
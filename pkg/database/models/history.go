@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+)
 
 type LocalComplianceJobLog struct {
 	Name     string    `gorm:"column:name"`
@@ -29,3 +34,36 @@ type LocalComplianceHistory struct {
 func (LocalComplianceHistory) TableName() string {
 	return "history.local_compliance"
 }
+
+type ComplianceHistory struct {
+	PolicyID                   string    `gorm:"column:policy_id"`
+	ClusterName                string    `gorm:"column:cluster_name"`
+	LeafHubName                string    `gorm:"column:leaf_hub_name"`
+	ClusterID                  *string   `gorm:"column:cluster_id"`
+	ComplianceDate             time.Time `gorm:"type:date;column:compliance_date"`
+	Compliance                 string    `gorm:"column:compliance"`
+	ComplianceChangedFrequency int       `gorm:"column:compliance_changed_frequency"`
+}
+
+// TableName specifies the table name for the global policy compliance history
+func (ComplianceHistory) TableName() string {
+	return "history.compliance"
+}
+
+// ManagedClusterHistory is the transition of the managed cluster, the Changes is the transited fields
+type ManagedClusterHistory struct {
+	ClusterID        string         `gorm:"column:cluster_id"`
+	ClusterName      string         `gorm:"column:cluster_name"`
+	LeafHubName      string         `gorm:"column:leaf_hub_name"`
+	Available        *string        `gorm:"column:available"`
+	Joined           *string        `gorm:"column:joined"`
+	Accepted         *string        `gorm:"column:accepted"`
+	OpenshiftVersion *string        `gorm:"column:openshift_version"`
+	Labels           datatypes.JSON `gorm:"column:labels;type:jsonb"`
+	Changes          pq.StringArray `gorm:"column:changes;type:text[]"`
+	CreatedAt        time.Time      `gorm:"column:created_at"`
+}
+
+func (ManagedClusterHistory) TableName() string {
+	return "history.managed_clusters"
+}
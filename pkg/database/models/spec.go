@@ -22,6 +22,20 @@ func (ManagedClusterLabel) TableName() string {
 	return "spec.managed_clusters_labels"
 }
 
+// ManagedClusterLabelJob is the label patch applied to the Clusters in bulk, the LabelSelector is empty if the
+// clusters are listed by the ID.
+type ManagedClusterLabelJob struct {
+	ID            string         `gorm:"column:id;primaryKey"`
+	LabelSelector string         `gorm:"column:label_selector"`
+	Patches       datatypes.JSON `gorm:"column:patches;type:jsonb"`
+	Clusters      datatypes.JSON `gorm:"column:clusters;type:jsonb"`
+	CreatedAt     time.Time      `gorm:"column:created_at;autoCreateTime:true"`
+}
+
+func (ManagedClusterLabelJob) TableName() string {
+	return "spec.managed_clusters_label_jobs"
+}
+
 // CREATE TABLE IF NOT EXISTS spec.policies (
 // 	id uuid PRIMARY KEY,
 // 	payload jsonb NOT NULL,
@@ -65,3 +79,88 @@ type SpecPlacementBinding struct {
 func (SpecPlacementBinding) TableName() string {
 	return "spec.placementbindings"
 }
+
+const (
+	// RolloutProgressing releases the waves one by one once the released ones are soaked and healthy
+	RolloutProgressing = "progressing"
+	// RolloutPaused keeps the released waves, it's paused by the user or the failed health of the released waves
+	RolloutPaused = "paused"
+	// RolloutAborted delivers the previous version to all the hubs again
+	RolloutAborted = "aborted"
+	// RolloutCompleted delivers the version to all the hubs
+	RolloutCompleted = "completed"
+)
+
+// SpecRollout is the rollout of a version of the global resource to the waves of the managed hubs, the
+// PreviousPayload is the last completed version and it's empty if no version is completed yet.
+type SpecRollout struct {
+	UID             string         `gorm:"column:uid;primaryKey"`
+	Kind            string         `gorm:"column:kind;not null"`
+	Namespace       string         `gorm:"column:namespace;not null"`
+	Name            string         `gorm:"column:name;not null"`
+	Digest          string         `gorm:"column:digest;not null"`
+	Payload         datatypes.JSON `gorm:"column:payload;type:jsonb"`
+	PreviousPayload datatypes.JSON `gorm:"column:previous_payload;type:jsonb"`
+	State           string         `gorm:"column:state;not null"`
+	Wave            int            `gorm:"column:wave;not null"`
+	WaveStartedAt   time.Time      `gorm:"column:wave_started_at;not null"`
+	Message         string         `gorm:"column:message;not null"`
+	UpdatedAt       time.Time      `gorm:"column:updated_at;autoUpdateTime:true"`
+}
+
+func (SpecRollout) TableName() string {
+	return "spec.rollouts"
+}
+
+// SpecDryRun is the objects applied by the server-side dry run on the hubs they target, the Hubs limit the targets if
+// it isn't empty, and the TargetHubs are the hubs the objects are sent to.
+type SpecDryRun struct {
+	ID         string         `gorm:"column:id;primaryKey"`
+	Objects    datatypes.JSON `gorm:"column:objects;type:jsonb"`
+	Hubs       datatypes.JSON `gorm:"column:hubs;type:jsonb"`
+	TargetHubs datatypes.JSON `gorm:"column:target_hubs;type:jsonb"`
+	CreatedBy  string         `gorm:"column:created_by;not null"`
+	CreatedAt  time.Time      `gorm:"column:created_at;autoCreateTime:true"`
+	SentAt     *time.Time     `gorm:"column:sent_at"`
+}
+
+func (SpecDryRun) TableName() string {
+	return "spec.dry_runs"
+}
+
+const (
+	// the spec changes are only sent to the hub while one of its maintenance windows is open
+	HubScheduleWindow = "window"
+	// the spec changes aren't sent to the hub while any of its change freezes is active
+	HubScheduleFreeze = "freeze"
+)
+
+// HubSchedule is the maintenance window or the change freeze of the hub, it starts by the cron expression in the
+// timezone and lasts for the duration. The spec changes deferred by the schedules are sent once they're released.
+type HubSchedule struct {
+	LeafHubName     string    `gorm:"column:leaf_hub_name;primaryKey"`
+	Name            string    `gorm:"column:name;primaryKey"`
+	Type            string    `gorm:"column:type;not null"`
+	Cron            string    `gorm:"column:cron;not null"`
+	DurationSeconds int64     `gorm:"column:duration_seconds;not null"`
+	Timezone        string    `gorm:"column:timezone;not null"`
+	UpdatedBy       string    `gorm:"column:updated_by;not null"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime:true"`
+}
+
+func (HubSchedule) TableName() string {
+	return "spec.hub_schedules"
+}
+
+// ManagedClusterLabelOwner is the owner of the label key of the managed clusters, global, hub or shared. The keys
+// without the owner are shared.
+type ManagedClusterLabelOwner struct {
+	LabelKey  string    `gorm:"column:label_key;primaryKey"`
+	Owner     string    `gorm:"column:owner;not null"`
+	UpdatedBy string    `gorm:"column:updated_by;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime:true"`
+}
+
+func (ManagedClusterLabelOwner) TableName() string {
+	return "spec.managed_cluster_label_owners"
+}
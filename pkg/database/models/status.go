@@ -98,3 +98,40 @@ type SubscriptionReport struct {
 func (SubscriptionReport) TableName() string {
 	return "status.subscription_reports"
 }
+
+// SpecApplyResult is the latest result of applying a global resource on a managed hub.
+type SpecApplyResult struct {
+	LeafHubName     string    `gorm:"column:leaf_hub_name;primaryKey"`
+	APIVersion      string    `gorm:"column:api_version;not null"`
+	Kind            string    `gorm:"column:kind;primaryKey"`
+	Namespace       string    `gorm:"column:namespace;primaryKey"`
+	Name            string    `gorm:"column:name;primaryKey"`
+	UID             string    `gorm:"column:uid;not null"`
+	Result          string    `gorm:"column:result;not null"`
+	Error           string    `gorm:"column:error;not null"`
+	ResourceVersion string    `gorm:"column:resource_version;not null"`
+	Deleted         bool      `gorm:"column:deleted;not null"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime:false"`
+}
+
+func (SpecApplyResult) TableName() string {
+	return "status.spec_apply_results"
+}
+
+// SpecDryRunResult is the result of applying the object of the dry run by the server-side dry run on the hub
+type SpecDryRunResult struct {
+	DryRunID    string         `gorm:"column:dry_run_id;primaryKey"`
+	LeafHubName string         `gorm:"column:leaf_hub_name;primaryKey"`
+	APIVersion  string         `gorm:"column:api_version;not null"`
+	Kind        string         `gorm:"column:kind;primaryKey"`
+	Namespace   string         `gorm:"column:namespace;primaryKey"`
+	Name        string         `gorm:"column:name;primaryKey"`
+	Result      string         `gorm:"column:result;not null"`
+	Diff        datatypes.JSON `gorm:"column:diff;type:jsonb"`
+	Message     string         `gorm:"column:message;not null"`
+	ReportedAt  time.Time      `gorm:"column:reported_at;not null"`
+}
+
+func (SpecDryRunResult) TableName() string {
+	return "status.spec_dry_run_results"
+}
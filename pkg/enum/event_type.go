@@ -43,4 +43,13 @@ const (
 
 	// Used to send security alerts:
 	SecurityAlertCountsType EventType = "io.open-cluster-management.operator.multiclusterglobalhubs.security.alertcounts"
+
+	// the results of applying the global resources on the managed hub
+	SpecApplyResultsType EventType = "io.open-cluster-management.operator.multiclusterglobalhubs.spec.applyresults"
+	// the changes of the global resources made on the managed hub
+	SpecDriftType EventType = "io.open-cluster-management.operator.multiclusterglobalhubs.spec.drift"
+	// the results of applying the global resources by the server-side dry run on the managed hub
+	SpecDryRunResultsType EventType = "io.open-cluster-management.operator.multiclusterglobalhubs.spec.dryrunresults"
+	// the conflicts of the managed cluster labels between the global hub and the managed hub
+	ManagedClusterLabelConflictType EventType = "io.open-cluster-management.operator.multiclusterglobalhubs.managedcluster.labelconflict"
 )
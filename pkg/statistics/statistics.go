@@ -84,6 +84,13 @@ func (s *Statistics) IncrementNumberOfConflations() {
 	s.numOfConflationUnits++
 }
 
+// DecrementNumberOfConflations decrements number of conflations
+func (s *Statistics) DecrementNumberOfConflations() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.numOfConflationUnits--
+}
+
 // AddDatabaseMetrics adds database metrics of the specific event type.
 func (s *Statistics) AddDatabaseMetrics(evt *cloudevents.Event, duration time.Duration, err error) {
 	eventMetrics, ok := s.eventMetrics[evt.Type()]
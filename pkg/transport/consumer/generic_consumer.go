@@ -33,6 +33,7 @@ type GenericConsumer struct {
 	eventChan            chan *cloudevents.Event
 	enableDatabaseOffset bool
 	clusterID            string
+	offsetMember         string
 
 	consumerCtx    context.Context
 	consumerCancel context.CancelFunc
@@ -58,6 +59,7 @@ func NewGenericConsumer(tranConfig *transport.TransportInternalConfig,
 		eventChan:            make(chan *cloudevents.Event),
 		assembler:            newMessageAssembler(),
 		enableDatabaseOffset: tranConfig.EnableDatabaseOffset,
+		offsetMember:         tranConfig.OffsetMember,
 	}
 	if err := c.initClient(tranConfig); err != nil {
 		return nil, err
@@ -143,7 +145,7 @@ func (c *GenericConsumer) Reconnect(ctx context.Context, tranConfig *transport.T
 func (c *GenericConsumer) Start(ctx context.Context) error {
 	receiveContext := cectx.WithLogger(ctx, logger.ZapLogger("cloudevents"))
 	if c.enableDatabaseOffset {
-		offsets, err := getInitOffset(c.clusterID)
+		offsets, err := getInitOffset(c.clusterID, c.offsetMember)
 		if err != nil {
 			return err
 		}
@@ -182,7 +184,9 @@ func (c *GenericConsumer) EventChan() chan *cloudevents.Event {
 	return c.eventChan
 }
 
-func getInitOffset(kafkaClusterIdentity string) ([]kafka.TopicPartition, error) {
+// getInitOffset returns the offsets of the status topics committed by the member, the member is empty if the status
+// isn't sharded between the manager replicas.
+func getInitOffset(kafkaClusterIdentity string, member string) ([]kafka.TopicPartition, error) {
 	db := database.GetGorm()
 	var positions []models.Transport
 	err := db.Where("name ~ ?", "^status*").
@@ -192,14 +196,18 @@ func getInitOffset(kafkaClusterIdentity string) ([]kafka.TopicPartition, error)
 		return nil, err
 	}
 	offsetToStart := []kafka.TopicPartition{}
-	for i, pos := range positions {
+	for _, pos := range positions {
+		topic, positionMember := transport.ParseOffsetName(pos.Name)
+		if positionMember != member {
+			continue
+		}
 		var kafkaPosition transport.EventPosition
 		err := json.Unmarshal(pos.Payload, &kafkaPosition)
 		if err != nil {
 			return nil, err
 		}
 		offsetToStart = append(offsetToStart, kafka.TopicPartition{
-			Topic:     &positions[i].Name,
+			Topic:     &topic,
 			Partition: kafkaPosition.Partition,
 			Offset:    kafka.Offset(kafkaPosition.Offset),
 		})
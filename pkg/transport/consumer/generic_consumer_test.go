@@ -51,13 +51,19 @@ func TestGetInitOffset(t *testing.T) {
 	databaseTransports = append(databaseTransports, generateTransport(kafkaClusterIdentity, "spec", 9))
 	databaseTransports = append(databaseTransports, generateTransport("", "status.hub3", 8))
 	databaseTransports = append(databaseTransports, generateTransport("another", "status.hub4", 7))
+	// the offsets of the sharded manager replicas
+	shard0 := generateTransport(kafkaClusterIdentity, "status", 6)
+	shard0.Name = transport.OffsetName("status", "shard-0")
+	shard1 := generateTransport(kafkaClusterIdentity, "status", 5)
+	shard1.Name = transport.OffsetName("status", "shard-1")
+	databaseTransports = append(databaseTransports, shard0, shard1)
 
 	db := database.GetGorm()
 	err = db.Clauses(clause.OnConflict{
 		UpdateAll: true,
 	}).CreateInBatches(databaseTransports, 100).Error
 	assert.Nil(t, err)
-	offsets, err := getInitOffset(kafkaClusterIdentity)
+	offsets, err := getInitOffset(kafkaClusterIdentity, "")
 	assert.Nil(t, err)
 
 	count := 0
@@ -69,6 +75,12 @@ func TestGetInitOffset(t *testing.T) {
 		count++
 	}
 	assert.Equal(t, 3, count)
+
+	offsets, err = getInitOffset(kafkaClusterIdentity, "shard-1")
+	assert.Nil(t, err)
+	assert.Len(t, offsets, 1)
+	assert.Equal(t, "status", *offsets[0].Topic)
+	assert.Equal(t, kafka.Offset(5), offsets[0].Offset)
 }
 
 func generateTransport(ownerIdentity string, topic string, offset int64) models.Transport {
@@ -8,9 +8,11 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
@@ -40,6 +42,9 @@ type TransportCtrl struct {
 	transportCallback TransportCallback
 	transportClient   *TransportClient
 
+	// the transport controller only runs on the leader by default
+	needLeaderElection *bool
+
 	mutex sync.Mutex
 }
 
@@ -86,6 +91,13 @@ func NewTransportCtrl(namespace, name string, callback TransportCallback,
 	}
 }
 
+// WithoutLeaderElection starts the controller on every replica, so the non-leader replicas can also consume and
+// produce the events, e.g. the manager replicas share the status path of the leaf hubs.
+func (c *TransportCtrl) WithoutLeaderElection() *TransportCtrl {
+	c.needLeaderElection = ptr.To(false)
+	return c
+}
+
 func (c *TransportCtrl) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -328,6 +340,7 @@ func (c *TransportCtrl) SetupWithManager(mgr ctrl.Manager) error {
 	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Secret{}, builder.WithPredicates(secretPred)).
+		WithOptions(controller.Options{NeedLeaderElection: c.needLeaderElection}).
 		Complete(c)
 }
 
@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"strings"
 	"time"
 )
 
@@ -40,6 +41,9 @@ type TransportInternalConfig struct {
 	// EnableDatabaseOffset affects only the manager, deciding if consumption starts from a database-stored offset
 	EnableDatabaseOffset bool
 	ConsumerGroupId      string
+	// OffsetMember separates the database offsets of the manager replicas which consume the status with their own
+	// consumer groups, it's empty if the status isn't sharded
+	OffsetMember string
 	// set the kafka credentail in the transport controller
 	KafkaCredential   *KafkaConfig
 	RestfulCredential *RestfulConfig
@@ -84,3 +88,18 @@ type EventPosition struct {
 	// 2. byo kafka, use the kafka bootstrapserver as the identity
 	OwnerIdentity string `json:"ownerIdentity"`
 }
+
+// OffsetName is the name of the database record of the topic offset. The offset of the sharded manager replica is
+// recorded with its member, e.g. "gh-status/shard-0", since the kafka topic name can't contain the "/".
+func OffsetName(topic, member string) string {
+	if member == "" {
+		return topic
+	}
+	return topic + "/" + member
+}
+
+// ParseOffsetName returns the topic and member of the database record of the topic offset
+func ParseOffsetName(name string) (topic, member string) {
+	topic, member, _ = strings.Cut(name, "/")
+	return topic, member
+}
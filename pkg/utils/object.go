@@ -24,22 +24,37 @@ const (
 
 // UpdateObject function updates a given k8s object.
 func UpdateObject(ctx context.Context, runtimeClient client.Client, obj *unstructured.Unstructured) error {
+	if err := applyObject(ctx, runtimeClient, obj, nil); err != nil {
+		return fmt.Errorf("failed to update object - %w", err)
+	}
+	return nil
+}
+
+// DryRunObject applies the object by the server-side dry run, the obj is set to the object the server would persist,
+// and the error is returned if the server would reject it, e.g. by the admission webhook.
+func DryRunObject(ctx context.Context, runtimeClient client.Client, obj *unstructured.Unstructured) error {
+	if err := applyObject(ctx, runtimeClient, obj, []string{metav1.DryRunAll}); err != nil {
+		return fmt.Errorf("failed to dry run object - %w", err)
+	}
+	return nil
+}
+
+func applyObject(ctx context.Context, runtimeClient client.Client, obj *unstructured.Unstructured,
+	dryRun []string,
+) error {
 	objectBytes, err := obj.MarshalJSON()
 	if err != nil {
-		return fmt.Errorf("failed to update object - %w", err)
+		return err
 	}
 	forceChanges := true
-	if err := runtimeClient.Patch(ctx, obj, client.RawPatch(types.ApplyPatchType, objectBytes), &client.PatchOptions{
+	return runtimeClient.Patch(ctx, obj, client.RawPatch(types.ApplyPatchType, objectBytes), &client.PatchOptions{
+		DryRun:       dryRun,
 		FieldManager: controllerName,
 		Force:        &forceChanges,
 		Raw: &metav1.PatchOptions{
 			FieldValidation: metav1.FieldValidationIgnore,
 		},
-	}); err != nil {
-		return fmt.Errorf("failed to update object - %w", err)
-	}
-
-	return nil
+	})
 }
 
 // DeleteObject tries to delete the given object from k8s. returns error and true/false if object was deleted or not.
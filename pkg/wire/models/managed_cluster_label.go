@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	// LabelOwnerGlobal means the label is always kept with the value of the global hub.
+	LabelOwnerGlobal = "global"
+	// LabelOwnerHub means the label is only changed on the hub, the changes of the global hub are ignored.
+	LabelOwnerHub = "hub"
+	// LabelOwnerShared means the label is kept with the value of the side changing it last.
+	LabelOwnerShared = "shared"
+
+	// LabelSourceGlobal means the value of the label is kept from the global hub.
+	LabelSourceGlobal = "global"
+	// LabelSourceHub means the value of the label is kept from the hub.
+	LabelSourceHub = "hub"
+)
+
+// ManagedClusterLabelConflicts is the conflicts of the managed cluster labels detected on a hub since the last event.
+type ManagedClusterLabelConflicts []ManagedClusterLabelConflict
+
+// ManagedClusterLabelConflict is a label of the managed cluster whose value on the hub differs from the one of the
+// global hub.
+type ManagedClusterLabelConflict struct {
+	ClusterName string `json:"clusterName"`
+	Key         string `json:"key"`
+
+	// Owner is the owner of the label key, global, hub or shared.
+	Owner string `json:"owner"`
+
+	// GlobalValue is the value of the global hub, it's nil if the label is deleted by the global hub.
+	GlobalValue *string `json:"globalValue"`
+
+	// HubValue is the value changed on the hub, it's nil if the label is deleted on the hub.
+	HubValue *string `json:"hubValue"`
+
+	// Winner is the side whose value is kept on the hub, global or hub.
+	Winner string `json:"winner"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
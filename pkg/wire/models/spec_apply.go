@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	// SpecApplied means the object is created or updated on the hub.
+	SpecApplied = "applied"
+	// SpecApplyFailed means the object failed to be applied or deleted on the hub.
+	SpecApplyFailed = "failed"
+	// SpecApplySkipped means the object isn't delivered to the hub, so it isn't on the hub or it's owned by the hub.
+	SpecApplySkipped = "skipped"
+	// SpecApplyRejected means the object isn't allowed to be applied or deleted by the allow lists of the hub.
+	SpecApplyRejected = "rejected"
+)
+
+// SpecApplyResults contains the latest apply result of each object delivered from the global hub to a hub.
+type SpecApplyResults struct {
+	Results []SpecApplyResult `json:"results"`
+}
+
+// SpecApplyResult is the result of applying an object delivered from the global hub.
+type SpecApplyResult struct {
+	// UID is the uid of the object on the global hub, it's empty for the object deleted from the global hub.
+	UID string `json:"uid,omitempty"`
+
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+
+	// Result is applied, failed, skipped or rejected.
+	Result string `json:"result"`
+
+	// Error is the error of the failed or rejected result.
+	Error string `json:"error,omitempty"`
+
+	// ResourceVersion is the resource version of the object on the hub after it's applied.
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+
+	// Deleted is true if the result is of deleting the object from the hub.
+	Deleted bool `json:"deleted,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
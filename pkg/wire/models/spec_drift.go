@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+const (
+	// DriftModeEnforce reverts the changes of the global resource made on the hub.
+	DriftModeEnforce = "enforce"
+	// DriftModeReport only reports the changes of the global resource made on the hub.
+	DriftModeReport = "report"
+
+	// SpecDriftModified means the spec fields of the object are changed on the hub.
+	SpecDriftModified = "modified"
+	// SpecDriftDeleted means the object is deleted, or its global resource label is removed, on the hub.
+	SpecDriftDeleted = "deleted"
+
+	// SpecDriftReverted means the object is applied again to revert the drift.
+	SpecDriftReverted = "reverted"
+	// SpecDriftReported means the drift is kept on the hub.
+	SpecDriftReported = "reported"
+	// SpecDriftRevertFailed means the drift failed to be reverted.
+	SpecDriftRevertFailed = "failed"
+)
+
+// SpecDriftEvents is the drifts of the global resources detected on a hub since the last event.
+type SpecDriftEvents []SpecDriftEvent
+
+// SpecDriftEvent is a drift of the object delivered from the global hub.
+type SpecDriftEvent struct {
+	// UID is the uid of the object on the global hub.
+	UID string `json:"uid,omitempty"`
+
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+
+	// DriftType is modified or deleted.
+	DriftType string `json:"driftType"`
+
+	// Mode is the drift mode of the object, enforce or report.
+	Mode string `json:"mode"`
+
+	// Action is reverted, reported or failed.
+	Action string `json:"action"`
+
+	// Fields is the changed top level fields of the object, e.g. spec.
+	Fields []string `json:"fields,omitempty"`
+
+	// Message is the error of the failed action.
+	Message string `json:"message,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+const (
+	// SpecDryRunCreate means the object doesn't exist on the hub and it would be created.
+	SpecDryRunCreate = "would-create"
+	// SpecDryRunUpdate means the object would be updated with the diff.
+	SpecDryRunUpdate = "would-update"
+	// SpecDryRunUnchanged means the object exists on the hub and it wouldn't be changed.
+	SpecDryRunUnchanged = "unchanged"
+	// SpecDryRunFail means the object would fail to be applied, e.g. it's rejected by the admission webhook.
+	SpecDryRunFail = "would-fail"
+)
+
+// SpecDryRunResults is the results of the objects of a dry run on a hub.
+type SpecDryRunResults struct {
+	// ID is the dry run requested by the global hub.
+	ID      string             `json:"id"`
+	Results []SpecDryRunResult `json:"results"`
+}
+
+// SpecDryRunResult is the result of applying the object by the server-side dry run.
+type SpecDryRunResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+
+	// Result is would-create, would-update, unchanged or would-fail.
+	Result string `json:"result"`
+
+	// Diff is the changed fields of the updated object.
+	Diff []SpecDryRunChange `json:"diff,omitempty"`
+
+	// Message is the error of the failed object, or why the result isn't from the server, e.g. the namespace of the
+	// object would be created by the agent.
+	Message string `json:"message,omitempty"`
+
+	ReportedAt time.Time `json:"reportedAt"`
+}
+
+// SpecDryRunChange is the change of the field from the object on the hub to the dry run one, the Before is empty if
+// the field is added and the After is empty if it's removed.
+type SpecDryRunChange struct {
+	// Path is the dot separated path of the field, e.g. spec.remediationAction. The lists are compared as a whole.
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
@@ -78,5 +78,33 @@ var _ = Describe("ManagerClusterLabelBundle", func() {
 			}
 			return fmt.Errorf("not found label on cluster { %s : %s}", "test", "add")
 		}, 5*time.Second, 100*time.Millisecond).ShouldNot(HaveOccurred())
+
+		By("Send ManagedClusterLabelBundle with the hub owned label")
+		bundleObj.Labels = map[string]string{"test": "update", "vendor": "Global"}
+		bundleObj.Version = 11
+		bundleObj.UpdateTimestamp = time.Now()
+		managedClusterLabelsSpecBundle.Owners = map[string]string{"vendor": "hub"}
+		payloadBytes, err = json.Marshal(managedClusterLabelsSpecBundle)
+		Expect(err).NotTo(HaveOccurred())
+
+		evt = utils.ToCloudEvent(constants.ManagedClustersLabelsMsgKey, constants.CloudEventSourceGlobalHub, agentConfig.LeafHubName, payloadBytes)
+		err = genericProducer.SendEvent(ctx, evt)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Check the hub owned label is kept with the value of the hub")
+		Eventually(func() error {
+			mc := clusterv1.ManagedCluster{}
+			err = runtimeClient.Get(ctx, runtimeclient.ObjectKeyFromObject(&managedCluster), &mc)
+			if err != nil {
+				return err
+			}
+			if mc.GetLabels()["test"] != "update" {
+				return fmt.Errorf("not found label on cluster { %s : %s}", "test", "update")
+			}
+			if mc.GetLabels()["vendor"] != "OpenShift" {
+				return fmt.Errorf("the hub owned label is overridden: %v", mc.Labels)
+			}
+			return nil
+		}, 5*time.Second, 100*time.Millisecond).ShouldNot(HaveOccurred())
 	})
 })
@@ -0,0 +1,112 @@
+package spec
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/spec/controllers/bundle"
+	"github.com/stolostron/multicluster-global-hub/pkg/constants"
+	"github.com/stolostron/multicluster-global-hub/pkg/transport"
+	"github.com/stolostron/multicluster-global-hub/pkg/utils"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+// go test ./test/integration/agent/spec -v -ginkgo.focus "SpecDrift"
+var _ = Describe("SpecDrift", Ordered, func() {
+	newConfigMap := func(name, mode string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Labels:      map[string]string{constants.GlobalHubGlobalResourceLabel: ""},
+				Annotations: map[string]string{constants.DriftModeAnnotation: mode},
+			},
+			Data: map[string]string{"hello": "world"},
+		}
+	}
+	sendBundle := func(object metav1.Object, deleted bool) {
+		baseBundle := bundle.NewBaseObjectsBundle()
+		if deleted {
+			baseBundle.AddDeletedObject(object)
+		} else {
+			baseBundle.AddObject(object, uuid.New().String())
+		}
+		payloadBytes, err := json.Marshal(baseBundle)
+		Expect(err).NotTo(HaveOccurred())
+		evt := utils.ToCloudEvent("Config", constants.CloudEventSourceGlobalHub, transport.Broadcast, payloadBytes)
+		Expect(genericProducer.SendEvent(ctx, evt)).To(Succeed())
+	}
+	updateData := func(cm *corev1.ConfigMap, value string) {
+		Eventually(func() error {
+			existing := &corev1.ConfigMap{}
+			if err := runtimeClient.Get(ctx, client.ObjectKeyFromObject(cm), existing); err != nil {
+				return err
+			}
+			existing.Data["hello"] = value
+			return runtimeClient.Update(ctx, existing)
+		}, 10*time.Second, 100*time.Millisecond).Should(Succeed())
+	}
+	getData := func(cm *corev1.ConfigMap) string {
+		existing := &corev1.ConfigMap{}
+		if err := runtimeClient.Get(ctx, client.ObjectKeyFromObject(cm), existing); err != nil {
+			return err.Error()
+		}
+		return existing.Data["hello"]
+	}
+
+	It("revert the changes of the enforced configmap", func() {
+		cm := newConfigMap("drift-enforce", wiremodels.DriftModeEnforce)
+		sendBundle(cm, false)
+		Eventually(func() string {
+			return getData(cm)
+		}, 10*time.Second, 100*time.Millisecond).Should(Equal("world"))
+
+		By("Change the configmap on the hub")
+		updateData(cm, "local")
+		Eventually(func() string {
+			return getData(cm)
+		}, 10*time.Second, 100*time.Millisecond).Should(Equal("world"))
+
+		By("Delete the configmap on the hub")
+		Expect(runtimeClient.Delete(ctx, cm)).To(Succeed())
+		Eventually(func() string {
+			return getData(cm)
+		}, 10*time.Second, 100*time.Millisecond).Should(Equal("world"))
+
+		By("Delete the configmap from the global hub")
+		deleted := newConfigMap("drift-enforce", wiremodels.DriftModeEnforce)
+		deleted.Data = nil
+		sendBundle(deleted, true)
+		Eventually(func() bool {
+			err := runtimeClient.Get(ctx, client.ObjectKeyFromObject(cm), &corev1.ConfigMap{})
+			return apierrors.IsNotFound(err)
+		}, 10*time.Second, 100*time.Millisecond).Should(BeTrue())
+		Consistently(func() bool {
+			err := runtimeClient.Get(ctx, client.ObjectKeyFromObject(cm), &corev1.ConfigMap{})
+			return apierrors.IsNotFound(err)
+		}, 3*time.Second, 100*time.Millisecond).Should(BeTrue())
+	})
+
+	It("keep the changes of the reported configmap", func() {
+		cm := newConfigMap("drift-report", wiremodels.DriftModeReport)
+		sendBundle(cm, false)
+		Eventually(func() string {
+			return getData(cm)
+		}, 10*time.Second, 100*time.Millisecond).Should(Equal("world"))
+
+		By("Change the configmap on the hub")
+		updateData(cm, "local")
+		Consistently(func() string {
+			return getData(cm)
+		}, 3*time.Second, 100*time.Millisecond).Should(Equal("local"))
+	})
+})
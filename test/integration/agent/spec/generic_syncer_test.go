@@ -9,7 +9,9 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	clustersv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -149,4 +151,57 @@ var _ = Describe("GenericSpecBundle", func() {
 			return runtimeClient.Get(ctx, client.ObjectKeyFromObject(cm), syncedConfigMap)
 		}, 10*time.Second, 100*time.Millisecond).ShouldNot(HaveOccurred())
 	})
+	It("delete the configmap which isn't targeted at the hub", func() {
+		By("Create the local configmap with the same name as the delivered one")
+		uid := uuid.New().String()
+		local := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "targeted", Namespace: "default"},
+			Data:       map[string]string{"hello": "local"},
+		}
+		Expect(runtimeClient.Create(ctx, local)).To(Succeed())
+
+		By("Send the identity of the configmap which isn't delivered to the hub")
+		identity := &unstructured.Unstructured{}
+		identity.SetAPIVersion("v1")
+		identity.SetKind("ConfigMap")
+		identity.SetName("targeted")
+		identity.SetNamespace("default")
+		identity.SetAnnotations(map[string]string{constants.OriginOwnerReferenceAnnotation: uid})
+		sendBundle := func(object metav1.Object, deleted bool) {
+			baseBundle := bundle.NewBaseObjectsBundle()
+			if deleted {
+				baseBundle.AddDeletedObject(object)
+			} else {
+				baseBundle.AddObject(object, uid)
+			}
+			payloadBytes, err := json.Marshal(baseBundle)
+			Expect(err).NotTo(HaveOccurred())
+			evt := utils.ToCloudEvent("Config", constants.CloudEventSourceGlobalHub, transport.Broadcast, payloadBytes)
+			Expect(genericProducer.SendEvent(ctx, evt)).To(Succeed())
+		}
+		sendBundle(identity, true)
+
+		By("Check the local configmap isn't deleted")
+		Consistently(func() error {
+			return runtimeClient.Get(ctx, client.ObjectKeyFromObject(local), &corev1.ConfigMap{})
+		}, 3*time.Second, 100*time.Millisecond).ShouldNot(HaveOccurred())
+		Expect(runtimeClient.Delete(ctx, local)).To(Succeed())
+
+		By("Deliver the configmap and then send its identity")
+		sendBundle(&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "targeted", Namespace: "default"},
+			Data:       map[string]string{"hello": "world"},
+		}, false)
+		Eventually(func() error {
+			return runtimeClient.Get(ctx, client.ObjectKeyFromObject(local), &corev1.ConfigMap{})
+		}, 10*time.Second, 100*time.Millisecond).ShouldNot(HaveOccurred())
+		sendBundle(identity, true)
+
+		By("Check the delivered configmap is deleted")
+		Eventually(func() bool {
+			err := runtimeClient.Get(ctx, client.ObjectKeyFromObject(local), &corev1.ConfigMap{})
+			return apierrors.IsNotFound(err)
+		}, 10*time.Second, 100*time.Millisecond).Should(BeTrue())
+	})
 })
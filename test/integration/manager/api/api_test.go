@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,7 +19,19 @@ import (
 	. "github.com/onsi/gomega"
 	"gorm.io/gorm"
 
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/audit"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/auditevents"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/authorization"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/dryruns"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/events"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/graphql"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/managedclusters"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/managedhubs"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/policies"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/rollouts"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/security"
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/specapply"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/restapis/util"
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
 	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
@@ -398,6 +411,449 @@ var _ = Describe("Nonk8s API Server", Ordered, func() {
 		}, 10*time.Second, 2*time.Second).Should(Succeed())
 	})
 
+	It("Should be able to patch the labels of the managed clusters in bulk", func() {
+		mc1Id := "2aa5547c-c172-47ed-b70b-db468c84d327"
+		mc2Id := "18c9e13c-4488-4dcd-a5ac-1196093abbc0"
+
+		By("Check the dry run lists the clusters selected by the label selector")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("POST", "/global-hub-api/v1/managedclusters/labels?dryRun",
+			bytes.NewBufferString(`{"labelSelector": "vendor=Other",
+				"patches": [{"op": "add", "path": "/metadata/labels/zone", "value": "east"}]}`))
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		job := &managedclusters.LabelJob{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), job)).To(Succeed())
+		Expect(job.DryRun).To(BeTrue())
+		Expect(job.JobID).To(BeEmpty())
+		Expect(job.Items).To(HaveLen(2))
+		Expect(job.Items[0].ClusterName).To(Equal("mc1"))
+		Expect(job.Items[0].State).To(Equal(managedclusters.LabelJobClusterAffected))
+		count := int64(0)
+		Expect(db.Model(&models.ManagedClusterLabelJob{}).Count(&count).Error).To(Succeed())
+		Expect(count).To(Equal(int64(0)))
+
+		By("Check none of the clusters is patched if a cluster isn't found")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("POST", "/global-hub-api/v1/managedclusters/labels",
+			bytes.NewBufferString(fmt.Sprintf(`{"clusterIds": ["%s", "%s"],
+				"patches": [{"op": "add", "path": "/metadata/labels/zone", "value": "east"}]}`, mc1Id, uuid.New())))
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(404))
+
+		By("Patch the clusters listed by the IDs")
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("POST", "/global-hub-api/v1/managedclusters/labels",
+			bytes.NewBufferString(fmt.Sprintf(`{"clusterIds": ["%s", "%s"],
+				"patches": [{"op": "add", "path": "/metadata/labels/zone", "value": "east"}]}`, mc1Id, mc2Id)))
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(201))
+		job = &managedclusters.LabelJob{}
+		Expect(json.Unmarshal(w3.Body.Bytes(), job)).To(Succeed())
+		Expect(job.JobID).NotTo(BeEmpty())
+		Expect(job.Items).To(HaveLen(2))
+		Expect(job.Items[1].State).To(Equal(managedclusters.LabelJobClusterPatched))
+
+		By("Check the labels of both clusters are patched and the existing labels are kept")
+		for _, clusterID := range []string{mc1Id, mc2Id} {
+			managedClusterLabel := models.ManagedClusterLabel{}
+			Expect(db.Where(&models.ManagedClusterLabel{ID: clusterID}).First(&managedClusterLabel).Error).
+				To(Succeed())
+			labels := map[string]string{}
+			Expect(json.Unmarshal(managedClusterLabel.Labels, &labels)).To(Succeed())
+			Expect(labels).To(HaveKeyWithValue("zone", "east"))
+			if clusterID == mc1Id {
+				Expect(labels).To(HaveKeyWithValue("foo", "test"))
+			}
+		}
+
+		By("Check the clusters of the job are pending until the hub reports the labels")
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET", "/global-hub-api/v1/managedclusters/labeljob/"+job.JobID, nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(200))
+		job = &managedclusters.LabelJob{}
+		Expect(json.Unmarshal(w4.Body.Bytes(), job)).To(Succeed())
+		Expect(job.Items).To(HaveLen(2))
+		Expect(job.Items[0].State).To(Equal(managedclusters.LabelJobClusterPending))
+		Expect(job.Patches).To(HaveLen(1))
+
+		w5 := httptest.NewRecorder()
+		req5, err := http.NewRequest("GET", "/global-hub-api/v1/managedclusters/labeljob/"+uuid.New().String(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w5, req5)
+		Expect(w5.Code).To(Equal(404))
+	})
+
+	It("Should be able to get the history of managed cluster", func() {
+		mc2Id := "18c9e13c-4488-4dcd-a5ac-1196093abbc0"
+
+		By("Update the availability and openshift version of the managed cluster")
+		err := db.Exec(`UPDATE status.managed_clusters SET payload = jsonb_set(jsonb_set(payload,
+			'{metadata,labels,openshiftVersion}', '"4.15.1"'),
+			'{status,conditions}', '[{"type": "ManagedClusterConditionAvailable", "status": "False"}]')
+			WHERE cluster_id = ?`, mc2Id).Error
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Check the transitions of the managed cluster are returned")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET",
+			fmt.Sprintf("/global-hub-api/v1/managedcluster/%s/history", mc2Id), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		fmt.Println("Managed Cluster History", w1.Body.String())
+		clusterHistory := &managedclusters.ManagedClusterHistory{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), clusterHistory)).To(Succeed())
+		Expect(clusterHistory.Items).To(HaveLen(2))
+		Expect(clusterHistory.Items[0].Changes).To(ConsistOf("available", "openshift_version", "labels"))
+		Expect(clusterHistory.Items[0].Available).To(Equal("False"))
+		Expect(clusterHistory.Items[0].OpenshiftVersion).To(Equal("4.15.1"))
+		Expect(clusterHistory.Items[1].Changes).To(ConsistOf("created"))
+		Expect(clusterHistory.Items[1].ClusterName).To(Equal("mc2"))
+
+		By("Check the transitions can be filtered by the changed field")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET",
+			fmt.Sprintf("/global-hub-api/v1/managedcluster/%s/history?change=openshift_version&limit=1", mc2Id), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200))
+		clusterHistory = &managedclusters.ManagedClusterHistory{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), clusterHistory)).To(Succeed())
+		Expect(clusterHistory.Items).To(HaveLen(1))
+		Expect(clusterHistory.Items[0].OpenshiftVersion).To(Equal("4.15.1"))
+
+		By("Check the invalid time is rejected")
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET",
+			fmt.Sprintf("/global-hub-api/v1/managedcluster/%s/history?since=yesterday", mc2Id), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(400))
+	})
+
+	It("Should be able to get the managed hubs", func() {
+		By("Create the heartbeats and the cluster info of the managed hubs")
+		heartbeat := time.Now().Truncate(time.Second)
+		err := db.Exec(`INSERT INTO status.leaf_hub_heartbeats (leaf_hub_name, status, last_timestamp) VALUES
+			('hub1', 'active', ?), ('hub2', 'inactive', ?)`, heartbeat, heartbeat.Add(-time.Hour)).Error
+		Expect(err).ToNot(HaveOccurred())
+		err = db.Exec(`INSERT INTO status.leaf_hubs (leaf_hub_name, cluster_id, payload) VALUES
+			('hub1', '6f0b5ad2-3f1c-4f4e-9a14-6f4d2b1c0a11', ?)`,
+			`{"consoleURL": "https://console.hub1", "grafanaURL": "https://grafana.hub1"}`).Error
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Check the managed hubs are listed")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", "/global-hub-api/v1/managedhubs", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		fmt.Println("Managed Hubs", w1.Body.String())
+		hubList := &managedhubs.ManagedHubList{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), hubList)).To(Succeed())
+		Expect(hubList.Items).To(HaveLen(2))
+		Expect(hubList.Items[0].Name).To(Equal("hub1"))
+		Expect(hubList.Items[0].Status).To(Equal("active"))
+		Expect(hubList.Items[0].ClusterID).To(Equal("6f0b5ad2-3f1c-4f4e-9a14-6f4d2b1c0a11"))
+		Expect(hubList.Items[0].ConsoleURL).To(Equal("https://console.hub1"))
+		Expect(hubList.Items[0].GrafanaURL).To(Equal("https://grafana.hub1"))
+		Expect(hubList.Items[0].LastHeartbeat.Equal(heartbeat)).To(BeTrue())
+		Expect(hubList.Items[0].ManagedClusters).To(Equal(int64(2)))
+		Expect(hubList.Items[0].AvailableManagedClusters).To(Equal(int64(0)))
+		Expect(hubList.Items[1].Name).To(Equal("hub2"))
+		Expect(hubList.Items[1].Status).To(Equal("inactive"))
+		Expect(hubList.Items[1].ManagedClusters).To(Equal(int64(0)))
+
+		By("Check the managed hubs can be filtered by the status")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", "/global-hub-api/v1/managedhubs?status=inactive", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200))
+		hubList = &managedhubs.ManagedHubList{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), hubList)).To(Succeed())
+		Expect(hubList.Items).To(HaveLen(1))
+		Expect(hubList.Items[0].Name).To(Equal("hub2"))
+
+		By("Check the managed hub can be got by the name")
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET", "/global-hub-api/v1/managedhub/hub1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(200))
+		hub := &managedhubs.ManagedHub{}
+		Expect(json.Unmarshal(w3.Body.Bytes(), hub)).To(Succeed())
+		Expect(hub.Name).To(Equal("hub1"))
+		Expect(hub.ManagedClusters).To(Equal(int64(2)))
+
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET", "/global-hub-api/v1/managedhub/hub3", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(404))
+
+		By("Check the resync is unavailable before the hub management is started")
+		w5 := httptest.NewRecorder()
+		req5, err := http.NewRequest("POST", "/global-hub-api/v1/managedhub/hub1/resync", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w5, req5)
+		Expect(w5.Code).To(Equal(503))
+
+		w6 := httptest.NewRecorder()
+		req6, err := http.NewRequest("POST", "/global-hub-api/v1/managedhub/hub3/resync", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w6, req6)
+		Expect(w6.Code).To(Equal(404))
+	})
+
+	It("Should only return the managed hubs the user is allowed to access", func() {
+		By("Set up the router with the authorizer which only allows hub1")
+		authorizedRouter, err := restapis.SetupRouter(&restapis.RestApiServerConfig{
+			ServerBasePath: "/global-hub-api/v1",
+			ClusterAPIURL:  testAuthServer.URL,
+			Authorizer:     &hubAuthorizer{allowedHub: "hub1"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Check only hub1 is listed")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", "/global-hub-api/v1/managedhubs", nil)
+		Expect(err).ToNot(HaveOccurred())
+		authorizedRouter.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		hubList := &managedhubs.ManagedHubList{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), hubList)).To(Succeed())
+		Expect(hubList.Items).To(HaveLen(1))
+		Expect(hubList.Items[0].Name).To(Equal("hub1"))
+
+		By("Check hub2 is forbidden")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", "/global-hub-api/v1/managedhub/hub2", nil)
+		Expect(err).ToNot(HaveOccurred())
+		authorizedRouter.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(403))
+
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("POST", "/global-hub-api/v1/managedhub/hub2/resync", nil)
+		Expect(err).ToNot(HaveOccurred())
+		authorizedRouter.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(403))
+
+		By("Check the managed clusters of hub1 are listed")
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET", "/global-hub-api/v1/managedclusters", nil)
+		Expect(err).ToNot(HaveOccurred())
+		authorizedRouter.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(200))
+		Expect(w4.Body.String()).To(ContainSubstring("mc1"))
+	})
+
+	It("Should be able to defer the spec changes of the managed hub by the schedules", func() {
+		By("Create the freeze of hub2 which is always active")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("PUT", "/global-hub-api/v1/managedhub/hub2/schedule/freeze1",
+			bytes.NewBufferString(`{"type":"freeze","cron":"* * * * *","duration":"2m"}`))
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("PUT", "/global-hub-api/v1/managedhub/hub2/schedule/window1",
+			bytes.NewBufferString(`{"type":"window","cron":"0 22 * * 6","duration":"6h","timezone":"Mars/Olympus"}`))
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(400))
+
+		By("Check the schedules of hub2 are listed with the deferral")
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET", "/global-hub-api/v1/managedhub/hub2/schedules", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(200))
+		scheduleList := &managedhubs.HubScheduleList{}
+		Expect(json.Unmarshal(w3.Body.Bytes(), scheduleList)).To(Succeed())
+		Expect(scheduleList.Items).To(HaveLen(1))
+		Expect(scheduleList.Items[0].Name).To(Equal("freeze1"))
+		Expect(scheduleList.Items[0].Duration).To(Equal("2m0s"))
+		Expect(scheduleList.Items[0].Active).To(BeTrue())
+		Expect(scheduleList.Deferral.Deferred).To(BeTrue())
+		Expect(scheduleList.Deferral.Reason).To(ContainSubstring("freeze1"))
+
+		By("Check the managed hub is deferred")
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET", "/global-hub-api/v1/managedhub/hub2", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(200))
+		hub := &managedhubs.ManagedHub{}
+		Expect(json.Unmarshal(w4.Body.Bytes(), hub)).To(Succeed())
+		Expect(hub.Deferral).NotTo(BeNil())
+		Expect(hub.Deferral.Deferred).To(BeTrue())
+
+		By("Check the schedule is deleted")
+		w5 := httptest.NewRecorder()
+		req5, err := http.NewRequest("DELETE", "/global-hub-api/v1/managedhub/hub2/schedule/freeze1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w5, req5)
+		Expect(w5.Code).To(Equal(200))
+
+		w6 := httptest.NewRecorder()
+		req6, err := http.NewRequest("DELETE", "/global-hub-api/v1/managedhub/hub2/schedule/freeze1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w6, req6)
+		Expect(w6.Code).To(Equal(404))
+	})
+
+	It("Should be able to export the managed clusters as csv", func() {
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", "/global-hub-api/v1/managedclusters", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req1.Header.Set("Accept", util.ContentTypeCSV)
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		Expect(w1.Header().Get("Content-Type")).To(Equal(util.ContentTypeCSV))
+		fmt.Println("Exported Managed Clusters", w1.Body.String())
+		Expect(w1.Body.String()).To(HavePrefix("Name,Age\n"))
+		Expect(w1.Body.String()).To(ContainSubstring("mc1,"))
+	})
+
+	It("Should be able to list the events", func() {
+		By("Create the events of the managed clusters and policies")
+		now := time.Now().UTC().Truncate(time.Second)
+		clusterID, eventPolicyID := uuid.New().String(), uuid.New().String()
+		err := db.Exec(`INSERT INTO event.managed_clusters (event_namespace, event_name, cluster_name, cluster_id,
+			leaf_hub_name, message, reason, event_type, created_at) VALUES
+			('cluster1', 'cluster1.event1', 'cluster1', ?, 'hub1', 'the cluster is available', 'Available', 'Normal', ?),
+			('cluster1', 'cluster1.event2', 'cluster1', ?, 'hub1', 'the cluster is unavailable', 'Unavailable',
+				'Warning', ?),
+			('cluster1', 'cluster1.event3', 'cluster1', ?, 'hub1', 'the cluster is available', 'Available', 'Normal', ?),
+			('cluster2', 'cluster2.event1', 'cluster2', ?, 'hub2', 'the cluster is available', 'Available', 'Normal', ?)`,
+			clusterID, now.Add(-3*time.Minute), clusterID, now.Add(-2*time.Minute), clusterID, now.Add(-time.Minute),
+			uuid.New().String(), now.Add(-time.Minute)).Error
+		Expect(err).ToNot(HaveOccurred())
+		err = db.Exec(`INSERT INTO event.local_policies (event_name, event_namespace, policy_id, cluster_id, cluster_name,
+			leaf_hub_name, message, reason, count, compliance, created_at) VALUES
+			('policy1.event1', 'cluster1', ?, ?, 'cluster1', 'hub1', 'NonCompliant; violation', 'PolicyStatusSync', 1,
+				'non_compliant', ?)`, eventPolicyID, clusterID, now.Add(-2*time.Minute)).Error
+		Expect(err).ToNot(HaveOccurred())
+		err = db.Exec(`INSERT INTO event.local_root_policies (event_name, event_namespace, policy_id, leaf_hub_name,
+			message, reason, count, compliance, created_at) VALUES
+			('policy1.event2', 'default', ?, 'hub1', 'Policy default.policy1 was propagated', 'PolicyPropagation', 1,
+				'non_compliant', ?)`, eventPolicyID, now.Add(-time.Minute)).Error
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Check the events of the managed clusters can be filtered")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/events/managedclusters?hub=hub1&reason=Available&since=%s",
+			now.Add(-10*time.Minute).Format(time.RFC3339)), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		fmt.Println("Managed Cluster Events", w1.Body.String())
+		clusterEvents := &events.ManagedClusterEventList{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), clusterEvents)).To(Succeed())
+		Expect(clusterEvents.Items).To(HaveLen(2))
+		Expect(clusterEvents.Items[0].EventName).To(Equal("cluster1.event1"))
+		Expect(clusterEvents.Items[1].EventName).To(Equal("cluster1.event3"))
+		Expect(clusterEvents.Metadata.Continue).To(BeEmpty())
+
+		By("Check the events of the managed clusters can be paged with the continue token")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", "/global-hub-api/v1/events/managedclusters?cluster=cluster1&limit=2", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200))
+		clusterEvents = &events.ManagedClusterEventList{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), clusterEvents)).To(Succeed())
+		Expect(clusterEvents.Items).To(HaveLen(2))
+		Expect(clusterEvents.Items[1].EventName).To(Equal("cluster1.event2"))
+		Expect(clusterEvents.Metadata.Continue).NotTo(BeEmpty())
+
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/events/managedclusters?cluster=cluster1&limit=2&continue=%s",
+			clusterEvents.Metadata.Continue), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(200))
+		clusterEvents = &events.ManagedClusterEventList{}
+		Expect(json.Unmarshal(w3.Body.Bytes(), clusterEvents)).To(Succeed())
+		Expect(clusterEvents.Items).To(HaveLen(1))
+		Expect(clusterEvents.Items[0].EventName).To(Equal("cluster1.event3"))
+		Expect(clusterEvents.Metadata.Continue).To(BeEmpty())
+
+		By("Check the events of the policy include the events of the root policy")
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET",
+			fmt.Sprintf("/global-hub-api/v1/events/policies?policy=%s", eventPolicyID), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(200))
+		fmt.Println("Policy Events", w4.Body.String())
+		policyEvents := &events.PolicyEventList{}
+		Expect(json.Unmarshal(w4.Body.Bytes(), policyEvents)).To(Succeed())
+		Expect(policyEvents.Items).To(HaveLen(2))
+		Expect(*policyEvents.Items[0].ClusterName).To(Equal("cluster1"))
+		Expect(policyEvents.Items[1].ClusterName).To(BeNil())
+		Expect(policyEvents.Items[1].Reason).To(Equal("PolicyPropagation"))
+
+		w5 := httptest.NewRecorder()
+		req5, err := http.NewRequest("GET",
+			fmt.Sprintf("/global-hub-api/v1/events/policies?policy=%s&message=VIOLATION", eventPolicyID), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w5, req5)
+		Expect(w5.Code).To(Equal(200))
+		policyEvents = &events.PolicyEventList{}
+		Expect(json.Unmarshal(w5.Body.Bytes(), policyEvents)).To(Succeed())
+		Expect(policyEvents.Items).To(HaveLen(1))
+		Expect(policyEvents.Items[0].EventName).To(Equal("policy1.event1"))
+
+		By("Check the watch streams the late event of a slower hub")
+		w6 := CreateTestResponseRecorder()
+		req6, err := http.NewRequest("GET", "/global-hub-api/v1/events/managedclusters?watch&hub=hub3", nil)
+		Expect(err).ToNot(HaveOccurred())
+		done := make(chan struct{})
+		go func() {
+			router.ServeHTTP(w6, req6)
+			close(done)
+		}()
+		time.Sleep(time.Second)
+		// the event is created an hour ago on the hub, which is before the events already inserted
+		err = db.Exec(`INSERT INTO event.managed_clusters (event_namespace, event_name, cluster_name, cluster_id,
+			leaf_hub_name, message, reason, event_type, created_at) VALUES
+			('cluster3', 'cluster3.late', 'cluster3', ?, 'hub3', 'the cluster is available', 'Available', 'Normal', ?)`,
+			uuid.New().String(), now.Add(-time.Hour)).Error
+		Expect(err).ToNot(HaveOccurred())
+		time.Sleep(6 * time.Second)
+		w6.closeClient()
+		<-done
+		fmt.Println("Watched Managed Cluster Events", w6.Body.String())
+		Expect(w6.Code).To(Equal(200))
+		Expect(w6.Body.String()).To(ContainSubstring("cluster3.late"))
+		Expect(w6.Body.String()).NotTo(ContainSubstring("cluster1.event1"))
+
+		By("Check the invalid queries are rejected")
+		for _, url := range []string{
+			"/global-hub-api/v1/events/managedclusters?since=yesterday",
+			"/global-hub-api/v1/events/managedclusters?limit=-1",
+			"/global-hub-api/v1/events/managedclusters?continue=invalid",
+			"/global-hub-api/v1/events/policies?policy=policy1",
+		} {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", url, nil)
+			Expect(err).ToNot(HaveOccurred())
+			router.ServeHTTP(w, req)
+			Expect(w.Code).To(Equal(400), url)
+		}
+	})
+
 	It("Should be able to list policies", func() {
 		plc1ID = uuid.New().String()
 		pr1ID, pb1ID := uuid.New().String(), uuid.New().String()
@@ -1000,6 +1456,185 @@ var _ = Describe("Nonk8s API Server", Ordered, func() {
 		}
 	})
 
+	It("Should be able to get policy compliance history", func() {
+		today := time.Now().Format("2006-01-02")
+
+		By("Check the compliance history is recorded when the compliance is created")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/policy/%s/compliancehistory", plc1ID), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		fmt.Println("Policy Compliance History", w1.Body.String())
+		historyList := &policies.PolicyComplianceHistoryList{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), historyList)).To(Succeed())
+		Expect(historyList.Items).To(HaveLen(2))
+		for _, item := range historyList.Items {
+			Expect(item.Date).To(Equal(today))
+			Expect(item.PolicyName).To(Equal("policy-config-audit"))
+			Expect(item.ChangedFrequency).To(Equal(0))
+		}
+
+		By("Check the compliance history can be filtered by the cluster")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/policy/%s/compliancehistory?hub=hub1&cluster=mc1", plc1ID), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200))
+		historyList = &policies.PolicyComplianceHistoryList{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), historyList)).To(Succeed())
+		Expect(historyList.Items).To(HaveLen(1))
+		Expect(historyList.Items[0].Compliance).To(Equal("non_compliant"))
+
+		By("Check the invalid date is rejected")
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/policy/%s/compliancehistory?startDate=20240101", plc1ID), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(400))
+
+		By("Check the compliance changes are listed after the compliance is updated")
+		err = db.Exec(`UPDATE status.compliance SET compliance = 'non_compliant'
+			WHERE policy_id = ? AND cluster_name = 'mc2' AND leaf_hub_name = 'hub1'`, plc1ID).Error
+		Expect(err).ToNot(HaveOccurred())
+
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET", "/global-hub-api/v1/policies/compliancechanges?hub=hub1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(200))
+		fmt.Println("Policy Compliance Changes", w4.Body.String())
+		historyList = &policies.PolicyComplianceHistoryList{}
+		Expect(json.Unmarshal(w4.Body.Bytes(), historyList)).To(Succeed())
+		Expect(historyList.Items).To(HaveLen(1))
+		Expect(historyList.Items[0].ClusterName).To(Equal("mc2"))
+		Expect(historyList.Items[0].Compliance).To(Equal("non_compliant"))
+		Expect(historyList.Items[0].ChangedFrequency).To(Equal(1))
+
+		By("Restore the compliance of the policy")
+		err = db.Exec(`UPDATE status.compliance SET compliance = 'compliant'
+			WHERE policy_id = ? AND cluster_name = 'mc2' AND leaf_hub_name = 'hub1'`, plc1ID).Error
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should be able to get the compliance timeline of the local policies", func() {
+		By("Create the daily compliance of the local policy across the months")
+		now := time.Now()
+		firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		lastDay, secondDay := firstDay.AddDate(0, 0, -1), firstDay.AddDate(0, 0, 1)
+		localPolicyID, cluster1ID, cluster2ID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+		err := db.Exec(`INSERT INTO history.local_compliance (policy_id, cluster_id, leaf_hub_name, compliance_date,
+			compliance, compliance_changed_frequency) VALUES
+			(?, ?, 'hub-timeline', ?, 'compliant', 0), (?, ?, 'hub-timeline', ?, 'compliant', 0),
+			(?, ?, 'hub-timeline', ?, 'compliant', 0), (?, ?, 'hub-timeline', ?, 'non_compliant', 1),
+			(?, ?, 'hub-timeline', ?, 'compliant', 0), (?, ?, 'hub-timeline', ?, 'compliant', 1)`,
+			localPolicyID, cluster1ID, lastDay, localPolicyID, cluster2ID, lastDay,
+			localPolicyID, cluster1ID, firstDay, localPolicyID, cluster2ID, firstDay,
+			localPolicyID, cluster1ID, secondDay, localPolicyID, cluster2ID, secondDay).Error
+		Expect(err).ToNot(HaveOccurred())
+		dates := fmt.Sprintf("startDate=%s&endDate=%s", lastDay.Format("2006-01-02"), secondDay.Format("2006-01-02"))
+
+		By("Check the daily compliance timeline of the policy")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/localpolicy/%s/compliancetimeline?%s", localPolicyID, dates), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		fmt.Println("Policy Compliance Timeline", w1.Body.String())
+		timeline := &policies.ComplianceTimeline{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), timeline)).To(Succeed())
+		Expect(timeline.Interval).To(Equal("day"))
+		Expect(timeline.Items).To(HaveLen(3))
+		Expect(timeline.Items[0].Date).To(Equal(lastDay.Format("2006-01-02")))
+		Expect(timeline.Items[0].Compliant).To(Equal(2))
+		Expect(timeline.Items[1].Compliant).To(Equal(1))
+		Expect(timeline.Items[1].NonCompliant).To(Equal(1))
+		Expect(timeline.Items[1].Changes).To(Equal(1))
+		Expect(timeline.Items[1].Rate).To(Equal(0.5))
+		Expect(timeline.Items[2].Compliant).To(Equal(2))
+
+		By("Check the monthly compliance timeline counts the worst compliance of the month")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/localpolicy/%s/compliancetimeline?%s&interval=month", localPolicyID, dates), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200))
+		timeline = &policies.ComplianceTimeline{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), timeline)).To(Succeed())
+		Expect(timeline.Items).To(HaveLen(2))
+		Expect(timeline.Items[0].Date).To(Equal(lastDay.Format("2006-01") + "-01"))
+		Expect(timeline.Items[0].Compliant).To(Equal(2))
+		Expect(timeline.Items[1].Date).To(Equal(firstDay.Format("2006-01-02")))
+		Expect(timeline.Items[1].Compliant).To(Equal(1))
+		Expect(timeline.Items[1].NonCompliant).To(Equal(1))
+		Expect(timeline.Items[1].Changes).To(Equal(2))
+
+		By("Check the worst compliance of the month follows the daily history: pending > non_compliant")
+		pendingPolicyID := uuid.New().String()
+		err = db.Exec(`INSERT INTO history.local_compliance (policy_id, cluster_id, leaf_hub_name, compliance_date,
+			compliance, compliance_changed_frequency) VALUES
+			(?, ?, 'hub-pending', ?, 'non_compliant', 0), (?, ?, 'hub-pending', ?, 'pending', 1)`,
+			pendingPolicyID, cluster1ID, firstDay, pendingPolicyID, cluster1ID, secondDay).Error
+		Expect(err).ToNot(HaveOccurred())
+		wp := httptest.NewRecorder()
+		reqp, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/localpolicy/%s/compliancetimeline?%s&interval=month", pendingPolicyID, dates), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(wp, reqp)
+		Expect(wp.Code).To(Equal(200))
+		timeline = &policies.ComplianceTimeline{}
+		Expect(json.Unmarshal(wp.Body.Bytes(), timeline)).To(Succeed())
+		Expect(timeline.Items).To(HaveLen(1))
+		Expect(timeline.Items[0].Pending).To(Equal(1))
+		Expect(timeline.Items[0].NonCompliant).To(Equal(0))
+
+		By("Check the compliance timeline of the cluster")
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/managedcluster/%s/compliancetimeline?%s", cluster2ID, dates), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(200))
+		timeline = &policies.ComplianceTimeline{}
+		Expect(json.Unmarshal(w3.Body.Bytes(), timeline)).To(Succeed())
+		Expect(timeline.Items).To(HaveLen(3))
+		Expect(timeline.Items[1].NonCompliant).To(Equal(1))
+		Expect(timeline.Items[1].Compliant).To(Equal(0))
+
+		By("Check the compliance timeline of the hub in csv")
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET", fmt.Sprintf(
+			"/global-hub-api/v1/managedhub/hub-timeline/compliancetimeline?%s&interval=month&format=csv", dates), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(200))
+		fmt.Println("Hub Compliance Timeline", w4.Body.String())
+		Expect(w4.Header().Get("Content-Type")).To(Equal("text/csv"))
+		Expect(w4.Body.String()).To(Equal(fmt.Sprintf(
+			"date,compliant,pending,unknown,non_compliant,changes,compliance_rate\n"+
+				"%s,2,0,0,0,0,1.0000\n%s,1,0,0,1,2,0.5000\n",
+			lastDay.Format("2006-01")+"-01", firstDay.Format("2006-01-02"))))
+
+		By("Check the invalid queries are rejected")
+		for _, url := range []string{
+			fmt.Sprintf("/global-hub-api/v1/localpolicy/%s/compliancetimeline?interval=year", localPolicyID),
+			fmt.Sprintf("/global-hub-api/v1/localpolicy/%s/compliancetimeline?format=xml", localPolicyID),
+			"/global-hub-api/v1/localpolicy/policy1/compliancetimeline",
+			"/global-hub-api/v1/managedcluster/cluster1/compliancetimeline",
+		} {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", url, nil)
+			Expect(err).ToNot(HaveOccurred())
+			router.ServeHTTP(w, req)
+			Expect(w.Code).To(Equal(400), url)
+		}
+	})
+
 	It("Should be able to list subscriptions", func() {
 		sub1ID, sub2ID = uuid.New().String(), uuid.New().String()
 		subscription1, subscription2 := `{
@@ -1442,7 +2077,326 @@ var _ = Describe("Nonk8s API Server", Ordered, func() {
 		Expect(w1.Body.String()).Should(MatchJSON(subscriptionReportStr))
 	})
 
+	It("Should be able to list the security alert counts", func() {
+		By("Create the alert counts of the managed hubs")
+		Expect(db.Create([]models.SecurityAlertCounts{
+			{
+				HubName: "hub1", Source: "stackrox/central", Low: 1, Medium: 2, High: 3, Critical: 4,
+				DetailURL: "https://central-stackrox.apps.hub1/main/violations",
+			},
+			{
+				HubName: "hub2", Source: "stackrox/central", Low: 5,
+				DetailURL: "https://central-stackrox.apps.hub2/main/violations",
+			},
+		}).Error).To(Succeed())
+
+		By("Check the alert counts are listed with the totals")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", "/global-hub-api/v1/security/alertcounts", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		alertCounts := &security.AlertCountsList{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), alertCounts)).To(Succeed())
+		Expect(alertCounts.Items).To(HaveLen(2))
+		Expect(alertCounts.Items[0].HubName).To(Equal("hub1"))
+		Expect(alertCounts.Items[0].Total).To(Equal(10))
+		Expect(alertCounts.Items[0].DetailURL).To(Equal("https://central-stackrox.apps.hub1/main/violations"))
+		Expect(alertCounts.Total).To(Equal(security.AlertCounts{Low: 6, Medium: 2, High: 3, Critical: 4, Total: 15}))
+
+		By("Check the alert counts are filtered by the hub and severity")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", "/global-hub-api/v1/security/alertcounts?severity=critical", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200))
+		alertCounts = &security.AlertCountsList{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), alertCounts)).To(Succeed())
+		Expect(alertCounts.Items).To(HaveLen(1))
+		Expect(alertCounts.Items[0].HubName).To(Equal("hub1"))
+
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET", "/global-hub-api/v1/security/alertcounts?hub=hub2&source=stackrox/central", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(200))
+		alertCounts = &security.AlertCountsList{}
+		Expect(json.Unmarshal(w3.Body.Bytes(), alertCounts)).To(Succeed())
+		Expect(alertCounts.Items).To(HaveLen(1))
+		Expect(alertCounts.Total.Total).To(Equal(5))
+
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET", "/global-hub-api/v1/security/alertcounts?severity=unknown", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(400))
+	})
+
+	It("Should be able to list the audit events of the label patches", func() {
+		By("Check the label patches of the managed cluster are recorded")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET",
+			"/global-hub-api/v1/audit/events?source=restapi&resource=managedclusters&action=patch", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		auditEvents := &auditevents.AuditEventList{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), auditEvents)).To(Succeed())
+		Expect(len(auditEvents.Items)).To(BeNumerically(">=", 2))
+		for _, event := range auditEvents.Items {
+			Expect(event.User).To(Equal("kube:admin"))
+			Expect(event.Request).NotTo(BeNil())
+			Expect(event.Objects).NotTo(BeEmpty())
+		}
+
+		By("Check the oldest patch adds the foo=bar label")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", "/global-hub-api/v1/audit/events?resource=managedclusters&since="+
+			time.Now().Add(-time.Hour).Format(time.RFC3339)+"&limit=5000", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200))
+		auditEvents = &auditevents.AuditEventList{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), auditEvents)).To(Succeed())
+		first := auditEvents.Items[len(auditEvents.Items)-1]
+		Expect(first.Result).To(Equal(audit.ResultSuccess))
+		Expect(first.Objects).To(HaveLen(1))
+		Expect(first.Objects[0].ID).To(Equal("2aa5547c-c172-47ed-b70b-db468c84d327"))
+		Expect(first.Objects[0].After).To(HaveKeyWithValue("foo", "bar"))
+
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET", "/global-hub-api/v1/audit/events?since=yesterday", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(400))
+	})
+
+	It("Should be able to list the apply results of the global resources", func() {
+		By("Create the apply results of the global policy on the managed hubs")
+		Expect(db.Create([]models.SpecApplyResult{
+			{
+				LeafHubName: "hub1", APIVersion: "policy.open-cluster-management.io/v1", Kind: "Policy",
+				Namespace: "default", Name: "policy1", UID: "global-policy1", Result: "applied",
+				ResourceVersion: "100", UpdatedAt: time.Now(),
+			},
+			{
+				LeafHubName: "hub2", APIVersion: "policy.open-cluster-management.io/v1", Kind: "Policy",
+				Namespace: "default", Name: "policy1", UID: "global-policy1", Result: "failed",
+				Error: "namespaces \"default\" is forbidden", UpdatedAt: time.Now(),
+			},
+		}).Error).To(Succeed())
+
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", "/global-hub-api/v1/specapplyresults?uid=global-policy1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		results := &specapply.SpecApplyResultList{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), results)).To(Succeed())
+		Expect(results.Items).To(HaveLen(2))
+		Expect(results.Items[0].HubName).To(Equal("hub1"))
+		Expect(results.Items[0].ResourceVersion).To(Equal("100"))
+
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", "/global-hub-api/v1/specapplyresults?kind=Policy&result=failed", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200))
+		results = &specapply.SpecApplyResultList{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), results)).To(Succeed())
+		Expect(results.Items).To(HaveLen(1))
+		Expect(results.Items[0].HubName).To(Equal("hub2"))
+		Expect(results.Items[0].Error).To(ContainSubstring("forbidden"))
+
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("GET", "/global-hub-api/v1/specapplyresults?result=unknown", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(400))
+	})
+
+	It("Should be able to pause, resume and abort the rollouts of the global resources", func() {
+		By("Create the paused rollout of the global policy")
+		Expect(db.Create(&models.SpecRollout{
+			UID: "global-policy2", Kind: "Policy", Namespace: "default", Name: "policy2", Digest: "digest2",
+			Payload: []byte(`{}`), State: models.RolloutPaused, Wave: 1, WaveStartedAt: time.Now(),
+			Message: "the wave canary is unhealthy",
+		}).Error).To(Succeed())
+
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("GET", "/global-hub-api/v1/rollouts?namespace=default&state=paused", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200))
+		list := &rollouts.RolloutList{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(list.Items[0].Wave).To(Equal(1))
+		Expect(list.Items[0].Message).To(ContainSubstring("unhealthy"))
+
+		By("Resume the rollout")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("POST", "/global-hub-api/v1/rollout/global-policy2/resume", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200), w2.Body.String())
+		row := &models.SpecRollout{}
+		Expect(db.Where("uid = ?", "global-policy2").First(row).Error).To(Succeed())
+		Expect(row.State).To(Equal(models.RolloutProgressing))
+		Expect(row.Message).To(BeEmpty())
+
+		By("The progressing rollout can't be resumed")
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("POST", "/global-hub-api/v1/rollout/global-policy2/resume", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(409))
+
+		By("Abort the rollout")
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("POST", "/global-hub-api/v1/rollout/global-policy2/abort", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(200), w4.Body.String())
+		Expect(db.Where("uid = ?", "global-policy2").First(row).Error).To(Succeed())
+		Expect(row.State).To(Equal(models.RolloutAborted))
+
+		w5 := httptest.NewRecorder()
+		req5, err := http.NewRequest("POST", "/global-hub-api/v1/rollout/unknown/pause", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w5, req5)
+		Expect(w5.Code).To(Equal(404))
+	})
+
+	It("Should be able to dry run the global resources and get the results", func() {
+		By("Create the dry run of the global policy")
+		w1 := httptest.NewRecorder()
+		req1, err := http.NewRequest("POST", "/global-hub-api/v1/dryruns", bytes.NewBufferString(`{"objects":[{
+			"apiVersion":"policy.open-cluster-management.io/v1","kind":"Policy",
+			"metadata":{"name":"policy3","namespace":"default"},"spec":{"disabled":false}}],"hubs":["hub1"]}`))
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(201), w1.Body.String())
+		created := &dryruns.CreatedDryRun{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), created)).To(Succeed())
+		dryRun := &models.SpecDryRun{}
+		Expect(db.Where("id = ?", created.ID).First(dryRun).Error).To(Succeed())
+		Expect(dryRun.SentAt).To(BeNil())
+
+		By("Report the result of the hub")
+		Expect(db.Create(&models.SpecDryRunResult{
+			DryRunID: created.ID, LeafHubName: "hub1", APIVersion: "policy.open-cluster-management.io/v1",
+			Kind: "Policy", Namespace: "default", Name: "policy3", Result: "would-update",
+			Diff: []byte(`[{"path":"spec.disabled","before":true,"after":false}]`), ReportedAt: time.Now(),
+		}).Error).To(Succeed())
+
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", "/global-hub-api/v1/dryrun/"+created.ID+"?hub=hub1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(200), w2.Body.String())
+		got := &dryruns.DryRun{}
+		Expect(json.Unmarshal(w2.Body.Bytes(), got)).To(Succeed())
+		Expect(got.Results).To(HaveLen(1))
+		Expect(got.Results[0].Result).To(Equal("would-update"))
+		Expect(got.Results[0].Diff).To(HaveLen(1))
+		Expect(got.Results[0].Diff[0].Path).To(Equal("spec.disabled"))
+
+		By("The invalid dry runs are rejected")
+		w3 := httptest.NewRecorder()
+		req3, err := http.NewRequest("POST", "/global-hub-api/v1/dryruns",
+			bytes.NewBufferString(`{"objects":[{"kind":"Policy"}]}`))
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w3, req3)
+		Expect(w3.Code).To(Equal(400))
+
+		w4 := httptest.NewRecorder()
+		req4, err := http.NewRequest("GET", "/global-hub-api/v1/dryrun/"+uuid.New().String(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w4, req4)
+		Expect(w4.Code).To(Equal(404))
+	})
+
+	It("Should be able to query the managed hubs with the nested clusters and policies by graphql", func() {
+		By("Query the non-compliant policies of the clusters of the hub")
+		w1 := httptest.NewRecorder()
+		body, err := json.Marshal(graphql.Request{
+			Query: `query($hub: String!) {
+				managedHubs(name: $hub) {
+					name
+					managedClusters {
+						name
+						policies(compliance: "non_compliant") { compliance policy { id local } events(limit: 5) { name } }
+					}
+					securityAlertCounts { total }
+				}
+			}`,
+			Variables: map[string]interface{}{"hub": "hub1"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		req1, err := http.NewRequest("POST", "/global-hub-api/v1/graphql", bytes.NewBuffer(body))
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w1, req1)
+		Expect(w1.Code).To(Equal(200), w1.Body.String())
+		fmt.Println("GraphQL Response", w1.Body.String())
+
+		type compliance struct {
+			Compliance string `json:"compliance"`
+			Policy     struct {
+				ID    string `json:"id"`
+				Local bool   `json:"local"`
+			} `json:"policy"`
+		}
+		result := &struct {
+			Data struct {
+				ManagedHubs []struct {
+					Name            string `json:"name"`
+					ManagedClusters []struct {
+						Name     string       `json:"name"`
+						Policies []compliance `json:"policies"`
+					} `json:"managedClusters"`
+					SecurityAlertCounts []struct {
+						Total int `json:"total"`
+					} `json:"securityAlertCounts"`
+				} `json:"managedHubs"`
+			} `json:"data"`
+		}{}
+		Expect(json.Unmarshal(w1.Body.Bytes(), result)).To(Succeed())
+		Expect(result.Data.ManagedHubs).To(HaveLen(1))
+		hub := result.Data.ManagedHubs[0]
+		Expect(hub.Name).To(Equal("hub1"))
+		Expect(hub.SecurityAlertCounts).To(HaveLen(1))
+		Expect(hub.SecurityAlertCounts[0].Total).To(Equal(10))
+		clusterPolicies := map[string][]compliance{}
+		for _, cluster := range hub.ManagedClusters {
+			clusterPolicies[cluster.Name] = cluster.Policies
+		}
+		Expect(clusterPolicies).To(HaveKey("mc1"))
+		Expect(clusterPolicies["mc1"]).To(HaveLen(1))
+		Expect(clusterPolicies["mc1"][0].Compliance).To(Equal("non_compliant"))
+		Expect(clusterPolicies["mc1"][0].Policy.ID).To(Equal(plc1ID))
+		Expect(clusterPolicies["mc1"][0].Policy.Local).To(BeFalse())
+		Expect(clusterPolicies["mc2"]).To(BeEmpty())
+
+		By("Check the mutation is rejected")
+		w2 := httptest.NewRecorder()
+		req2, err := http.NewRequest("GET", "/global-hub-api/v1/graphql?query="+
+			url.QueryEscape(`mutation { managedHubs { name } }`), nil)
+		Expect(err).ToNot(HaveOccurred())
+		router.ServeHTTP(w2, req2)
+		Expect(w2.Code).To(Equal(400))
+	})
+
 	AfterAll(func() {
 		database.CloseGorm(database.GetSqlDb())
 	})
 })
+
+// hubAuthorizer only allows to access the resources of the hub
+type hubAuthorizer struct {
+	allowedHub string
+}
+
+func (a *hubAuthorizer) Authorize(ctx context.Context, attrs *authorization.Attributes) (bool, error) {
+	return attrs.LeafHub == a.allowedHub, nil
+}
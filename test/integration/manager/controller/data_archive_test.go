@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/stolostron/multicluster-global-hub/pkg/database/archive"
+	"github.com/stolostron/multicluster-global-hub/test/integration/utils/testminio"
+)
+
+var _ = Describe("data archive", Ordered, func() {
+	const (
+		tableName     = "event.local_root_policies"
+		partitionName = "event.local_root_policies_2019_01"
+		restoreSchema = "archive_restore"
+	)
+	var testMinio *testminio.TestMinio
+	var archiver *archive.Archiver
+	var manifest *archive.Manifest
+
+	type rootPolicyEvent struct {
+		EventName string
+		PolicyID  string
+		Message   string
+		Count     int
+		CreatedAt time.Time
+	}
+	policyID := uuid.New().String()
+	createdAt := time.Date(2019, 1, 15, 8, 30, 0, 123456000, time.UTC)
+	expectedEvents := []rootPolicyEvent{
+		{"policy1.event1", policyID, "Policy default.policy1 was propagated", 1, createdAt},
+		{"policy1.event2", policyID, "the message with the \"quote\", comma\nand the new line", 2, createdAt},
+		{"policy1.event3", policyID, "", 3, createdAt.Add(time.Hour)},
+	}
+
+	BeforeAll(func() {
+		By("Start the minio server")
+		var err error
+		testMinio, err = testminio.NewTestMinio()
+		Expect(err).NotTo(HaveOccurred())
+
+		archiver, err = archive.NewArchiver(&archive.Config{
+			Endpoint:        testMinio.Endpoint,
+			Bucket:          "test-archive",
+			Insecure:        true,
+			AccessKeyID:     testMinio.AccessKeyID,
+			SecretAccessKey: testMinio.SecretAccessKey,
+		}, testPostgres.URI, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Create the partition with the events")
+		Expect(createPartitionTable(tableName, createdAt)).To(Succeed())
+		for _, e := range expectedEvents {
+			err := db.Exec(`INSERT INTO event.local_root_policies (event_name, event_namespace, policy_id,
+				leaf_hub_name, message, reason, count, compliance, created_at) VALUES
+				(?, 'default', ?, 'hub1', ?, 'PolicyPropagation', ?, 'compliant', ?)`,
+				e.EventName, e.PolicyID, e.Message, e.Count, e.CreatedAt).Error
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	AfterAll(func() {
+		Expect(db.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", restoreSchema)).Error).To(Succeed())
+		Expect(db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", partitionName)).Error).To(Succeed())
+		Expect(testMinio.Stop()).To(Succeed())
+	})
+
+	It("should archive the partition to the object storage", func() {
+		var err error
+		manifest, err = archiver.Archive(ctx, tableName, partitionName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Rows).To(Equal(int64(len(expectedEvents))))
+		Expect(manifest.Object).To(Equal(
+			"data-retention/event.local_root_policies/event.local_root_policies_2019_01.csv.gz"))
+		Expect(manifest.Key).To(Equal(
+			"data-retention/event.local_root_policies/event.local_root_policies_2019_01.manifest.json"))
+		Expect(manifest.SHA256).To(HaveLen(64))
+		Expect(manifest.Columns).NotTo(BeEmpty())
+
+		By("Drop the archived partition")
+		Expect(db.Exec(fmt.Sprintf("DROP TABLE %s", partitionName)).Error).To(Succeed())
+	})
+
+	It("should restore the archive into the scratch schema", func() {
+		identifier, err := archiver.Restore(ctx, manifest.Key, restoreSchema)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identifier).To(Equal(`"archive_restore"."local_root_policies_2019_01"`))
+
+		restored := []rootPolicyEvent{}
+		err = db.Raw(fmt.Sprintf(`SELECT event_name, policy_id, message, count, created_at FROM %s
+			ORDER BY event_name`, identifier)).Scan(&restored).Error
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored).To(HaveLen(len(expectedEvents)))
+		for i, e := range expectedEvents {
+			Expect(restored[i].EventName).To(Equal(e.EventName))
+			Expect(restored[i].PolicyID).To(Equal(e.PolicyID))
+			Expect(restored[i].Message).To(Equal(e.Message))
+			Expect(restored[i].Count).To(Equal(e.Count))
+			Expect(restored[i].CreatedAt.Equal(e.CreatedAt)).To(BeTrue(), "%v != %v", restored[i].CreatedAt, e.CreatedAt)
+		}
+	})
+
+	It("should reject the archive which doesn't match the checksum", func() {
+		By("Replace the archive with the one without rows")
+		buf := &bytes.Buffer{}
+		writer := gzip.NewWriter(buf)
+		_, err := fmt.Fprintln(writer, "event_name")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		client, err := minio.New(testMinio.Endpoint, &minio.Options{
+			Creds: credentials.NewStaticV4(testMinio.AccessKeyID, testMinio.SecretAccessKey, ""),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.PutObject(ctx, "test-archive", manifest.Object, bytes.NewReader(buf.Bytes()),
+			int64(buf.Len()), minio.PutObjectOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = archiver.Restore(ctx, manifest.Key, "archive_restore_tampered")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("checksum"))
+
+		By("The restore is rolled back")
+		var exists bool
+		Expect(db.Raw("SELECT to_regclass('archive_restore_tampered.local_root_policies_2019_01') IS NOT NULL").
+			Row().Scan(&exists)).To(Succeed())
+		Expect(exists).To(BeFalse())
+	})
+})
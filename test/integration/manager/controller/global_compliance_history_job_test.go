@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-co-op/gocron"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/cronjob/task"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+)
+
+// go test ./test/integration/manager/controller -v -ginkgo.focus "GlobalComplianceHistory"
+var _ = Describe("GlobalComplianceHistory", Ordered, func() {
+	policyID := "00000000-0000-0000-0000-000000000011"
+
+	It("record the compliance changes of the status.compliance to the history.compliance", func() {
+		By("Create the data to the status.compliance table")
+		err := db.Exec(`
+		INSERT INTO "status"."compliance" ("policy_id", "cluster_name", "leaf_hub_name", "error", "compliance") VALUES
+		(?, 'managedcluster-1', 'hub4', 'none', 'compliant'),
+		(?, 'managedcluster-2', 'hub4', 'none', 'compliant'),
+		(?, 'managedcluster-3', 'hub4', 'none', 'non_compliant');
+		`, policyID, policyID, policyID).Error
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Check the compliance is recorded by the trigger")
+		Eventually(func() error {
+			histories := []models.ComplianceHistory{}
+			if err := db.Where("policy_id = ?", policyID).Find(&histories).Error; err != nil {
+				return err
+			}
+			if len(histories) != 3 {
+				return fmt.Errorf("expected 3 items, but got %d in the compliance history", len(histories))
+			}
+			return nil
+		}, 10*time.Second, 2*time.Second).ShouldNot(HaveOccurred())
+
+		By("Update the compliance of the managedcluster-1")
+		err = db.Exec(`UPDATE status.compliance SET compliance = 'non_compliant'
+			WHERE policy_id = ? AND cluster_name = 'managedcluster-1'`, policyID).Error
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() error {
+			history := models.ComplianceHistory{}
+			if err := db.Where("policy_id = ? AND cluster_name = ?", policyID, "managedcluster-1").
+				First(&history).Error; err != nil {
+				return err
+			}
+			if history.Compliance != "non_compliant" || history.ComplianceChangedFrequency != 1 {
+				return fmt.Errorf("expected non_compliant: 1, but got %s: %d", history.Compliance,
+					history.ComplianceChangedFrequency)
+			}
+			return nil
+		}, 10*time.Second, 2*time.Second).ShouldNot(HaveOccurred())
+
+		By("Update the compliance of the managedcluster-1 back to compliant")
+		err = db.Exec(`UPDATE status.compliance SET compliance = 'compliant'
+			WHERE policy_id = ? AND cluster_name = 'managedcluster-1'`, policyID).Error
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() error {
+			history := models.ComplianceHistory{}
+			if err := db.Where("policy_id = ? AND cluster_name = ?", policyID, "managedcluster-1").
+				First(&history).Error; err != nil {
+				return err
+			}
+			// the compliance of the day is the worst state of the day
+			if history.Compliance != "non_compliant" || history.ComplianceChangedFrequency != 2 {
+				return fmt.Errorf("expected non_compliant: 2, but got %s: %d", history.Compliance,
+					history.ComplianceChangedFrequency)
+			}
+			return nil
+		}, 10*time.Second, 2*time.Second).ShouldNot(HaveOccurred())
+	})
+
+	It("snapshot the status.compliance to the history.compliance", func() {
+		By("Delete the compliance history to simulate the unchanged compliance of a new day")
+		err := db.Where("policy_id = ?", policyID).Delete(&models.ComplianceHistory{}).Error
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Create the sync job")
+		s := gocron.NewScheduler(time.UTC)
+		complianceJob, err := s.Every(1).Day().DoWithJobDetails(task.GlobalComplianceHistory, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		fmt.Println("set global compliance job", "scheduleAt", complianceJob.ScheduledAtTime())
+		s.StartAsync()
+		defer s.Clear()
+
+		By("Check whether the data is synced to the history.compliance table")
+		Eventually(func() error {
+			histories := []models.ComplianceHistory{}
+			if err := db.Where("policy_id = ?", policyID).Find(&histories).Error; err != nil {
+				return err
+			}
+			if len(histories) != 3 {
+				return fmt.Errorf("expected 3 items, but got %d in the compliance history", len(histories))
+			}
+			for _, history := range histories {
+				if history.ComplianceChangedFrequency != 0 {
+					return fmt.Errorf("the snapshot shouldn't change the frequency: %+v", history)
+				}
+			}
+			return nil
+		}, 10*time.Second, 2*time.Second).ShouldNot(HaveOccurred())
+
+		By("Check whether the job log is created")
+		Eventually(func() error {
+			var count int64
+			if err := db.Model(&models.LocalComplianceJobLog{}).Where("name = ?", task.GlobalComplianceTaskName).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count < 1 {
+				return fmt.Errorf("the job log of %s isn't created", task.GlobalComplianceTaskName)
+			}
+			return nil
+		}, 10*time.Second, 2*time.Second).ShouldNot(HaveOccurred())
+	})
+})
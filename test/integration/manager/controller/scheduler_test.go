@@ -10,6 +10,7 @@ import (
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/configs"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/cronjob"
 	"github.com/stolostron/multicluster-global-hub/manager/pkg/processes/cronjob/task"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/archive"
 )
 
 var _ = Describe("scheduler", func() {
@@ -29,7 +30,7 @@ var _ = Describe("scheduler", func() {
 		Expect(err).To(Succeed())
 
 		_, err = scheduler.Every(1).Month(1, 15, 28).At("00:00").Tag(task.RetentionTaskName).
-			DoWithJobDetails(task.DataRetention, ctx, managerConfig.DatabaseConfig.DataRetention)
+			DoWithJobDetails(task.DataRetention, ctx, managerConfig.DatabaseConfig.DataRetention, (*archive.Archiver)(nil))
 		Expect(err).To(Succeed())
 
 		globalScheduler := cronjob.NewGlobalHubScheduler(scheduler,
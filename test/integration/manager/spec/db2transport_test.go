@@ -52,6 +52,22 @@ func (s *agentSyncer) Sync(ctx context.Context, payload []byte) error {
 	return nil
 }
 
+// notifiedAgentSyncer receives the bundles with the expected content
+type notifiedAgentSyncer struct {
+	expected string
+	received chan struct{}
+}
+
+func (s *notifiedAgentSyncer) Sync(ctx context.Context, payload []byte) error {
+	if strings.Contains(string(payload), s.expected) {
+		select {
+		case s.received <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
 // go test ./test/integration/manager/spec -v -ginkgo.focus "Database to Transport Syncer"
 var _ = Describe("Database to Transport Syncer", Ordered, func() {
 	var db *gorm.DB
@@ -67,6 +83,13 @@ var _ = Describe("Database to Transport Syncer", Ordered, func() {
 			agentDispatcher.RegisterSyncer(eventType, newGenericAgentSyncer(eventType))
 		}
 
+		By("ManagedHub")
+		// the spec bundles are delivered to the hubs with the heartbeats
+		Expect(db.Create(&models.LeafHubHeartbeat{
+			Name:         leafhubName,
+			LastUpdateAt: time.Now(),
+		}).Error).ToNot(HaveOccurred())
+
 		By("ManagedClusterLabels")
 		labelPayload, err := json.Marshal(labelsToAdd)
 		Expect(err).Should(Succeed())
@@ -134,6 +157,18 @@ var _ = Describe("Database to Transport Syncer", Ordered, func() {
 		}, 30*time.Second, 1*time.Second).Should(Succeed())
 	})
 
+	It("should sync the changed labels to the hub on the notification of the table", func() {
+		// the labels syncer only polls the table as the safety net, so the labels are synced by the notification
+		received := make(chan struct{}, 1)
+		agentDispatcher.RegisterSyncer("ManagedClustersLabels", &notifiedAgentSyncer{
+			expected: "notified-label",
+			received: received,
+		})
+		Expect(db.Model(&models.ManagedClusterLabel{}).Where("id = ?", managedclusterUID).Updates(
+			&models.ManagedClusterLabel{Labels: []byte(`{"notified-label":"true"}`), Version: 1}).Error).To(Succeed())
+		Eventually(received, 3*time.Second).Should(Receive())
+	})
+
 	// It("Test managed cluster labels syncer", func() {
 	// 	Eventually(func() error {
 	// 		var managedClusterLabel models.ManagedClusterLabel
@@ -0,0 +1,62 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	eventversion "github.com/stolostron/multicluster-global-hub/pkg/bundle/version"
+	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/models"
+	"github.com/stolostron/multicluster-global-hub/pkg/enum"
+	wiremodels "github.com/stolostron/multicluster-global-hub/pkg/wire/models"
+)
+
+// go test ./test/integration/manager/status -v -ginkgo.focus "SpecDriftHandler"
+var _ = Describe("SpecDriftHandler", Ordered, func() {
+	const leafHubName = "hub1"
+
+	var (
+		version        = eventversion.NewVersion()
+		statusTopicCtx context.Context
+	)
+	BeforeAll(func() {
+		statusTopicCtx = cecontext.WithTopic(ctx, "event")
+	})
+
+	It("Should be able to sync the drifts of the global resources", func() {
+		By("Create event")
+		drifts := wiremodels.SpecDriftEvents{
+			{
+				UID: "global-policy1", APIVersion: "policy.open-cluster-management.io/v1", Kind: "Policy",
+				Namespace: "default", Name: "policy1", DriftType: wiremodels.SpecDriftModified,
+				Mode: wiremodels.DriftModeEnforce, Action: wiremodels.SpecDriftReverted, Fields: []string{"spec"},
+				CreatedAt: time.Now(),
+			},
+		}
+		version.Incr()
+		event := ToCloudEvent(leafHubName, string(enum.SpecDriftType), version, drifts)
+
+		By("Sync event with transport")
+		Expect(producer.SendEvent(statusTopicCtx, *event)).To(Succeed())
+		version.Next()
+
+		By("Check the table")
+		Eventually(func() error {
+			drift := &models.SpecDriftEvent{}
+			if err := database.GetGorm().Where("leaf_hub_name = ? AND name = ?", leafHubName, "policy1").
+				First(drift).Error; err != nil {
+				return err
+			}
+			if drift.UID != "global-policy1" || drift.Action != wiremodels.SpecDriftReverted ||
+				string(drift.Fields) != `["spec"]` {
+				return fmt.Errorf("unexpected drift %+v", drift)
+			}
+			return nil
+		}, 30*time.Second, 100*time.Millisecond).Should(Succeed())
+	})
+})
@@ -50,7 +50,7 @@ var _ = Describe("deploy default addon", func() {
 			}, work)
 		}, timeout, interval).ShouldNot(HaveOccurred())
 
-		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(8))
+		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(9))
 	})
 
 	It("Should create default addon with OCP label", func() {
@@ -87,7 +87,7 @@ var _ = Describe("deploy default addon", func() {
 			}, work)
 		}, timeout, interval).ShouldNot(HaveOccurred())
 
-		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(8))
+		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(9))
 	})
 
 	It("Should create default addon and ACM", func() {
@@ -131,7 +131,7 @@ var _ = Describe("deploy default addon", func() {
 		}, timeout, interval).ShouldNot(HaveOccurred())
 
 		// contains both the ACM and the Global Hub manifests
-		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(17))
+		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(18))
 	})
 
 	It("Should create agent for the local-cluster", func() {
@@ -176,7 +176,7 @@ var _ = Describe("deploy default addon", func() {
 			}, work)
 		}, timeout, interval).ShouldNot(HaveOccurred())
 
-		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(8))
+		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(9))
 
 		By("set InstallAgentOnLocal to false as a default value")
 		Eventually(func() bool {
@@ -65,7 +65,7 @@ var _ = Describe("deploy hosted addon", func() {
 			}, work)
 		}, timeout, interval).ShouldNot(HaveOccurred())
 
-		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(2))
+		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(3))
 		hostingWork := &workv1.ManifestWork{}
 		Eventually(func() error {
 			return runtimeClient.Get(ctx, types.NamespacedName{
@@ -131,7 +131,7 @@ var _ = Describe("deploy hosted addon", func() {
 			}, work)
 		}, timeout, interval).ShouldNot(HaveOccurred())
 
-		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(11))
+		Expect(len(work.Spec.Workload.Manifests)).Should(Equal(12))
 		hostingWork := &workv1.ManifestWork{}
 		Eventually(func() error {
 			return runtimeClient.Get(ctx, types.NamespacedName{
@@ -0,0 +1,148 @@
+package testminio
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+const (
+	accessKeyID     = "minio-test"
+	secretAccessKey = "minio-test-secret"
+	// MINIO_BINARY is the path of the minio server, it's downloaded to the cache directory if it isn't set and the
+	// minio isn't found in the PATH
+	binaryEnv   = "MINIO_BINARY"
+	downloadURL = "https://dl.min.io/server/minio/release/%s-%s/minio"
+)
+
+// TestMinio is a MinIO server running as a child process, which serves the S3 API on the Endpoint with plain HTTP
+type TestMinio struct {
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	command         *exec.Cmd
+	dataPath        string
+}
+
+func NewTestMinio() (*TestMinio, error) {
+	binary, err := minioBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	port := rand.Intn(65535-1024) + 1024
+	for !isPortAvailable(port) {
+		port = rand.Intn(65535-1024) + 1024
+	}
+	dataPath, err := os.MkdirTemp("", "minio-")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &TestMinio{
+		Endpoint:        fmt.Sprintf("127.0.0.1:%d", port),
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		dataPath:        dataPath,
+	}
+	m.command = exec.Command(binary, "server", dataPath, "--address", m.Endpoint, "--quiet") // #nosec G204
+	m.command.Env = append(os.Environ(), "MINIO_ROOT_USER="+accessKeyID, "MINIO_ROOT_PASSWORD="+secretAccessKey)
+	m.command.Stdout = os.Stdout
+	m.command.Stderr = os.Stderr
+	if err := m.command.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start minio: %w", err)
+	}
+
+	// wait until the server is ready
+	healthURL := fmt.Sprintf("http://%s/minio/health/live", m.Endpoint)
+	for i := 0; i < 60; i++ {
+		resp, err := http.Get(healthURL) // #nosec G107
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return m, nil
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	_ = m.Stop()
+	return nil, fmt.Errorf("minio isn't ready on %s", m.Endpoint)
+}
+
+func (m *TestMinio) Stop() error {
+	if m.command != nil && m.command.Process != nil {
+		if err := m.command.Process.Signal(syscall.SIGTERM); err != nil {
+			return fmt.Errorf("failed to terminate minio: %w", err)
+		}
+		_ = m.command.Wait()
+	}
+	return os.RemoveAll(m.dataPath)
+}
+
+func minioBinary() (string, error) {
+	if binary := os.Getenv(binaryEnv); binary != "" {
+		return binary, nil
+	}
+	if binary, err := exec.LookPath("minio"); err == nil {
+		return binary, nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	binary := filepath.Join(cacheDir, "multicluster-global-hub", "minio")
+	if _, err := os.Stat(binary); err == nil {
+		return binary, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(binary), 0o755); err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf(downloadURL, runtime.GOOS, runtime.GOARCH)
+	resp, err := http.Get(url) // #nosec G107
+	if err != nil {
+		return "", fmt.Errorf("failed to download minio from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download minio from %s: %s", url, resp.Status)
+	}
+	// download to a temporary file first, so an interrupted download isn't cached
+	file, err := os.CreateTemp(filepath.Dir(binary), "minio-download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to download minio from %s: %w", url, err)
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(file.Name(), 0o755); err != nil { // #nosec G302
+		return "", err
+	}
+	if err := os.Rename(file.Name(), binary); err != nil {
+		return "", err
+	}
+	return binary, nil
+}
+
+func isPortAvailable(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+	_ = listener.Close()
+	return true
+}
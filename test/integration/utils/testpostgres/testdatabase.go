@@ -1,6 +1,7 @@
 package testpostgres
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,9 +9,21 @@ import (
 	"strings"
 
 	"github.com/stolostron/multicluster-global-hub/pkg/database"
+	"github.com/stolostron/multicluster-global-hub/pkg/database/migration"
 )
 
+// InitDatabase initializes the database as the operator does with the global resources enabled
 func InitDatabase(uri string) error {
+	return initDatabase(uri, true)
+}
+
+// InitDatabaseWithoutGlobalResource initializes the database as the operator does by default, the database.old isn't
+// applied
+func InitDatabaseWithoutGlobalResource(uri string) error {
+	return initDatabase(uri, false)
+}
+
+func initDatabase(uri string, enableGlobalResource bool) error {
 	err := database.InitGormInstance(&database.DatabaseConfig{
 		URL:      uri,
 		Dialect:  database.PostgresDialect,
@@ -20,62 +33,44 @@ func InitDatabase(uri string) error {
 		return err
 	}
 
-	db := database.GetGorm()
-
 	_, currentFile, _, ok := runtime.Caller(0)
 	if !ok {
 		return fmt.Errorf("failed to get current dir: no caller information")
 	}
 	dirname := strings.Replace(currentFile, "test/integration/utils/testpostgres/testdatabase.go", "", 1)
 
-	sqlDir := filepath.Join(dirname, "operator", "pkg", "controllers", "storage", "database")
-	files, err := os.ReadDir(sqlDir)
-	if err != nil {
+	if err := execSQLDir(filepath.Join(dirname, "operator", "pkg", "controllers", "storage", "database")); err != nil {
 		return err
 	}
-
-	for _, file := range files {
-		filePath := filepath.Join(sqlDir, file.Name())
-		if file.Name() == "5.privileges.sql" {
-			continue
-		}
-		fileContent, err := os.ReadFile(filePath)
+	if enableGlobalResource {
+		err := execSQLDir(filepath.Join(dirname, "operator", "pkg", "controllers", "storage", "database.old"))
 		if err != nil {
 			return err
 		}
-
-		result := db.Exec(string(fileContent))
-		if result.Error != nil {
-			return result.Error
-		}
-		fmt.Printf("script %s executed successfully.\n", file.Name())
 	}
 
-	sqlDir = filepath.Join(dirname, "operator", "pkg", "controllers", "storage", "upgrade")
-	upgradeFiles, err := os.ReadDir(sqlDir)
+	// the migrations are applied after the database.old as the operator does, some of them depend on the global
+	// resources
+	migrator, err := migration.NewMigrator(database.GetSqlDb())
 	if err != nil {
 		return err
 	}
-	for _, file := range upgradeFiles {
-		filePath := filepath.Join(sqlDir, file.Name())
-		fileContent, err := os.ReadFile(filePath)
-		if err != nil {
-			return err
-		}
-
-		result := db.Exec(string(fileContent))
-		if result.Error != nil {
-			return result.Error
-		}
-		fmt.Printf("script %s executed successfully.\n", file.Name())
+	migrations, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		fmt.Printf("migration %d_%s applied successfully.\n", m.Version, m.Name)
 	}
+	return nil
+}
 
-	sqlDir = filepath.Join(dirname, "operator", "pkg", "controllers", "storage", "database.old")
-	oldfiles, err := os.ReadDir(sqlDir)
+func execSQLDir(sqlDir string) error {
+	files, err := os.ReadDir(sqlDir)
 	if err != nil {
 		return err
 	}
-	for _, file := range oldfiles {
+	for _, file := range files {
 		filePath := filepath.Join(sqlDir, file.Name())
 		if file.Name() == "5.privileges.sql" {
 			continue
@@ -85,7 +80,7 @@ func InitDatabase(uri string) error {
 			return err
 		}
 
-		result := db.Exec(string(fileContent))
+		result := database.GetGorm().Exec(string(fileContent))
 		if result.Error != nil {
 			return result.Error
 		}